@@ -193,7 +193,8 @@ func processoql(client *client.Client, q string) (any, error) {
 	case oql.CreateOp:
 		return nil, client.Create(&v)
 	case oql.InsertOp:
-		return nil, client.Insert(&v)
+		_, err := client.Insert(&v)
+		return nil, err
 	case oql.SelectOp:
 		return client.Select(&v)
 	case oql.DeleteOp:
@@ -15,6 +15,7 @@ import (
 
 	storage "github.com/nagarajRPoojari/orange/parrot"
 	"github.com/nagarajRPoojari/orange/parrot/conf"
+	"github.com/nagarajRPoojari/orange/parrot/io"
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 
 	"github.com/nagarajRPoojari/orange/parrot/memtable"
@@ -30,7 +31,24 @@ const MILLION = 10_00_000
 //   - sst/memtable size is set to 2MB
 //   - WAL is disabled
 //   - cache & manifest sync() are enabled
+//   - tables are mapped with mmap
 func BenchmarkParrot_Read(b *testing.B) {
+	benchmarkRead(b, "parrot/read", "benchmark-parrot-read.json", io.ReadOpts{})
+}
+
+// BenchmarkParrot_Read_Pread benchmarks same reads as BenchmarkParrot_Read,
+// with entries read by pread into pooled buffers
+func BenchmarkParrot_Read_Pread(b *testing.B) {
+	benchmarkRead(b, "parrot/read with pread", "benchmark-parrot-read-pread.json", io.ReadOpts{Mode: io.ReadPread})
+}
+
+// BenchmarkParrot_Read_Direct benchmarks same reads as BenchmarkParrot_Read,
+// with entries read by pread bypassing page cache
+func BenchmarkParrot_Read_Direct(b *testing.B) {
+	benchmarkRead(b, "parrot/read with O_DIRECT", "benchmark-parrot-read-direct.json", io.ReadOpts{Mode: io.ReadPread, Direct: true})
+}
+
+func benchmarkRead(b *testing.B, name string, report string, read io.ReadOpts) {
 	log.Disable()
 
 	dbName := "test"
@@ -58,8 +76,11 @@ func BenchmarkParrot_Read(b *testing.B) {
 			CompactionWALWriterBufferSize: conf.DefaultWriterBufferSize,
 			Level0MaxSizeInBytes:          1024 * 2,
 			MaxSizeInBytesGrowthFactor:    2,
+			TableRead:                     read,
 		},
 	)
+	// close waits for running compaction, before temp dir is removed
+	b.Cleanup(db.Close)
 	for i := range b.N {
 		db.Put(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
@@ -87,7 +108,7 @@ func BenchmarkParrot_Read(b *testing.B) {
 	dataTransfered := float64(b.N * payloadSize / (1024 * 1024))
 
 	BenchmarkReport{
-		Name:                      "parrot/read",
+		Name:                      name,
 		TotalOps:                  b.N,
 		PayloadSizeInBytes:        payloadSize, // 16 bytes
 		TotalDataTransferredInMB:  dataTransfered,
@@ -95,7 +116,7 @@ func BenchmarkParrot_Read(b *testing.B) {
 		OpsPerSec:                 opsPerSec,
 		DataTransferredInMBPerSec: dataTransfered / elapsed.Seconds(),
 		AverageLatencyInMicroSec:  avgLatencyMs,
-	}.Dump("benchmark-parrot-read.json")
+	}.Dump(report)
 	dumpGoroutines()
 }
 
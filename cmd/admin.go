@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/nagarajRPoojari/orange/net/client"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminDocument string
+	adminWait     bool
+	// rewrites run in background by default, unlike flushes
+	adminRewriteWait bool
+	adminStart       int64
+	adminEnd         int64
+)
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminFlushCmd, adminCompactCmd, adminRewriteCmd)
+
+	adminCmd.PersistentFlags().StringVarP(&Port, "port", "p", "8080", "Server port to connect to")
+	adminCmd.PersistentFlags().StringVarP(&Address, "address", "a", "127.0.0.1", "Server address to connect to")
+	adminCmd.PersistentFlags().StringVar(&adminDocument, "document", "", "Collection to operate on")
+
+	adminFlushCmd.Flags().BoolVar(&adminWait, "wait", true, "Return once memtables are written to disk")
+	adminRewriteCmd.Flags().BoolVar(&adminRewriteWait, "wait", false, "Return once every document is rewritten")
+	adminCompactCmd.Flags().Int64Var(&adminStart, "start", math.MinInt64, "First _ID of range")
+	adminCompactCmd.Flags().Int64Var(&adminEnd, "end", math.MaxInt64, "_ID range ends before")
+}
+
+// adminCmd groups maintenance operations run by a live server
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Run maintenance operations on a running server",
+}
+
+var adminFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Write memtables to disk without waiting for them to fill up",
+	Long: `Write active memtable of --document to disk without waiting for it to fill
+up, or of every collection if --document isn't set.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if err := adminClient().Flush(adminDocument, adminWait); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("👍")
+	},
+}
+
+var adminCompactCmd = &cobra.Command{
+	Use:   "compact --document <name>",
+	Short: "Compact tables holding an _ID of [start, end)",
+	Long: `Compact every level holding an _ID of [--start, --end) of --document down to
+the last level, dropping overwritten & deleted documents. Whole collection is
+compacted by default.
+
+Documents still in memtables aren't touched, run admin flush first.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if adminDocument == "" {
+			fmt.Fprintln(os.Stderr, "--document is required")
+			os.Exit(1)
+		}
+		if err := adminClient().CompactRange(adminDocument, adminStart, adminEnd); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("👍")
+	},
+}
+
+var adminRewriteCmd = &cobra.Command{
+	Use:   "rewrite --document <name>",
+	Short: "Rewrite documents stored with an older schema",
+	Long: `Rewrite documents of --document stored with a schema older than its current
+one, see ALTER DOCUMENT. Such documents are upgraded as they're read anyway,
+rewriting them saves upgrading them on every read.
+
+Documents are rewritten in background unless --wait is set.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if adminDocument == "" {
+			fmt.Fprintln(os.Stderr, "--document is required")
+			os.Exit(1)
+		}
+		if err := adminClient().Rewrite(adminDocument, adminRewriteWait); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("👍")
+	},
+}
+
+func adminClient() *client.Client {
+	p, _ := strconv.ParseInt(Port, 10, 0)
+	return client.NewClient(Address, p)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nagarajRPoojari/orange/internal/config"
+	odb "github.com/nagarajRPoojari/orange/internal/db"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbCheckCmd, dbRepairCmd)
+}
+
+// dbCmd groups offline tools working on a whole data directory
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Check & repair a data directory",
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check [dir]",
+	Short: "Cross-check manifests against table files & verify every table decodes",
+	Long: `Cross-check manifest of every collection against its level-N directories &
+verify that every table decodes. Nothing is changed, exits with 1 if any issue
+is found.
+
+dir defaults to data directory of config.toml in working directory, server
+must not be running.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		report, err := storage.Check[types.ID, *odb.InternalValueType](dataDir(args), keyring)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		printCheckReport(report, "Repair would")
+		if len(report.Issues) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var dbRepairCmd = &cobra.Command{
+	Use:   "repair [dir]",
+	Short: "Fix issues found by check",
+	Long: `Fix issues found by check:
+  - corrupt tables are moved to <dir>/quarantine & dropped from manifest
+  - tables missing on disk are dropped from manifest
+  - table files no manifest refers to are removed
+  - a missing or unreadable manifest is rebuilt from valid tables found
+
+dir defaults to data directory of config.toml in working directory, server
+must not be running.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		report, err := storage.Repair[types.ID, *odb.InternalValueType](dataDir(args), keyring)
+		if report != nil {
+			printCheckReport(report, "Repair")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dataDir returns directory given as argument, or the configured one
+func dataDir(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return config.GetConfig().Directory
+}
+
+func printCheckReport(report *storage.CheckReport, fixHeader string) {
+	fmt.Printf("%d collections, %d valid tables, %d issues\n", len(report.Families), report.Tables, len(report.Issues))
+	if len(report.Issues) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\nCollection\tPath\tProblem\t%s\n", fixHeader)
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Family, issue.Path, issue.Problem, issue.Fix)
+	}
+	w.Flush()
+}
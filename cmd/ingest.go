@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nagarajRPoojari/orange/internal/config"
+	odb "github.com/nagarajRPoojari/orange/internal/db"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/pkg/oql"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(ingestCmd)
+}
+
+// ingestCmd represents the ingest command
+var ingestCmd = &cobra.Command{
+	Use:   "ingest <document> <file>...",
+	Short: "Bulk load table files into a collection",
+	Long: `Bulk load table files into a collection, bypassing WAL, memtables & compaction.
+
+Files ending in .jsonl hold one document per line, they're built into tables
+first. Any other file is taken as a table built by sstable.Writer, its index
+is expected next to it with .index extension.
+
+Data directory is taken from config.toml of working directory, server must
+not be running.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if err := ingest(args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func ingest(document string, files []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := odb.NewOrangedb(ctx, config.GetConfig())
+	defer db.Close()
+
+	tmp, err := os.MkdirTemp("", "orange-ingest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	paths := make([]string, 0, len(files))
+	for i, file := range files {
+		if filepath.Ext(file) != ".jsonl" {
+			paths = append(paths, file)
+			continue
+		}
+
+		docs, err := readDocuments(file)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(tmp, fmt.Sprintf("%d.db", i))
+		if err := db.BuildTable(document, path, docs); err != nil {
+			return fmt.Errorf("failed to build table from %s: %w", file, err)
+		}
+		paths = append(paths, path)
+	}
+
+	if err := db.IngestFiles(document, paths); err != nil {
+		return err
+	}
+	fmt.Printf("ingested %d files into %s\n", len(paths), document)
+	return nil
+}
+
+// readDocuments reads one json document per line, blank lines are skipped
+func readDocuments(file string) ([]oql.Value, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var docs []oql.Value
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var doc oql.Value
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, scanner.Err()
+}
@@ -50,8 +50,14 @@ func processoql(client *client.Client, q string) (any, error) {
 	switch v := op.(type) {
 	case oql.CreateOp:
 		return nil, client.Create(&v)
+	case oql.AlterOp:
+		return nil, client.Alter(&v)
 	case oql.InsertOp:
-		return nil, client.Insert(&v)
+		id, err := client.Insert(&v)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf(`{"_ID": %d}`, id)), nil
 	case oql.SelectOp:
 		return client.Select(&v)
 	case oql.DeleteOp:
@@ -24,13 +24,33 @@ package cmd
 import (
 	"os"
 
+	"github.com/nagarajRPoojari/orange/parrot/io"
 	"github.com/spf13/cobra"
 )
 
+// keyfile of encrypted data directory, read by offline tools
+var keyfile string
+
+// keyring loaded from keyfile, nil if none is given
+var keyring *io.Keyring
+
+// diskFS returns files on disk, decrypted with keyring
+func diskFS() io.VFS {
+	return io.WithKeyring(io.OS, keyring)
+}
+
 // RootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "orange",
 	Short: "NoSQL database",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if keyfile == "" {
+			return nil
+		}
+		var err error
+		keyring, err = io.LoadKeyring(keyfile)
+		return err
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -52,4 +72,6 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	rootCmd.PersistentFlags().StringVar(&keyfile, "keyfile", "", "Keyfile to read encrypted files with, see encryption.keyfile of config")
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	odb "github.com/nagarajRPoojari/orange/internal/db"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/sstable"
+	ptypes "github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/spf13/cobra"
+)
+
+var sstDumpLimit int
+
+func init() {
+	rootCmd.AddCommand(sstCmd)
+	sstCmd.AddCommand(sstDumpCmd, sstVerifyCmd, sstStatsCmd)
+
+	sstDumpCmd.Flags().IntVarP(&sstDumpLimit, "limit", "n", 0, "Maximum number of entries to print, 0 prints all")
+}
+
+// sstCmd groups tools inspecting table files of a collection, tables are
+// given by path of sst-*.db file, index is expected next to it
+var sstCmd = &cobra.Command{
+	Use:   "sst",
+	Short: "Inspect table files",
+}
+
+var sstDumpCmd = &cobra.Command{
+	Use:   "dump <sst-file>",
+	Short: "Print entries & range tombstones of a table as json, one per line",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if err := dumpTable(args[0], sstDumpLimit); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+var sstVerifyCmd = &cobra.Command{
+	Use:   "verify <sst-file>...",
+	Short: "Check that tables decode & index matches data file",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		failed := false
+		for _, path := range args {
+			s, err := verifyTable(path)
+			if err != nil {
+				failed = true
+				fmt.Printf("FAIL %s: %v\n", path, err)
+				continue
+			}
+			fmt.Printf("OK   %s: %d entries, %d range tombstones\n", path, s.Entries, s.RangeTombstones)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var sstStatsCmd = &cobra.Command{
+	Use:   "stats <sst-file>...",
+	Short: "Summarise key range, entry counts & tombstones of tables",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if err := printTableStats(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dumpedRange is json form of a range tombstone, it deletes _ID of
+// [start, end) for reads of time onwards
+type dumpedRange struct {
+	Start int64 `json:"rangeStart"`
+	End   int64 `json:"rangeEnd"`
+	Time  int64 `json:"time,omitempty"`
+}
+
+// dumpedEntry is json form of a table entry
+type dumpedEntry struct {
+	ID      int64            `json:"_ID"`
+	Deleted bool             `json:"deleted,omitempty"`
+	Operand bool             `json:"operand,omitempty"`
+	Ref     *ptypes.ValueRef `json:"ref,omitempty"`
+	Value   map[string]any   `json:"value,omitempty"`
+}
+
+func dumpTable(path string, limit int) error {
+	table, err := sstable.Open[types.ID, *odb.InternalValueType](diskFS(), path, nil)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	count := 0
+	var encErr error
+	err = table.Each(func(pl ptypes.Payload[types.ID, *odb.InternalValueType]) bool {
+		entry := dumpedEntry{ID: pl.Key.K, Ref: pl.Ref}
+		if pl.Ref == nil {
+			entry.Deleted = pl.Val.IsDeleted()
+			entry.Operand = ptypes.IsOperand(pl.Val)
+			entry.Value = pl.Val.Payload
+		}
+		if encErr = enc.Encode(entry); encErr != nil {
+			return false
+		}
+		count++
+		return limit <= 0 || count < limit
+	})
+	if err != nil {
+		return err
+	}
+	if encErr != nil {
+		return encErr
+	}
+
+	for _, r := range table.RangeTombstones() {
+		if err := enc.Encode(dumpedRange{Start: r.Start.K, End: r.End.K, Time: r.Time}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyTable(path string) (sstable.Summary[types.ID], error) {
+	table, err := sstable.Open[types.ID, *odb.InternalValueType](diskFS(), path, nil)
+	if err != nil {
+		return sstable.Summary[types.ID]{}, err
+	}
+	return table.Verify()
+}
+
+func printTableStats(paths []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "Table\tCodec\tKey\tEntries\tTombstones\tRange Tombstones\tOperands\tRefs\tFirst _ID\tLast _ID\tSize(bytes)")
+
+	for _, path := range paths {
+		table, err := sstable.Open[types.ID, *odb.InternalValueType](diskFS(), path, nil)
+		if err != nil {
+			return err
+		}
+		s, err := table.Verify()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		keyID, err := io.GetFileManager().KeyID(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if keyID == "" {
+			keyID = "-"
+		}
+
+		codecName := "gob (legacy)"
+		if table.Codec != nil {
+			codecName = table.Codec.Name()
+		}
+		first, last := "-", "-"
+		if s.Entries > 0 {
+			first, last = fmt.Sprint(s.First.K), fmt.Sprint(s.Last.K)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%d\n",
+			path, codecName, keyID, s.Entries, s.Tombstones, s.RangeTombstones, s.Operands, s.Refs, first, last, s.SizeInBytes)
+	}
+	return w.Flush()
+}
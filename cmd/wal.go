@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	odb "github.com/nagarajRPoojari/orange/internal/db"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/compactor"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	walDumpKind     string
+	walReplayDryRun bool
+)
+
+func init() {
+	rootCmd.AddCommand(walCmd)
+	walCmd.AddCommand(walDumpCmd, walReplayCmd)
+
+	walDumpCmd.Flags().StringVar(&walDumpKind, "kind", "", "Log kind: commit, memtable or gc, guessed from file name if not set")
+	walReplayCmd.Flags().BoolVar(&walReplayDryRun, "dry-run", false, "Only list what would be replayed")
+}
+
+// walCmd groups tools inspecting write-ahead logs of a data directory
+var walCmd = &cobra.Command{
+	Use:   "wal",
+	Short: "Inspect write-ahead logs",
+}
+
+var walDumpCmd = &cobra.Command{
+	Use:   "dump <file>",
+	Short: "Print records of a log as json, one per line",
+	Long: `Print records of a log as json, one per line.
+
+Log kind is guessed from file name unless --kind is set:
+  log-<id>.log    commit log segment, one line per entry of a record
+  gc-wal.log      compaction log
+  anything else   memtable log`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if err := dumpLog(args[0], walDumpKind); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+var walReplayCmd = &cobra.Command{
+	Use:   "replay --dry-run <dir>",
+	Short: "List entries replaying logs of dir would apply, in order",
+	Long: `List entries replaying logs of dir would apply, in order.
+
+dir is either commit log directory (<data>/log) or a memtable log directory.
+Entries of collections already flushed past them are listed as skipped, as
+are records failing to decode & everything after them in same file.
+
+Logs are replayed when server starts, so only --dry-run is supported.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		if !walReplayDryRun {
+			fmt.Fprintln(os.Stderr, "only --dry-run is supported, logs are replayed when server starts")
+			os.Exit(1)
+		}
+		if err := planReplay(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dumpedEvent is json form of a log record, or an entry of it
+type dumpedEvent struct {
+	Offset int64  `json:"offset"`
+	Op     string `json:"op,omitempty"`
+
+	// commit & memtable logs
+	Family string         `json:"family,omitempty"`
+	ID     *int64         `json:"_ID,omitempty"`
+	End    *int64         `json:"end,omitempty"`
+	Value  map[string]any `json:"value,omitempty"`
+
+	// compaction log
+	Path string `json:"path,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func newDumpedEvent(offset int64, family string, event memtable.MemTableEvent[types.ID, *odb.InternalValueType]) dumpedEvent {
+	id := event.Key.K
+	d := dumpedEvent{Offset: offset, Op: string(event.Op), Family: family, ID: &id}
+	if event.Op == memtable.DeleteRangeOperation {
+		end := event.End.K
+		d.End = &end
+	}
+	if event.Value != nil {
+		d.Value = event.Value.Payload
+	}
+	return d
+}
+
+func dumpLog(path string, kind string) error {
+	if kind == "" {
+		kind = guessLogKind(path)
+	}
+
+	var events []dumpedEvent
+	switch kind {
+	case "commit":
+		records, err := storage.InspectCommitLog[types.ID, *odb.InternalValueType](diskFS(), path)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.Err != nil {
+				events = append(events, dumpedEvent{Offset: record.Offset, Error: record.Err.Error()})
+				continue
+			}
+			for _, entry := range record.Event {
+				events = append(events, newDumpedEvent(record.Offset, entry.Family, entry.Event))
+			}
+		}
+	case "memtable":
+		records, err := memtable.InspectLog[types.ID, *odb.InternalValueType](diskFS(), path)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.Err != nil {
+				events = append(events, dumpedEvent{Offset: record.Offset, Error: record.Err.Error()})
+				continue
+			}
+			events = append(events, newDumpedEvent(record.Offset, "", record.Event))
+		}
+	case "gc":
+		records, err := wal.Inspect(diskFS(), path, wal.GobResolver[compactor.Event])
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			d := dumpedEvent{Offset: record.Offset, Op: string(record.Event.Op), Path: record.Event.Path}
+			if record.Err != nil {
+				d.Error = record.Err.Error()
+			}
+			events = append(events, d)
+		}
+	default:
+		return fmt.Errorf("unknown log kind %q, expected commit, memtable or gc", kind)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guessLogKind tells kind of log from its file name, see walDumpCmd
+func guessLogKind(path string) string {
+	base := filepath.Base(path)
+	switch {
+	case strings.HasPrefix(base, "log-"):
+		return "commit"
+	case base == "gc-wal.log":
+		return "gc"
+	default:
+		return "memtable"
+	}
+}
+
+func planReplay(dir string) error {
+	steps, err := storage.PlanRecovery[types.ID, *odb.InternalValueType](dir, keyring)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tLog\tRecord\tCollection\tOp\t_ID\tResult")
+
+	applied, skipped, failed := 0, 0, 0
+	for i, step := range steps {
+		op, id, result := "-", "-", "apply"
+		if step.Err == nil {
+			op, id = string(step.Event.Op), fmt.Sprint(step.Event.Key.K)
+			if step.Event.Op == memtable.DeleteRangeOperation {
+				id = fmt.Sprintf("[%d, %d)", step.Event.Key.K, step.Event.End.K)
+			}
+		}
+		switch {
+		case step.Err != nil:
+			failed++
+			result = fmt.Sprintf("error: %v", step.Err)
+		case step.Skip != "":
+			skipped++
+			result = "skip: " + step.Skip
+		default:
+			applied++
+		}
+
+		family := step.Family
+		if family == "" {
+			family = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n", i+1, filepath.Base(step.Path), step.Pos.Index, family, op, id, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d entries would be applied, %d skipped, %d records fail to decode\n", applied, skipped, failed)
+	return nil
+}
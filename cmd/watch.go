@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/net/client"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	pb "github.com/nagarajRPoojari/orange/pkg/proto/ops"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var watchFrom string
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&Port, "port", "p", "8080", "Server port to connect to")
+	watchCmd.Flags().StringVarP(&Address, "address", "a", "127.0.0.1", "Server address to connect to")
+	watchCmd.Flags().StringVar(&watchFrom, "from", "", "Position to resume after, changes committed from now on if empty")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <document>",
+	Short: "Tail inserts & deletes of a document",
+	Long: `Print every insert & delete of document in commit order as they're committed,
+one per line: position, commit time, op, _ID (or _ID range) & inserted document.
+
+Pass position of last change seen as --from to resume after it, "0.0.0" starts
+at oldest change kept in commit log. Watch reconnects & resumes on its own if
+server goes away.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Disable()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		p, _ := strconv.ParseInt(Port, 10, 0)
+		cl := client.NewClient(Address, p)
+
+		from := watchFrom
+		for {
+			err := cl.Subscribe(ctx, args[0], from, func(res *pb.SubscribeRes) error {
+				printChange(res)
+				from = res.Position
+				return nil
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if status.Code(err) != codes.Unavailable {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			time.Sleep(time.Second)
+		}
+	},
+}
+
+func printChange(res *pb.SubscribeRes) {
+	at := "-"
+	if res.Time != 0 {
+		at = time.Unix(0, res.Time).UTC().Format(time.RFC3339Nano)
+	}
+	id := strconv.FormatInt(res.Id, 10)
+	if res.Op == "delete_range" {
+		id = fmt.Sprintf("%d..%d", res.Id, res.EndId)
+	}
+	fmt.Printf("%s\t%s\t%s\t%s\t%s\n", res.Position, at, res.Op, id, res.Data)
+}
@@ -10,6 +10,18 @@ import (
 type Config struct {
 	Directory string `mapstructure:"directory"`
 
+	// codec of stored documents: "document" (default) or "gob"
+	Codec string `mapstructure:"codec"`
+
+	// window old versions of documents are kept for, so they can be read
+	// with SELECT ... AS OF. turned off if 0, AS OF fails then & for times
+	// older than window
+	Retention time.Duration `mapstructure:"retention"`
+
+	// id of node among ones generating snowflake _IDs of same collections,
+	// 0 to 1023. two nodes sharing an id may generate same _IDs
+	NodeID int64 `mapstructure:"node_id"`
+
 	Memtable struct {
 		Threshold           int           `mapstructure:"threshold"`
 		QueueHardLimit      int           `mapstructure:"queue_hard_limit"`
@@ -19,6 +31,13 @@ type Config struct {
 		WALTimeInterval     time.Duration `mapstructure:"wal_time_interval"`
 		WALEventChSize      int32         `mapstructure:"wal_event_ch_size"`
 		WALWriterBufferSize int           `mapstructure:"wal_writer_buffer_size"`
+		// budget of memtables of all collections, largest one is flushed
+		// once they hold more. turned off if 0
+		WriteBufferSize int64 `mapstructure:"write_buffer_size"`
+		// window commit log is kept for after it's flushed, so watchers
+		// can resume reading changes from it, resuming from older positions
+		// fails. dropped once flushed if 0
+		ChangeRetention time.Duration `mapstructure:"change_retention"`
 	} `mapstructure:"memtable"`
 
 	Compaction struct {
@@ -29,7 +48,31 @@ type Config struct {
 		WALTimeInterval            time.Duration `mapstructure:"wal_time_interval"`
 		WALEventChSize             int32         `mapstructure:"wal_event_ch_size"`
 		WALWriterBufferSize        int           `mapstructure:"wal_writer_buffer_size"`
+		Workers                    int           `mapstructure:"workers"`
+		Subcompactions             int           `mapstructure:"subcompactions"`
 	} `mapstructure:"compaction"`
+
+	// tables, logs & manifests are encrypted with AES-GCM using keys of
+	// keyfile, last key of it encrypts new files. turned off if not set
+	Encryption struct {
+		Keyfile string `mapstructure:"keyfile"`
+	} `mapstructure:"encryption"`
+
+	// how tables are read: "mmap" (default) maps them into memory, "pread"
+	// reads documents into pooled buffers, bypassing page cache if
+	// direct_io is set
+	SSTable struct {
+		ReadMode string `mapstructure:"read_mode"`
+		DirectIO bool   `mapstructure:"direct_io"`
+	} `mapstructure:"sstable"`
+
+	// documents larger than threshold (in bytes) are kept in a value log &
+	// aren't rewritten by compaction, turned off if 0
+	ValueLog struct {
+		Threshold   int     `mapstructure:"threshold"`
+		SegmentSize int64   `mapstructure:"segment_size"`
+		GCRatio     float64 `mapstructure:"gc_ratio"`
+	} `mapstructure:"value_log"`
 }
 
 func init() {
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"maps"
+	"math"
+
+	"github.com/nagarajRPoojari/orange/internal/errors"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	ptypes "github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/pkg/oql"
+	"github.com/nagarajRPoojari/orange/pkg/schema"
+)
+
+// Documents are stored with version of schema they're inserted with, see
+// schema.VersionField. Altering schema doesn't touch stored documents,
+// they're upgraded to current schema as they're read & can be rewritten
+// with it in background by Rewrite.
+
+// AlterCollection adds, drops or renames a top level field of collection,
+// see schema.SchemaHandler.Alter. Writes to collection wait for it.
+func (t *Oragedb) AlterCollection(op oql.AlterOp) error {
+	if _, err := t.schemaHandler.LoadFromCatalog(op.Document); err != nil {
+		return err
+	}
+
+	mu := t.collectionLock(op.Document)
+	mu.Lock()
+	defer mu.Unlock()
+
+	version, err := t.schemaHandler.Alter(op.Document, schema.Alteration{
+		Action:  op.Action,
+		Field:   op.Field,
+		Type:    op.Type,
+		Default: op.Default,
+		To:      op.To,
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s altered to schema version=%d", op.Document, version)
+	return nil
+}
+
+// upgrade returns copy of stored document doc upgraded to current schema
+// of document, without its version. nil if doc is nil
+func (t *Oragedb) upgrade(document string, doc map[string]interface{}) (map[string]interface{}, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	// payload is shared with memtable, it's never changed in place
+	doc = maps.Clone(doc)
+
+	version := int64(1)
+	if v, ok := doc[schema.VersionField].(int64); ok {
+		version = v
+		delete(doc, schema.VersionField)
+	}
+	if err := t.schemaHandler.Upgrade(document, doc, int(version)); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// versioned returns payload doc verified with schema version is stored as
+func versioned(doc map[string]interface{}, version int) map[string]interface{} {
+	if version > 1 {
+		doc[schema.VersionField] = int64(version)
+	}
+	return doc
+}
+
+// Rewrite rewrites documents of collection stored with an older schema
+// with current one, so they no longer are upgraded as they're read. With
+// wait, it returns once every document is rewritten, otherwise documents
+// are rewritten in background.
+func (t *Oragedb) Rewrite(document string, wait bool) error {
+	if _, err := t.schemaHandler.LoadFromCatalog(document); err != nil {
+		return err
+	}
+	db, err := t.storage.Family(document)
+	if err != nil {
+		return errors.AdminError("failed to get db for " + document)
+	}
+
+	if wait {
+		return t.rewrite(db, document)
+	}
+	go func() {
+		if err := t.rewrite(db, document); err != nil {
+			log.Errorf("failed to rewrite %s, err=%v", document, err)
+		}
+	}()
+	return nil
+}
+
+// rewrite rewrites documents of collection in batches, writes to
+// collection wait while a batch is rewritten
+func (t *Oragedb) rewrite(db *storage.Family[types.ID, *InternalValueType], document string) error {
+	const rewriteBatch = 1024
+
+	rewritten := 0
+	r := ptypes.KeyRange[types.ID]{}
+	for {
+		if err := t.context.Err(); err != nil {
+			return err
+		}
+
+		done, last, n, err := t.rewriteBatch(db, document, r, rewriteBatch)
+		if err != nil {
+			return errors.AdminError("failed to rewrite %s, err=%v", document, err)
+		}
+		rewritten += n
+		if done || last.K == math.MaxInt64 {
+			break
+		}
+		r = ptypes.From(types.ID{K: last.K + 1})
+	}
+
+	log.Infof("rewrote %d documents of %s", rewritten, document)
+	return nil
+}
+
+// rewriteBatch rewrites documents stored with an older schema among first
+// size documents of r, returns last _ID scanned, number of documents
+// rewritten & whether r is scanned whole
+func (t *Oragedb) rewriteBatch(db *storage.Family[types.ID, *InternalValueType], document string, r ptypes.KeyRange[types.ID], size int) (bool, types.ID, int, error) {
+	mu := t.collectionLock(document)
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, version, err := t.schemaHandler.LoadVersion(document)
+	if err != nil {
+		return false, types.ID{}, 0, err
+	}
+
+	var ids []types.ID
+	var docs []map[string]interface{}
+	scanned := 0
+	var last types.ID
+	if err := db.Scan(r, func(id types.ID, doc *InternalValueType) bool {
+		if v, _ := doc.Payload[schema.VersionField].(int64); max(v, 1) < int64(version) {
+			ids, docs = append(ids, id), append(docs, doc.Payload)
+		}
+		scanned++
+		last = id
+		return scanned < size
+	}); err != nil {
+		return false, last, 0, err
+	}
+
+	batch := storage.NewWriteBatch[types.ID, *InternalValueType]()
+	for i, doc := range docs {
+		upgraded, err := t.upgrade(document, doc)
+		if err != nil {
+			return false, last, 0, err
+		}
+		batch.Put(document, ids[i], &InternalValueType{Payload: versioned(upgraded, version)})
+	}
+	if batch.Len() > 0 {
+		if err := t.storage.Write(batch).Err; err != nil {
+			return false, last, 0, err
+		}
+	}
+	return scanned < size, last, len(docs), nil
+}
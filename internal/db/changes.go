@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/internal/errors"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+)
+
+// ops of a Change
+const (
+	ChangeInsert      = "insert"
+	ChangeDelete      = "delete"
+	ChangeDeleteRange = "delete_range"
+)
+
+// Change is an insert or delete of documents of a collection, as it's
+// committed
+type Change struct {
+	// token watching resumes after, see Watch
+	Position string
+	// ChangeInsert, ChangeDelete or ChangeDeleteRange
+	Op string
+	ID int64
+	// last _ID of deleted range
+	EndID int64
+	// inserted document, nil for deletes
+	Doc map[string]interface{}
+	// commit time, zero for changes committed by older versions
+	Time time.Time
+}
+
+// Watch calls fn with every change of document committed after position
+// from, in commit order, until ctx is done or fn returns an error. Changes
+// committed from now on are watched if from is empty, see
+// storage.Storage.Subscribe
+func (t *Oragedb) Watch(ctx context.Context, document string, from string, fn func(Change) error) error {
+	if _, err := t.schemaHandler.LoadFromCatalog(document); err != nil {
+		return err
+	}
+
+	var pos storage.ChangePosition
+	var err error
+	if from == "" {
+		pos, err = t.storage.ChangeHead()
+	} else {
+		pos, err = storage.ParseChangePosition(from)
+	}
+	if err != nil {
+		return errors.WatchError("%v", err)
+	}
+
+	db, err := t.storage.Family(document)
+	if err != nil {
+		return errors.WatchError("failed to get db for " + document)
+	}
+
+	return db.Subscribe(ctx, pos, func(c storage.Change[types.ID, *InternalValueType]) error {
+		change := Change{Position: c.Position.String(), ID: c.Key.K}
+		// changes logged before write times were kept have none
+		if c.Time != 0 {
+			change.Time = time.Unix(0, c.Time)
+		}
+		switch c.Op {
+		case memtable.WriteOperation:
+			change.Op = ChangeInsert
+			// documents are upgraded to current schema, like GetDoc
+			var err error
+			if change.Doc, err = t.upgrade(document, c.Value.Payload); err != nil {
+				return err
+			}
+		case memtable.DeleteOperation:
+			change.Op = ChangeDelete
+		case memtable.DeleteRangeOperation:
+			// storage ranges exclude end
+			change.Op = ChangeDeleteRange
+			change.EndID = c.End.K - 1
+		default:
+			// merges aren't written by collections
+			return nil
+		}
+		return fn(change)
+	})
+}
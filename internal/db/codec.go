@@ -0,0 +1,378 @@
+package db
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/internal/errors"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+)
+
+// documentCodecID is recorded in headers of files written by DocumentCodec
+const documentCodecID byte = 2
+
+// DocumentCodec is a compact binary codec for collections. Ids are fixed
+// 8 byte keys & documents are lists of tagged fields, so neither gob type
+// descriptors nor gob registrations are involved.
+//
+//   - id: [prefix |] 8 byte big endian K, prefix is set for index entries
+//   - document: uvarint field count | (uvarint name size | name | value)...
+//   - value: tag | payload, see appendValue
+type DocumentCodec struct{}
+
+func (DocumentCodec) ID() byte { return documentCodecID }
+
+func (DocumentCodec) Name() string { return "orange.document" }
+
+func (DocumentCodec) AppendKey(buf []byte, key any) ([]byte, error) {
+	id, ok := key.(types.ID)
+	if !ok {
+		return nil, errors.CodecError("unexpected key %T", key)
+	}
+	buf = append(buf, id.Prefix...)
+	return binary.BigEndian.AppendUint64(buf, uint64(id.K)), nil
+}
+
+func (DocumentCodec) DecodeKey(data []byte, key any) error {
+	id, ok := key.(*types.ID)
+	if !ok {
+		return errors.CodecError("unexpected key %T", key)
+	}
+	if len(data) < 8 {
+		return errors.CodecError("malformed id of %d bytes", len(data))
+	}
+	id.Prefix = string(data[:len(data)-8])
+	id.K = int64(binary.BigEndian.Uint64(data[len(data)-8:]))
+	return nil
+}
+
+func (DocumentCodec) AppendValue(buf []byte, value any) ([]byte, error) {
+	doc, ok := value.(*InternalValueType)
+	if !ok {
+		return nil, errors.CodecError("unexpected value %T", value)
+	}
+	return appendDocument(buf, doc.Payload)
+}
+
+func (DocumentCodec) DecodeValue(data []byte, value any) error {
+	doc, ok := value.(**InternalValueType)
+	if !ok {
+		return errors.CodecError("unexpected value %T", value)
+	}
+
+	payload, rest, err := readDocument(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.CodecError("%d trailing bytes", len(rest))
+	}
+	*doc = &InternalValueType{Payload: payload}
+	return nil
+}
+
+func init() {
+	if err := codec.Register(DocumentCodec{}); err != nil {
+		panic(err)
+	}
+}
+
+// value tags
+const (
+	tagNil byte = iota
+	tagBool
+	tagString
+	tagBytes
+	tagInt
+	tagInt64
+	tagFloat64
+	tagTime
+	tagDocument
+	tagList
+	tagID
+	tagINT
+	tagINT64
+	tagINT32
+	tagINT16
+	tagINT8
+	tagFLOAT
+	tagFLOAT32
+	tagFLOAT64
+	tagBOOL
+	tagSTRING
+	tagDATE
+	tagTIME
+	tagDATETIME
+	tagTIMESTAMP
+	tagDECIMAL
+	tagBYTE
+)
+
+func appendDocument(buf []byte, doc map[string]interface{}) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(len(doc)))
+	for name, v := range doc {
+		buf = appendString(buf, name)
+
+		var err error
+		if buf, err = appendValue(buf, v); err != nil {
+			return nil, errors.CodecError("field %q: %v", name, err)
+		}
+	}
+	return buf, nil
+}
+
+// appendValue appends tagged value, integers are zigzag varints, floats
+// are 8 byte IEEE 754 bits & times are in their binary marshalled form
+func appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, tagNil), nil
+	case bool:
+		return appendBool(append(buf, tagBool), v), nil
+	case string:
+		return appendString(append(buf, tagString), v), nil
+	case []byte:
+		return appendString(append(buf, tagBytes), string(v)), nil
+	case int:
+		return binary.AppendVarint(append(buf, tagInt), int64(v)), nil
+	case int64:
+		return binary.AppendVarint(append(buf, tagInt64), v), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, tagFloat64), math.Float64bits(v)), nil
+	case time.Time:
+		return appendTime(append(buf, tagTime), v)
+	case map[string]interface{}:
+		return appendDocument(append(buf, tagDocument), v)
+	case []interface{}:
+		buf = binary.AppendUvarint(append(buf, tagList), uint64(len(v)))
+		for _, item := range v {
+			var err error
+			if buf, err = appendValue(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case types.ID:
+		return binary.AppendVarint(append(buf, tagID), v.K), nil
+	case types.INT:
+		return binary.AppendVarint(append(buf, tagINT), int64(v)), nil
+	case types.INT64:
+		return binary.AppendVarint(append(buf, tagINT64), int64(v)), nil
+	case types.INT32:
+		return binary.AppendVarint(append(buf, tagINT32), int64(v)), nil
+	case types.INT16:
+		return binary.AppendVarint(append(buf, tagINT16), int64(v)), nil
+	case types.INT8:
+		return binary.AppendVarint(append(buf, tagINT8), int64(v)), nil
+	case types.FLOAT:
+		return binary.AppendVarint(append(buf, tagFLOAT), int64(v)), nil
+	case types.FLOAT32:
+		return binary.BigEndian.AppendUint32(append(buf, tagFLOAT32), math.Float32bits(float32(v))), nil
+	case types.FLOAT64:
+		return binary.BigEndian.AppendUint64(append(buf, tagFLOAT64), math.Float64bits(float64(v))), nil
+	case types.BOOL:
+		return appendBool(append(buf, tagBOOL), bool(v)), nil
+	case types.STRING:
+		return appendString(append(buf, tagSTRING), string(v)), nil
+	case types.DATE:
+		return appendTime(append(buf, tagDATE), v.Time)
+	case types.TIME:
+		return appendTime(append(buf, tagTIME), v.Time)
+	case types.DATETIME:
+		return appendTime(append(buf, tagDATETIME), v.Time)
+	case types.TIMESTAMP:
+		return appendTime(append(buf, tagTIMESTAMP), v.Time)
+	case types.DECIMAL:
+		return appendString(append(buf, tagDECIMAL), string(v)), nil
+	case types.BYTE:
+		return append(buf, tagBYTE, byte(v)), nil
+	}
+	return nil, errors.CodecError("unsupported type %T", v)
+}
+
+func appendBool(buf []byte, v bool) []byte {
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func appendString(buf []byte, v string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+func appendTime(buf []byte, v time.Time) ([]byte, error) {
+	b, err := v.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return appendString(buf, string(b)), nil
+}
+
+// reader walks encoded document, first error sticks & zero values are
+// returned from then on
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) fail(msg string, args ...any) {
+	if r.err == nil {
+		r.err = errors.CodecError(msg, args...)
+	}
+	r.data = nil
+}
+
+func (r *reader) byte() byte {
+	if len(r.data) < 1 {
+		r.fail("truncated document")
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail("malformed uvarint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail("malformed varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) fixed(size int) []byte {
+	if len(r.data) < size {
+		r.fail("truncated document")
+		return make([]byte, size)
+	}
+	b := r.data[:size]
+	r.data = r.data[size:]
+	return b
+}
+
+func (r *reader) string() string {
+	size := r.uvarint()
+	if uint64(len(r.data)) < size {
+		r.fail("truncated string")
+		return ""
+	}
+	return string(r.fixed(int(size)))
+}
+
+func (r *reader) time() time.Time {
+	var t time.Time
+	if err := t.UnmarshalBinary([]byte(r.string())); err != nil && r.err == nil {
+		r.fail("malformed time: %v", err)
+	}
+	return t
+}
+
+func (r *reader) document() map[string]interface{} {
+	count := r.uvarint()
+	if count == 0 {
+		return nil
+	}
+
+	doc := make(map[string]interface{}, min(count, uint64(len(r.data))))
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		name := r.string()
+		doc[name] = r.value()
+	}
+	return doc
+}
+
+func (r *reader) value() interface{} {
+	switch tag := r.byte(); tag {
+	case tagNil:
+		return nil
+	case tagBool:
+		return r.byte() == 1
+	case tagString:
+		return r.string()
+	case tagBytes:
+		return []byte(r.string())
+	case tagInt:
+		return int(r.varint())
+	case tagInt64:
+		return r.varint()
+	case tagFloat64:
+		return math.Float64frombits(binary.BigEndian.Uint64(r.fixed(8)))
+	case tagTime:
+		return r.time()
+	case tagDocument:
+		doc := r.document()
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+		return doc
+	case tagList:
+		count := r.uvarint()
+		list := make([]interface{}, 0, min(count, uint64(len(r.data))))
+		for i := uint64(0); i < count && r.err == nil; i++ {
+			list = append(list, r.value())
+		}
+		return list
+	case tagID:
+		return types.ID{K: r.varint()}
+	case tagINT:
+		return types.INT(r.varint())
+	case tagINT64:
+		return types.INT64(r.varint())
+	case tagINT32:
+		return types.INT32(r.varint())
+	case tagINT16:
+		return types.INT16(r.varint())
+	case tagINT8:
+		return types.INT8(r.varint())
+	case tagFLOAT:
+		return types.FLOAT(r.varint())
+	case tagFLOAT32:
+		return types.FLOAT32(math.Float32frombits(binary.BigEndian.Uint32(r.fixed(4))))
+	case tagFLOAT64:
+		return types.FLOAT64(math.Float64frombits(binary.BigEndian.Uint64(r.fixed(8))))
+	case tagBOOL:
+		return types.BOOL(r.byte() == 1)
+	case tagSTRING:
+		return types.STRING(r.string())
+	case tagDATE:
+		return types.DATE{Time: r.time()}
+	case tagTIME:
+		return types.TIME{Time: r.time()}
+	case tagDATETIME:
+		return types.DATETIME{Time: r.time()}
+	case tagTIMESTAMP:
+		return types.TIMESTAMP{Time: r.time()}
+	case tagDECIMAL:
+		return types.DECIMAL(r.string())
+	case tagBYTE:
+		return types.BYTE(r.byte())
+	default:
+		r.fail("unknown tag %d", tag)
+		return nil
+	}
+}
+
+func readDocument(data []byte) (map[string]interface{}, []byte, error) {
+	r := &reader{data: data}
+	doc := r.document()
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return doc, r.data, nil
+}
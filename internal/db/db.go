@@ -3,13 +3,21 @@ package db
 import (
 	"context"
 	"fmt"
+	"math"
 	"path"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/nagarajRPoojari/orange/internal/config"
 	"github.com/nagarajRPoojari/orange/internal/errors"
 	"github.com/nagarajRPoojari/orange/internal/types"
 	storage "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/sstable"
+	ptypes "github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 	"github.com/nagarajRPoojari/orange/pkg/oql"
 	"github.com/nagarajRPoojari/orange/pkg/schema"
 )
@@ -23,11 +31,6 @@ type InternalValueType struct {
 	d       bool
 }
 
-// @todo: fix
-func (t *InternalValueType) SizeOf() uintptr {
-	return 8
-}
-
 func (t *InternalValueType) MarkDeleted() {
 	t.d = true
 }
@@ -39,12 +42,23 @@ func (t *InternalValueType) IsDeleted() bool {
 type DBopts struct {
 }
 
+// name of storage holding all collections, it is not a valid
+// collection name so it never collides with one
+const systemFamily = "orange-system"
+
 // Oragedb represents the core database engine, holding the schema
-// handler, a map of database instances, and configuration options
+// handler, a storage with a family per collection, and configuration options
 type Oragedb struct {
 	schemaHandler *schema.SchemaHandler
 
-	dbMap *sync.Map
+	// collections share a single commit log, flusher & compaction workers
+	storage *storage.Storage[types.ID, *InternalValueType]
+
+	// *sync.RWMutex per collection, see lockForWrite
+	locks sync.Map
+
+	// *sequence per collection generating _IDs, see nextID
+	sequences sync.Map
 
 	// context for smooth teardown
 	context context.Context
@@ -54,6 +68,9 @@ type Oragedb struct {
 
 // NewOrangedb initializes the Oragedb instance with schema and config setup
 func NewOrangedb(context context.Context, conf config.Config) *Oragedb {
+	if conf.NodeID < 0 || conf.NodeID > MaxNodeID {
+		log.Fatalf("invalid node_id=%d, expected 0 to %d", conf.NodeID, MaxNodeID)
+	}
 
 	return &Oragedb{
 		schemaHandler: schema.NewSchemaHandler(
@@ -61,8 +78,8 @@ func NewOrangedb(context context.Context, conf config.Config) *Oragedb {
 				Dir: path.Join(conf.Directory, "catalog"),
 			},
 		),
+		storage: newStorage(context, conf),
 		context: context,
-		dbMap:   &sync.Map{},
 		conf:    conf,
 	}
 }
@@ -81,8 +98,15 @@ func (t *Oragedb) ProcessQuery(q string) (any, error) {
 	case oql.CreateOp:
 		return nil, t.CreateCollection(v)
 	case oql.InsertOp:
-		return nil, t.InsertDoc(v)
+		return t.InsertDoc(v)
+	case oql.CreateIndexOp:
+		return nil, t.CreateIndex(v)
+	case oql.AlterOp:
+		return nil, t.AlterCollection(v)
 	case oql.SelectOp:
+		if v.Field != "" {
+			return t.FindDocs(v)
+		}
 		return t.GetDoc(v)
 	case oql.DeleteOp:
 		return nil, t.DeleteDoc(v)
@@ -93,138 +117,385 @@ func (t *Oragedb) ProcessQuery(q string) (any, error) {
 
 // CreateCollection creates a new collection and stores its schema in the catalog
 func (t *Oragedb) CreateCollection(op oql.CreateOp) error {
-	db := t.createDB(op.Document)
-	t.dbMap.LoadOrStore(op.Document, db)
+	// index families are named <document>#<index>
+	if strings.Contains(op.Document, "#") {
+		return errors.InsertError("invalid collection name %q", op.Document)
+	}
+	if _, err := t.storage.Family(op.Document); err != nil {
+		return err
+	}
 
 	return t.schemaHandler.SavetoCatalog(op.Document, op.Schema)
 }
 
-// createDB initializes a new parrot instance
-func (t *Oragedb) createDB(dbName string) *storage.Storage[types.ID, *InternalValueType] {
+// documentCodec returns codec new files are written with, files written by
+// either codec stay readable, switching affects new files only
+func documentCodec(conf config.Config) (codec.Codec, error) {
+	switch conf.Codec {
+	case "", "document":
+		return DocumentCodec{}, nil
+	case "gob":
+		return codec.Gob, nil
+	}
+	return nil, errors.CodecError("unknown codec %q, expected \"document\" or \"gob\"", conf.Codec)
+}
+
+// newStorage initializes parrot instance backing all collections
+func newStorage(context context.Context, conf config.Config) *storage.Storage[types.ID, *InternalValueType] {
+	c, err := documentCodec(conf)
+	if err != nil {
+		log.Fatalf("invalid codec config, err=%v", err)
+	}
+
+	var keyring *io.Keyring
+	if conf.Encryption.Keyfile != "" {
+		if keyring, err = io.LoadKeyring(conf.Encryption.Keyfile); err != nil {
+			log.Fatalf("failed to load encryption keys, err=%v", err)
+		}
+	}
+
+	readMode, err := io.ParseReadMode(conf.SSTable.ReadMode)
+	if err != nil {
+		log.Fatalf("invalid sstable config, err=%v", err)
+	}
+
 	db := storage.NewStorage[types.ID, *InternalValueType](
-		dbName,
-		t.context,
+		systemFamily,
+		context,
 		storage.StorageOpts{
-			Directory:                     path.Join(t.conf.Directory, dbName),
-			TurnOnMemtableWal:             t.conf.Memtable.TurnOnWAL,
-			MemtableThreshold:             t.conf.Memtable.Threshold,
-			MemtableWALTimeInterval:       t.conf.Memtable.WALTimeInterval,
-			MemtableWALEventChSize:        t.conf.Memtable.WALEventChSize,
-			MemtableWALWriterBufferSize:   t.conf.Memtable.WALWriterBufferSize,
-			FlushTimeInterval:             t.conf.Memtable.FlushTimeInterval,
-			TurnOnCompaction:              t.conf.Compaction.TurnOn,
-			CompactionTimeInterval:        t.conf.Compaction.TimeInterval,
-			CompactionWALTimeInterval:     t.conf.Compaction.WALTimeInterval,
-			CompactionWALEventChSize:      t.conf.Compaction.WALEventChSize,
-			CompactionWALWriterBufferSize: t.conf.Compaction.WALWriterBufferSize,
-			Level0MaxSizeInBytes:          t.conf.Compaction.Level0MaxSizeInBytes,
-			MaxSizeInBytesGrowthFactor:    t.conf.Compaction.MaxSizeInBytesGrowthFactor,
+			Directory:                     conf.Directory,
+			TurnOnMemtableWal:             conf.Memtable.TurnOnWAL,
+			MemtableThreshold:             conf.Memtable.Threshold,
+			MemtableWALTimeInterval:       conf.Memtable.WALTimeInterval,
+			MemtableWALEventChSize:        conf.Memtable.WALEventChSize,
+			MemtableWALWriterBufferSize:   conf.Memtable.WALWriterBufferSize,
+			WriteBufferSize:               conf.Memtable.WriteBufferSize,
+			ChangeRetention:               conf.Memtable.ChangeRetention,
+			FlushTimeInterval:             conf.Memtable.FlushTimeInterval,
+			TurnOnCompaction:              conf.Compaction.TurnOn,
+			CompactionTimeInterval:        conf.Compaction.TimeInterval,
+			CompactionWALTimeInterval:     conf.Compaction.WALTimeInterval,
+			CompactionWALEventChSize:      conf.Compaction.WALEventChSize,
+			CompactionWALWriterBufferSize: conf.Compaction.WALWriterBufferSize,
+			CompactionWorkers:             conf.Compaction.Workers,
+			MaxSubcompactions:             conf.Compaction.Subcompactions,
+			Level0MaxSizeInBytes:          conf.Compaction.Level0MaxSizeInBytes,
+			MaxSizeInBytesGrowthFactor:    conf.Compaction.MaxSizeInBytesGrowthFactor,
+			Codec:                         c,
+			Keyring:                       keyring,
+			ValueLogThreshold:             conf.ValueLog.Threshold,
+			ValueLogSegmentSize:           conf.ValueLog.SegmentSize,
+			ValueLogGCRatio:               conf.ValueLog.GCRatio,
+			TableRead:                     io.ReadOpts{Mode: readMode, Direct: conf.SSTable.DirectIO},
+			Retention:                     conf.Retention,
 		})
 
 	return db
 }
 
-// InsertDoc validates and inserts a document into the target collection.
-func (t *Oragedb) InsertDoc(op oql.InsertOp) error {
-	schema, err := t.schemaHandler.LoadFromCatalog(op.Document)
+// Close stops background workers & persists commit log, pending writes
+// aren't flushed but recovered from it on next open
+func (t *Oragedb) Close() {
+	t.storage.Close()
+}
+
+// AssignID generates _ID of document of op if it has none & collection
+// generates them, see schema.IDStrategy. Replicated inserts are assigned
+// one first, so every replica stores document with same _ID.
+func (t *Oragedb) AssignID(op oql.InsertOp) error {
+	docSchema, err := t.schemaHandler.LoadFromCatalog(op.Document)
 	if err != nil {
 		return err
 	}
+	strategy := schema.IDStrategy(docSchema)
+	if _, ok := op.Value["_ID"]; ok || strategy == "" {
+		return nil
+	}
 
-	if err := t.schemaHandler.VerifyAndCastData(schema, op.Value); err != nil {
+	id, err := t.nextID(op.Document, strategy)
+	if err != nil {
 		return err
 	}
+	op.Value["_ID"] = id
+	return nil
+}
+
+// InsertDoc validates and inserts a document into the target collection,
+// returns _ID it is inserted with.
+func (t *Oragedb) InsertDoc(op oql.InsertOp) (int64, error) {
+	if err := t.AssignID(op); err != nil {
+		return 0, err
+	}
 
-	val, ok := t.dbMap.Load(op.Document)
-	if !ok {
-		db := t.createDB(op.Document)
-		t.dbMap.LoadOrStore(op.Document, db)
+	db, err := t.storage.Family(op.Document)
+	if err != nil {
+		return 0, errors.InsertError("failed to get db for " + op.Document)
+	}
+
+	// schema isn't altered while lock is held, so document is stored with
+	// version it's verified against
+	indexes, unlock, err := t.lockForWrite(op.Document)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
 
-		val = db
+	docSchema, version, err := t.schemaHandler.LoadVersion(op.Document)
+	if err != nil {
+		return 0, err
 	}
 
-	db, ok := val.(*storage.Storage[types.ID, *InternalValueType])
-	if !ok {
-		return errors.InsertError("failed to get db for " + op.Document)
+	if err := t.schemaHandler.VerifyAndCastData(docSchema, op.Value); err != nil {
+		return 0, err
 	}
 
 	if id, ok := op.Value["_ID"]; ok {
-
-		// @todo: need to verify this block
-		// id is assumed to be casted to int64 by schemaHandler
-		// still id.(int64) fails sometimes
-
-		var castedId types.ID
-
-		switch v := id.(type) {
-		case int64:
-			castedId = types.ID{K: v}
-		case int:
-			castedId = types.ID{K: int64(v)}
-		case float64:
-			castedId = types.ID{K: int64(v)}
-		default:
-			return fmt.Errorf("unexpected type for id: %T %v", id, id)
+		castedId, err := castID(id)
+		if err != nil {
+			return 0, err
 		}
 
 		op.Value["_ID"] = castedId
 
-		res := db.Put(castedId, &InternalValueType{Payload: op.Value})
-		return res.Err
+		// _IDs inserted by clients aren't generated afterwards
+		if schema.IDStrategy(docSchema) != "" {
+			if err := t.observeID(op.Document, castedId.K); err != nil {
+				return 0, err
+			}
+		}
+
+		batch := storage.NewWriteBatch[types.ID, *InternalValueType]()
+		if len(indexes) > 0 {
+			if err := t.checkUnique(op.Document, indexes, castedId.K, op.Value); err != nil {
+				return 0, err
+			}
+			// entries of overwritten document are replaced
+			old, err := t.currentDoc(db, op.Document, castedId)
+			if err != nil {
+				return 0, err
+			}
+			if err := indexDoc(batch, op.Document, indexes, castedId.K, old, op.Value); err != nil {
+				return 0, err
+			}
+		}
+		batch.Put(op.Document, castedId, &InternalValueType{Payload: versioned(op.Value, version)})
+		return castedId.K, t.storage.Write(batch).Err
 	}
 
-	return nil
+	return 0, nil
+}
+
+// castID converts _ID of a verified document to its key
+func castID(id any) (types.ID, error) {
+	// @todo: need to verify this block
+	// id is assumed to be casted to int64 by schemaHandler
+	// still id.(int64) fails sometimes
+	switch v := id.(type) {
+	case int64:
+		return types.ID{K: v}, nil
+	case int:
+		return types.ID{K: int64(v)}, nil
+	case float64:
+		return types.ID{K: int64(v)}, nil
+	}
+	return types.ID{}, fmt.Errorf("unexpected type for id: %T %v", id, id)
 }
 
 // GetDoc retrieves a document by ID from the specified collection.
 func (t *Oragedb) GetDoc(op oql.SelectOp) (map[string]interface{}, error) {
-	schema, err := t.schemaHandler.LoadFromCatalog(op.Document)
-	if err != nil {
+	if _, err := t.schemaHandler.LoadFromCatalog(op.Document); err != nil {
 		return nil, err
 	}
 
-	val, ok := t.dbMap.Load(op.Document)
-	if !ok {
-		db := t.createDB(op.Document)
-		t.dbMap.LoadOrStore(op.Document, db)
-
-		val = db
-	}
-
-	db, ok := val.(*storage.Storage[types.ID, *InternalValueType])
-	if !ok {
+	db, err := t.storage.Family(op.Document)
+	if err != nil {
 		return nil, errors.SelectError("failed to get db for " + op.Document)
 	}
 
 	castedId := types.ID{K: op.ID}
-	res := db.Get(castedId)
+	var res storage.ReadStatus[*InternalValueType]
+	if op.AsOf.IsZero() {
+		res = db.Get(castedId)
+	} else {
+		res = db.GetAt(castedId, op.AsOf)
+	}
 
 	if res.Err != nil {
 		return nil, res.Err
 	}
 
-	// verifying loaded data & typecasting back to compatible schema types
-	t.schemaHandler.VerifyAndCastData(schema, res.Value.Payload)
+	// documents are verified & cast to schema types as they're written,
+	// stored ones aren't verified again: they're only upgraded to current
+	// schema. one stored before a field was added without a default lacks
+	// it & would fail as missing
+	return t.upgrade(op.Document, res.Value.Payload)
+}
 
-	return res.Value.Payload, nil
+// BuildTable writes docs of collection to a table file at path, which can
+// be loaded by IngestFiles. Docs are verified like inserts & sorted by _ID.
+func (t *Oragedb) BuildTable(document string, path string, docs []oql.Value) error {
+	schema, version, err := t.schemaHandler.LoadVersion(document)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		id  types.ID
+		doc oql.Value
+	}
+	entries := make([]entry, 0, len(docs))
+	for _, doc := range docs {
+		if err := t.schemaHandler.VerifyAndCastData(schema, doc); err != nil {
+			return err
+		}
+		id, ok := doc["_ID"]
+		if !ok {
+			return errors.IngestError("document without _ID can't be ingested")
+		}
+		castedId, err := castID(id)
+		if err != nil {
+			return err
+		}
+		doc["_ID"] = castedId
+		entries = append(entries, entry{id: castedId, doc: doc})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id.Less(entries[j].id)
+	})
+
+	c, err := documentCodec(t.conf)
+	if err != nil {
+		return err
+	}
+	w, err := sstable.NewWriter[types.ID, *InternalValueType](path, c, nil)
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if i > 0 && entries[i-1].id == e.id {
+			w.Abort()
+			return errors.IngestError("duplicate _ID=%d", e.id.K)
+		}
+		if err := w.Add(e.id, &InternalValueType{Payload: versioned(e.doc, version)}); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+	return w.Finish()
+}
+
+// IngestFiles loads table files built by BuildTable into collection,
+// see storage.Family.IngestFiles
+func (t *Oragedb) IngestFiles(document string, paths []string) error {
+	docSchema, err := t.schemaHandler.LoadFromCatalog(document)
+	if err != nil {
+		return err
+	}
+	// ingested documents bypass index entries
+	if indexes, err := t.schemaHandler.LoadIndexes(document); err != nil {
+		return err
+	} else if len(indexes) > 0 {
+		return errors.IngestError("%s has secondary indexes, files can't be ingested", document)
+	}
+	// sequence isn't moved past ingested _IDs
+	if schema.IDStrategy(docSchema) != "" {
+		return errors.IngestError("%s generates _IDs, files can't be ingested", document)
+	}
+
+	db, err := t.storage.Family(document)
+	if err != nil {
+		return errors.IngestError("failed to get db for " + document)
+	}
+	return db.IngestFiles(paths)
+}
+
+// Flush writes active memtable of document to disk, or of every collection
+// if document is empty, see storage.Storage.Flush
+func (t *Oragedb) Flush(document string, wait bool) error {
+	if document == "" {
+		return t.storage.Flush(wait)
+	}
+	if _, err := t.schemaHandler.LoadFromCatalog(document); err != nil {
+		return err
+	}
+
+	db, err := t.storage.Family(document)
+	if err != nil {
+		return errors.AdminError("failed to get db for " + document)
+	}
+	return db.Flush(wait)
+}
+
+// CompactRange compacts tables of document holding an _ID of [start, end),
+// see storage.Family.CompactRange
+func (t *Oragedb) CompactRange(document string, start, end int64) error {
+	if _, err := t.schemaHandler.LoadFromCatalog(document); err != nil {
+		return err
+	}
+
+	db, err := t.storage.Family(document)
+	if err != nil {
+		return errors.AdminError("failed to get db for " + document)
+	}
+	return db.CompactRange(types.ID{K: start}, types.ID{K: end})
 }
 
 // DeleteDoc deletes a document by ID from the specified collection.
+// Range deletes drop all documents of [ID, EndID] with a single tombstone.
 func (t *Oragedb) DeleteDoc(op oql.DeleteOp) error {
-	val, ok := t.dbMap.Load(op.Document)
-	if !ok {
-		db := t.createDB(op.Document)
-		t.dbMap.LoadOrStore(op.Document, db)
+	db, err := t.storage.Family(op.Document)
+	if err != nil {
+		return errors.DeleteError("failed to delete db for " + op.Document)
+	}
 
-		val = db
+	indexes, unlock, err := t.lockForWrite(op.Document)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	db, ok := val.(*storage.Storage[types.ID, *InternalValueType])
-	if !ok {
-		return errors.DeleteError("failed to delete db for " + op.Document)
+	batch := storage.NewWriteBatch[types.ID, *InternalValueType]()
+	if op.Range {
+		// storage ranges exclude end
+		if op.EndID == math.MaxInt64 {
+			return errors.DeleteError("_ID range end out of bounds")
+		}
+		r := ptypes.Between(types.ID{K: op.ID}, types.ID{K: op.EndID + 1})
+		batch.DeleteRange(op.Document, r.Start, r.End)
+
+		if len(indexes) > 0 {
+			// entries of every document of range are deleted along
+			var docs []*InternalValueType
+			var ids []int64
+			if err := db.Scan(r, func(id types.ID, doc *InternalValueType) bool {
+				ids, docs = append(ids, id.K), append(docs, doc)
+				return true
+			}); err != nil {
+				return err
+			}
+			for i, doc := range docs {
+				old, err := t.upgrade(op.Document, doc.Payload)
+				if err != nil {
+					return err
+				}
+				if err := indexDoc(batch, op.Document, indexes, ids[i], old, nil); err != nil {
+					return err
+				}
+			}
+		}
+		return t.storage.Write(batch).Err
 	}
 
 	castedId := types.ID{K: op.ID}
-	res := db.Delete(castedId, &InternalValueType{})
-	return res.Err
+	batch.Delete(op.Document, castedId, &InternalValueType{})
+	if len(indexes) > 0 {
+		old, err := t.currentDoc(db, op.Document, castedId)
+		if err != nil {
+			return err
+		}
+		if err := indexDoc(batch, op.Document, indexes, op.ID, old, nil); err != nil {
+			return err
+		}
+	}
+	return t.storage.Write(batch).Err
 }
@@ -0,0 +1,294 @@
+package db
+
+import (
+	stderrors "errors"
+	"math"
+	"sync"
+
+	"github.com/nagarajRPoojari/orange/internal/errors"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	perrors "github.com/nagarajRPoojari/orange/parrot/errors"
+	ptypes "github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/pkg/oql"
+	"github.com/nagarajRPoojari/orange/pkg/schema"
+)
+
+// Secondary indexes are families of storage named <document>#<index>.
+// An entry of an index is keyed by encoded field value of a document as
+// id prefix & its _ID, so documents with same value are adjacent & in
+// _ID order. Entries are written in same batch as documents they index.
+
+// indexFamily returns name of family holding entries of index of document
+func indexFamily(document string, index string) string {
+	return document + "#" + index
+}
+
+// indexKey returns key of index entry of document id with field value v,
+// v is a value of a verified document, see VerifyAndCastData
+func indexKey(v interface{}, id int64) (types.ID, error) {
+	prefix, err := appendValue(nil, v)
+	if err != nil {
+		return types.ID{}, err
+	}
+	return types.ID{K: id, Prefix: string(prefix)}, nil
+}
+
+// prefixRange returns range of index entries of all documents with field
+// value encoded as prefix
+func prefixRange(prefix string) ptypes.KeyRange[types.ID] {
+	return ptypes.Between(types.ID{K: math.MinInt64, Prefix: prefix}, types.ID{K: math.MinInt64, Prefix: prefix + "\x00"})
+}
+
+// collectionLock returns lock guarding index entries & schema of document.
+// Writes of documents without indexes share it, see lockForWrite
+func (t *Oragedb) collectionLock(document string) *sync.RWMutex {
+	mu, _ := t.locks.LoadOrStore(document, &sync.RWMutex{})
+	return mu.(*sync.RWMutex)
+}
+
+// lockForWrite locks document for a write & returns its indexes along
+// with unlock. Writes reading old documents to update index entries are
+// serialized, others run concurrently.
+func (t *Oragedb) lockForWrite(document string) ([]schema.Index, func(), error) {
+	mu := t.collectionLock(document)
+
+	mu.RLock()
+	indexes, err := t.schemaHandler.LoadIndexes(document)
+	if err != nil || len(indexes) == 0 {
+		return nil, mu.RUnlock, err
+	}
+	mu.RUnlock()
+
+	// index might be dropped or created while lock isn't held
+	mu.Lock()
+	indexes, err = t.schemaHandler.LoadIndexes(document)
+	return indexes, mu.Unlock, err
+}
+
+// storedDoc returns stored document of id, nil if there's none
+func storedDoc(db *storage.Family[types.ID, *InternalValueType], id types.ID) (map[string]interface{}, error) {
+	res := db.Get(id)
+	if res.Err == nil {
+		return res.Value.Payload, nil
+	}
+	var notFound perrors.KeyNotFoundErr
+	var deleted perrors.KeyDeletederr
+	if stderrors.As(res.Err, &notFound) || stderrors.As(res.Err, &deleted) {
+		return nil, nil
+	}
+	return nil, res.Err
+}
+
+// currentDoc returns document id of collection upgraded to current schema,
+// nil if there's none
+func (t *Oragedb) currentDoc(db *storage.Family[types.ID, *InternalValueType], document string, id types.ID) (map[string]interface{}, error) {
+	doc, err := storedDoc(db, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.upgrade(document, doc)
+}
+
+// indexDoc adds writes keeping indexes in sync with document id being
+// replaced by doc (nil if deleted) to batch, old is the stored document
+func indexDoc(batch *storage.WriteBatch[types.ID, *InternalValueType], document string, indexes []schema.Index, id int64, old, doc map[string]interface{}) error {
+	for _, idx := range indexes {
+		var oldKey, newKey types.ID
+		var err error
+		if old != nil {
+			if oldKey, err = indexKey(old[idx.Field], id); err != nil {
+				return err
+			}
+		}
+		if doc != nil {
+			if newKey, err = indexKey(doc[idx.Field], id); err != nil {
+				return err
+			}
+		}
+
+		if old != nil && (doc == nil || oldKey != newKey) {
+			batch.Delete(indexFamily(document, idx.Name), oldKey, &InternalValueType{})
+		}
+		if doc != nil && (old == nil || oldKey != newKey) {
+			batch.Put(indexFamily(document, idx.Name), newKey, &InternalValueType{})
+		}
+	}
+	return nil
+}
+
+// checkUnique fails with errors.UniqueViolation if a document other than id
+// holds value of a unique field of doc, caller must hold lock of document
+func (t *Oragedb) checkUnique(document string, indexes []schema.Index, id int64, doc map[string]interface{}) error {
+	for _, idx := range indexes {
+		// like nulls, missing values don't collide
+		if !idx.Unique || doc[idx.Field] == nil {
+			continue
+		}
+		key, err := indexKey(doc[idx.Field], id)
+		if err != nil {
+			return err
+		}
+		indexDB, err := t.storage.Family(indexFamily(document, idx.Name))
+		if err != nil {
+			return errors.InsertError("failed to get index db for " + document)
+		}
+
+		holder, held := int64(0), false
+		if err := indexDB.Scan(prefixRange(key.Prefix), func(entry types.ID, _ *InternalValueType) bool {
+			holder, held = entry.K, entry.K != id
+			return !held
+		}); err != nil {
+			return err
+		}
+		if held {
+			return errors.UniqueViolation{Document: document, Field: idx.Field, Value: doc[idx.Field], ID: holder}
+		}
+	}
+	return nil
+}
+
+// CreateIndex creates a secondary index of document on a top level field
+// & backfills it with documents already stored. Writes to document wait
+// for backfill.
+func (t *Oragedb) CreateIndex(op oql.CreateIndexOp) error {
+	if _, err := t.schemaHandler.LoadFromCatalog(op.Document); err != nil {
+		return err
+	}
+
+	mu := t.collectionLock(op.Document)
+	mu.Lock()
+	defer mu.Unlock()
+
+	indexes, err := t.schemaHandler.LoadIndexes(op.Document)
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		if idx.Name == op.Name || idx.Field == op.Field {
+			return errors.IndexError("index %s on %s(%s) already exists", idx.Name, op.Document, idx.Field)
+		}
+	}
+
+	db, err := t.storage.Family(op.Document)
+	if err != nil {
+		return errors.IndexError("failed to get db for " + op.Document)
+	}
+	family := indexFamily(op.Document, op.Name)
+	if _, err := t.storage.Family(family); err != nil {
+		return errors.IndexError("failed to get index db %s, err=%v", family, err)
+	}
+
+	// entries left by a backfill which didn't finish are dropped, encoded
+	// values never start with 0xff
+	clear := storage.NewWriteBatch[types.ID, *InternalValueType]().
+		DeleteRange(family, types.ID{K: math.MinInt64}, types.ID{K: math.MinInt64, Prefix: "\xff"})
+	if err := t.storage.Write(clear).Err; err != nil {
+		return errors.IndexError("failed to clear index %s, err=%v", op.Name, err)
+	}
+
+	// writes are blocked while scanning, so documents are scanned & indexed
+	// in batches
+	const backfillBatch = 1024
+	r := ptypes.KeyRange[types.ID]{}
+	for {
+		batch := storage.NewWriteBatch[types.ID, *InternalValueType]()
+		var last types.ID
+		var keyErr error
+		err := db.Scan(r, func(id types.ID, doc *InternalValueType) bool {
+			var upgraded map[string]interface{}
+			if upgraded, keyErr = t.upgrade(op.Document, doc.Payload); keyErr != nil {
+				return false
+			}
+			var key types.ID
+			if key, keyErr = indexKey(upgraded[op.Field], id.K); keyErr != nil {
+				return false
+			}
+			batch.Put(family, key, &InternalValueType{})
+			last = id
+			return batch.Len() < backfillBatch
+		})
+		if err == nil {
+			err = keyErr
+		}
+		if err == nil {
+			err = t.storage.Write(batch).Err
+		}
+		if err != nil {
+			return errors.IndexError("failed to backfill index %s, err=%v", op.Name, err)
+		}
+		if batch.Len() < backfillBatch || last.K == math.MaxInt64 {
+			break
+		}
+		r = ptypes.From(types.ID{K: last.K + 1})
+	}
+
+	return t.schemaHandler.SaveIndex(op.Document, schema.Index{Name: op.Name, Field: op.Field})
+}
+
+// FindDocs returns documents of collection whose op.Field equals op.Value
+// in _ID order, looked up through index on field
+func (t *Oragedb) FindDocs(op oql.SelectOp) ([]map[string]interface{}, error) {
+	docSchema, err := t.schemaHandler.LoadFromCatalog(op.Document)
+	if err != nil {
+		return nil, err
+	}
+	if !op.AsOf.IsZero() {
+		return nil, errors.SelectError("AS OF isn't supported for lookups by field")
+	}
+
+	indexes, err := t.schemaHandler.LoadIndexes(op.Document)
+	if err != nil {
+		return nil, err
+	}
+	var index string
+	for _, idx := range indexes {
+		if idx.Field == op.Field {
+			index = idx.Name
+		}
+	}
+	if index == "" {
+		return nil, errors.SelectError("%s has no index on %s", op.Document, op.Field)
+	}
+
+	fieldType, _ := schema.FieldType(docSchema, op.Field)
+	value, err := types.TypeCast(fieldType, op.Value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := indexKey(value, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := t.storage.Family(op.Document)
+	if err != nil {
+		return nil, errors.SelectError("failed to get db for " + op.Document)
+	}
+	indexDB, err := t.storage.Family(indexFamily(op.Document, index))
+	if err != nil {
+		return nil, errors.SelectError("failed to get index db for " + op.Document)
+	}
+
+	var ids []types.ID
+	if err := indexDB.Scan(prefixRange(key.Prefix), func(id types.ID, _ *InternalValueType) bool {
+		ids = append(ids, types.ID{K: id.K})
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	docs := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		doc, err := t.currentDoc(db, op.Document, id)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		// upgraded like GetDoc, stored documents aren't verified again
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/internal/errors"
+	"github.com/nagarajRPoojari/orange/internal/types"
+	storage "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/pkg/schema"
+)
+
+// Collections generating _IDs have a sequence holding greatest _ID handed
+// out or inserted. It's stored in sequenceFamily & written before any
+// document holding a greater _ID, so commit log replays it along with
+// documents after a crash & generated _IDs never overwrite a document.
+
+// name of family holding sequences of all collections keyed by collection
+// name, like systemFamily it is not a valid collection name
+const sequenceFamily = "orange-sequences"
+
+// snowflake _IDs are ms since snowflakeEpoch, node id & a sequence number
+// of _IDs generated in same ms, from high to low bits
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+
+	// MaxNodeID is greatest node id snowflake _IDs are generated with
+	MaxNodeID = 1<<snowflakeNodeBits - 1
+)
+
+var snowflakeEpoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type sequence struct {
+	mu sync.Mutex
+	// greatest _ID handed out or inserted, 0 if none
+	last   int64
+	loaded bool
+}
+
+// sequence returns sequence of document, loading it from store at first use
+func (t *Oragedb) sequence(document string) (*sequence, func(), error) {
+	v, _ := t.sequences.LoadOrStore(document, &sequence{})
+	seq := v.(*sequence)
+
+	seq.mu.Lock()
+	if !seq.loaded {
+		db, err := t.storage.Family(sequenceFamily)
+		if err != nil {
+			seq.mu.Unlock()
+			return nil, nil, errors.InsertError("failed to get sequence db, err=%v", err)
+		}
+		stored, err := storedDoc(db, types.ID{Prefix: document})
+		if err != nil {
+			seq.mu.Unlock()
+			return nil, nil, err
+		}
+		seq.last, _ = stored["last"].(int64)
+		seq.loaded = true
+	}
+	return seq, seq.mu.Unlock, nil
+}
+
+// advance stores last as greatest _ID of sequence of document, caller
+// must hold seq.mu
+func (t *Oragedb) advance(document string, seq *sequence, last int64) error {
+	batch := storage.NewWriteBatch[types.ID, *InternalValueType]().
+		Put(sequenceFamily, types.ID{Prefix: document}, &InternalValueType{Payload: map[string]interface{}{"last": last}})
+	if err := t.storage.Write(batch).Err; err != nil {
+		return errors.InsertError("failed to store sequence of %s, err=%v", document, err)
+	}
+	seq.last = last
+	return nil
+}
+
+// nextID generates _ID of a document of collection with given strategy,
+// see schema.IDStrategy
+func (t *Oragedb) nextID(document string, strategy string) (int64, error) {
+	seq, unlock, err := t.sequence(document)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var id int64
+	switch strategy {
+	case schema.SequentialID:
+		if seq.last == math.MaxInt64 {
+			return 0, errors.InsertError("_IDs of %s ran out", document)
+		}
+		id = max(seq.last+1, 1)
+	case schema.SnowflakeID:
+		id = nextSnowflake(seq.last, time.Now(), t.conf.NodeID)
+	default:
+		return 0, errors.InsertError("unknown _ID strategy %q of %s", strategy, document)
+	}
+
+	if err := t.advance(document, seq, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// observeID moves sequence of document past id of a document being
+// inserted, so it isn't generated later
+func (t *Oragedb) observeID(document string, id int64) error {
+	seq, unlock, err := t.sequence(document)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if id <= seq.last {
+		return nil
+	}
+	return t.advance(document, seq, id)
+}
+
+// nextSnowflake returns smallest snowflake _ID of node greater than last,
+// not older than now. _IDs are generated ahead of clock if it steps back
+// or more than 4096 are generated in a ms.
+func nextSnowflake(last int64, now time.Time, node int64) int64 {
+	const seqMask = 1<<snowflakeSeqBits - 1
+	const timeShift = snowflakeNodeBits + snowflakeSeqBits
+
+	ms := max(now.Sub(snowflakeEpoch).Milliseconds(), last>>timeShift)
+	if id := ms<<timeShift | node<<snowflakeSeqBits; id > last {
+		return id
+	}
+	// last is of same ms, it's followed by next sequence number if it's
+	// generated by node
+	if last>>snowflakeSeqBits&MaxNodeID == node && last&seqMask != seqMask {
+		return last + 1
+	}
+	return (ms+1)<<timeShift | node<<snowflakeSeqBits
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/nagarajRPoojari/orange/internal/types"
+)
+
+const (
+	// memory a map costs regardless of its entries, header & first bucket
+	mapOverhead = 48
+	// memory a map entry costs beyond its key & value, bucket slot & tophash
+	mapEntryOverhead = 16
+	// size of an interface{} holding a field value
+	ifaceSize = unsafe.Sizeof(any(nil))
+)
+
+// SizeOf estimates memory held by document, nested documents & lists
+// included. It's what memtable thresholds & write buffer are accounted in.
+func (t *InternalValueType) SizeOf() uintptr {
+	return unsafe.Sizeof(*t) + sizeOfDocument(t.Payload)
+}
+
+func sizeOfDocument(doc map[string]interface{}) uintptr {
+	if doc == nil {
+		return 0
+	}
+	size := uintptr(mapOverhead)
+	for name, v := range doc {
+		size += unsafe.Sizeof(name) + uintptr(len(name)) + ifaceSize + mapEntryOverhead + sizeOfValue(v)
+	}
+	return size
+}
+
+// sizeOfValue estimates memory a field value holds beyond interface{} it's
+// stored in
+func sizeOfValue(v interface{}) uintptr {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return unsafe.Sizeof(v) + uintptr(len(v))
+	case types.STRING:
+		return unsafe.Sizeof(v) + uintptr(len(v))
+	case types.DECIMAL:
+		return unsafe.Sizeof(v) + uintptr(len(v))
+	case []byte:
+		return unsafe.Sizeof(v) + uintptr(cap(v))
+	case map[string]interface{}:
+		return sizeOfDocument(v)
+	case []interface{}:
+		size := unsafe.Sizeof(v)
+		for _, item := range v {
+			size += ifaceSize + sizeOfValue(item)
+		}
+		return size
+	}
+
+	// other named types of documents built outside of schema casts
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.Type().Size() + uintptr(rv.Len())
+	case reflect.Map:
+		size := uintptr(mapOverhead)
+		iter := rv.MapRange()
+		for iter.Next() {
+			size += mapEntryOverhead + sizeOfValue(iter.Key().Interface()) + sizeOfValue(iter.Value().Interface())
+		}
+		return size
+	case reflect.Slice:
+		size := rv.Type().Size()
+		for i := 0; i < rv.Len(); i++ {
+			size += sizeOfValue(rv.Index(i).Interface())
+		}
+		return size
+	default:
+		// fixed size scalars, e.g numbers, bools & times
+		return rv.Type().Size()
+	}
+}
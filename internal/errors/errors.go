@@ -32,3 +32,37 @@ func SelectError(msg string, args ...any) error {
 func DeleteError(msg string, args ...any) error {
 	return DBError(fmt.Sprintf("select error: "+msg, args...))
 }
+
+func IngestError(msg string, args ...any) error {
+	return DBError(fmt.Sprintf("ingest error: "+msg, args...))
+}
+
+func AdminError(msg string, args ...any) error {
+	return DBError(fmt.Sprintf("admin error: "+msg, args...))
+}
+
+func CodecError(msg string, args ...any) error {
+	return DBError(fmt.Sprintf("codec error: "+msg, args...))
+}
+
+func WatchError(msg string, args ...any) error {
+	return DBError(fmt.Sprintf("watch error: "+msg, args...))
+}
+
+func IndexError(msg string, args ...any) error {
+	return DBError(fmt.Sprintf("index error: "+msg, args...))
+}
+
+// UniqueViolation is returned by inserts of a document whose value of a
+// unique field is already held by another document
+type UniqueViolation struct {
+	Document string
+	Field    string
+	Value    any
+	// _ID of document holding value
+	ID int64
+}
+
+func (e UniqueViolation) Error() string {
+	return fmt.Sprintf("Database error: unique violation: %s.%s=%v is held by _ID=%d", e.Document, e.Field, e.Value, e.ID)
+}
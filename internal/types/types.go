@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strconv"
 	"time"
+	"unsafe"
 
 	"encoding/gob"
 
@@ -97,6 +98,13 @@ type BYTE byteValue
 
 type ID struct {
 	K int64
+	// encoded field value of secondary index entries, ids of index entries
+	// are ordered by it first. empty for documents
+	Prefix string `json:",omitempty"`
+}
+
+func (t ID) SizeOf() uintptr {
+	return unsafe.Sizeof(t) + uintptr(len(t.Prefix))
 }
 
 func (t ID) Less(other any) bool {
@@ -104,6 +112,9 @@ func (t ID) Less(other any) bool {
 	if !ok {
 		return false
 	}
+	if t.Prefix != otherInt.Prefix {
+		return t.Prefix < otherInt.Prefix
+	}
 	return t.K < otherInt.K
 }
 
@@ -13,9 +13,14 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// adminTimeout bounds admin calls, flushes & compactions take far longer
+// than a single op
+const adminTimeout = 10 * time.Minute
+
 type Client struct {
 	conn   *grpc.ClientConn
 	client pb.OpsClient
+	admin  pb.AdminClient
 }
 
 func NewClient(addr string, port int64, retry ...int) *Client {
@@ -48,6 +53,7 @@ func NewClient(addr string, port int64, retry ...int) *Client {
 	return &Client{
 		conn:   conn,
 		client: client,
+		admin:  pb.NewAdminClient(conn),
 	}
 }
 
@@ -64,17 +70,29 @@ func (t *Client) Create(op *oql.CreateOp) error {
 	return nil
 }
 
-func (t *Client) Insert(op *oql.InsertOp) error {
+func (t *Client) Alter(op *oql.AlterOp) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	alterAdapter := &adapter.AlterOpAdapter{Native: op}
+
+	_, err := t.client.Alter(ctx, alterAdapter.ToProtobuf())
+	return err
+}
+
+// Insert inserts document of op & returns its _ID, generated by server if
+// document has none
+func (t *Client) Insert(op *oql.InsertOp) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
 	insertAdapter := &adapter.InsertOpAdapter{Native: op}
 
-	_, err := t.client.Insert(ctx, insertAdapter.ToProtobuf())
+	res, err := t.client.Insert(ctx, insertAdapter.ToProtobuf())
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return res.Id, nil
 }
 
 func (t *Client) SecondaryInsert(op *oql.InsertOp) error {
@@ -131,3 +149,51 @@ func (t *Client) SecondaryDelete(op *oql.DeleteOp) error {
 
 	return nil
 }
+
+// Subscribe calls fn with every change of document committed after position
+// from, or from now on if from is empty, until ctx is done, fn or stream
+// returns an error. Position of a change is passed as from to resume after it.
+func (t *Client) Subscribe(ctx context.Context, document string, from string, fn func(*pb.SubscribeRes) error) error {
+	stream, err := t.client.Subscribe(ctx, &pb.SubscribeReq{Document: document, FromPosition: from})
+	if err != nil {
+		return err
+	}
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := fn(res); err != nil {
+			return err
+		}
+	}
+}
+
+// Flush writes active memtable of document to disk, or of every document if
+// empty. With wait, it returns once data is flushed.
+func (t *Client) Flush(document string, wait bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	_, err := t.admin.Flush(ctx, &pb.FlushReq{Document: document, Wait: wait})
+	return err
+}
+
+// CompactRange compacts tables of document holding an _ID of [start, end)
+func (t *Client) CompactRange(document string, start, end int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	_, err := t.admin.CompactRange(ctx, &pb.CompactRangeReq{Document: document, StartId: start, EndId: end})
+	return err
+}
+
+// Rewrite rewrites documents of document stored with an older schema with
+// current one. With wait, it returns once every document is rewritten.
+func (t *Client) Rewrite(document string, wait bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	_, err := t.admin.Rewrite(ctx, &pb.RewriteReq{Document: document, Wait: wait})
+	return err
+}
@@ -28,6 +28,13 @@ type OpsServer struct {
 	replOpts *ReplicationOpts
 }
 
+// AdminServer serves maintenance operations of a single shard, they aren't
+// routed to replicas
+type AdminServer struct {
+	pb.UnimplementedAdminServer
+	db *odb.Oragedb
+}
+
 type ReplicationType string
 
 const (
@@ -89,6 +96,7 @@ func (t *Server) Run() {
 
 	grpcServer := grpc.NewServer()
 	pb.RegisterOpsServer(grpcServer, &OpsServer{db: t.db, replOpts: t.replicationOpts})
+	pb.RegisterAdminServer(grpcServer, &AdminServer{db: t.db})
 
 	log.Infof("gRPC server listening on %s", t.addr)
 	if err := grpcServer.Serve(lis); err != nil {
@@ -98,6 +106,7 @@ func (t *Server) Run() {
 
 func (t *Server) Stop() {
 	t.cancel()
+	t.db.Close()
 }
 
 func (t *OpsServer) Create(ctx context.Context, req *pb.CreateReq) (*pb.CreatRes, error) {
@@ -110,10 +119,25 @@ func (t *OpsServer) Create(ctx context.Context, req *pb.CreateReq) (*pb.CreatRes
 	return &pb.CreatRes{Status: true}, nil
 }
 
+func (t *OpsServer) Alter(ctx context.Context, req *pb.AlterReq) (*pb.AlterRes, error) {
+	alterAdapter := &adapter.AlterOpAdapter{Pb: req}
+	// like create, alter queries are broadcasted to all nodes
+	op := alterAdapter.ToNative()
+	if err := t.db.AlterCollection(*op); err != nil {
+		return nil, err
+	}
+	return &pb.AlterRes{Status: true}, nil
+}
+
 func (t *OpsServer) Insert(ctx context.Context, req *pb.InsertReq) (*pb.InsertRes, error) {
 	insertAdapter := &adapter.InsertOpAdapter{Pb: req}
 	op := insertAdapter.ToNative()
 
+	// replicas are sent _ID generated here, so they store same document
+	if err := t.db.AssignID(*op); err != nil {
+		return nil, err
+	}
+
 	// if replication is turned on call secondary inserts on replicas
 	// @todo: based on ack level decide whether to commit or abort
 	//        yhea, commit & abort not supported yet
@@ -125,21 +149,23 @@ func (t *OpsServer) Insert(ctx context.Context, req *pb.InsertReq) (*pb.InsertRe
 		}
 	}
 
-	if err := t.db.InsertDoc(*op); err != nil {
+	id, err := t.db.InsertDoc(*op)
+	if err != nil {
 		return nil, err
 	}
 
-	return &pb.InsertRes{Status: true}, nil
+	return &pb.InsertRes{Status: true, Id: id}, nil
 }
 
 // SecondaryInsert are called by primary replicas & it should not call replication
 func (t *OpsServer) SecondaryInsert(ctx context.Context, req *pb.InsertReq) (*pb.InsertRes, error) {
 	insertAdapter := &adapter.InsertOpAdapter{Pb: req}
 	op := insertAdapter.ToNative()
-	if err := t.db.InsertDoc(*op); err != nil {
+	id, err := t.db.InsertDoc(*op)
+	if err != nil {
 		return nil, err
 	}
-	return &pb.InsertRes{Status: true}, nil
+	return &pb.InsertRes{Status: true, Id: id}, nil
 }
 
 func (t *OpsServer) Delete(ctx context.Context, req *pb.DeleteReq) (*pb.DeleteRes, error) {
@@ -183,6 +209,48 @@ func (t *OpsServer) Select(ctx context.Context, req *pb.SelectReq) (*pb.SelectRe
 	return &pb.SelectRes{Data: *jsonAdapter.ToProtobuf()}, nil
 }
 
+// Subscribe streams changes of document committed after req.FromPosition
+// until client goes away. Changes are read from this shard only.
+func (t *OpsServer) Subscribe(req *pb.SubscribeReq, stream pb.Ops_SubscribeServer) error {
+	return t.db.Watch(stream.Context(), req.Document, req.FromPosition, func(change odb.Change) error {
+		res := &pb.SubscribeRes{
+			Position: change.Position,
+			Op:       change.Op,
+			Id:       change.ID,
+			EndId:    change.EndID,
+		}
+		if !change.Time.IsZero() {
+			res.Time = change.Time.UnixNano()
+		}
+		if change.Doc != nil {
+			jsonAdapter := &adapter.JsonAdapter{Native: &change.Doc}
+			res.Data = *jsonAdapter.ToProtobuf()
+		}
+		return stream.Send(res)
+	})
+}
+
+func (t *AdminServer) Flush(ctx context.Context, req *pb.FlushReq) (*pb.FlushRes, error) {
+	if err := t.db.Flush(req.Document, req.Wait); err != nil {
+		return nil, err
+	}
+	return &pb.FlushRes{Status: true}, nil
+}
+
+func (t *AdminServer) CompactRange(ctx context.Context, req *pb.CompactRangeReq) (*pb.CompactRangeRes, error) {
+	if err := t.db.CompactRange(req.Document, req.StartId, req.EndId); err != nil {
+		return nil, err
+	}
+	return &pb.CompactRangeRes{Status: true}, nil
+}
+
+func (t *AdminServer) Rewrite(ctx context.Context, req *pb.RewriteReq) (*pb.RewriteRes, error) {
+	if err := t.db.Rewrite(req.Document, req.Wait); err != nil {
+		return nil, err
+	}
+	return &pb.RewriteRes{Status: true}, nil
+}
+
 func buildHostNameForK8sShards(replicas int) []*client.Client {
 	shards := make([]*client.Client, 0)
 	for i := range replicas {
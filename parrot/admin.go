@@ -0,0 +1,189 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+// Flush seals active memtable of every family, so it's written to level-0
+// without waiting for it to fill up. With wait, it returns once sealed
+// memtables are flushed & commit log segments they pinned are released,
+// otherwise they're left to flush scheduler.
+func (t *Storage[K, V]) Flush(wait bool) error {
+	return t.flush(t.snapshot(), wait)
+}
+
+// Sync returns once writes acknowledged before it are durable, i.e their
+// commit log records are synced. Otherwise records are written out
+// periodically & synced as segments are closed.
+func (t *Storage[K, V]) Sync() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.log == nil {
+		return errors.WALDisablederr
+	}
+	return t.log.wal.Sync()
+}
+
+// CompactRange compacts default family, see Family.CompactRange
+func (t *Storage[K, V]) CompactRange(start K, end K) error {
+	return t.def.CompactRange(start, end)
+}
+
+// Flush seals active memtable of family, see Storage.Flush
+func (t *Family[K, V]) Flush(wait bool) error {
+	return t.storage.flush([]*Family[K, V]{t}, wait)
+}
+
+func (t *Storage[K, V]) flush(fams []*Family[K, V], wait bool) error {
+	if err := t.context.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	sealed := false
+	for _, fam := range fams {
+		sealed = fam.store.Seal() || sealed
+	}
+	// as on overflow, sealed memtables must not pin active segment
+	if sealed && t.log != nil {
+		t.log.rotate()
+	}
+	t.mu.Unlock()
+
+	if !wait {
+		return nil
+	}
+	for _, fam := range fams {
+		for fam.store.FlushNext() {
+		}
+	}
+	if t.log != nil {
+		t.releaseLog()
+	}
+	for _, fam := range fams {
+		if err := fam.manifest.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompactRange compacts every level holding a key of [start, end) into the
+// level below it, down to the last level, regardless of level sizes. Older
+// versions, tombstones & range tombstones covering range are dropped on the
+// way, so space of deleted keys is reclaimed.
+//   - levels are compacted whole, tables outside range move along
+//   - memtables aren't flushed, see Flush
+//   - it waits for running compaction of family & blocks scheduled ones
+//   - tables encrypted with a retired key are rewritten with active one, so
+//     compacting whole key space completes a key rotation
+func (t *Family[K, V]) CompactRange(start K, end K) error {
+	if !t.storage.less(start, end) {
+		return errors.RaiseKeyRangeErr("start=%v must sort before end=%v", start, end)
+	}
+	if t.gc == nil {
+		return errors.CompactionDisabledErr
+	}
+
+	resume, err := t.pauseCompaction()
+	if err != nil {
+		return err
+	}
+	defer resume()
+
+	r := types.KeyRange[K]{Start: start, End: end, HasStart: true, HasEnd: true}
+	lsm := t.manifest.GetLSM()
+
+	last := -1
+	for l := 0; l < lsm.LevelsCount(); l++ {
+		if level, err := lsm.GetLevel(l); err == nil && level.TablesCount() > 0 {
+			last = l
+		}
+	}
+
+	// level receiving output of previous compaction
+	target := -1
+	for l := 0; l <= last; l++ {
+		level, err := lsm.GetLevel(l)
+		if err != nil {
+			break
+		}
+
+		overlap, stale := false, false
+		for _, table := range level.GetTables() {
+			if overlap, err = t.rangeOverlaps(table, r); err != nil {
+				return err
+			}
+			if overlap {
+				stale = staleKey(t.manifest.VFS(), table)
+				break
+			}
+		}
+		if !overlap {
+			continue
+		}
+		// a lone table of last level has nothing to be merged with, unless
+		// it's rewritten to be re-encrypted with active key
+		if l == last && level.TablesCount() == 1 && target != l && !stale {
+			continue
+		}
+
+		if err := t.gc.CompactLevel(l); err != nil {
+			return err
+		}
+		target = l + 1
+	}
+
+	if err := t.manifest.Sync(); err != nil {
+		return err
+	}
+	log.Infof("compacted range [%v, %v) of family=%s", start, end, t.name)
+	return nil
+}
+
+// staleKey reports whether table is encrypted with other than active key,
+// or written in plaintext while encryption is on
+func staleKey(fs io.VFS, table *metadata.SSTable) bool {
+	fm := io.Manager(fs)
+	ring := fm.Keyring()
+	if ring == nil {
+		return false
+	}
+	keyID, err := fm.KeyID(table.DBPath)
+	return err == nil && keyID != ring.Active()
+}
+
+// rangeOverlaps reports whether table holds a key of r or a range tombstone
+// touching it
+func (t *Family[K, V]) rangeOverlaps(table *metadata.SSTable, r types.KeyRange[K]) (bool, error) {
+	cache := t.store.DecoderCache
+
+	lo, hi, ok, err := cache.Bounds(table.DBPath, table.IndexPath)
+	if err != nil {
+		return false, err
+	}
+	if ok && r.Overlaps(t.storage.less, lo, hi) {
+		return true, nil
+	}
+
+	ranges, err := cache.RangeTombstones(table)
+	if err != nil {
+		return false, err
+	}
+	for _, tombstone := range ranges {
+		if tombstone.Overlaps(t.storage.less, r.Start, r.End) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
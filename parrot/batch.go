@@ -0,0 +1,48 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// WriteBatch collects writes across families to be applied atomically
+// through Storage.Write
+type WriteBatch[K types.Key, V types.Value] struct {
+	entries []logEntry[K, V]
+}
+
+func NewWriteBatch[K types.Key, V types.Value]() *WriteBatch[K, V] {
+	return &WriteBatch[K, V]{}
+}
+
+func (t *WriteBatch[K, V]) Put(family string, key K, value V) *WriteBatch[K, V] {
+	t.entries = append(t.entries, logEntry[K, V]{Family: family, Key: key, Value: value, Op: memtable.WriteOperation})
+	return t
+}
+
+func (t *WriteBatch[K, V]) Delete(family string, key K, tomstone V) *WriteBatch[K, V] {
+	t.entries = append(t.entries, logEntry[K, V]{Family: family, Key: key, Value: tomstone, Op: memtable.DeleteOperation})
+	return t
+}
+
+func (t *WriteBatch[K, V]) Merge(family string, key K, operand V) *WriteBatch[K, V] {
+	t.entries = append(t.entries, logEntry[K, V]{Family: family, Key: key, Value: operand, Op: memtable.MergeOperation})
+	return t
+}
+
+// DeleteRange deletes keys of [start, end) with a single range tombstone
+func (t *WriteBatch[K, V]) DeleteRange(family string, start K, end K) *WriteBatch[K, V] {
+	t.entries = append(t.entries, logEntry[K, V]{Family: family, Key: start, End: end, Op: memtable.DeleteRangeOperation})
+	return t
+}
+
+// Len returns number of entries in batch
+func (t *WriteBatch[K, V]) Len() int {
+	return len(t.entries)
+}
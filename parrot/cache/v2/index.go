@@ -12,9 +12,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"sync"
 
+	"github.com/nagarajRPoojari/orange/parrot/codec"
 	fio "github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
 	"github.com/nagarajRPoojari/orange/parrot/types"
 	"github.com/nagarajRPoojari/orange/parrot/utils"
 
@@ -26,106 +30,282 @@ import (
 type CacheManager[K types.Key, V types.Value] struct {
 	// using sync.Map to prevent race
 	cache sync.Map
+
+	// loads values kept in value log, nil if values aren't separated
+	resolver Resolver[V]
+
+	opts CacheManagerOpts
+}
+
+type CacheManagerOpts struct {
+	// VFS tables are kept on, files on disk if nil
+	VFS fio.VFS
+	// how table files are read, mapped with mmap by default
+	Read fio.ReadOpts
+	// comparator tables of types.BytesKey keys are ordered by, bytewise if nil
+	Comparator types.Comparator
 }
 
-func NewCacheManager[K types.Key, V types.Value]() *CacheManager[K, V] {
+// Resolver loads value pointed by a value log ref
+type Resolver[V types.Value] func(ref types.ValueRef) (V, error)
+
+func NewCacheManager[K types.Key, V types.Value](opts CacheManagerOpts) *CacheManager[K, V] {
 	return &CacheManager[K, V]{
 		cache: sync.Map{},
+		opts:  opts,
 	}
 }
 
-// Get loads value for single key
+// SetResolver sets loader of values separated into value log, it must be
+// set before tables holding refs are read
+func (m *CacheManager[K, V]) SetResolver(resolver Resolver[V]) {
+	m.resolver = resolver
+}
+
+// Get loads value for single key, value kept in value log is resolved
 func (m *CacheManager[K, V]) Get(dbPath string, indexPath string, key K) (types.Payload[K, V], error) {
-	val, loaded := m.cache.Load(dbPath)
-	var null types.Payload[K, V]
-	if loaded {
-		return val.(*CacheUnit[K, V]).GetDecodedForKey(key)
+	return m.GetAt(dbPath, indexPath, key, math.MaxInt64)
+}
+
+// GetAt loads version of key visible at ts, i.e newest one written at or
+// before ts, value kept in value log is resolved
+func (m *CacheManager[K, V]) GetAt(dbPath string, indexPath string, key K, ts int64) (types.Payload[K, V], error) {
+	unit, err := m.unit(dbPath, indexPath)
+	if err != nil {
+		return types.Payload[K, V]{}, err
+	}
+	pl, err := unit.GetDecodedForKeyAt(key, ts)
+	if err != nil {
+		return pl, err
+	}
+	if err := m.Resolve(&pl); err != nil {
+		return types.Payload[K, V]{}, err
+	}
+	return pl, nil
+}
+
+// Lookup loads entry for single key, value log ref is left unresolved
+func (m *CacheManager[K, V]) Lookup(dbPath string, indexPath string, key K) (types.Payload[K, V], error) {
+	unit, err := m.unit(dbPath, indexPath)
+	if err != nil {
+		return types.Payload[K, V]{}, err
+	}
+	return unit.GetDecodedForKey(key)
+}
+
+// Resolve loads value of pl from value log if it holds a ref
+func (m *CacheManager[K, V]) Resolve(pl *types.Payload[K, V]) error {
+	if pl.Ref == nil {
+		return nil
+	}
+	if m.resolver == nil {
+		return perrors.DecodeErr("key=%v refers to value log, but no value log is open", pl.Key)
 	}
 
-	fm := fio.GetFileManager()
-	dbFileReader, err := fm.OpenForRead(dbPath)
+	v, err := m.resolver(*pl.Ref)
 	if err != nil {
-		return null, err
+		return err
 	}
-	indexFileReader, err := fm.OpenForRead(indexPath)
+	pl.Val, pl.Ref = v, nil
+	return nil
+}
+
+// GetRange loads entries of range in key order, including tombstones.
+// Value log refs are left unresolved, see Resolve
+func (m *CacheManager[K, V]) GetRange(dbPath string, indexPath string, r types.KeyRange[K]) ([]types.Payload[K, V], error) {
+	unit, err := m.unit(dbPath, indexPath)
 	if err != nil {
-		return null, err
+		return nil, err
 	}
+	return unit.getDecodedForRange(r)
+}
 
-	// Create new cache and use LoadOrStore to avoid race
-	newCache := &CacheUnit[K, V]{
-		onceDecodeAllValues: sync.Once{},
-		onceDecodeIndex:     sync.Once{},
-		dbPayload:           dbFileReader.GetPayload(),
-		indexPayload:        indexFileReader.GetPayload(),
+// RangeTombstones loads range tombstones of table from its index, along
+// with ones kept in manifest by tables written before index held them
+func (m *CacheManager[K, V]) RangeTombstones(table *metadata.SSTable) ([]types.KeyRange[K], error) {
+	unit, err := m.unit(table.DBPath, table.IndexPath)
+	if err != nil {
+		return nil, err
 	}
-	actual, _ := m.cache.LoadOrStore(dbPath, newCache)
+	return unit.getRangeTombstones(table.RangeTombstones)
+}
 
-	return actual.(*CacheUnit[K, V]).GetDecodedForKey(key)
+// Bounds returns smallest & largest key of table, ok is false if table
+// has no entries
+func (m *CacheManager[K, V]) Bounds(dbPath string, indexPath string) (first K, last K, ok bool, err error) {
+	unit, err := m.unit(dbPath, indexPath)
+	if err != nil {
+		return first, last, false, err
+	}
+	unit.loadIndex()
+	if unit.err != nil {
+		return first, last, false, unit.err
+	}
+	if len(unit.indexDecoded) == 0 {
+		return first, last, false, nil
+	}
+	return unit.indexDecoded[0].Key, unit.indexDecoded[len(unit.indexDecoded)-1].Key, true, nil
 }
 
-// GetFullPayload loads full payload list
+// GetFullPayload loads full payload list, value log refs are left unresolved
 func (m *CacheManager[K, V]) GetFullPayload(dbPath string, indexPath string) ([]types.Payload[K, V], error) {
+	unit, err := m.unit(dbPath, indexPath)
+	if err != nil {
+		return nil, err
+	}
+	return unit.getDecodedForAll()
+}
 
+// unit returns cache unit of table, opening its files on first access
+func (m *CacheManager[K, V]) unit(dbPath string, indexPath string) (*CacheUnit[K, V], error) {
 	val, loaded := m.cache.Load(dbPath)
 	if loaded {
-		return val.(*CacheUnit[K, V]).getDecodedForAll()
+		return val.(*CacheUnit[K, V]), nil
 	}
 
-	fm := fio.GetFileManager()
-	dbFileReader, err := fm.OpenForRead(dbPath)
+	fm := fio.Manager(m.opts.VFS)
+	dbFileReader, err := fm.OpenForReadWith(dbPath, m.opts.Read)
 	if err != nil {
 		return nil, err
 	}
-	indexFileReader, err := fm.OpenForRead(indexPath)
+	indexFileReader, err := fm.OpenForReadWith(indexPath, m.opts.Read)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create new cache and use LoadOrStore to avoid race
-	newCache := &CacheUnit[K, V]{
-		onceDecodeAllValues: sync.Once{},
-		onceDecodeIndex:     sync.Once{},
-		dbPayload:           dbFileReader.GetPayload(),
-		indexPayload:        indexFileReader.GetPayload(),
-	}
+	newCache := newUnit[K, V](dbFileReader, indexFileReader, types.OrderOf[K](m.opts.Comparator))
 	actual, _ := m.cache.LoadOrStore(dbPath, newCache)
 
-	return actual.(*CacheUnit[K, V]).getDecodedForAll()
+	return actual.(*CacheUnit[K, V]), nil
+}
 
+// NewUnit wraps contents of table files without caching them, tools
+// inspecting tables decode them through it exactly as reads do. Keys are
+// searched under order of comparator c, bytewise if nil
+func NewUnit[K types.Key, V types.Value](dbPayload []byte, indexPayload []byte, c types.Comparator) *CacheUnit[K, V] {
+	return newUnit[K, V](fio.NewPayloadReader(dbPayload), fio.NewPayloadReader(indexPayload), types.OrderOf[K](c))
+}
+
+func newUnit[K types.Key, V types.Value](db *fio.FileReader, index *fio.FileReader, less types.Order[K]) *CacheUnit[K, V] {
+	return &CacheUnit[K, V]{
+		onceDecodeAllValues: sync.Once{},
+		onceDecodeIndex:     sync.Once{},
+		db:                  db,
+		index:               index,
+		less:                less,
+	}
 }
 
 // CacheUnit holds data(index, data) related to single SSTable
 type CacheUnit[K types.Key, V types.Value] struct {
-	// dbpayload directly maps to data file mmap page (shared with multiple readers)
-	dbPayload []byte
+	// db reads data file, shared with multiple readers. Entries are read
+	// from mapped pages or into pooled buffers, see fio.ReadMode
+	db *fio.FileReader
+
+	// index reads index file, it's read whole once & decoded
+	index *fio.FileReader
 
-	// indexPayload directly maps to index file mmap page (shared with multiple readers)
-	indexPayload []byte
+	// order keys of table are sorted by
+	less types.Order[K]
 
 	onceDecodeAllValues sync.Once
 	onceDecodeIndex     sync.Once
+	onceDecodeRanges    sync.Once
 
 	// decoded version of loaded payload
 	indexDecoded []utils.IndexPayload[K, V]
 	err          error
+
+	// codec named by table header, nil for legacy gob tables
+	codec codec.Codec
+
+	// range tombstones decoded from index
+	tombstones []types.KeyRange[K]
+
+	// range tombstones of index & manifest
+	ranges    []types.KeyRange[K]
+	rangesErr error
+}
+
+// Index returns decoded index entries in key order
+func (dc *CacheUnit[K, V]) Index() ([]utils.IndexPayload[K, V], error) {
+	dc.loadIndex()
+	return dc.indexDecoded, dc.err
+}
+
+// Decode decodes entry pointed by index entry, value log ref is left
+// unresolved & tombstones are returned as is
+func (dc *CacheUnit[K, V]) Decode(k utils.IndexPayload[K, V]) (types.Payload[K, V], error) {
+	dc.loadIndex()
+	if dc.err != nil {
+		return types.Payload[K, V]{}, dc.err
+	}
+	return dc.decode(k)
+}
+
+// RangeTombstones returns range tombstones kept in index
+func (dc *CacheUnit[K, V]) RangeTombstones() ([]types.KeyRange[K], error) {
+	dc.loadIndex()
+	return dc.tombstones, dc.err
+}
+
+// Codec returns codec named by table header, nil for legacy gob tables
+func (dc *CacheUnit[K, V]) Codec() codec.Codec {
+	dc.loadIndex()
+	return dc.codec
+}
+
+// DataSize returns size of data file
+func (dc *CacheUnit[K, V]) DataSize() int64 {
+	return dc.db.Size()
 }
 
 // loadIndex loads .index file and caches
 //   - will be executed only once per cache unit
 func (dc *CacheUnit[K, V]) loadIndex() {
 	dc.onceDecodeIndex.Do(func() {
+		payload, err := dc.index.ReadRange(0, int(dc.index.Size()))
+		if err != nil {
+			dc.err = perrors.ReadErr("index, err=%v", err)
+			return
+		}
+		// decoded entries don't refer to payload
+		defer payload.Release()
+
+		id, data, ok := codec.ParseHeader(payload.Bytes())
+		if !ok {
+			dc.indexDecoded, dc.err = dc.loadLegacyIndex(payload.Bytes())
+			return
+		}
+
+		c, err := codec.Lookup(id)
+		if err != nil {
+			dc.err = err
+			return
+		}
+		dc.codec = c
+
 		// @todo: pre allocate
 		var result []utils.IndexPayload[K, V]
-		indexDecoder := gob.NewDecoder(bytes.NewReader(dc.indexPayload))
+		for len(data) > 0 {
+			record, rest, ok := codec.ReadFrame(data)
+			if !ok {
+				dc.err = perrors.DecodeErr("truncated index")
+				return
+			}
+			data = rest
 
-		for {
-			var entry utils.IndexPayload[K, V]
-			err := indexDecoder.Decode(&entry)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
+			// empty record ends entries, range tombstones follow
+			if len(record) == 0 {
+				if dc.tombstones, dc.err = loadRangeTombstones[K](c, data); dc.err != nil {
+					return
 				}
+				break
+			}
+
+			entry, err := utils.DecodeIndex[K, V](c, record)
+			if err != nil {
 				dc.err = fmt.Errorf("failed to decode index: %w", err)
 				return
 			}
@@ -136,48 +316,201 @@ func (dc *CacheUnit[K, V]) loadIndex() {
 	})
 }
 
+// loadRangeTombstones decodes range tombstones following index entries
+func loadRangeTombstones[K types.Key](c codec.Codec, data []byte) ([]types.KeyRange[K], error) {
+	var ranges []types.KeyRange[K]
+	for len(data) > 0 {
+		record, rest, ok := codec.ReadFrame(data)
+		if !ok {
+			return nil, perrors.DecodeErr("truncated index")
+		}
+		data = rest
+
+		r, err := utils.DecodeRangeTombstone[K](c, record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode index: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// loadLegacyIndex decodes index of tables written as gob streams, before
+// tables had headers
+func (dc *CacheUnit[K, V]) loadLegacyIndex(payload []byte) ([]utils.IndexPayload[K, V], error) {
+	var result []utils.IndexPayload[K, V]
+	indexDecoder := gob.NewDecoder(bytes.NewReader(payload))
+
+	for {
+		var entry utils.IndexPayload[K, V]
+		err := indexDecoder.Decode(&entry)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode index: %w", err)
+		}
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// search returns position of first index entry not less than key
+func (dc *CacheUnit[K, V]) search(key K) int {
+	return sort.Search(len(dc.indexDecoded), func(i int) bool {
+		return !dc.less(dc.indexDecoded[i].Key, key)
+	})
+}
+
+// decode decodes entry pointed by index entry
+func (dc *CacheUnit[K, V]) decode(k utils.IndexPayload[K, V]) (types.Payload[K, V], error) {
+	if k.Offset < 0 || k.Size < 0 || k.Offset+k.Size > dc.db.Size() {
+		return types.Payload[K, V]{}, perrors.IndexOutOfBoundErr("key=%v", k.Key)
+	}
+
+	buf, err := dc.db.ReadRange(k.Offset, int(k.Size))
+	if err != nil {
+		return types.Payload[K, V]{}, perrors.ReadErr("key=%v, err=%v", k.Key, err)
+	}
+	// decoded entries don't refer to buf
+	defer buf.Release()
+	return dc.decodeData(k, buf.Bytes())
+}
+
+// decodeSpan decodes entries pointed by run of index entries, entries lie
+// in key order in data file so their span is read at once rather than
+// entry by entry
+func (dc *CacheUnit[K, V]) decodeSpan(index []utils.IndexPayload[K, V]) ([]types.Payload[K, V], error) {
+	result := make([]types.Payload[K, V], 0, len(index))
+	if len(index) == 0 {
+		return result, nil
+	}
+
+	start, end := index[0].Offset, index[len(index)-1].Offset+index[len(index)-1].Size
+	if start < 0 || end < start || end > dc.db.Size() {
+		return nil, perrors.IndexOutOfBoundErr("key=%v", index[0].Key)
+	}
+	buf, err := dc.db.ReadRange(start, int(end-start))
+	if err != nil {
+		return nil, perrors.ReadErr("key=%v, err=%v", index[0].Key, err)
+	}
+	defer buf.Release()
+
+	data := buf.Bytes()
+	for _, k := range index {
+		if k.Offset < start || k.Size < 0 || k.Offset+k.Size > end {
+			return nil, perrors.IndexOutOfBoundErr("key=%v", k.Key)
+		}
+		entry, err := dc.decodeData(k, data[k.Offset-start:k.Offset-start+k.Size])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// decodeData decodes entry pointed by index entry from its bytes
+func (dc *CacheUnit[K, V]) decodeData(k utils.IndexPayload[K, V], data []byte) (types.Payload[K, V], error) {
+	if dc.codec == nil {
+		valDecoder := gob.NewDecoder(bytes.NewReader(data))
+		var entry types.Payload[K, V]
+		if err := valDecoder.Decode(&entry); err != nil {
+			return types.Payload[K, V]{}, perrors.DecodeErr("key=%v, err=%v", k.Key, err)
+		}
+		return entry, nil
+	}
+
+	entry, err := codec.DecodePayload[K, V](dc.codec, data)
+	if err != nil {
+		return types.Payload[K, V]{}, perrors.DecodeErr("key=%v, err=%v", k.Key, err)
+	}
+	return entry, nil
+}
+
 // GetDecodedForKey loads value for specific key
 //   - @todo: caches loaded valu
 //   - does binary search on index file to search for corresponding value offset
 func (dc *CacheUnit[K, V]) GetDecodedForKey(key K) (types.Payload[K, V], error) {
+	return dc.GetDecodedForKeyAt(key, math.MaxInt64)
+}
+
+// GetDecodedForKeyAt loads version of key visible at ts, versions of a key
+// are laid out newest first so first one written at or before ts is taken
+func (dc *CacheUnit[K, V]) GetDecodedForKeyAt(key K, ts int64) (types.Payload[K, V], error) {
 	dc.loadIndex()
 
 	if dc.err != nil {
 		return types.Payload[K, V]{}, dc.err
 	}
 
-	left, mid, right := 0, 0, len(dc.indexDecoded)
+	for i := dc.search(key); i < len(dc.indexDecoded) && dc.indexDecoded[i].Key == key; i++ {
+		entry, err := dc.decode(dc.indexDecoded[i])
+		if err != nil {
+			return types.Payload[K, V]{}, err
+		}
+		if entry.Time > ts {
+			continue
+		}
 
-	for left <= right {
-		mid = left + (right-left)/2
-		midK := dc.indexDecoded[mid]
-		if midK.Key.Less(key) {
-			left = mid + 1
-		} else {
-			if midK.Key == key {
-				if int(midK.Offset+midK.Size) > len(dc.dbPayload) {
-					return types.Payload[K, V]{}, perrors.IndexOutOfBoundErr("key=%v", key)
-				}
+		// only live values are separated into value log
+		if entry.Ref == nil && entry.Val.IsDeleted() {
+			return entry, perrors.RaiseKeyDeletederr("key=%v", key)
+		}
+		return entry, nil
+	}
+	return types.Payload[K, V]{}, perrors.RaiseKeyNotFoundErr("key=%v", key)
+}
 
-				valDecoder := gob.NewDecoder(bytes.NewReader(dc.dbPayload[midK.Offset : midK.Offset+midK.Size]))
-				var entry types.Payload[K, V]
-				if err := valDecoder.Decode(&entry); err != nil {
-					return types.Payload[K, V]{}, perrors.DecodeErr("key=%v, err=%v", key, err)
-				}
+// getDecodedForRange loads entries of range, index is searched for start
+// of range & walked until its end
+func (dc *CacheUnit[K, V]) getDecodedForRange(r types.KeyRange[K]) ([]types.Payload[K, V], error) {
+	dc.loadIndex()
 
-				if entry.Val.IsDeleted() {
-					fmt.Println("deleted key ", key)
-					return entry, perrors.RaiseKeyDeletederr("key=%v", key)
-				}
+	if dc.err != nil {
+		return nil, dc.err
+	}
 
-				return entry, nil
-			} else {
-				right = mid - 1
-			}
-		}
+	i := 0
+	if r.HasStart {
+		i = dc.search(r.Start)
 	}
+	j := i
+	for j < len(dc.indexDecoded) && !r.After(dc.less, dc.indexDecoded[j].Key) {
+		j++
+	}
+	if i == j {
+		return nil, nil
+	}
+	return dc.decodeSpan(dc.indexDecoded[i:j])
+}
 
-	return types.Payload[K, V]{}, perrors.RaiseKeyNotFoundErr("key=%v", key)
+// getRangeTombstones returns range tombstones of index & ones kept in
+// manifest, latter are decoded only once
+func (dc *CacheUnit[K, V]) getRangeTombstones(tombstones []metadata.RangeTombstone) ([]types.KeyRange[K], error) {
+	dc.loadIndex()
+
+	if dc.err != nil {
+		return nil, dc.err
+	}
+	if len(tombstones) == 0 {
+		return dc.tombstones, nil
+	}
+
+	dc.onceDecodeRanges.Do(func() {
+		if dc.codec == nil {
+			dc.rangesErr = perrors.DecodeErr("legacy table can't hold range tombstones")
+			return
+		}
+		ranges, err := utils.DecodeRangeTombstones[K](dc.codec, tombstones)
+		if err != nil {
+			dc.rangesErr = err
+			return
+		}
+		dc.ranges = append(append([]types.KeyRange[K]{}, dc.tombstones...), ranges...)
+	})
+	return dc.ranges, dc.rangesErr
 }
 
 // getDecodedForAll to load all entries of SSTable for compaction
@@ -191,23 +524,5 @@ func (dc *CacheUnit[K, V]) getDecodedForAll() ([]types.Payload[K, V], error) {
 		return nil, dc.err
 	}
 
-	// @todo: pre allocate
-	result := make([]types.Payload[K, V], 0)
-
-	for _, k := range dc.indexDecoded {
-		// Validate range
-		if int(k.Offset+k.Size) > len(dc.dbPayload) {
-			return nil, perrors.IndexOutOfBoundErr("key=%v", k.Key)
-		}
-
-		valDecoder := gob.NewDecoder(bytes.NewReader(dc.dbPayload[k.Offset : k.Offset+k.Size]))
-		var entry types.Payload[K, V]
-		if err := valDecoder.Decode(&entry); err != nil {
-			return nil, perrors.DecodeErr("key=%v, err=%v", k.Key, err)
-		}
-
-		result = append(result, entry)
-	}
-
-	return result, nil
+	return dc.decodeSpan(dc.indexDecoded)
 }
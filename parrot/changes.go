@@ -0,0 +1,201 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
+)
+
+// Subscribe polls commit log at least this far apart
+const minChangePollInterval = 10 * time.Millisecond
+
+// ChangePosition locates a change in commit log, changes are ordered by it.
+// Zero position comes before every change.
+type ChangePosition struct {
+	// record holding change
+	Log metadata.LogPosition
+	// index of change among entries of record
+	Entry int
+}
+
+func (t ChangePosition) Less(other ChangePosition) bool {
+	if t.Log != other.Log {
+		return t.Log.Less(other.Log)
+	}
+	return t.Entry < other.Entry
+}
+
+func (t ChangePosition) IsZero() bool {
+	return t == ChangePosition{}
+}
+
+// String formats position as a token read back by ParseChangePosition
+func (t ChangePosition) String() string {
+	return fmt.Sprintf("%d.%d.%d", t.Log.Segment, t.Log.Index, t.Entry)
+}
+
+// ParseChangePosition parses token returned by ChangePosition.String
+func ParseChangePosition(token string) (ChangePosition, error) {
+	var pos ChangePosition
+	var rest string
+	n, _ := fmt.Sscanf(token, "%d.%d.%d%s", &pos.Log.Segment, &pos.Log.Index, &pos.Entry, &rest)
+	if n != 3 || pos.Log.Segment < 0 || pos.Log.Index < 0 || pos.Entry < 0 {
+		return ChangePosition{}, fmt.Errorf("invalid change position %q", token)
+	}
+	return pos, nil
+}
+
+// Change is a write applied to a family, as it's logged
+type Change[K types.Key, V types.Value] struct {
+	Position ChangePosition
+	Op       memtable.MemtableOperation
+	Key      K
+	// value written, tombstone of deletes or operand of merges
+	Value V
+	// end of deleted range, see WriteBatch.DeleteRange
+	End K
+	// time change is written at in unix nanoseconds
+	Time int64
+}
+
+// ChangeHead returns position of latest change logged, changes following
+// it are the ones written afterwards
+func (t *Storage[K, V]) ChangeHead() (ChangePosition, error) {
+	if t.log == nil {
+		return ChangePosition{}, errors.WALDisablederr
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return ChangePosition{
+		Log:   metadata.LogPosition{Segment: t.log.segment, Index: t.log.count},
+		Entry: max(t.log.entries-1, 0),
+	}, nil
+}
+
+// Subscribe calls fn with every change of family logged after from, in
+// commit order, until ctx is done or fn returns an error. Subscribers
+// pass position of last change they've seen to resume, e.g after restart.
+//   - changes are read from commit log, once they're written out to it
+//     (see MemtableWALTimeInterval). Zero position starts at oldest
+//     segment kept
+//   - segments are kept for ChangeRetention after they're closed, so a
+//     subscriber can only resume from a position logged within it. Older
+//     positions fail with errors.ChangeLogErr, as do ones of segments that
+//     were never logged
+//   - each poll decodes only records appended since the last one
+func (t *Storage[K, V]) Subscribe(ctx context.Context, family string, from ChangePosition, fn func(Change[K, V]) error) error {
+	if t.log == nil {
+		return errors.WALDisablederr
+	}
+
+	ticker := time.NewTicker(max(t.opts.MemtableWALTimeInterval, minChangePollInterval))
+	defer ticker.Stop()
+
+	// cursor: tail of segment being read & number of its records read
+	var (
+		segment int64
+		tail    *wal.Tail[logRecord[K, V]]
+		index   int64
+	)
+	defer func() {
+		if tail != nil {
+			tail.Close()
+		}
+	}()
+
+	// from is moved past every record read, so each tick reads on from
+	// where the last one stopped
+	start := from
+	for {
+		t.mu.RLock()
+		segments := append([]int64(nil), t.log.segments...)
+		t.mu.RUnlock()
+
+		if from.Log.Segment != 0 && !slices.Contains(segments, from.Log.Segment) {
+			return errors.RaiseChangeLogErr("position=%v is no longer kept in commit log, see ChangeRetention", start)
+		}
+
+		for i, seg := range segments {
+			if seg < from.Log.Segment {
+				continue
+			}
+			if tail == nil || segment != seg {
+				if tail != nil {
+					tail.Close()
+				}
+				var err error
+				if tail, err = wal.OpenTail(t.opts.VFS, t.log.path(seg), resolveLogCodec[K, V]); err != nil {
+					return errors.RaiseChangeLogErr("failed to open commit log segment=%d, err=%v", seg, err)
+				}
+				segment, index = seg, 0
+			}
+			records, err := tail.Read()
+			if err != nil {
+				return errors.RaiseChangeLogErr("failed to read commit log segment=%d, err=%v", seg, err)
+			}
+
+			for _, record := range records {
+				index++
+				pos := metadata.LogPosition{Segment: seg, Index: index}
+				if record.Err != nil {
+					return errors.RaiseChangeLogErr("failed to decode record=%v, err=%v", pos, record.Err)
+				}
+				if pos.Less(from.Log) {
+					continue
+				}
+
+				for k, entry := range record.Event.Entries {
+					change := ChangePosition{Log: pos, Entry: k}
+					if entry.Family != family || !from.Less(change) {
+						continue
+					}
+					if err := fn(Change[K, V]{
+						Position: change,
+						Op:       entry.Op,
+						Key:      entry.Key,
+						Value:    entry.Value,
+						End:      entry.End,
+						Time:     entry.Time,
+					}); err != nil {
+						return err
+					}
+				}
+				from = ChangePosition{Log: pos, Entry: len(record.Event.Entries)}
+			}
+
+			// segments followed by another are closed & read whole, so
+			// they can be released without losing position
+			if i+1 < len(segments) {
+				from = ChangePosition{Log: metadata.LogPosition{Segment: segments[i+1]}}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.context.Done():
+			return t.context.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Subscribe calls fn with every change of family logged after from, see
+// Storage.Subscribe
+func (t *Family[K, V]) Subscribe(ctx context.Context, from ChangePosition, fn func(Change[K, V]) error) error {
+	return t.storage.Subscribe(ctx, t.name, from, fn)
+}
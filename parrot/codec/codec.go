@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// Codec serializes keys & values of a storage. Records of ssts & logs are
+// framed by storage, codec only handles a single key or value.
+//
+// Note: keys & values are passed as `any` since types.Key is a constraint,
+// they always hold storage's concrete key & value types. Decode methods get
+// a pointer to them, like encoding/json.
+type Codec interface {
+	// ID is recorded in header of every file, so files written by a codec stay
+	// readable after storage switches to another one. It must never change.
+	ID() byte
+
+	Name() string
+
+	AppendKey(buf []byte, key any) ([]byte, error)
+	DecodeKey(data []byte, key any) error
+
+	// deleted & operand markers are kept by storage, codec need not encode them
+	AppendValue(buf []byte, value any) ([]byte, error)
+	DecodeValue(data []byte, value any) error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[byte]Codec{GobID: Gob}
+)
+
+// Register makes c available for reading files written by it,
+// re-registering same codec is a no-op
+func Register(c Codec) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if existing, ok := registry[c.ID()]; ok {
+		if existing.Name() != c.Name() {
+			return errors.RaiseCodecErr("id %d already taken by %q", c.ID(), existing.Name())
+		}
+		return nil
+	}
+	registry[c.ID()] = c
+	return nil
+}
+
+// Lookup returns registered codec with id
+func Lookup(id byte) (Codec, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	c, ok := registry[id]
+	if !ok {
+		return nil, errors.RaiseCodecErr("unknown codec id %d", id)
+	}
+	return c, nil
+}
+
+// file header: magic followed by codec id. Files written before codecs were
+// introduced are plain gob streams, which never start with a zero byte.
+var magic = []byte{0x00, 'p', 'r', 't'}
+
+const HeaderSize = 5
+
+// AppendHeader appends file header naming codec with id
+func AppendHeader(buf []byte, id byte) []byte {
+	buf = append(buf, magic...)
+	return append(buf, id)
+}
+
+// ParseHeader returns codec id named by file header & data following it.
+// ok is false for legacy gob files, data is returned as is.
+func ParseHeader(data []byte) (id byte, rest []byte, ok bool) {
+	if len(data) < HeaderSize || !bytes.Equal(data[:len(magic)], magic) {
+		return 0, data, false
+	}
+	return data[len(magic)], data[HeaderSize:], true
+}
+
+// entry flags
+const (
+	flagDeleted byte = 1 << iota
+	flagOperand
+	// value is kept in value log, entry holds its types.ValueRef
+	flagRef
+	// entry is stamped with time it was written, see types.Payload.Time
+	flagTimed
+)
+
+// AppendEntry appends key & value as a single record
+//   - flags | uvarint key size | key | value
+func AppendEntry[K types.Key, V types.Value](c Codec, buf []byte, key K, value V) ([]byte, error) {
+	return AppendPayload(c, buf, types.Payload[K, V]{Key: key, Val: value})
+}
+
+// AppendPayload appends pl as a single record, payloads pointing to value
+// log are stored as
+//   - flags | uvarint key size | key | uvarint segment | uvarint offset | uvarint size
+//
+// write time of stamped payloads follows flags as uvarint
+func AppendPayload[K types.Key, V types.Value](c Codec, buf []byte, pl types.Payload[K, V]) ([]byte, error) {
+	var flags byte
+	if pl.Ref != nil {
+		flags |= flagRef
+	} else {
+		if pl.Val.IsDeleted() {
+			flags |= flagDeleted
+		}
+		if types.IsOperand(pl.Val) {
+			flags |= flagOperand
+		}
+	}
+	if pl.Time > 0 {
+		flags |= flagTimed
+	}
+	buf = append(buf, flags)
+	if pl.Time > 0 {
+		buf = binary.AppendUvarint(buf, uint64(pl.Time))
+	}
+
+	keyBuf, err := c.AppendKey(nil, pl.Key)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(keyBuf)))
+	buf = append(buf, keyBuf...)
+
+	if pl.Ref == nil {
+		return c.AppendValue(buf, pl.Val)
+	}
+	buf = binary.AppendUvarint(buf, uint64(pl.Ref.Segment))
+	buf = binary.AppendUvarint(buf, uint64(pl.Ref.Offset))
+	return binary.AppendUvarint(buf, uint64(pl.Ref.Size)), nil
+}
+
+// DecodeEntry decodes record built by AppendEntry
+func DecodeEntry[K types.Key, V types.Value](c Codec, data []byte) (K, V, error) {
+	pl, err := DecodePayload[K, V](c, data)
+	if err == nil && pl.Ref != nil {
+		err = errors.RaiseCodecErr("unresolved value of key=%v", pl.Key)
+	}
+	return pl.Key, pl.Val, err
+}
+
+// DecodePayload decodes record built by AppendPayload or AppendEntry,
+// value log pointers are returned unresolved
+func DecodePayload[K types.Key, V types.Value](c Codec, data []byte) (types.Payload[K, V], error) {
+	var pl types.Payload[K, V]
+	if len(data) < 1 {
+		return pl, errors.RaiseCodecErr("empty entry")
+	}
+	flags, data := data[0], data[1:]
+
+	if flags&flagTimed != 0 {
+		time, n := binary.Uvarint(data)
+		if n <= 0 {
+			return pl, errors.RaiseCodecErr("truncated entry time")
+		}
+		pl.Time, data = int64(time), data[n:]
+	}
+
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return pl, errors.RaiseCodecErr("truncated entry key")
+	}
+	data = data[n:]
+
+	if err := c.DecodeKey(data[:size], &pl.Key); err != nil {
+		return pl, err
+	}
+	data = data[size:]
+
+	if flags&flagRef != 0 {
+		var fields [3]uint64
+		for i := range fields {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return pl, errors.RaiseCodecErr("truncated value ref of key=%v", pl.Key)
+			}
+			fields[i], data = v, data[n:]
+		}
+		pl.Ref = &types.ValueRef{Segment: int64(fields[0]), Offset: int64(fields[1]), Size: int64(fields[2])}
+		return pl, nil
+	}
+
+	if err := c.DecodeValue(data, &pl.Val); err != nil {
+		return pl, err
+	}
+	if any(pl.Val) == nil {
+		return pl, errors.RaiseCodecErr("nil value for key=%v", pl.Key)
+	}
+
+	if flags&flagDeleted != 0 {
+		pl.Val.MarkDeleted()
+	}
+	if flags&flagOperand != 0 {
+		if o, ok := any(pl.Val).(types.Operand); ok {
+			o.MarkOperand()
+		}
+	}
+	return pl, nil
+}
+
+// AppendFrame appends record prefixed by its size
+func AppendFrame(buf []byte, record []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(record)))
+	return append(buf, record...)
+}
+
+// ReadFrame returns first record of data & data following it, ok is false
+// if data holds no complete record, e.g torn tail of a log
+func ReadFrame(data []byte) (record []byte, rest []byte, ok bool) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return nil, data, false
+	}
+	return data[n : n+int(size)], data[n+int(size):], true
+}
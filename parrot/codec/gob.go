@@ -0,0 +1,49 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+const GobID byte = 1
+
+// Gob is the default codec, every key & value is a self describing gob
+// message so it works with any type at the cost of size & speed.
+// Interface fields need gob.Register of their concrete types.
+var Gob Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) ID() byte { return GobID }
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) AppendKey(buf []byte, key any) ([]byte, error) {
+	return appendGob(buf, key)
+}
+
+func (gobCodec) DecodeKey(data []byte, key any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(key)
+}
+
+func (gobCodec) AppendValue(buf []byte, value any) ([]byte, error) {
+	return appendGob(buf, value)
+}
+
+func (gobCodec) DecodeValue(data []byte, value any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+func appendGob(buf []byte, v any) ([]byte, error) {
+	w := bytes.NewBuffer(buf)
+	if err := gob.NewEncoder(w).Encode(v); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
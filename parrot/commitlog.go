@@ -0,0 +1,254 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
+)
+
+// logEntry is a single operation on a family
+type logEntry[K types.Key, V types.Value] struct {
+	Family string
+	Key    K
+	Value  V
+	Op     memtable.MemtableOperation
+
+	// end of deleted range, see WriteBatch.DeleteRange
+	End K
+
+	// time entry is written at, see Storage.apply
+	Time int64
+}
+
+func (t logEntry[K, V]) event() memtable.MemTableEvent[K, V] {
+	return memtable.MemTableEvent[K, V]{Key: t.Key, Value: t.Value, Op: t.Op, End: t.End, Time: t.Time}
+}
+
+// logRecord is the unit of commit log, entries of a record are
+// replayed all or none
+type logRecord[K types.Key, V types.Value] struct {
+	Entries []logEntry[K, V]
+}
+
+// logCodec encodes commit log records with storage codec
+//   - uvarint entry count | (uvarint family size | family | uvarint event size | event)...
+type logCodec[K types.Key, V types.Value] struct {
+	c codec.Codec
+}
+
+func (t logCodec[K, V]) ID() byte { return t.c.ID() }
+
+func (t logCodec[K, V]) Encode(buf []byte, rec logRecord[K, V]) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(len(rec.Entries)))
+
+	var event []byte
+	for _, entry := range rec.Entries {
+		var err error
+		event, err = memtable.AppendEvent(t.c, event[:0], entry.event())
+		if err != nil {
+			return nil, err
+		}
+		buf = codec.AppendFrame(buf, []byte(entry.Family))
+		buf = codec.AppendFrame(buf, event)
+	}
+	return buf, nil
+}
+
+func (t logCodec[K, V]) Decode(data []byte) (logRecord[K, V], error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return logRecord[K, V]{}, errors.RaiseCodecErr("truncated log record")
+	}
+	data = data[n:]
+
+	rec := logRecord[K, V]{Entries: make([]logEntry[K, V], 0, count)}
+	for range count {
+		family, rest, ok := codec.ReadFrame(data)
+		if !ok {
+			return logRecord[K, V]{}, errors.RaiseCodecErr("truncated log entry")
+		}
+		raw, rest, ok := codec.ReadFrame(rest)
+		if !ok {
+			return logRecord[K, V]{}, errors.RaiseCodecErr("truncated log entry")
+		}
+		data = rest
+
+		event, err := memtable.DecodeEvent[K, V](t.c, raw)
+		if err != nil {
+			return logRecord[K, V]{}, err
+		}
+		rec.Entries = append(rec.Entries, logEntry[K, V]{Family: string(family), Key: event.Key, Value: event.Value, Op: event.Op, End: event.End, Time: event.Time})
+	}
+	return rec, nil
+}
+
+// rawCodec writes records encoded by commitLog.append as they are
+type rawCodec struct {
+	id byte
+}
+
+func (t rawCodec) ID() byte { return t.id }
+
+func (t rawCodec) Encode(buf []byte, rec []byte) ([]byte, error) {
+	return append(buf, rec...), nil
+}
+
+func (t rawCodec) Decode(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func resolveLogCodec[K types.Key, V types.Value](id byte) (wal.Codec[logRecord[K, V]], error) {
+	c, err := codec.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return logCodec[K, V]{c: c}, nil
+}
+
+// commitLog is write-ahead log shared by all families of a storage.
+// It's split into segments, a new segment is started whenever a memtable
+// overflows. A segment is deleted once every memtable holding its data is flushed
+// & it's been closed for ChangeRetention, see Storage.Subscribe.
+type commitLog[K types.Key, V types.Value] struct {
+	dir   string
+	opts  wal.WALOpts
+	codec codec.Codec
+
+	// id of active segment, ids are increasing
+	segment int64
+	wal     *wal.WAL[[]byte]
+	// records appended to active segment
+	count int64
+	// entries of last record appended, see Storage.ChangeHead
+	entries int
+
+	// ids of all segments on disk, including active one
+	segments []int64
+
+	// ids of segments found on open, see replay
+	recovered []int64
+}
+
+// openCommitLog lists existing segments at dir & starts a new active segment.
+// Existing segments are left for replay.
+func openCommitLog[K types.Key, V types.Value](dir string, opts wal.WALOpts, c codec.Codec) *commitLog[K, V] {
+	t := &commitLog[K, V]{dir: dir, opts: opts, codec: c}
+
+	files, err := io.Glob(opts.VFS, filepath.Join(dir, "log-*.log"))
+	if err != nil {
+		log.Errorf("error listing commit log segments: %v", err)
+	}
+	for _, file := range files {
+		var id int64
+		if _, err := fmt.Sscanf(filepath.Base(file), "log-%d.log", &id); err == nil {
+			t.segments = append(t.segments, id)
+		}
+	}
+	sort.Slice(t.segments, func(i, j int) bool {
+		return t.segments[i] < t.segments[j]
+	})
+	t.recovered = append([]int64(nil), t.segments...)
+
+	t.rotate()
+	return t
+}
+
+func (t *commitLog[K, V]) path(segment int64) string {
+	return filepath.Join(t.dir, fmt.Sprintf("log-%d.log", segment))
+}
+
+// replay calls fn for every record of segments written before open along
+// with its position, oldest first
+func (t *commitLog[K, V]) replay(fn func(pos metadata.LogPosition, rec logRecord[K, V])) {
+	for _, segment := range t.recovered {
+		records, err := wal.ReplayWithCodec(t.opts.VFS, t.path(segment), resolveLogCodec[K, V])
+		if err != nil {
+			continue
+		}
+		for i, rec := range records {
+			fn(metadata.LogPosition{Segment: segment, Index: int64(i + 1)}, rec)
+		}
+	}
+}
+
+// append logs rec to active segment & returns its position. rec is encoded
+// before append returns, values of rec are handed to memtables afterwards
+// & log writer must not see them change
+func (t *commitLog[K, V]) append(rec logRecord[K, V]) (metadata.LogPosition, error) {
+	data, err := logCodec[K, V]{c: t.codec}.Encode(nil, rec)
+	if err != nil {
+		return metadata.LogPosition{}, err
+	}
+	t.wal.Append(data)
+	t.count++
+	t.entries = len(rec.Entries)
+	return metadata.LogPosition{Segment: t.segment, Index: t.count}, nil
+}
+
+// rotate closes active segment & starts a new one
+func (t *commitLog[K, V]) rotate() {
+	next := time.Now().UnixNano()
+	if n := len(t.segments); n > 0 && next <= t.segments[n-1] {
+		next = t.segments[n-1] + 1
+	}
+
+	opts := t.opts
+	opts.Path = t.path(next)
+	wl, _ := wal.NewWALWithCodec[[]byte](opts, rawCodec{id: t.codec.ID()})
+
+	if t.wal != nil {
+		// closing drains pending records to disk
+		t.wal.Close()
+	}
+	t.wal = wl
+	t.segment = next
+	t.count = 0
+	t.entries = 0
+	t.segments = append(t.segments, next)
+}
+
+// obsolete returns segments older than oldest which were closed before
+// horizon (unix nanos), active segment is never obsolete
+func (t *commitLog[K, V]) obsolete(oldest int64, horizon int64) []int64 {
+	var segments []int64
+	for i, segment := range t.segments {
+		// a segment is closed once the next one is started
+		if segment >= oldest || segment == t.segment || t.segments[i+1] > horizon {
+			break
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// release deletes obsolete segments, see obsolete. Segments released by
+// another release since they were listed are skipped.
+func (t *commitLog[K, V]) release(segments []int64) {
+	fm := io.Manager(t.opts.VFS)
+	for _, segment := range segments {
+		if len(t.segments) == 0 || t.segments[0] != segment {
+			continue
+		}
+		if err := fm.Delete(t.path(segment)); err != nil {
+			log.Errorf("failed to delete commit log segment, err=%v", err)
+		}
+		t.segments = t.segments[1:]
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package compactor
+
+import (
+	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+// FilterDecision tells a compaction strategy what to do with an entry
+type FilterDecision int
+
+const (
+	// FilterKeep retains the entry unchanged
+	FilterKeep FilterDecision = iota
+	// FilterDrop removes the entry from compaction output, a tombstone
+	// takes its place while deeper levels might hold older versions
+	FilterDrop
+	// FilterReplace retains the key with the value returned by the filter
+	FilterReplace
+)
+
+// CompactionFilter is consulted by compaction strategies for every live entry
+// that survives the merge of input tables. It allows purging or rewriting
+// documents without pushing every key through the write path.
+//
+// Note: key is passed as `any` since types.Key is a constraint & can't be used
+// as a plain interface type; it always holds the storage's concrete key type.
+type CompactionFilter interface {
+	Filter(level int, key any, value types.Value) (FilterDecision, types.Value)
+}
+
+// CompactionFilterFunc adapts an ordinary function to CompactionFilter
+type CompactionFilterFunc func(level int, key any, value types.Value) (FilterDecision, types.Value)
+
+func (f CompactionFilterFunc) Filter(level int, key any, value types.Value) (FilterDecision, types.Value) {
+	return f(level, key, value)
+}
+
+// applyFilter runs filter on merged entries of level l & returns the retained ones.
+//   - tombstones are not passed to filter, they must survive to shadow older versions
+//   - pending merge operands are not passed either, they aren't full values
+//   - a replacement of an incompatible type is ignored & the original is kept
+//   - values kept in value log are loaded for filter, a kept one stays there
+//   - a dropped version takes older versions of its key along & leaves a
+//     tombstone, which shadows versions held by deeper levels. Tombstones are
+//     dropped later on if output is bottom level, see dropTombstones
+func applyFilter[K types.Key, V types.Value](filter CompactionFilter, cache *v2.CacheManager[K, V], l int, merged []types.Payload[K, V]) []types.Payload[K, V] {
+	if filter == nil {
+		return merged
+	}
+
+	var (
+		dropped    K
+		hasDropped bool
+	)
+	retained := merged[:0]
+	for _, pl := range merged {
+		if hasDropped && pl.Key == dropped {
+			continue
+		}
+
+		value := pl.Val
+		if pl.Ref != nil {
+			resolved := pl
+			if err := cache.Resolve(&resolved); err != nil {
+				log.Panicf("failed to resolve value while running gc, key=%v, err=%v", pl.Key, err)
+			}
+			value = resolved.Val
+		} else if pl.Val.IsDeleted() || types.IsOperand(pl.Val) {
+			retained = append(retained, pl)
+			continue
+		}
+
+		decision, replacement := filter.Filter(l, pl.Key, value)
+		switch decision {
+		case FilterDrop:
+			dropped, hasDropped = pl.Key, true
+			pl.Val, pl.Ref = types.NewTombstone[V](), nil
+		case FilterReplace:
+			v, ok := replacement.(V)
+			if !ok {
+				log.Warnf("compaction filter returned %T for key=%v, keeping original", replacement, pl.Key)
+				break
+			}
+			pl.Val, pl.Ref = v, nil
+		}
+		retained = append(retained, pl)
+	}
+	return retained
+}
@@ -9,14 +9,20 @@ package compactor
 import (
 	"container/heap"
 	"context"
+	"math"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 	"github.com/nagarajRPoojari/orange/parrot/wal"
 
 	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
 	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/merge"
 	"github.com/nagarajRPoojari/orange/parrot/metadata"
 	"github.com/nagarajRPoojari/orange/parrot/types"
 	"github.com/nagarajRPoojari/orange/parrot/utils"
@@ -47,6 +53,13 @@ type GCOpts struct {
 	WALWriterBufferSize int
 	// Directory path where WAL files will be stored
 	WALLogDir string
+
+	// Log shared with other GC instances, see OpenLog. Takes precedence over
+	// WAL options when set
+	Log *wal.WAL[Event]
+
+	// VFS log is kept on, VFS of manifest if nil
+	VFS io.VFS
 }
 
 // GC handles garbage collection and compaction for the storage engine.
@@ -68,29 +81,48 @@ type GC[K types.Key, V types.Value] struct {
 }
 
 func NewGC[K types.Key, V types.Value](mf *metadata.Manifest, cache *v2.CacheManager[K, V], strategy CompactionStrategy[K, V], opts GCOpts) *GC[K, V] {
+	if opts.VFS == nil {
+		opts.VFS = mf.VFS()
+	}
+	wl := opts.Log
+	if wl == nil {
+		wl = OpenLog(opts)
+	}
+
+	gc := &GC[K, V]{mf, cache, strategy, wl, &opts}
 
+	return gc
+}
+
+// OpenLog rolls back partial operations recorded in compaction log at
+// opts.WALLogDir & opens it for appending. Paths in log are absolute,
+// so a single log can be shared by GC instances of different LSMs.
+func OpenLog(opts GCOpts) *wal.WAL[Event] {
 	logPath := filepath.Join(opts.WALLogDir, "gc-wal.log")
+
+	fm := io.Manager(opts.VFS)
+	events, err := wal.Replay[Event](opts.VFS, logPath)
+	if err == nil {
+		rollback(fm, events)
+
+		// log is started afresh once partial runs are rolled back
+		fm.Delete(logPath)
+	}
+
 	wl, _ := wal.NewWAL[Event](
 		wal.WALOpts{
 			Path:             logPath,
 			TimeInterval:     opts.WALTimeInterval,
 			EventChSize:      opts.WALEventChSize,
 			WriterBufferSize: opts.WALWriterBufferSize,
+			VFS:              opts.VFS,
 		},
 	)
-
-	events, err := wal.Replay[Event](logPath)
-	if err == nil {
-		rollback(events)
-	}
-
-	gc := &GC[K, V]{mf, cache, strategy, wl, &opts}
-
-	return gc
+	return wl
 }
 
 // #WIP: rollback supports partial recovery from broken compaction process.
-func rollback(events []Event) {
+func rollback(fm *io.FileManager, events []Event) {
 	partialDeletes := map[string]struct{}{}
 	partialWrites := map[string]struct{}{}
 	for _, event := range events {
@@ -106,7 +138,6 @@ func rollback(events []Event) {
 		}
 	}
 
-	fm := io.GetFileManager()
 	for path := range partialDeletes {
 		fm.Delete(path)
 	}
@@ -125,12 +156,49 @@ func (t *GC[K, V]) Run(ctx context.Context) {
 			log.Infof("Shutting down gc")
 			return
 		case <-ticker.C:
+			// select picks randomly among ready cases, never start a run
+			// once shutdown is requested
+			if ctx.Err() != nil {
+				log.Infof("Shutting down gc")
+				return
+			}
 			// gc should run synchronously
-			t.strategy.Run(t.mf, t.cache, t.wal, 0)
+			t.RunOnce()
 		}
 	}
 }
 
+// RunOnce runs a single compaction pass, used by owners scheduling
+// compaction of several LSMs on their own workers
+func (t *GC[K, V]) RunOnce() {
+	// a level may overflow while levels above it don't, e.g after
+	// CompactRange, so every level is checked
+	for l := 0; l < t.mf.GetLSM().LevelsCount(); l++ {
+		t.strategy.Run(t.mf, t.cache, t.wal, l)
+	}
+}
+
+// CompactLevel merges all tables of level l into level l+1 regardless of
+// level size, strategy must implement LevelCompactor
+func (t *GC[K, V]) CompactLevel(l int) error {
+	c, ok := t.strategy.(LevelCompactor[K, V])
+	if !ok {
+		return errors.RaiseCompactionErr("strategy %T can't compact a level on demand", t.strategy)
+	}
+	c.CompactLevel(t.mf, t.cache, t.wal, l)
+	return nil
+}
+
+// Score tells how far LSM is over limits of strategy, compaction is due once
+// it reaches 1. Strategies not implementing Scorer are always due
+func (t *GC[K, V]) Score() float64 {
+	s, ok := t.strategy.(Scorer)
+	if !ok {
+		return 1
+	}
+	return s.Score(t.mf)
+}
+
 type CompactionStrategyOpts interface {
 }
 
@@ -138,6 +206,18 @@ type CompactionStrategy[K types.Key, V types.Value] interface {
 	Run(*metadata.Manifest, *v2.CacheManager[K, V], *wal.WAL[Event], int)
 }
 
+// LevelCompactor is implemented by strategies able to compact a level on
+// demand, see GC.CompactLevel
+type LevelCompactor[K types.Key, V types.Value] interface {
+	CompactLevel(*metadata.Manifest, *v2.CacheManager[K, V], *wal.WAL[Event], int)
+}
+
+// Scorer is implemented by strategies able to tell how urgently LSM needs
+// compaction, see GC.Score
+type Scorer interface {
+	Score(*metadata.Manifest) float64
+}
+
 type SizeTiredCompactionOpts struct {
 	// Soft size limit for level 0 (in bytes)
 	Level0MaxSizeInBytes int64
@@ -145,13 +225,51 @@ type SizeTiredCompactionOpts struct {
 	// Growth factor used to compute soft size limits for higher levels.
 	// For level x: maxSize = Level0MaxSizeInBytes * max(x * growthFactor, 1)
 	MaxSizeInBytesGrowthFactor int32
+
+	// Maximum number of key ranges a compaction is split into, ranges are
+	// merged & written in parallel, each to its own table. 0 or 1 disables
+	// subcompactions. Filter & MergeOperator must be safe for concurrent
+	// use if enabled
+	MaxSubcompactions int
+	// Minimum number of input entries per subcompaction, defaults to 4096
+	SubcompactionMinEntries int
+
+	// Versions newer than Retention are kept for reads of past times along
+	// with newest version older than that, only newest version of each key
+	// is kept if 0
+	Retention time.Duration
 }
 
+const (
+	defaultSubcompactionMinEntries = 4096
+	// keys sampled per subcompaction while picking split points
+	splitSamplesPerRange = 32
+)
+
 // SizeTiredCompaction implements a size-tiered compaction strategy.
 // It selects SSTables for compaction based on their sizes, grouping similar-sized files.
 type SizeTiredCompaction[K types.Key, V types.Value] struct {
 	// Configuration options for size-tiered compaction
 	Opts SizeTiredCompactionOpts
+
+	// Filter is called for every surviving entry, optional
+	Filter CompactionFilter
+
+	// MergeOperator folds merge operands with older versions, optional
+	MergeOperator merge.Operator
+
+	// Codec of compacted tables, gob if nil. Inputs are read with codec
+	// they were written by
+	Codec codec.Codec
+
+	// Comparator tables of types.BytesKey keys are ordered by, bytewise
+	// if nil
+	Comparator types.Comparator
+}
+
+// order returns order of keys of compacted tables
+func (t *SizeTiredCompaction[K, V]) order() types.Order[K] {
+	return types.OrderOf[K](t.Comparator)
 }
 
 func (t *SizeTiredCompaction[K, V]) Run(mf *metadata.Manifest, cache *v2.CacheManager[K, V], wal *wal.WAL[Event], l int) {
@@ -162,141 +280,495 @@ func (t *SizeTiredCompaction[K, V]) Run(mf *metadata.Manifest, cache *v2.CacheMa
 
 	size := levelL.SizeInBytes.Load()
 	// check level-l overflow according to size tired compaction strategy
-	if int64(size) > t.Opts.Level0MaxSizeInBytes*max(int64(l)*int64(t.Opts.MaxSizeInBytesGrowthFactor), 1) {
-		log.Infof("Size(level=%d)=%d, growth_factor=%d, l0MaxSize=%d", l, size, t.Opts.MaxSizeInBytesGrowthFactor, t.Opts.MaxSizeInBytesGrowthFactor)
-		log.Infof("Compaction started on level ", l)
+	if size <= t.maxSize(l) {
+		return
+	}
+	log.Infof("Size(level=%d)=%d, growth_factor=%d, l0MaxSize=%d", l, size, t.Opts.MaxSizeInBytesGrowthFactor, t.Opts.MaxSizeInBytesGrowthFactor)
+	t.CompactLevel(mf, cache, wal, l)
 
-		tablesCount := levelL.TablesCount()
+	// adding new table to next level can lead to overflow
+	t.Run(mf, cache, wal, l+1)
+}
 
-		// keeping track of all read ssts id & file, (for deletion)
-		l0TablesIds := []int{}
-		l0TablePaths := []string{}
-		l0TableIndexPaths := []string{}
+// maxSize returns soft size limit of level l
+func (t *SizeTiredCompaction[K, V]) maxSize(l int) int64 {
+	return t.Opts.Level0MaxSizeInBytes * max(int64(l)*int64(t.Opts.MaxSizeInBytesGrowthFactor), 1)
+}
 
-		sstList := make([][]types.Payload[K, V], tablesCount)
+// Score returns highest ratio of level size to its soft limit, a level
+// overflows once its ratio exceeds 1
+func (t *SizeTiredCompaction[K, V]) Score(mf *metadata.Manifest) float64 {
+	lsm := mf.GetLSM()
 
-		// total size of level=l ( sum of all sst size )
-		totalSizeInBytes := 0
+	score := 0.0
+	for l := 0; l < lsm.LevelsCount(); l++ {
+		level, err := lsm.GetLevel(l)
+		if err != nil {
+			break
+		}
+		score = max(score, float64(level.SizeInBytes.Load())/float64(max(t.maxSize(l), 1)))
+	}
+	return score
+}
 
-		keyCount := 0
-		index := 0
+// CompactLevel merges all tables of level l into new tables of level l+1,
+// regardless of level size. Tombstones are dropped once no level below
+// holds tables, there is nothing left for them to shadow.
+//   - large inputs are split into key ranges merged in parallel, each
+//     written to its own table, see SizeTiredCompactionOpts.MaxSubcompactions
+func (t *SizeTiredCompaction[K, V]) CompactLevel(mf *metadata.Manifest, cache *v2.CacheManager[K, V], wal *wal.WAL[Event], l int) {
+	levelL, err := mf.GetLSM().GetLevel(l)
+	if err != nil || levelL.TablesCount() == 0 {
+		return
+	}
+	log.Infof("Compaction started on level ", l)
+
+	// snapshot of tables, sorted newest first (higher id => newer table)
+	// so that duplicate keys resolve to most recent version while merging
+	tables := levelL.GetTables()
+	tablesIds := make([]int, 0, len(tables))
+	for id := range tables {
+		tablesIds = append(tablesIds, id)
+	}
+	sort.Slice(tablesIds, func(i, j int) bool {
+		return tablesIds[i] > tablesIds[j]
+	})
+	tablesCount := len(tablesIds)
+
+	// keeping track of all read ssts id & file, (for deletion)
+	l0TablePaths := []string{}
+	l0TableIndexPaths := []string{}
 
-		// Load all sst from level=l
-		for id, table := range levelL.GetTables() {
+	sstList := make([][]types.Payload[K, V], tablesCount)
+	rangesList := make([][]types.KeyRange[K], tablesCount)
 
-			sst, err := cache.GetFullPayload(table.DBPath, table.IndexPath)
-			if err != nil {
-				log.Panicf("failed to read sst while running gc %v", err)
+	keyCount := 0
+
+	// Load all sst from level=l
+	for index, id := range tablesIds {
+		table := tables[id]
+		sst, err := cache.GetFullPayload(table.DBPath, table.IndexPath)
+		if err != nil {
+			log.Panicf("failed to read sst while running gc %v", err)
+		}
+		sstList[index] = sst
+		keyCount += len(sst)
+
+		ranges, err := cache.RangeTombstones(table)
+		if err != nil {
+			log.Panicf("failed to read range tombstones while running gc %v", err)
+		}
+		rangesList[index] = ranges
+
+		l0TablePaths = append(l0TablePaths, table.DBPath)
+		l0TableIndexPaths = append(l0TableIndexPaths, table.IndexPath)
+	}
+
+	// order of update:
+	// - write merged ssts to level-l+1
+	// - update level-l+1 manifest
+	// - update level-l manifest
+	// - delete level-l[:tablesCount] ssts
+
+	nextLevel, err := mf.GetLSM().GetLevel(l + 1)
+	if err != nil {
+		// indicates no next level, so create one
+		mf.GetLSM().AppendLevel()
+		nextLevel, _ = mf.GetLSM().GetLevel(l + 1)
+	}
+
+	// tombstones & range tombstones keep shadowing older tables of deeper
+	// levels, they're dropped once nothing is left below
+	deeper := hasDeeperTables(mf, l)
+
+	// one table per key range, ids follow range order so they're all newer
+	// than tables already in level-l+1
+	bounds := t.splitPoints(sstList, keyCount)
+	outputs := make([]*metadata.SSTable, len(bounds)+1)
+	firstId := nextLevel.GetNextId()
+
+	var wg sync.WaitGroup
+	for i := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outputs[i] = t.subcompact(mf, cache, wal, l, subRange(bounds, i), firstId+i, sstList, rangesList, deeper)
+		}()
+	}
+	wg.Wait()
+	if len(outputs) > 1 {
+		log.Infof("level=%d compacted by %d subcompactions", l, len(outputs))
+	}
+
+	// publish merged tables & retire inputs as a single step for readers
+	mf.Install(func() {
+		// @todo: getPath & SetSSTable should be atomic
+		// for now no two go routines can SetSSTable on same level
+		// - only gc can append table for level > 0
+		// - only flusher can append table for lebel = 0
+		for i, table := range outputs {
+			// filter & range tombstones might have dropped everything,
+			// nothing is written then
+			if table != nil {
+				nextLevel.SetSSTable(firstId+i, table)
 			}
-			sstList[index] = sst
-			totalSizeInBytes += int(table.SizeInBytes)
-			keyCount += len(sst)
+		}
+
+		// clearing only read tables
+		levelL.Clear(tablesIds)
+	})
+
+	// merged tables must be durable in manifest before inputs are deleted
+	if err := mf.Sync(); err != nil {
+		log.Panicf("failed to sync manifest, error=%v", err)
+	}
 
-			index++
+	manager := io.Manager(mf.VFS())
 
-			l0TablesIds = append(l0TablesIds, id)
-			l0TablePaths = append(l0TablePaths, table.DBPath)
-			l0TableIndexPaths = append(l0TableIndexPaths, table.IndexPath)
+	// - Concurrent read routines may still be accessing these L0 files.
+	// - Fortunately, the OS will not actually remove the files from disk
+	//   until all file descriptors referencing them are closed.
+	for _, path := range l0TablePaths {
+		wal.Append(Event{Path: path, Op: DeleteStarted})
+		if err := manager.Delete(path); err != nil {
+			log.Panicf("failed to delete %s, got error=%v", path, err)
+		}
+		wal.Append(Event{Path: path, Op: DeleteCompleted})
+	}
+
+	for _, path := range l0TableIndexPaths {
+		wal.Append(Event{Path: path, Op: DeleteStarted})
+		if err := manager.Delete(path); err != nil {
+			log.Panicf("failed to delete %s, got error=%v", path, err)
+		}
+		wal.Append(Event{Path: path, Op: DeleteCompleted})
+	}
+}
 
-			if index == tablesCount {
-				break
+// subcompact merges entries of r from input tables of level l & writes them
+// to table id of level l+1. nil is returned if nothing is left to write.
+// Range tombstones are clipped to r, a table must not shadow keys of ranges
+// written by other subcompactions
+func (t *SizeTiredCompaction[K, V]) subcompact(
+	mf *metadata.Manifest,
+	cache *v2.CacheManager[K, V],
+	wal *wal.WAL[Event],
+	l int,
+	r types.KeyRange[K],
+	id int,
+	sstList [][]types.Payload[K, V],
+	rangesList [][]types.KeyRange[K],
+	deeper bool,
+) *metadata.SSTable {
+	less := t.order()
+	runs := make([][]types.Payload[K, V], len(sstList))
+	for i, sst := range sstList {
+		runs[i] = slice(less, sst, r)
+	}
+
+	merged := t.merge(cache, runs, rangesList)
+	merged = applyFilter(t.Filter, cache, l, merged)
+
+	var ranges []types.KeyRange[K]
+	if deeper {
+		for _, tombstones := range rangesList {
+			for _, tombstone := range tombstones {
+				if clipped, ok := tombstone.Intersect(less, r); ok {
+					ranges = append(ranges, clipped)
+				}
 			}
 		}
+	} else {
+		merged = dropTombstones(merged)
+	}
 
-		// K-way merge using next-pointer min heap
-		h := &MergerHeap[K, V]{h: make([]Pair[K, V], 0)}
-		merged := make([]types.Payload[K, V], keyCount)
+	if len(merged) == 0 && len(ranges) == 0 {
+		return nil
+	}
+	return t.writeTable(mf, wal, l+1, id, merged, ranges)
+}
+
+// merge k-way merges runs, ordered newest first, into a single run holding
+// most recent version of each key & older versions still readable within
+// retention, see versions
+func (t *SizeTiredCompaction[K, V]) merge(cache *v2.CacheManager[K, V], runs [][]types.Payload[K, V], rangesList [][]types.KeyRange[K]) []types.Payload[K, V] {
+	keyCount := 0
+	for _, run := range runs {
+		keyCount += len(run)
+	}
+
+	// K-way merge using next-pointer min heap
+	less := t.order()
+	h := &MergerHeap[K, V]{h: make([]Pair[K, V], 0), less: less}
+	merged := make([]types.Payload[K, V], 0, keyCount)
 
-		// init with min payload(j=0) of all tables
-		for i := range tablesCount {
-			heap.Push(h, Pair[K, V]{pl: &sstList[i][0], I: i, J: 0})
+	// init with min payload(j=0) of all tables
+	for i := range runs {
+		if len(runs[i]) > 0 {
+			heap.Push(h, Pair[K, V]{pl: &runs[i][0], I: i, J: 0})
 		}
+	}
 
-		for keyCount > 0 {
-			// pop the minimum payload
-			poped := heap.Pop(h).(Pair[K, V])
-			merged[len(merged)-keyCount] = *poped.pl
-			i, j := poped.I, poped.J
+	horizon := t.horizon()
+	// versions of key being merged, newest first
+	var group []sourced[K, V]
+
+	for h.Len() > 0 {
+		// pop the minimum payload
+		poped := heap.Pop(h).(Pair[K, V])
+		i, j := poped.I, poped.J
+
+		// ties are popped newest table first & versions of a table are
+		// laid out newest first, so versions of a key come newest first
+		key := poped.pl.Key
+		if len(group) > 0 && group[0].pl.Key != key {
+			merged = t.versions(cache, merged, group, horizon)
+			group = group[:0]
+		}
 
-			// push the next pointed payload by current popped paylod
-			if j < len(sstList[i])-1 {
-				heap.Push(h, Pair[K, V]{pl: &sstList[i][j+1], I: i, J: j + 1})
+		// range tombstones delete versions of older tables, a table's own
+		// entries are newer. A version deleted within retention is still
+		// read before range was written, a tombstone at that time hides it
+		// from later reads once range is merged
+		since, ok := covered(less, rangesList[:i], key)
+		switch {
+		case !ok:
+			group = append(group, sourced[K, V]{pl: *poped.pl, run: i})
+		case since > horizon:
+			if n := len(group); n == 0 || group[n-1].run != deletedRun || group[n-1].pl.Time != since {
+				tombstone := types.Payload[K, V]{Key: key, Val: types.NewTombstone[V](), Time: since}
+				group = append(group, sourced[K, V]{pl: tombstone, run: deletedRun})
 			}
-			keyCount--
+			group = append(group, sourced[K, V]{pl: *poped.pl, run: i})
+		}
+
+		// push the next pointed payload by current popped paylod
+		if j < len(runs[i])-1 {
+			heap.Push(h, Pair[K, V]{pl: &runs[i][j+1], I: i, J: j + 1})
+		}
+	}
+	if len(group) > 0 {
+		merged = t.versions(cache, merged, group, horizon)
+	}
+	return merged
+}
+
+// deletedRun is run of tombstones standing for range tombstones, see merge
+const deletedRun = -1
+
+// sourced is a version of key along with index of run it comes from
+type sourced[K types.Key, V types.Value] struct {
+	pl  types.Payload[K, V]
+	run int
+}
+
+// versions appends versions of a single key, ordered newest first, to merged.
+//   - a merge operand already folds older versions of its own run, it's
+//     folded into newest version of older runs. result stays an operand until
+//     a value or tombstone is reached
+//   - versions older than newest one written at or before horizon can't be
+//     read anymore & are dropped
+func (t *SizeTiredCompaction[K, V]) versions(cache *v2.CacheManager[K, V], merged []types.Payload[K, V], group []sourced[K, V], horizon int64) []types.Payload[K, V] {
+	// oldest first, so older version an operand is folded into is final
+	for i := len(group) - 1; i >= 0; i-- {
+		pl := &group[i].pl
+		if t.MergeOperator == nil || pl.Ref != nil || !types.IsOperand(pl.Val) {
+			continue
 		}
 
-		// order of update:
-		// - write merged sst to level-l+1
-		// - update level-l+1 manifest
-		// - update level-l manifest
-		// - delete level-l[:tablesCount] ssts
+		j := i + 1
+		for j < len(group) && group[j].run == group[i].run {
+			j++
+		}
+		if j == len(group) {
+			continue
+		}
 
-		// save to file before updating manifest
-		nextLevel, err := mf.GetLSM().GetLevel(l + 1)
+		older := group[j].pl
+		if err := cache.Resolve(&older); err != nil {
+			log.Panicf("failed to resolve value while running gc, key=%v, err=%v", older.Key, err)
+		}
+		v, err := merge.Combine(t.MergeOperator, pl.Key, older.Val, pl.Val)
 		if err != nil {
-			// indicates no next level, so create one
-			mf.GetLSM().AppendLevel()
-			nextLevel, _ = mf.GetLSM().GetLevel(l + 1)
+			log.Panicf("failed to merge operands while running gc, key=%v, err=%v", pl.Key, err)
 		}
+		pl.Val = v
+	}
 
-		manager := io.GetFileManager()
-		l1TablesNextId := nextLevel.GetNextId()
-		dbPath := mf.FormatDBPath(l+1, l1TablesNextId)
-		indexPath := mf.FormatIndexPath(l+1, l1TablesNextId)
+	for _, v := range group {
+		merged = append(merged, v.pl)
+		if v.pl.Time <= horizon {
+			break
+		}
+	}
+	return merged
+}
 
-		dbWriter := manager.OpenForWrite(dbPath)
-		indexWriter := manager.OpenForWrite(indexPath)
-		defer dbWriter.Close()
-		defer indexWriter.Close()
+// horizon returns time versions must be newer than to be kept, along with
+// newest version written at or before it
+func (t *SizeTiredCompaction[K, V]) horizon() int64 {
+	if t.Opts.Retention <= 0 {
+		return math.MaxInt64
+	}
+	return time.Now().UnixNano() - int64(t.Opts.Retention)
+}
 
-		wal.Append(Event{Path: dbPath, Op: WriteStarted})
-		err = utils.Encode(dbWriter.GetFile(), indexWriter.GetFile(), merged)
-		if err != nil {
-			log.Fatalf("error=%v\n", err)
+// writeTable writes merged entries & range tombstones to table id of level l,
+// table isn't added to manifest
+func (t *SizeTiredCompaction[K, V]) writeTable(mf *metadata.Manifest, wal *wal.WAL[Event], l int, id int, merged []types.Payload[K, V], ranges []types.KeyRange[K]) *metadata.SSTable {
+	// total size of merged entries & value log segments they refer to,
+	// values in value log are carried over as refs
+	var totalSizeInBytes int64
+	segments := map[int64]struct{}{}
+	for _, pl := range merged {
+		if pl.Ref != nil {
+			totalSizeInBytes += int64(pl.Ref.SizeOf())
+			segments[pl.Ref.Segment] = struct{}{}
+			continue
 		}
-		wal.Append(Event{Path: dbPath, Op: WriteCompleted})
+		totalSizeInBytes += int64(pl.Val.SizeOf())
+	}
 
-		// Ensure all buffered data is flushed to disk through fsync system call
-		dbWriter.GetFile().Sync()
-		indexWriter.GetFile().Sync()
-		log.Infof("LSM address - %p %p %p\n", mf.GetLSM(), levelL, nextLevel)
+	manager := io.Manager(mf.VFS())
+	dbPath := mf.FormatDBPath(l, id)
+	indexPath := mf.FormatIndexPath(l, id)
 
-		// @todo: getPath & SetSSTable should be atomic
-		// for now no two go routines can SetSSTable on same level
-		// - only gc can append table for level > 0
-		// - only flusher can append table for lebel = 0
-		nextLevel.SetSSTable(
-			l1TablesNextId,
-			metadata.NewSSTable(dbPath, indexPath, int64(totalSizeInBytes)),
-		)
+	dbWriter, err := manager.OpenForWrite(dbPath)
+	if err != nil {
+		log.Panicf("failed to open table, error=%v", err)
+	}
+	defer dbWriter.Close()
+	indexWriter, err := manager.OpenForWrite(indexPath)
+	if err != nil {
+		log.Panicf("failed to open table index, error=%v", err)
+	}
+	defer indexWriter.Close()
 
-		// clearing only read tables
-		levelL.Clear(l0TablesIds)
-
-		// - Concurrent read routines may still be accessing these L0 files.
-		// - Fortunately, the OS will not actually remove the files from disk
-		//   until all file descriptors referencing them are closed.
-		for _, path := range l0TablePaths {
-			wal.Append(Event{Path: path, Op: DeleteStarted})
-			if err := manager.Delete(path); err != nil {
-				log.Panicf("failed to delete %s, got error=%v", path, err)
-			}
-			wal.Append(Event{Path: path, Op: DeleteCompleted})
+	wal.Append(Event{Path: dbPath, Op: WriteStarted})
+	c := t.Codec
+	if c == nil {
+		c = codec.Gob
+	}
+	err = utils.Encode(dbWriter.Writer(), indexWriter.Writer(), merged, ranges, c)
+	if err != nil {
+		log.Fatalf("error=%v\n", err)
+	}
+	wal.Append(Event{Path: dbPath, Op: WriteCompleted})
+
+	// Ensure all buffered data is flushed to disk through fsync system call
+	if err := dbWriter.Sync(); err != nil {
+		log.Panicf("failed to sync table, error=%v", err)
+	}
+	if err := indexWriter.Sync(); err != nil {
+		log.Panicf("failed to sync table index, error=%v", err)
+	}
+
+	table := metadata.NewSSTable(dbPath, indexPath, totalSizeInBytes)
+	for segment := range segments {
+		table.ValueSegments = append(table.ValueSegments, segment)
+	}
+	sort.Slice(table.ValueSegments, func(i, j int) bool {
+		return table.ValueSegments[i] < table.ValueSegments[j]
+	})
+	return table
+}
+
+// splitPoints picks keys splitting input of a compaction into key ranges of
+// about equal entry counts, none if input is too small to be split
+func (t *SizeTiredCompaction[K, V]) splitPoints(sstList [][]types.Payload[K, V], keyCount int) []K {
+	minEntries := t.Opts.SubcompactionMinEntries
+	if minEntries <= 0 {
+		minEntries = defaultSubcompactionMinEntries
+	}
+	n := min(t.Opts.MaxSubcompactions, keyCount/minEntries)
+	if n <= 1 {
+		return nil
+	}
+
+	// a few sampled keys per range are enough to even them out
+	step := max(keyCount/(n*splitSamplesPerRange), 1)
+	sample := make([]K, 0, keyCount/step+len(sstList))
+	for _, sst := range sstList {
+		for j := 0; j < len(sst); j += step {
+			sample = append(sample, sst[j].Key)
 		}
+	}
+	less := t.order()
+	sort.Slice(sample, func(i, j int) bool {
+		return less(sample[i], sample[j])
+	})
+
+	bounds := make([]K, 0, n-1)
+	for i := 1; i < n; i++ {
+		key := sample[i*len(sample)/n]
+		if len(bounds) > 0 && !less(bounds[len(bounds)-1], key) {
+			continue
+		}
+		bounds = append(bounds, key)
+	}
+	return bounds
+}
+
+// subRange returns i-th key range split by bounds, first & last ranges are
+// unbounded
+func subRange[K types.Key](bounds []K, i int) types.KeyRange[K] {
+	var r types.KeyRange[K]
+	if i > 0 {
+		r.Start, r.HasStart = bounds[i-1], true
+	}
+	if i < len(bounds) {
+		r.End, r.HasEnd = bounds[i], true
+	}
+	return r
+}
 
-		for _, path := range l0TableIndexPaths {
-			wal.Append(Event{Path: path, Op: DeleteStarted})
-			if err := manager.Delete(path); err != nil {
-				log.Panicf("failed to delete %s, got error=%v", path, err)
+// slice returns entries of run sorted by less falling in r
+func slice[K types.Key, V types.Value](less types.Order[K], run []types.Payload[K, V], r types.KeyRange[K]) []types.Payload[K, V] {
+	lo := sort.Search(len(run), func(j int) bool {
+		return !r.Before(less, run[j].Key)
+	})
+	hi := sort.Search(len(run), func(j int) bool {
+		return r.After(less, run[j].Key)
+	})
+	return run[lo:max(lo, hi)]
+}
+
+// covered returns time of oldest range tombstone of tables deleting key, ok
+// is false if none does
+func covered[K types.Key](less types.Order[K], tables [][]types.KeyRange[K], key K) (since int64, ok bool) {
+	for _, ranges := range tables {
+		for _, r := range ranges {
+			if r.Contains(less, key) && (!ok || r.Time < since) {
+				since, ok = r.Time, true
 			}
-			wal.Append(Event{Path: path, Op: DeleteCompleted})
 		}
+	}
+	return since, ok
+}
 
-		// adding new table to next level can lead to overflow
-		t.Run(mf, cache, wal, l+1)
-
-	} else {
-		return
+// dropTombstones removes deleted entries, merged is reused. A tombstone
+// followed by an older version of its key is kept, it hides that version
+// from reads of later times
+func dropTombstones[K types.Key, V types.Value](merged []types.Payload[K, V]) []types.Payload[K, V] {
+	retained := merged[:0]
+	for i, pl := range merged {
+		if pl.Ref == nil && pl.Val.IsDeleted() && (i == len(merged)-1 || merged[i+1].Key != pl.Key) {
+			continue
+		}
+		retained = append(retained, pl)
 	}
+	return retained
+}
 
+// hasDeeperTables reports whether any level below l holds tables
+func hasDeeperTables(mf *metadata.Manifest, l int) bool {
+	for cnt := l + 1; ; cnt++ {
+		level, err := mf.GetLSM().GetLevel(cnt)
+		if err != nil {
+			return false
+		}
+		if level.TablesCount() > 0 {
+			return true
+		}
+	}
 }
@@ -17,15 +17,21 @@ type Pair[K types.Key, V types.Value] struct {
 }
 
 type MergerHeap[K types.Key, V types.Value] struct {
-	h []Pair[K, V]
+	h    []Pair[K, V]
+	less types.Order[K]
 }
 
 func (h *MergerHeap[K, V]) Len() int {
 	return len(h.h)
 }
 
+// Less orders by key, equal keys are ordered by table index so that
+// version from the newer table (lower index) pops first
 func (h *MergerHeap[K, V]) Less(i, j int) bool {
-	return h.h[i].pl.Key.Less(h.h[j].pl.Key)
+	if h.h[i].pl.Key == h.h[j].pl.Key {
+		return h.h[i].I < h.h[j].I
+	}
+	return h.less(h.h[i].pl.Key, h.h[j].pl.Key)
 }
 
 func (h *MergerHeap[K, V]) Swap(i, j int) {
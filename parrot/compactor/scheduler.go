@@ -0,0 +1,202 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package compactor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+// Task is a unit scheduled by Scheduler, e.g. a family of a storage
+type Task interface {
+	// Score tells how urgently task needs compaction, it's due once score
+	// reaches 1. It's called often, so it must be cheap
+	Score() float64
+	// Compact runs a compaction pass, never called concurrently for a task
+	Compact()
+}
+
+type SchedulerOpts struct {
+	// Number of tasks compacted concurrently, defaults to 1
+	Workers int
+	// Interval tasks are scored at, defaults to 1s
+	TimeInterval time.Duration
+}
+
+// Scheduler compacts tasks registered by any number of storages on a shared
+// pool of workers. Every tick, due tasks are started highest score first
+// while workers are free, so an overflowing level-0 of a busy family isn't
+// starved by many small ones & idle tasks cost a score each.
+type Scheduler struct {
+	opts SchedulerOpts
+
+	mu    sync.Mutex
+	tasks map[Task]*taskState
+	// current tick, a task is started at most once per tick
+	tick int64
+
+	// free workers
+	sem chan struct{}
+	// wakes scheduling loop before next tick, see Notify
+	wake chan struct{}
+	// tracks running tasks
+	wg sync.WaitGroup
+}
+
+type taskState struct {
+	// closed once running compaction is over, nil if task is idle
+	done chan struct{}
+	// tick task was last started in
+	tick int64
+}
+
+func NewScheduler(opts SchedulerOpts) *Scheduler {
+	opts.Workers = max(opts.Workers, 1)
+	if opts.TimeInterval <= 0 {
+		opts.TimeInterval = time.Second
+	}
+	return &Scheduler{
+		opts:  opts,
+		tasks: map[Task]*taskState{},
+		sem:   make(chan struct{}, opts.Workers),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Register adds task to be scheduled, registering it twice is a no-op
+func (t *Scheduler) Register(task Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.tasks[task]; !ok {
+		t.tasks[task] = &taskState{tick: -1}
+	}
+}
+
+// Unregister removes task & waits for its running compaction to finish
+func (t *Scheduler) Unregister(task Task) {
+	t.mu.Lock()
+	var done chan struct{}
+	if state, ok := t.tasks[task]; ok {
+		done = state.done
+	}
+	delete(t.tasks, task)
+	t.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// Notify asks for tasks to be scored without waiting for next tick, e.g
+// after a flush added tables
+func (t *Scheduler) Notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run schedules tasks until ctx is done & waits for running ones to finish
+func (t *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.opts.TimeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Infof("Shutting down compaction scheduler")
+			t.wg.Wait()
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			t.tick++
+			t.mu.Unlock()
+		case <-t.wake:
+		}
+		// select picks randomly among ready cases, never start a run
+		// once shutdown is requested
+		if ctx.Err() == nil {
+			t.schedule(ctx)
+		}
+	}
+}
+
+// schedule starts due tasks, highest score first, while workers are free
+func (t *Scheduler) schedule(ctx context.Context) {
+	type candidate struct {
+		task  Task
+		score float64
+	}
+
+	t.mu.Lock()
+	idle := make([]Task, 0, len(t.tasks))
+	for task, state := range t.tasks {
+		if state.done == nil && state.tick != t.tick {
+			idle = append(idle, task)
+		}
+	}
+	t.mu.Unlock()
+
+	due := make([]candidate, 0, len(idle))
+	for _, task := range idle {
+		if score := task.Score(); score >= 1 {
+			due = append(due, candidate{task, score})
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].score > due[j].score
+	})
+
+	for _, c := range due {
+		select {
+		case t.sem <- struct{}{}:
+		default:
+			// all workers busy, rest is retried once one is free
+			return
+		}
+		if !t.start(ctx, c.task) {
+			<-t.sem
+		}
+	}
+}
+
+// start runs task on a worker unless it was unregistered or started
+// meanwhile
+func (t *Scheduler) start(ctx context.Context, task Task) bool {
+	t.mu.Lock()
+	state, ok := t.tasks[task]
+	if !ok || state.done != nil || state.tick == t.tick {
+		t.mu.Unlock()
+		return false
+	}
+	done := make(chan struct{})
+	state.done, state.tick = done, t.tick
+	t.mu.Unlock()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer close(done)
+
+		if ctx.Err() == nil {
+			task.Compact()
+		}
+
+		t.mu.Lock()
+		state.done = nil
+		t.mu.Unlock()
+
+		<-t.sem
+		// a freed worker can pick up tasks left over by last tick
+		t.Notify()
+	}()
+	return true
+}
@@ -38,6 +38,10 @@ func FileNotFounderr(msg string, args ...any) IOErr {
 	return IOErr(fmt.Sprintf("file not found: "+msg, args...))
 }
 
+func ReadErr(msg string, args ...any) IOErr {
+	return IOErr(fmt.Sprintf("failed to read: "+msg, args...))
+}
+
 type WALErr string
 
 func (t WALErr) Error() string {
@@ -65,3 +69,109 @@ func (t GeneralErr) Error() string {
 func IndexOutOfBoundErr(msg string, args ...any) GeneralErr {
 	return GeneralErr(fmt.Sprintf("index out of bound: "+msg, args...))
 }
+
+type MergeErr string
+
+func (t MergeErr) Error() string {
+	return fmt.Sprintf("merge err: %s", string(t))
+}
+
+const MergeOperatorMissingErr = MergeErr("no merge operator registered")
+
+func RaiseMergeErr(msg string, args ...any) MergeErr {
+	return MergeErr(fmt.Sprintf(msg, args...))
+}
+
+type FamilyErr string
+
+func (t FamilyErr) Error() string {
+	return fmt.Sprintf("family err: %s", string(t))
+}
+
+func RaiseInvalidFamilyErr(msg string, args ...any) FamilyErr {
+	return FamilyErr(fmt.Sprintf("invalid family: "+msg, args...))
+}
+
+type KeyErr string
+
+func (t KeyErr) Error() string {
+	return fmt.Sprintf("key err: %s", string(t))
+}
+
+func RaiseKeyEncodingErr(msg string, args ...any) KeyErr {
+	return KeyErr(fmt.Sprintf("encoding: "+msg, args...))
+}
+
+func RaiseComparatorErr(msg string, args ...any) KeyErr {
+	return KeyErr(fmt.Sprintf("comparator: "+msg, args...))
+}
+
+func RaiseKeyRangeErr(msg string, args ...any) KeyErr {
+	return KeyErr(fmt.Sprintf("invalid range: "+msg, args...))
+}
+
+type CodecErr string
+
+func (t CodecErr) Error() string {
+	return fmt.Sprintf("codec err: %s", string(t))
+}
+
+func RaiseCodecErr(msg string, args ...any) CodecErr {
+	return CodecErr(fmt.Sprintf(msg, args...))
+}
+
+type ValueLogErr string
+
+func (t ValueLogErr) Error() string {
+	return fmt.Sprintf("value log err: %s", string(t))
+}
+
+func RaiseValueLogErr(msg string, args ...any) ValueLogErr {
+	return ValueLogErr(fmt.Sprintf(msg, args...))
+}
+
+type SSTableErr string
+
+func (t SSTableErr) Error() string {
+	return fmt.Sprintf("sstable err: %s", string(t))
+}
+
+func RaiseSSTableErr(msg string, args ...any) SSTableErr {
+	return SSTableErr(fmt.Sprintf(msg, args...))
+}
+
+func RaiseIngestErr(msg string, args ...any) SSTableErr {
+	return SSTableErr(fmt.Sprintf("ingest: "+msg, args...))
+}
+
+type CompactionErr string
+
+func (t CompactionErr) Error() string {
+	return fmt.Sprintf("compaction err: %s", string(t))
+}
+
+const CompactionDisabledErr = CompactionErr("compaction disabled")
+
+func RaiseCompactionErr(msg string, args ...any) CompactionErr {
+	return CompactionErr(fmt.Sprintf(msg, args...))
+}
+
+type ChangeLogErr string
+
+func (t ChangeLogErr) Error() string {
+	return fmt.Sprintf("change log err: %s", string(t))
+}
+
+func RaiseChangeLogErr(msg string, args ...any) ChangeLogErr {
+	return ChangeLogErr(fmt.Sprintf(msg, args...))
+}
+
+type RetentionErr string
+
+func (t RetentionErr) Error() string {
+	return fmt.Sprintf("retention err: %s", string(t))
+}
+
+func RaiseRetentionErr(msg string, args ...any) RetentionErr {
+	return RetentionErr(fmt.Sprintf(msg, args...))
+}
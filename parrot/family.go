@@ -0,0 +1,308 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/compactor"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/parrot/vlog"
+)
+
+// directories of storage root which can't be used as family names
+var reservedFamilies = map[string]struct{}{
+	"log":             {},
+	"gc":              {},
+	"wal":             {},
+	"vlog":            {},
+	quarantineDir:     {},
+	metadata.MANIFEST: {},
+}
+
+// Family is a column family: an independent keyspace with its own memtables
+// and LSM, sharing commit log, flush scheduler & compaction workers with
+// other families of the same storage.
+type Family[K types.Key, V types.Value] struct {
+	name     string
+	store    *memtable.MemtableStore[K, V]
+	manifest *metadata.Manifest
+	gc       *compactor.GC[K, V]
+
+	// value log of large values, nil if values aren't separated
+	vlog *vlog.ValueLog
+	// segment after which next value log gc pass starts, see collectValueLog
+	vlogCursor int64
+
+	reader *Reader[K, V]
+	writer *Writer[K, V]
+
+	// storage family belongs to
+	storage *Storage[K, V]
+
+	// schedules compaction of family, nil if compaction is turned off
+	task *compactionTask[K, V]
+	// set while family is compacted, see pauseCompaction
+	compacting atomic.Bool
+
+	// guards migration of old per-memtable logs, see migrateWAL
+	migrated sync.Once
+}
+
+// Name returns family name
+func (t *Family[K, V]) Name() string {
+	return t.name
+}
+
+func (t *Family[K, V]) Get(key K) ReadStatus[V] {
+	t.storage.mu.RLock()
+	defer t.storage.mu.RUnlock()
+	return t.reader.Get(key)
+}
+
+// GetAt returns value key had at ts, see Storage.GetAt
+func (t *Family[K, V]) GetAt(key K, ts time.Time) ReadStatus[V] {
+	// versions current before horizon may be gone, a read then finds
+	// whichever survived
+	if t.storage.opts.Retention <= 0 {
+		return ReadStatus[V]{Err: errors.RaiseRetentionErr("past versions aren't kept, retention is off")}
+	}
+	if horizon := time.Now().Add(-t.storage.opts.Retention); ts.Before(horizon) {
+		return ReadStatus[V]{Err: errors.RaiseRetentionErr("%s is older than retention horizon %s", ts.Format(time.RFC3339Nano), horizon.Format(time.RFC3339Nano))}
+	}
+
+	t.storage.mu.RLock()
+	defer t.storage.mu.RUnlock()
+	return t.reader.GetAt(key, ts)
+}
+
+// Scan calls fn for live entries of range in key order until fn returns false.
+// Writes are blocked while fn runs, so fn should be short.
+func (t *Family[K, V]) Scan(r types.KeyRange[K], fn func(key K, value V) bool) error {
+	t.storage.mu.RLock()
+	defer t.storage.mu.RUnlock()
+	return t.reader.Scan(r, fn)
+}
+
+func (t *Family[K, V]) Put(key K, value V) WriteStatus {
+	return t.writer.Put(key, value)
+}
+
+func (t *Family[K, V]) Delete(key K, tomstone V) WriteStatus {
+	return t.writer.Delete(key, tomstone)
+}
+
+// DeleteRange deletes keys of [start, end), see Storage.DeleteRange
+func (t *Family[K, V]) DeleteRange(start K, end K) WriteStatus {
+	return t.writer.DeleteRange(start, end)
+}
+
+// Merge records operand for key, see Storage.Merge
+func (t *Family[K, V]) Merge(key K, operand V) WriteStatus {
+	return t.writer.Merge(key, operand)
+}
+
+// Family returns family with given name, creating it if needed.
+// Family named after storage is the default one.
+func (t *Storage[K, V]) Family(name string) (*Family[K, V], error) {
+	t.familyMu.Lock()
+	fam, ok := t.families[name]
+	if !ok {
+		_, reserved := reservedFamilies[name]
+		if name != t.name && (reserved || name == "" || strings.ContainsAny(name, `/\.`)) {
+			t.familyMu.Unlock()
+			return nil, errors.RaiseInvalidFamilyErr("name=%q", name)
+		}
+
+		var err error
+		if fam, err = t.openFamily(name); err != nil {
+			t.familyMu.Unlock()
+			return nil, err
+		}
+		t.families[name] = fam
+		if fam.task != nil {
+			t.scheduler.Register(fam.task)
+		}
+	}
+	t.familyMu.Unlock()
+
+	// migration writes through commit log, so it can't run under familyMu
+	if t.log != nil {
+		fam.migrated.Do(func() {
+			t.migrateWAL(fam, filepath.Join(t.familyDir(name), "wal"))
+		})
+	}
+	return fam, nil
+}
+
+// Families returns names of all opened families
+func (t *Storage[K, V]) Families() []string {
+	t.familyMu.Lock()
+	defer t.familyMu.Unlock()
+
+	names := make([]string, 0, len(t.families))
+	for name := range t.families {
+		names = append(names, name)
+	}
+	return names
+}
+
+// snapshot returns all opened families
+func (t *Storage[K, V]) snapshot() []*Family[K, V] {
+	t.familyMu.Lock()
+	defer t.familyMu.Unlock()
+
+	fams := make([]*Family[K, V], 0, len(t.families))
+	for _, fam := range t.families {
+		fams = append(fams, fam)
+	}
+	return fams
+}
+
+// familyDir returns root directory of family, default family keeps
+// single family layout so existing directories open as is
+//   - <root>/manifest/<family>/manifest.json
+//   - <root>/<family>/level-N/sst-<id>.db
+func (t *Storage[K, V]) familyDir(name string) string {
+	if name == t.name {
+		return t.opts.Directory
+	}
+	return filepath.Join(t.opts.Directory, name)
+}
+
+// openFamily loads manifest & memtables of family, memtable wal is
+// replaced by shared commit log
+func (t *Storage[K, V]) openFamily(name string) (*Family[K, V], error) {
+	dir := t.familyDir(name)
+
+	mf := metadata.NewManifest(name, metadata.ManifestOpts{Dir: dir, VFS: t.opts.VFS})
+	if err := mf.Load(); err != nil {
+		return nil, err
+	}
+
+	// tables of byte keys are only readable under comparator they're written with
+	if _, ok := any(*new(K)).(types.BytesKey); ok {
+		active := t.comparator.Name()
+		switch recorded := mf.GetLSM().GetComparator(); recorded {
+		case "":
+			mf.GetLSM().SetComparator(active)
+		case active:
+		default:
+			return nil, errors.RaiseComparatorErr("family=%s is ordered by %q, not %q", name, recorded, active)
+		}
+	}
+
+	var vl *vlog.ValueLog
+	if t.opts.ValueLogThreshold > 0 {
+		var err error
+		vl, err = vlog.Open(vlog.Opts{
+			Dir:         filepath.Join(dir, "vlog"),
+			SegmentSize: t.opts.ValueLogSegmentSize,
+			Codec:       t.opts.Codec,
+			VFS:         t.opts.VFS,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store := memtable.NewMemtableStore[K, V](
+		mf,
+		t.context,
+		memtable.MemtableOpts{
+			MemtableSoftLimit: int64(t.opts.MemtableThreshold),
+			QueueHardLimit:    t.opts.QueueHardLimit,
+			QueueSoftLimit:    t.opts.QueueSoftLimit,
+			FlushTimeInterval: t.opts.FlushTimeInterval,
+			MergeOperator:     t.opts.MergeOperator,
+			Codec:             t.opts.Codec,
+			ValueLog:          vl,
+			ValueLogThreshold: t.opts.ValueLogThreshold,
+			ExternalFlush:     true,
+			WriteBuffer:       t.buffer,
+			TableRead:         t.opts.TableRead,
+			Retention:         t.opts.Retention,
+			Comparator:        t.comparator,
+		})
+
+	fam := &Family[K, V]{name: name, store: store, manifest: mf, vlog: vl, storage: t}
+	fam.reader = NewReader(store, ReaderOpts{})
+	fam.writer = NewWriter(t, name, WriterOpts{})
+
+	if t.opts.TurnOnCompaction {
+		fam.gc = compactor.NewGC(
+			mf,
+			(*v2.CacheManager[K, V])(store.DecoderCache),
+			&compactor.SizeTiredCompaction[K, V]{
+				Opts: compactor.SizeTiredCompactionOpts{
+					Level0MaxSizeInBytes:       t.opts.Level0MaxSizeInBytes,
+					MaxSizeInBytesGrowthFactor: t.opts.MaxSizeInBytesGrowthFactor,
+					MaxSubcompactions:          t.opts.MaxSubcompactions,
+					SubcompactionMinEntries:    t.opts.SubcompactionMinEntries,
+					Retention:                  t.opts.Retention,
+				},
+				Filter:        t.opts.CompactionFilter,
+				MergeOperator: t.opts.MergeOperator,
+				Codec:         t.opts.Codec,
+				Comparator:    t.comparator,
+			},
+			compactor.GCOpts{Log: t.gcLog},
+		)
+		fam.task = &compactionTask[K, V]{fam: fam}
+	}
+
+	return fam, nil
+}
+
+// migrateWAL moves per-memtable logs written before families shared a
+// commit log into the commit log & applies them.
+func (t *Storage[K, V]) migrateWAL(fam *Family[K, V], dir string) {
+	files := memtable.SortedLogs(t.opts.VFS, dir)
+	if len(files) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, file := range files {
+		events, err := memtable.ReplayLog[K, V](t.opts.VFS, file)
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			// old logs carry no write times, events are stamped as they're moved
+			entry := logEntry[K, V]{Family: fam.name, Key: event.Key, Value: event.Value, Op: event.Op, End: event.End, Time: t.clock.Now()}
+			pos, err := t.log.append(logRecord[K, V]{Entries: []logEntry[K, V]{entry}})
+			if err != nil {
+				log.Errorf("failed to migrate log event for key=%v, err=%v", event.Key, err)
+				continue
+			}
+			if _, err := fam.store.Apply(entry.event(), pos); err != nil {
+				log.Errorf("failed to migrate log event for key=%v, err=%v", event.Key, err)
+			}
+		}
+	}
+
+	// closing segment makes migrated events durable before old logs go away
+	t.log.rotate()
+	for _, file := range files {
+		if err := io.Manager(t.opts.VFS).Delete(file); err != nil {
+			log.Errorf("failed to delete migrated log, err=%v", err)
+		}
+	}
+}
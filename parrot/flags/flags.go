@@ -6,4 +6,7 @@ const (
 	KeyDeletedFlag  Flag = 1
 	KeyNotFoundFlag Flag = 2
 	KeyFoundFlag    Flag = 0
+	// KeyOperandFlag marks a pending merge operand, older versions are needed
+	// to resolve the value
+	KeyOperandFlag Flag = 3
 )
@@ -0,0 +1,289 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/sstable"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+// IngestFiles loads tables built by sstable.Writer into default family,
+// see Family.IngestFiles
+func (t *Storage[K, V]) IngestFiles(paths []string) error {
+	return t.def.IngestFiles(paths)
+}
+
+// ingestFile is a verified table waiting to be registered
+type ingestFile[K types.Key] struct {
+	path    string
+	summary sstable.Summary[K]
+
+	// copy of table inside family directory
+	staged string
+
+	level int
+	id    int
+}
+
+// IngestFiles loads tables built offline by sstable.Writer, bypassing commit
+// log, memtables & compaction. Ingested entries are newer than any version
+// already stored, all files become visible at once or none does.
+//   - files must not overlap each other, nor unflushed writes of family
+//   - each file goes to deepest level with no overlapping table at or above
+//     it, so compaction doesn't have to rewrite it soon
+//   - files are copied, sources are left in place
+func (t *Family[K, V]) IngestFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// sources are on disk, read with keys of this storage if any
+	disk := io.WithKeyring(io.OS, t.storage.opts.Keyring)
+	files := make([]*ingestFile[K], 0, len(paths))
+	for _, path := range paths {
+		f, err := verifyIngestFile[K, V](disk, path, t.storage.comparator)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return t.storage.less(files[i].summary.First, files[j].summary.First)
+	})
+	for i := 1; i < len(files); i++ {
+		if !t.storage.less(files[i-1].summary.Last, files[i].summary.First) {
+			return errors.RaiseIngestErr("%s overlaps %s", files[i-1].path, files[i].path)
+		}
+	}
+
+	// copying is slow, so it's done before writes are blocked
+	fs := t.manifest.VFS()
+	defer func() {
+		for _, f := range files {
+			if f.staged != "" {
+				fs.Remove(f.staged)
+				fs.Remove(sstable.IndexPath(f.staged))
+			}
+		}
+	}()
+	dir := t.storage.familyDir(t.name)
+	if err := fs.MkdirAll(dir, 0755); err != nil {
+		return errors.RaiseIngestErr("failed to create dir=%s, err=%v", dir, err)
+	}
+	for i, f := range files {
+		f.staged = filepath.Join(dir, fmt.Sprintf("ingest-%d-%d.db", time.Now().UnixNano(), i))
+		if err := copyTable(disk, fs, f.path, f.staged); err != nil {
+			return err
+		}
+	}
+
+	// compaction moves tables of deeper levels around without holding
+	// manifest, so it's kept off while levels are picked
+	resume, err := t.pauseCompaction()
+	if err != nil {
+		return err
+	}
+	defer resume()
+
+	// no write can slip in between overlap checks & registration
+	t.storage.mu.Lock()
+	defer t.storage.mu.Unlock()
+
+	for _, f := range files {
+		if t.store.Overlaps(f.summary.First, f.summary.Last) {
+			return errors.RaiseIngestErr("%s overlaps unflushed writes of family=%s", f.path, t.name)
+		}
+	}
+
+	t.manifest.Install(func() {
+		err = t.install(files)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := t.manifest.Sync(); err != nil {
+		return err
+	}
+	log.Infof("ingested %d tables into family=%s", len(files), t.name)
+	return nil
+}
+
+// pauseCompaction waits for running compaction of family to finish & keeps
+// it from being scheduled until returned func is called
+func (t *Family[K, V]) pauseCompaction() (func(), error) {
+	for !t.compacting.CompareAndSwap(false, true) {
+		// a job queued at shutdown is never picked up
+		if err := t.storage.context.Err(); err != nil {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return func() {
+		t.compacting.Store(false)
+	}, nil
+}
+
+// verifyIngestFile checks that table at path of disk can be ingested as is
+func verifyIngestFile[K types.Key, V types.Value](disk io.VFS, path string, cmp types.Comparator) (*ingestFile[K], error) {
+	table, err := sstable.Open[K, V](disk, path, cmp)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := table.Verify()
+	if err != nil {
+		return nil, err
+	}
+	if table.Codec == nil {
+		return nil, errors.RaiseIngestErr("%s has no header, only tables built by sstable.Writer can be ingested", path)
+	}
+	if summary.Entries == 0 {
+		return nil, errors.RaiseIngestErr("%s has no entries", path)
+	}
+	if summary.Refs > 0 {
+		return nil, errors.RaiseIngestErr("%s refers to a value log", path)
+	}
+	if summary.RangeTombstones > 0 {
+		return nil, errors.RaiseIngestErr("%s holds range tombstones", path)
+	}
+	return &ingestFile[K]{path: path, summary: summary}, nil
+}
+
+// install moves staged files into their levels & registers them, caller
+// must hold manifest exclusively
+func (t *Family[K, V]) install(files []*ingestFile[K]) error {
+	lsm := t.manifest.GetLSM()
+	fs := t.manifest.VFS()
+
+	prev := 0
+	for _, f := range files {
+		level, err := t.ingestLevel(f.summary.First, f.summary.Last)
+		if err != nil {
+			return err
+		}
+		lvl, _ := lsm.GetLevel(level)
+
+		// ids order tables of a level, ingested ones are newest
+		f.level, f.id = level, max(lvl.GetNextId(), prev+1)
+		prev = f.id
+	}
+
+	for i, f := range files {
+		err := fs.MkdirAll(t.manifest.FormatLevelPath(f.level), 0755)
+		if err == nil {
+			err = renameTable(fs, f.staged, t.manifest.FormatDBPath(f.level, f.id))
+		}
+		if err != nil {
+			// files moved so far aren't registered, drop them
+			for _, moved := range files[:i] {
+				dbPath := t.manifest.FormatDBPath(moved.level, moved.id)
+				fs.Remove(dbPath)
+				fs.Remove(sstable.IndexPath(dbPath))
+			}
+			return errors.RaiseIngestErr("failed to move %s, err=%v", f.path, err)
+		}
+		f.staged = ""
+	}
+
+	for _, f := range files {
+		lvl, _ := lsm.GetLevel(f.level)
+		dbPath := t.manifest.FormatDBPath(f.level, f.id)
+		lvl.SetSSTable(f.id, metadata.NewSSTable(dbPath, t.manifest.FormatIndexPath(f.level, f.id), f.summary.SizeInBytes))
+	}
+	return nil
+}
+
+// ingestLevel returns deepest level such that no table at or above it
+// holds a key of [first, last], tables of level-0 may overlap each other
+// so it's used if nothing else is free
+func (t *Family[K, V]) ingestLevel(first K, last K) (int, error) {
+	target := 0
+	for l := 0; ; l++ {
+		level, err := t.manifest.GetLSM().GetLevel(l)
+		if err != nil {
+			return target, nil
+		}
+		for _, table := range level.GetTables() {
+			overlap, err := t.tableOverlaps(table, first, last)
+			if err != nil {
+				return 0, err
+			}
+			if overlap {
+				return target, nil
+			}
+		}
+		target = l
+	}
+}
+
+// tableOverlaps reports whether table holds a key of [first, last] or a
+// range tombstone covering one
+func (t *Family[K, V]) tableOverlaps(table *metadata.SSTable, first K, last K) (bool, error) {
+	cache := t.store.DecoderCache
+
+	lo, hi, ok, err := cache.Bounds(table.DBPath, table.IndexPath)
+	if err != nil {
+		return false, err
+	}
+	if ok && !t.storage.less(last, lo) && !t.storage.less(hi, first) {
+		return true, nil
+	}
+
+	ranges, err := cache.RangeTombstones(table)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range ranges {
+		if r.Overlaps(t.storage.less, first, last) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// copyTable copies data & index file of table at src of disk to dst of fs,
+// copies are durable once it returns
+func copyTable(disk io.VFS, fs io.VFS, src string, dst string) error {
+	for _, pair := range [][2]string{{src, dst}, {sstable.IndexPath(src), sstable.IndexPath(dst)}} {
+		if err := copyFile(disk, fs, pair[0], pair[1]); err != nil {
+			return errors.RaiseIngestErr("failed to copy %s, err=%v", pair[0], err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies src of disk to dst of fs, copy is encrypted with active
+// key if encryption is on, whether or not src is
+func copyFile(disk io.VFS, fs io.VFS, src string, dst string) error {
+	data, err := io.Manager(disk).ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return io.Manager(fs).WriteFile(dst, data)
+}
+
+// renameTable moves data & index file of table at src to dst
+func renameTable(fs io.VFS, src string, dst string) error {
+	if err := fs.Rename(src, dst); err != nil {
+		return err
+	}
+	if err := fs.Rename(sstable.IndexPath(src), sstable.IndexPath(dst)); err != nil {
+		fs.Rename(dst, src)
+		return err
+	}
+	return nil
+}
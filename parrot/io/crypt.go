@@ -0,0 +1,491 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encrypted files are laid out as
+//   - header: magic | version | key id size (1 byte) | key id
+//   - frames: sealed size (uint32) | nonce | AES-GCM sealed chunk of plaintext
+//
+// Every frame is authenticated along with header & its offset in file, so
+// frames can't be swapped, moved across files or attributed to another key.
+// Files without magic are read as plaintext, so data written before
+// encryption was turned on stays readable.
+const (
+	cryptMagic   = "\x00PRTENC"
+	cryptVersion = 1
+
+	// plaintext buffered per frame
+	frameSize    = 64 * 1024
+	frameSizeLen = 4
+	maxKeyIDLen  = 255
+)
+
+// Keyring holds keys files are encrypted with, by id. New files are written
+// with active key, files written with any key of ring stay readable. Keys
+// are rotated by adding a new active key, tables are re-encrypted with it
+// as compaction rewrites them.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]cipher.AEAD{}}
+}
+
+// LoadKeyring reads keyfile at path, a line per key of id & hex encoded
+// 16, 24 or 32 bytes AES key separated by space. Blank lines & lines
+// starting with # are skipped, last key is active.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile %s, error=%v", path, err)
+	}
+
+	t := NewKeyring()
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("keyfile %s:%d: expected <id> <hex key>", path, n+1)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("keyfile %s:%d: key isn't hex encoded", path, n+1)
+		}
+		if err := t.Add(fields[0], key); err != nil {
+			return nil, fmt.Errorf("keyfile %s:%d: %v", path, n+1, err)
+		}
+	}
+	if t.active == "" {
+		return nil, fmt.Errorf("keyfile %s has no keys", path)
+	}
+	return t, nil
+}
+
+// Add adds key by id & makes it active
+func (t *Keyring) Add(id string, key []byte) error {
+	if id == "" || len(id) > maxKeyIDLen {
+		return fmt.Errorf("key id must be 1 to %d bytes", maxKeyIDLen)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("invalid key %s, error=%v", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.keys[id]; ok {
+		return fmt.Errorf("duplicate key id %s", id)
+	}
+	t.keys[id] = aead
+	t.active = id
+	return nil
+}
+
+// Active returns id of key new files are written with
+func (t *Keyring) Active() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.active
+}
+
+func (t *Keyring) get(id string) (cipher.AEAD, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	aead, ok := t.keys[id]
+	return aead, ok
+}
+
+func appendCryptHeader(buf []byte, keyID string) []byte {
+	buf = append(buf, cryptMagic...)
+	buf = append(buf, cryptVersion, byte(len(keyID)))
+	return append(buf, keyID...)
+}
+
+// parseCryptHeader returns key id & header size, ok is false if data isn't
+// encrypted
+func parseCryptHeader(data []byte) (keyID string, size int, ok bool, err error) {
+	if !bytes.HasPrefix(data, []byte(cryptMagic)) {
+		return "", 0, false, nil
+	}
+	rest := data[len(cryptMagic):]
+	if len(rest) < 2 || int(rest[1]) > len(rest)-2 {
+		return "", 0, true, fmt.Errorf("truncated encryption header")
+	}
+	if rest[0] != cryptVersion {
+		return "", 0, true, fmt.Errorf("unknown encryption version %d", rest[0])
+	}
+	size = len(cryptMagic) + 2 + int(rest[1])
+	return string(rest[2 : 2+int(rest[1])]), size, true, nil
+}
+
+// frameAD binds frame to header of its file & its offset
+func frameAD(buf []byte, header []byte, offset int64) []byte {
+	buf = append(buf[:0], header...)
+	return binary.LittleEndian.AppendUint64(buf, uint64(offset))
+}
+
+// encrypter seals each write as a frame, it's buffered by FileWriter so
+// frames hold up to frameSize bytes. Larger writes are split, so a read
+// never decrypts more than a frame past range it covers.
+type encrypter struct {
+	file   File
+	aead   cipher.AEAD
+	header []byte
+	// offset of next frame in file
+	offset int64
+
+	buf, ad []byte
+}
+
+func (t *encrypter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > written {
+		chunk := p[written:min(len(p), written+frameSize)]
+		if err := t.seal(chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+	return written, nil
+}
+
+// seal writes chunk as a frame
+func (t *encrypter) seal(chunk []byte) error {
+	nonceSize := t.aead.NonceSize()
+	t.buf = binary.LittleEndian.AppendUint32(t.buf[:0], uint32(nonceSize+len(chunk)+t.aead.Overhead()))
+	t.buf = append(t.buf, make([]byte, nonceSize)...)
+	nonce := t.buf[frameSizeLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	t.ad = frameAD(t.ad, t.header, t.offset)
+	t.buf = t.aead.Seal(t.buf, nonce, chunk, t.ad)
+
+	if _, err := t.file.Write(t.buf); err != nil {
+		return err
+	}
+	t.offset += int64(len(t.buf))
+	return nil
+}
+
+// decrypt returns plaintext of data, which is returned as is if it isn't
+// encrypted. A frame torn by crash at the tail ends plaintext, it's left to
+// format of file to tell whether it's complete.
+func decrypt(data []byte, ring *Keyring) ([]byte, error) {
+	keyID, size, ok, err := parseCryptHeader(data)
+	if !ok || err != nil {
+		return data, err
+	}
+	if ring == nil {
+		return nil, fmt.Errorf("file is encrypted with key %s, no keyring is set", keyID)
+	}
+	aead, ok := ring.get(keyID)
+	if !ok {
+		return nil, fmt.Errorf("file is encrypted with unknown key %s", keyID)
+	}
+
+	header := data[:size]
+	plain := make([]byte, 0, len(data))
+	var ad []byte
+	for offset := int64(size); offset < int64(len(data)); {
+		frame := data[offset:]
+		if len(frame) < frameSizeLen {
+			break
+		}
+		sealedSize := int(binary.LittleEndian.Uint32(frame))
+		frame = frame[frameSizeLen:]
+		if sealedSize < aead.NonceSize()+aead.Overhead() || sealedSize > len(frame) {
+			break
+		}
+		nonce, sealed := frame[:aead.NonceSize()], frame[aead.NonceSize():sealedSize]
+
+		ad = frameAD(ad, header, offset)
+		if plain, err = aead.Open(plain, nonce, sealed, ad); err != nil {
+			return nil, fmt.Errorf("failed to decrypt frame at offset %d, error=%v", offset, err)
+		}
+		offset += int64(frameSizeLen + sealedSize)
+	}
+	return plain, nil
+}
+
+// frame locates a frame of an encrypted file
+type frame struct {
+	// offset of its plaintext & of frame in file
+	plain, file int64
+	// size of its plaintext
+	size int
+}
+
+// cryptReader serves reads of an encrypted file, only frames a read
+// covers are decrypted
+type cryptReader struct {
+	aead   cipher.AEAD
+	header []byte
+	pool   *BufferPool
+
+	// reads sealed bytes of file
+	sealed func(off int64, n int) (Buffer, error)
+
+	// complete frames in file order, next is offset of first one past them
+	frames []frame
+	next   int64
+	// size of plaintext
+	size int64
+}
+
+// newCryptReader returns reader of file of given size whose sealed bytes
+// are read by sealed, nil if file isn't encrypted
+func (t *FileManager) newCryptReader(sealed func(off int64, n int) (Buffer, error), size int64, pool *BufferPool) (*cryptReader, error) {
+	prefix, err := sealed(0, int(min(size, int64(len(cryptMagic)+2+maxKeyIDLen))))
+	if err != nil {
+		return nil, err
+	}
+	defer prefix.Release()
+	keyID, n, ok, err := parseCryptHeader(prefix.Bytes())
+	if !ok || err != nil {
+		return nil, err
+	}
+	ring := t.Keyring()
+	if ring == nil {
+		return nil, fmt.Errorf("file is encrypted with key %s, no keyring is set", keyID)
+	}
+	aead, ok := ring.get(keyID)
+	if !ok {
+		return nil, fmt.Errorf("file is encrypted with unknown key %s", keyID)
+	}
+
+	r := &cryptReader{
+		aead:   aead,
+		header: bytes.Clone(prefix.Bytes()[:n]),
+		pool:   pool,
+		sealed: sealed,
+		next:   int64(n),
+	}
+	if err := r.index(size); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// index adds frames lying before end of file, a frame torn by crash at the
+// tail is left out like decrypt does
+func (t *cryptReader) index(end int64) error {
+	for end-t.next >= frameSizeLen {
+		prefix, err := t.sealed(t.next, frameSizeLen)
+		if err != nil {
+			return err
+		}
+		sealedSize := int(binary.LittleEndian.Uint32(prefix.Bytes()))
+		prefix.Release()
+		if sealedSize < t.aead.NonceSize()+t.aead.Overhead() || int64(sealedSize) > end-t.next-frameSizeLen {
+			return nil
+		}
+
+		f := frame{plain: t.size, file: t.next, size: sealedSize - t.aead.NonceSize() - t.aead.Overhead()}
+		t.frames = append(t.frames, f)
+		t.size += int64(f.size)
+		t.next += int64(frameSizeLen + sealedSize)
+	}
+	return nil
+}
+
+// read decrypts frames covering n bytes of plaintext at off into a pooled
+// buffer, range must lie within plaintext
+func (t *cryptReader) read(off int64, n int) (Buffer, error) {
+	if n == 0 {
+		return Buffer{data: []byte{}}, nil
+	}
+	// first & last frame covering range
+	end := off + int64(n)
+	i := sort.Search(len(t.frames), func(i int) bool {
+		return t.frames[i].plain+int64(t.frames[i].size) > off
+	})
+	j := sort.Search(len(t.frames), func(j int) bool {
+		return t.frames[j].plain+int64(t.frames[j].size) >= end
+	})
+	first, last := t.frames[i], t.frames[j]
+
+	overhead := t.aead.NonceSize() + t.aead.Overhead()
+	start := first.file
+	src, err := t.sealed(start, int(last.file+int64(frameSizeLen+overhead+last.size)-start))
+	if err != nil {
+		return Buffer{}, err
+	}
+	defer src.Release()
+
+	buf := t.pool.get(int(last.plain + int64(last.size) - first.plain))
+	plain := (*buf)[:0]
+	ad := make([]byte, 0, len(t.header)+8)
+	for _, f := range t.frames[i : j+1] {
+		chunk := src.Bytes()[f.file-start+frameSizeLen:][:overhead+f.size]
+		ad = frameAD(ad, t.header, f.file)
+		if plain, err = t.aead.Open(plain, chunk[:t.aead.NonceSize()], chunk[t.aead.NonceSize():], ad); err != nil {
+			t.pool.put(buf)
+			return Buffer{}, fmt.Errorf("failed to decrypt frame at offset %d, error=%v", f.file, err)
+		}
+	}
+	skip := off - first.plain
+	return Buffer{data: plain[skip : skip+int64(n)], buf: buf, pool: t.pool}, nil
+}
+
+// Keyring returns keys files are encrypted with, nil if encryption is off.
+// see WithKeyring
+func (t *FileManager) Keyring() *Keyring {
+	return t.ring
+}
+
+// ReadFile reads whole file at path, decrypting it if it's encrypted
+func (t *FileManager) ReadFile(path string) ([]byte, error) {
+	data, err := ReadFile(t.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decrypt(data, t.Keyring())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// WriteFile writes data to a new file at path, encrypted if keyring is
+// set. It fails if file exists & is durable once it returns.
+func (t *FileManager) WriteFile(path string, data []byte) error {
+	f, err := t.fs.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fw, err := t.newFileWriter(f, 0)
+	if err == nil {
+		_, err = fw.Writer().Write(data)
+	}
+	if err == nil {
+		err = fw.Sync()
+	}
+	fw.Close()
+	if err != nil {
+		t.fs.Remove(path)
+	}
+	return err
+}
+
+// KeyID returns id of key file at path is encrypted with, empty if file
+// isn't encrypted
+func (t *FileManager) KeyID(path string) (string, error) {
+	f, err := t.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(cryptMagic)+2+maxKeyIDLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	keyID, _, _, err := parseCryptHeader(header[:n])
+	return keyID, err
+}
+
+// newFileWriter wraps f opened for write, size is its current size. An
+// empty file is encrypted if keyring is set, an existing one keeps being
+// written as it was started, so appends never mix both.
+func (t *FileManager) newFileWriter(f File, size int64) (*FileWriter, error) {
+	fw := &FileWriter{file: f, manager: t}
+
+	var header []byte
+	if size == 0 {
+		ring := t.Keyring()
+		if ring == nil {
+			return fw, nil
+		}
+		header = appendCryptHeader(nil, ring.Active())
+		if _, err := f.Write(header); err != nil {
+			return nil, err
+		}
+	} else {
+		prefix := make([]byte, min(size, int64(len(cryptMagic)+2+maxKeyIDLen)))
+		if _, err := f.ReadAt(prefix, 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		_, n, ok, err := parseCryptHeader(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return fw, nil
+		}
+		header = prefix[:n]
+		if size, err = dropTornFrame(f, int64(n), size); err != nil {
+			return nil, err
+		}
+	}
+
+	keyID, _, _, _ := parseCryptHeader(header)
+	ring := t.Keyring()
+	if ring == nil {
+		return nil, fmt.Errorf("file is encrypted with key %s, no keyring is set", keyID)
+	}
+	aead, ok := ring.get(keyID)
+	if !ok {
+		return nil, fmt.Errorf("file is encrypted with unknown key %s", keyID)
+	}
+	fw.enc = &encrypter{file: f, aead: aead, header: header, offset: max(size, int64(len(header)))}
+	fw.buffered = bufio.NewWriterSize(fw.enc, frameSize)
+	return fw, nil
+}
+
+// dropTornFrame truncates frame torn by crash at the tail of f, frames
+// appended after it would be unreadable otherwise. It returns size of f
+// without it.
+func dropTornFrame(f File, offset int64, size int64) (int64, error) {
+	var sealedSize [frameSizeLen]byte
+	for offset < size {
+		if size-offset < frameSizeLen {
+			break
+		}
+		if _, err := f.ReadAt(sealedSize[:], offset); err != nil {
+			return 0, err
+		}
+		next := offset + frameSizeLen + int64(binary.LittleEndian.Uint32(sealedSize[:]))
+		if next > size {
+			break
+		}
+		offset = next
+	}
+	if offset < size {
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
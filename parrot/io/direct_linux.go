@@ -0,0 +1,12 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import "syscall"
+
+// oDirect is flag opening a file for reads bypassing page cache
+const oDirect = syscall.O_DIRECT
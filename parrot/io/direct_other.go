@@ -0,0 +1,13 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+//go:build !linux
+
+package io
+
+// oDirect is flag opening a file for reads bypassing page cache, direct
+// reads aren't supported off linux
+const oDirect = 0
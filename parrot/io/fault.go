@@ -0,0 +1,349 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// FaultFS wraps a VFS to inject failures, e.g to test crash recovery.
+// Contents written to a file are durable once it's synced, while creates,
+// truncates, renames & removes are taken as durable right away, so a
+// file rewritten in place may be found empty after crash.
+//   - DropUnsynced reverts files to their durable contents as a crash would
+//   - Durable & CrashAfter capture durable contents without disturbing
+//     files, e.g to reopen them as they'd be found after crash
+//   - FailSync makes fsyncs fail, contents stay unsynced
+//   - SetSpace limits bytes written, writes past it fail with ENOSPC
+type FaultFS struct {
+	fs VFS
+
+	mu sync.Mutex
+	// durable contents of files modified since their last sync, by clean
+	// path
+	durable map[string][]byte
+	syncErr error
+	// bytes left to write, unlimited if negative
+	space int64
+	// bumped by DropUnsynced, files opened before are detached
+	gen int
+
+	// count of mutating ops, see CrashAfter
+	ops     int64
+	crashAt int64
+	crash   string
+	image   *MemFS
+	imgErr  error
+}
+
+func NewFaultFS(fs VFS) *FaultFS {
+	return &FaultFS{fs: orOS(fs), durable: map[string][]byte{}, space: -1}
+}
+
+// FailSync makes every fsync fail with err from now on, nil stops it
+func (t *FaultFS) FailSync(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syncErr = err
+}
+
+// SetSpace lets n more bytes be written, later writes fail with ENOSPC
+// as on a full disk. Negative n lifts the limit.
+func (t *FaultFS) SetSpace(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.space = n
+}
+
+// Ops returns number of mutating ops (opens for write, writes, syncs,
+// truncates, renames, removes & mkdirs) run so far
+func (t *FaultFS) Ops() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ops
+}
+
+// CrashAfter captures durable contents of files under dir right after n
+// more mutating ops, as a crash at that point would leave them. Files
+// keep being written, see Crashed.
+func (t *FaultFS) CrashAfter(n int64, dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.crashAt, t.crash = t.ops+max(n, 1), dir
+	t.image, t.imgErr = nil, nil
+}
+
+// Crashed returns durable contents captured by CrashAfter, nil until
+// crash point is reached
+func (t *FaultFS) Crashed() (*MemFS, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.image, t.imgErr
+}
+
+// Durable returns durable contents of files under dir in a new MemFS
+func (t *FaultFS) Durable(dir string) (*MemFS, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot(dir)
+}
+
+// snapshot copies durable contents of dir into a new MemFS, caller holds mu
+func (t *FaultFS) snapshot(dir string) (*MemFS, error) {
+	image := NewMemFS()
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		if err := image.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		entries, err := t.fs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if e.IsDir() {
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+
+			data, ok := t.durable[filepath.Clean(path)]
+			if !ok {
+				if data, err = ReadFile(t.fs, path); err != nil {
+					return err
+				}
+			}
+			f, err := image.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			f.Write(data)
+			f.Close()
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// op counts a mutating op, caller holds mu
+func (t *FaultFS) op() {
+	t.ops++
+	if t.ops == t.crashAt {
+		t.image, t.imgErr = t.snapshot(t.crash)
+	}
+}
+
+// DropUnsynced reverts every file to its durable contents, as if machine
+// crashed. Files opened before are detached: writes, truncates & syncs
+// through them are discarded as if process writing them is gone.
+func (t *FaultFS) DropUnsynced() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.gen++
+	var errs []error
+	for path, data := range t.durable {
+		f, err := t.fs.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := f.Write(data); err != nil {
+			errs = append(errs, err)
+		}
+		errs = append(errs, f.Sync(), f.Close())
+	}
+	t.durable = map[string][]byte{}
+	return errors.Join(errs...)
+}
+
+// track records contents of file at path as durable before it's first
+// modified since its last sync, caller holds mu
+func (t *FaultFS) track(path string) error {
+	if _, ok := t.durable[path]; ok {
+		return nil
+	}
+	data, err := ReadFile(t.fs, path)
+	if err != nil {
+		return err
+	}
+	t.durable[path] = data
+	return nil
+}
+
+func (t *FaultFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	path := filepath.Clean(name)
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, statErr := t.fs.Stat(name)
+	f, err := t.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	// a new file or truncate is durable right away, contents aren't
+	switch {
+	case statErr != nil:
+		t.durable[path] = nil
+	case write && flag&os.O_TRUNC != 0:
+		t.durable[path] = nil
+	}
+	if write {
+		t.op()
+	}
+	return &faultFile{File: f, fs: t, path: path, gen: t.gen}, nil
+}
+
+func (t *FaultFS) Remove(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.fs.Remove(name); err != nil {
+		return err
+	}
+	delete(t.durable, filepath.Clean(name))
+	t.op()
+	return nil
+}
+
+func (t *FaultFS) Rename(oldpath string, newpath string) error {
+	from, to := filepath.Clean(oldpath), filepath.Clean(newpath)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.fs.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	// durable contents move along with file
+	if data, ok := t.durable[from]; ok {
+		t.durable[to] = data
+	} else {
+		delete(t.durable, to)
+	}
+	delete(t.durable, from)
+	t.op()
+	return nil
+}
+
+func (t *FaultFS) MkdirAll(path string, perm fs.FileMode) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.fs.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	t.op()
+	return nil
+}
+
+func (t *FaultFS) Stat(name string) (fs.FileInfo, error) {
+	return t.fs.Stat(name)
+}
+
+func (t *FaultFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return t.fs.ReadDir(name)
+}
+
+func (t *FaultFS) Map(f File) ([]byte, func() error, error) {
+	file, ok := f.(*faultFile)
+	if !ok {
+		return nil, nil, fs.ErrInvalid
+	}
+	return t.fs.Map(file.File)
+}
+
+// faultFile is a file of FaultFS, it's detached once its generation is
+// dropped
+type faultFile struct {
+	File
+	fs   *FaultFS
+	path string
+	gen  int
+}
+
+// modify tracks file before n bytes are written to it & returns bytes
+// allowed to be written, caller holds mu of fs
+func (t *faultFile) modify(n int) (int, error) {
+	if err := t.fs.track(t.path); err != nil {
+		return 0, err
+	}
+	if t.fs.space < 0 {
+		return n, nil
+	}
+	allowed := int(min(int64(n), t.fs.space))
+	t.fs.space -= int64(allowed)
+	if allowed < n {
+		return allowed, syscall.ENOSPC
+	}
+	return n, nil
+}
+
+func (t *faultFile) Write(p []byte) (int, error) {
+	t.fs.mu.Lock()
+	defer t.fs.mu.Unlock()
+	if t.gen != t.fs.gen {
+		return len(p), nil
+	}
+
+	allowed, err := t.modify(len(p))
+	n, werr := t.File.Write(p[:allowed])
+	t.fs.op()
+	if werr != nil {
+		return n, werr
+	}
+	if err != nil {
+		return n, &fs.PathError{Op: "write", Path: t.Name(), Err: err}
+	}
+	return n, nil
+}
+
+func (t *faultFile) Truncate(size int64) error {
+	t.fs.mu.Lock()
+	defer t.fs.mu.Unlock()
+	if t.gen != t.fs.gen {
+		return nil
+	}
+	if err := t.fs.track(t.path); err != nil {
+		return err
+	}
+	if err := t.File.Truncate(size); err != nil {
+		return err
+	}
+	// size is durable right away, like creates
+	if data := t.fs.durable[t.path]; int64(len(data)) > size {
+		t.fs.durable[t.path] = data[:size]
+	}
+	t.fs.op()
+	return nil
+}
+
+func (t *faultFile) Sync() error {
+	t.fs.mu.Lock()
+	defer t.fs.mu.Unlock()
+	if t.gen != t.fs.gen {
+		return nil
+	}
+	if t.fs.syncErr != nil {
+		return &fs.PathError{Op: "sync", Path: t.Name(), Err: t.fs.syncErr}
+	}
+	if err := t.File.Sync(); err != nil {
+		return err
+	}
+	delete(t.fs.durable, t.path)
+	t.fs.op()
+	return nil
+}
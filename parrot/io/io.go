@@ -7,115 +7,339 @@
 package io
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
-
-	"github.com/edsrzf/mmap-go"
 )
 
+// FileReader reads a file opened for read, contents are either held in
+// memory (mapped or given) or read on demand, see ReadMode. Encrypted
+// files are decrypted a frame at a time as they're read.
 type FileReader struct {
-	payload mmap.MMap
-	file    *os.File
+	payload []byte
+	file    File
+	// unmaps file, nil if it isn't mapped
+	unmap func() error
+
+	// reads ranges on demand, nil if payload is held
+	pread *preader
+	// decrypts ranges read, nil if file isn't encrypted
+	crypt *cryptReader
+}
+
+// NewPayloadReader wraps contents held in memory, e.g read by tools
+// inspecting files
+func NewPayloadReader(payload []byte) *FileReader {
+	return &FileReader{payload: payload}
 }
 
-func (t *FileReader) GetFile() *os.File {
+func (t *FileReader) GetFile() File {
 	return t.file
 }
 
-func (t *FileReader) GetPayload() mmap.MMap {
+// GetPayload returns contents held in memory, nil if they're read on
+// demand or encrypted, see ReadRange
+func (t *FileReader) GetPayload() []byte {
 	return t.payload
 }
 
+// Size returns size of contents
+func (t *FileReader) Size() int64 {
+	if t.crypt != nil {
+		return t.crypt.size
+	}
+	if t.pread != nil {
+		return t.pread.size
+	}
+	return int64(len(t.payload))
+}
+
+// ReadRange returns n bytes of contents at off. Contents held in memory
+// are returned as is, others are read or decrypted into a pooled buffer.
+func (t *FileReader) ReadRange(off int64, n int) (Buffer, error) {
+	if off < 0 || n < 0 || off+int64(n) > t.Size() {
+		return Buffer{}, fmt.Errorf("range [%d, %d) is out of contents of size %d", off, off+int64(n), t.Size())
+	}
+	if t.crypt != nil {
+		return t.crypt.read(off, n)
+	}
+	if t.pread != nil {
+		return t.pread.read(off, n)
+	}
+	return Buffer{data: t.payload[off : off+int64(n)]}, nil
+}
+
+// Refresh picks up contents appended since reader was opened, see
+// OpenForTail. It must not run concurrently with reads.
+func (t *FileReader) Refresh() error {
+	if t.pread == nil {
+		return fmt.Errorf("%s isn't read on demand, it can't be refreshed", t.file.Name())
+	}
+	info, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+	t.pread.size = info.Size()
+	if t.crypt != nil {
+		return t.crypt.index(t.pread.size)
+	}
+	return nil
+}
+
 func (t *FileReader) Close() {
-	t.file.Close()
-	t.payload.Unmap()
+	if t.file != nil {
+		t.file.Close()
+	}
+	if t.unmap != nil {
+		t.unmap()
+	}
 }
 
 type FileWriter struct {
-	file *os.File
+	file    File
+	manager *FileManager
+
+	// set if file is encrypted, writes are sealed in frames of buffered
+	// bytes, see Flush
+	enc      *encrypter
+	buffered *bufio.Writer
 }
 
+// Truncate empties file, an encrypted file is started over with active
+// key. Only size 0 is supported for encrypted files.
 func (t *FileWriter) Truncate(size int64) error {
+	if t.enc != nil && size != 0 {
+		return fmt.Errorf("encrypted file %s can only be truncated to 0", t.file.Name())
+	}
 	if err := t.file.Truncate(size); err != nil {
 		return err
 	}
 
-	_, err := t.file.Seek(0, 0)
-	return err
+	if _, err := t.file.Seek(0, 0); err != nil {
+		return err
+	}
+	if t.enc == nil {
+		return nil
+	}
+	fw, err := t.manager.newFileWriter(t.file, 0)
+	if err != nil {
+		return err
+	}
+	if fw.enc == nil {
+		return fmt.Errorf("encrypted file %s can't be started over, no keyring is set", t.file.Name())
+	}
+	// writers wrapping Writer keep working
+	t.enc = fw.enc
+	t.buffered.Reset(fw.enc)
+	return nil
 }
 
-func (t *FileWriter) GetFile() *os.File {
+// GetFile returns underlying file, writes must go through Writer as file
+// might be encrypted
+func (t *FileWriter) GetFile() File {
 	return t.file
 }
 
-func (t *FileWriter) Close() {
-	t.file.Close()
+// Writer returns writer of file contents, encrypting them if file is
+// encrypted. Encrypted writes are buffered until Flush.
+func (t *FileWriter) Writer() io.Writer {
+	if t.buffered != nil {
+		return t.buffered
+	}
+	return t.file
 }
 
-func (t *FileWriter) Write(data []byte) {
-	_, err := t.file.Write(data)
+// Empty reports whether file holds no contents, header of an encrypted
+// file isn't counted
+func (t *FileWriter) Empty() (bool, error) {
+	if t.enc != nil {
+		return t.enc.offset == int64(len(t.enc.header)) && t.buffered.Buffered() == 0, nil
+	}
+	info, err := t.file.Stat()
 	if err != nil {
-		log.Fatalf("failed to write data, error=%v", err)
+		return false, err
 	}
+	return info.Size() == 0, nil
+}
 
-	// Sync call to flush data to disk
-	t.file.Sync()
+// Flush writes out contents buffered for encryption
+func (t *FileWriter) Flush() error {
+	if t.buffered != nil {
+		return t.buffered.Flush()
+	}
+	return nil
+}
+
+// Sync flushes & fsyncs file
+func (t *FileWriter) Sync() error {
+	if err := t.Flush(); err != nil {
+		return err
+	}
+	return t.file.Sync()
 }
 
+func (t *FileWriter) Close() {
+	if err := t.Flush(); err != nil {
+		log.Errorf("failed to flush %s, error=%v", t.file.Name(), err)
+	}
+	t.file.Close()
+}
+
+// Write writes data & syncs it to disk
+func (t *FileWriter) Write(data []byte) error {
+	if _, err := t.Writer().Write(data); err != nil {
+		return fmt.Errorf("failed to write %s, error=%w", t.file.Name(), err)
+	}
+	if err := t.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s, error=%w", t.file.Name(), err)
+	}
+	return nil
+}
+
+// FileManager opens files of a VFS, sharing read only mappings of them
 type FileManager struct {
+	fs VFS
+
 	sharedFileReadersMap sync.Map
 	lockMap              sync.Map
 
 	// globalMu prevents multiple goroutines creating same instance
 	globalMu sync.Mutex
+
+	// keys new files are encrypted with, nil if encryption is off
+	ring *Keyring
 }
 
-func newFileManager() *FileManager {
+func newFileManager(fs VFS, ring *Keyring) *FileManager {
 	return &FileManager{
+		fs:                   fs,
 		sharedFileReadersMap: sync.Map{},
 		lockMap:              sync.Map{},
+		ring:                 ring,
 	}
 }
 
-var singleInstance *FileManager
-var once sync.Once
+// managers holds FileManager by VFS, so components sharing a VFS share
+// its readers. VFS returned by WithKeyring holds its own
+var managers sync.Map
+
+// Manager returns FileManager of fs, nil fs is OS
+func Manager(fs VFS) *FileManager {
+	fs = orOS(fs)
+	if k, ok := fs.(*keyedFS); ok {
+		return k.manager
+	}
+	if m, ok := managers.Load(fs); ok {
+		return m.(*FileManager)
+	}
+	m, _ := managers.LoadOrStore(fs, newFileManager(fs, nil))
+	return m.(*FileManager)
+}
 
+// keyedFS is VFS whose files are encrypted with keyring of its manager,
+// see WithKeyring
+type keyedFS struct {
+	VFS
+	manager *FileManager
+}
+
+// WithKeyring returns fs whose files are encrypted with active key of ring
+// & read with any key of it, fs is returned as is if ring is nil. A storage
+// hands it to its components in place of fs, so storages sharing a
+// filesystem keep keys of their own.
+func WithKeyring(fs VFS, ring *Keyring) VFS {
+	fs = orOS(fs)
+	if ring == nil {
+		return fs
+	}
+	k := &keyedFS{VFS: fs}
+	k.manager = newFileManager(k, ring)
+	return k
+}
+
+// GetFileManager returns FileManager of files on disk
 func GetFileManager() *FileManager {
-	once.Do(func() { singleInstance = newFileManager() })
-	return singleInstance
+	return Manager(OS)
+}
+
+// FS returns VFS files are managed on
+func (t *FileManager) FS() VFS {
+	return t.fs
 }
 
-func (t *FileManager) openForSharedRead(path string) (*FileReader, error) {
-	f, err := os.Open(path)
+func (t *FileManager) openReader(path string, opts ReadOpts) (*FileReader, error) {
+	direct := opts.Mode == ReadPread && opts.Direct && oDirect != 0
+	flag := os.O_RDONLY
+	if direct {
+		flag |= oDirect
+	}
+	f, err := t.fs.OpenFile(path, flag, 0)
+	if err != nil && direct {
+		log.Warnf("direct reads of %s aren't supported, reading through page cache, error=%v", path, err)
+		direct = false
+		f, err = t.fs.OpenFile(path, os.O_RDONLY, 0)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s, error=%v", path, err)
 	}
 
 	info, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return nil, fmt.Errorf("failed to get stats of file %s, error=%v", path, err)
 	}
 
 	// empty files can't be opened through mmap
 	if info.Size() == 0 || info.IsDir() {
+		f.Close()
 		return nil, fmt.Errorf(
-			"invalid file for mmap: size=%d, isDir=%v",
+			"invalid file for %v: size=%d, isDir=%v",
+			opts.Mode,
 			info.Size(),
 			info.IsDir(),
 		)
 	}
 
-	mmapData, err := mmap.Map(f, mmap.RDONLY, 0)
+	pool := opts.Pool
+	if pool == nil {
+		pool = DefaultBufferPool
+	}
+	if opts.Mode == ReadPread {
+		r := &preader{file: f, size: info.Size(), direct: direct, pool: pool}
+		crypt, err := t.newCryptReader(r.read, r.size, pool)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read file %s, error=%v", path, err)
+		}
+		return &FileReader{file: f, pread: r, crypt: crypt}, nil
+	}
+
+	data, unmap, err := t.fs.Map(f)
 	if err != nil {
+		f.Close()
 		return nil, fmt.Errorf("unable to open mmap, error=%v", err)
 	}
 
-	fileReader := &FileReader{payload: mmapData, file: f}
-	return fileReader, nil
+	// frames of encrypted files are decrypted from mapped pages as they're
+	// read
+	mapped := func(off int64, n int) (Buffer, error) {
+		return Buffer{data: data[off : off+int64(n)]}, nil
+	}
+	crypt, err := t.newCryptReader(mapped, int64(len(data)), pool)
+	if err != nil {
+		unmap()
+		f.Close()
+		return nil, fmt.Errorf("failed to decrypt file %s, error=%v", path, err)
+	}
+	if crypt != nil {
+		return &FileReader{file: f, unmap: unmap, crypt: crypt}, nil
+	}
+	return &FileReader{payload: data, file: f, unmap: unmap}, nil
 }
 
 func (t *FileManager) getOrCreateLock(path string) *sync.Mutex {
@@ -142,6 +366,13 @@ func (t *FileManager) getOrCreateLock(path string) *sync.Mutex {
 //   - if write is using mmap with RDWR mode or without mmap, might update same
 //     page cache, leading to torn or corrupt data
 func (t *FileManager) OpenForRead(path string) (*FileReader, error) {
+	return t.OpenForReadWith(path, ReadOpts{})
+}
+
+// OpenForReadWith opens file at path for reads as configured by opts,
+// reader is shared by every caller opening same path, so it's read as
+// first caller configured
+func (t *FileManager) OpenForReadWith(path string, opts ReadOpts) (*FileReader, error) {
 	lock := t.getOrCreateLock(path)
 	lock.Lock()
 	defer lock.Unlock()
@@ -149,7 +380,7 @@ func (t *FileManager) OpenForRead(path string) (*FileReader, error) {
 	if reader, ok := t.sharedFileReadersMap.Load(path); ok {
 		return reader.(*FileReader), nil
 	}
-	reader, err := t.openForSharedRead(path)
+	reader, err := t.openReader(path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -158,50 +389,73 @@ func (t *FileManager) OpenForRead(path string) (*FileReader, error) {
 	return reader, nil
 }
 
+// OpenForTail opens file at path, which may still be appended to, for reads
+// with pread. Reader isn't shared, it's closed by caller & sees contents
+// appended after it's opened once refreshed, see FileReader.Refresh
+func (t *FileManager) OpenForTail(path string) (*FileReader, error) {
+	return t.openReader(path, ReadOpts{Mode: ReadPread})
+}
+
 // OpenForWrite requires Close call to flush data to disk properly.
 // Suitable for single write/dump
-func (t *FileManager) OpenForWrite(path string) *FileWriter {
+func (t *FileManager) OpenForWrite(path string) (*FileWriter, error) {
 	dir := filepath.Dir(path)
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Fatalf("failed to create directory %v: %v", dir, err)
+	if err := t.fs.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %v: %v", dir, err)
 	}
 
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	f, err := t.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		log.Fatalf("failed to open file for writing %v", err)
+		return nil, fmt.Errorf("failed to open file for writing %v", err)
 	}
-	return &FileWriter{file: f}
+	fw, err := t.newFileWriter(f, 0)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open file for writing %v", err)
+	}
+	return fw, nil
 }
 
 // OpenForAppend requires Close call to flush data to disk properly.
 // Suitable for single write/dump
-func (t *FileManager) OpenForAppend(path string) *FileWriter {
+func (t *FileManager) OpenForAppend(path string) (*FileWriter, error) {
 	dir := filepath.Dir(path)
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Fatalf("failed to create directory %v: %v", dir, err)
+	if err := t.fs.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %v: %v", dir, err)
 	}
 
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	// read access is needed to find out how existing file is encrypted
+	f, err := t.fs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for writing %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to get stats of file %s, error=%v", path, err)
+	}
+	fw, err := t.newFileWriter(f, info.Size())
 	if err != nil {
-		log.Fatalf("failed to open file for writing %v", err)
+		f.Close()
+		return nil, fmt.Errorf("failed to open file %s for append, error=%v", path, err)
 	}
 
-	return &FileWriter{file: f}
+	return fw, nil
 }
 
-// Exists checks whether the given file or directory exists on disk.
+// Exists checks whether the given file or directory exists.
 // Returns true if the path exists and is accessible, false otherwise.
 func (t *FileManager) Exists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := t.fs.Stat(path)
 	return err == nil
 }
 
 // Delete removes the file or directory at the given path.
 // Returns an error if the deletion fails.
 func (t *FileManager) Delete(path string) error {
-	if err := os.Remove(path); err != nil {
+	if err := t.fs.Remove(path); err != nil {
 		return fmt.Errorf("failed to delete %s", path)
 	}
 	return nil
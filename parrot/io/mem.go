@@ -0,0 +1,368 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemFS is VFS keeping files in memory, contents are lost with it. Syncs
+// are no-ops, see FaultFS to simulate crashes.
+type MemFS struct {
+	mu sync.RWMutex
+	// by clean path, root is always present
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	mu      sync.RWMutex
+	name    string
+	dir     bool
+	mode    fs.FileMode
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemFS() *MemFS {
+	root := &memNode{name: "/", dir: true, mode: fs.ModeDir | 0755, modTime: time.Now()}
+	return &MemFS{nodes: map[string]*memNode{string(filepath.Separator): root}}
+}
+
+func memPath(name string) string {
+	// relative paths share a namespace with absolute ones under root
+	return filepath.Join(string(filepath.Separator), name)
+}
+
+func pathError(op string, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// parent returns dir node of name, caller holds mu
+func (t *MemFS) parent(name string) (*memNode, bool) {
+	dir, ok := t.nodes[filepath.Dir(name)]
+	return dir, ok && dir.dir
+}
+
+func (t *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	path := memPath(name)
+
+	t.mu.Lock()
+	node, ok := t.nodes[path]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		t.mu.Unlock()
+		return nil, pathError("open", name, fs.ErrExist)
+	case !ok && flag&os.O_CREATE == 0:
+		t.mu.Unlock()
+		return nil, pathError("open", name, fs.ErrNotExist)
+	case !ok:
+		if _, ok := t.parent(path); !ok {
+			t.mu.Unlock()
+			return nil, pathError("open", name, fs.ErrNotExist)
+		}
+		node = &memNode{name: filepath.Base(path), mode: perm, modTime: time.Now()}
+		t.nodes[path] = node
+	case node.dir && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		t.mu.Unlock()
+		return nil, pathError("open", name, syscall.EISDIR)
+	}
+	t.mu.Unlock()
+
+	if flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		node.mu.Lock()
+		node.data = nil
+		node.modTime = time.Now()
+		node.mu.Unlock()
+	}
+	return &memFile{node: node, name: name, flag: flag}, nil
+}
+
+func (t *MemFS) Remove(name string) error {
+	path := memPath(name)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[path]
+	if !ok {
+		return pathError("remove", name, fs.ErrNotExist)
+	}
+	if node.dir && len(t.children(path)) > 0 {
+		return pathError("remove", name, syscall.ENOTEMPTY)
+	}
+	delete(t.nodes, path)
+	return nil
+}
+
+// children returns paths of direct children of dir, caller holds mu
+func (t *MemFS) children(dir string) []string {
+	var paths []string
+	for path := range t.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (t *MemFS) Rename(oldpath string, newpath string) error {
+	from, to := memPath(oldpath), memPath(newpath)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[from]
+	if !ok {
+		return pathError("rename", oldpath, fs.ErrNotExist)
+	}
+	if _, ok := t.parent(to); !ok {
+		return pathError("rename", newpath, fs.ErrNotExist)
+	}
+	if existing, ok := t.nodes[to]; ok && existing.dir {
+		return pathError("rename", newpath, fs.ErrExist)
+	}
+	if from == to {
+		return nil
+	}
+
+	if node.dir {
+		prefix := from + string(filepath.Separator)
+		for path, child := range t.nodes {
+			if strings.HasPrefix(path, prefix) {
+				delete(t.nodes, path)
+				t.nodes[to+string(filepath.Separator)+path[len(prefix):]] = child
+			}
+		}
+	}
+	delete(t.nodes, from)
+	node.mu.Lock()
+	node.name = filepath.Base(to)
+	node.mu.Unlock()
+	t.nodes[to] = node
+	return nil
+}
+
+func (t *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	path = memPath(path)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		node, ok := t.nodes[dir]
+		if ok {
+			if !node.dir {
+				return pathError("mkdir", dir, syscall.ENOTDIR)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+	for _, dir := range missing {
+		t.nodes[dir] = &memNode{name: filepath.Base(dir), dir: true, mode: fs.ModeDir | perm, modTime: time.Now()}
+	}
+	return nil
+}
+
+func (t *MemFS) Stat(name string) (fs.FileInfo, error) {
+	t.mu.RLock()
+	node, ok := t.nodes[memPath(name)]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, pathError("stat", name, fs.ErrNotExist)
+	}
+	return node.info(), nil
+}
+
+func (t *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path := memPath(name)
+
+	t.mu.RLock()
+	node, ok := t.nodes[path]
+	if !ok || !node.dir {
+		t.mu.RUnlock()
+		if ok {
+			return nil, pathError("readdir", name, syscall.ENOTDIR)
+		}
+		return nil, pathError("readdir", name, fs.ErrNotExist)
+	}
+	children := t.children(path)
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(t.nodes[child].info()))
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Map returns a copy of contents, so it's unaffected by later writes
+func (t *MemFS) Map(f File) ([]byte, func() error, error) {
+	file, ok := f.(*memFile)
+	if !ok {
+		return nil, nil, fs.ErrInvalid
+	}
+	file.node.mu.RLock()
+	data := append([]byte(nil), file.node.data...)
+	file.node.mu.RUnlock()
+	return data, func() error { return nil }, nil
+}
+
+func (t *memNode) info() fs.FileInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return memInfo{name: t.name, size: int64(len(t.data)), mode: t.mode, modTime: t.modTime, dir: t.dir}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	dir     bool
+}
+
+func (t memInfo) Name() string       { return t.name }
+func (t memInfo) Size() int64        { return t.size }
+func (t memInfo) Mode() fs.FileMode  { return t.mode }
+func (t memInfo) ModTime() time.Time { return t.modTime }
+func (t memInfo) IsDir() bool        { return t.dir }
+func (t memInfo) Sys() any           { return nil }
+
+// memFile is a handle of memNode with its own offset
+type memFile struct {
+	node   *memNode
+	name   string
+	flag   int
+	offset int64
+	closed bool
+}
+
+func (t *memFile) check(write bool) error {
+	if t.closed {
+		return fs.ErrClosed
+	}
+	writable := t.flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if write && !writable || !write && t.flag&os.O_WRONLY != 0 {
+		return pathError("access", t.name, fs.ErrPermission)
+	}
+	return nil
+}
+
+func (t *memFile) Name() string { return t.name }
+
+func (t *memFile) Read(p []byte) (int, error) {
+	n, err := t.ReadAt(p, t.offset)
+	t.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (t *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := t.check(false); err != nil {
+		return 0, err
+	}
+	t.node.mu.RLock()
+	defer t.node.mu.RUnlock()
+	if off >= int64(len(t.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, t.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (t *memFile) Write(p []byte) (int, error) {
+	if err := t.check(true); err != nil {
+		return 0, err
+	}
+	t.node.mu.Lock()
+	defer t.node.mu.Unlock()
+	if t.flag&os.O_APPEND != 0 {
+		t.offset = int64(len(t.node.data))
+	}
+	end := t.offset + int64(len(p))
+	if end > int64(len(t.node.data)) {
+		t.node.data = append(t.node.data, make([]byte, end-int64(len(t.node.data)))...)
+	}
+	copy(t.node.data[t.offset:], p)
+	t.offset = end
+	t.node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (t *memFile) Seek(offset int64, whence int) (int64, error) {
+	if t.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += t.offset
+	case io.SeekEnd:
+		t.node.mu.RLock()
+		offset += int64(len(t.node.data))
+		t.node.mu.RUnlock()
+	}
+	if offset < 0 {
+		return 0, pathError("seek", t.name, fs.ErrInvalid)
+	}
+	t.offset = offset
+	return offset, nil
+}
+
+func (t *memFile) Stat() (fs.FileInfo, error) {
+	if t.closed {
+		return nil, fs.ErrClosed
+	}
+	return t.node.info(), nil
+}
+
+func (t *memFile) Sync() error {
+	if t.closed {
+		return fs.ErrClosed
+	}
+	return nil
+}
+
+func (t *memFile) Truncate(size int64) error {
+	if err := t.check(true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return pathError("truncate", t.name, fs.ErrInvalid)
+	}
+	t.node.mu.Lock()
+	defer t.node.mu.Unlock()
+	if size <= int64(len(t.node.data)) {
+		t.node.data = t.node.data[:size:size]
+	} else {
+		t.node.data = append(t.node.data, make([]byte, size-int64(len(t.node.data)))...)
+	}
+	t.node.modTime = time.Now()
+	return nil
+}
+
+func (t *memFile) Close() error {
+	if t.closed {
+		return fs.ErrClosed
+	}
+	t.closed = true
+	return nil
+}
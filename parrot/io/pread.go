@@ -0,0 +1,183 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ReadMode selects how files opened by OpenForReadWith are read
+type ReadMode int
+
+const (
+	// ReadMmap maps whole file into memory & reads are served from mapped
+	// pages, default. Pages are faulted in by kernel on access & an I/O
+	// error while faulting them in crashes process with SIGBUS.
+	ReadMmap ReadMode = iota
+	// ReadPread reads requested ranges with ReadAt into buffers of a
+	// BufferPool, files take no address space & I/O errors are returned
+	// to reader.
+	ReadPread
+)
+
+// ParseReadMode parses name of mode as written in config, "mmap" or "pread"
+func ParseReadMode(name string) (ReadMode, error) {
+	switch name {
+	case "", "mmap":
+		return ReadMmap, nil
+	case "pread":
+		return ReadPread, nil
+	}
+	return ReadMmap, fmt.Errorf("unknown read mode %q, expected mmap or pread", name)
+}
+
+func (t ReadMode) String() string {
+	if t == ReadPread {
+		return "pread"
+	}
+	return "mmap"
+}
+
+// ReadOpts configures how a file is read, see OpenForReadWith
+type ReadOpts struct {
+	Mode ReadMode
+	// Direct opens files with O_DIRECT, reads then bypass page cache &
+	// every read goes to disk. Only used with ReadPread on linux, reads
+	// fall back to page cache if filesystem doesn't support it
+	Direct bool
+	// Pool buffers of ReadPread reads come from, DefaultBufferPool if nil
+	Pool *BufferPool
+}
+
+const (
+	// alignment of buffers, offsets & sizes of O_DIRECT reads
+	directAlign = 4096
+
+	// buffers are pooled in power of two classes from 4KB to 1MB, larger
+	// ones are allocated per read
+	minBufferShift = 12
+	bufferClasses  = 9
+)
+
+// BufferPool hands out buffers ReadPread reads go to. Buffers are pooled
+// by size in power of two classes, so they're reused by reads of any size,
+// & aligned for O_DIRECT.
+type BufferPool struct {
+	classes [bufferClasses]sync.Pool
+
+	// buffers handed out & not released yet
+	inUse atomic.Int64
+}
+
+func NewBufferPool() *BufferPool {
+	return &BufferPool{}
+}
+
+// DefaultBufferPool is shared by readers with no pool of their own
+var DefaultBufferPool = NewBufferPool()
+
+// InUse returns number of buffers handed out & not released yet
+func (t *BufferPool) InUse() int64 {
+	return t.inUse.Load()
+}
+
+// class returns index of smallest class holding n bytes, bufferClasses if
+// n is too large to be pooled
+func class(n int) int {
+	if n <= 1<<minBufferShift {
+		return 0
+	}
+	return min(bits.Len(uint(n-1))-minBufferShift, bufferClasses)
+}
+
+// get returns an aligned buffer of at least n bytes
+func (t *BufferPool) get(n int) *[]byte {
+	t.inUse.Add(1)
+	c := class(n)
+	if c == bufferClasses {
+		buf := alignedBuffer(n)
+		return &buf
+	}
+	if buf, ok := t.classes[c].Get().(*[]byte); ok {
+		return buf
+	}
+	buf := alignedBuffer(1 << (c + minBufferShift))
+	return &buf
+}
+
+func (t *BufferPool) put(buf *[]byte) {
+	t.inUse.Add(-1)
+	if c := class(cap(*buf)); c < bufferClasses && cap(*buf) == 1<<(c+minBufferShift) {
+		t.classes[c].Put(buf)
+	}
+}
+
+// alignedBuffer allocates n bytes starting at a directAlign boundary
+func alignedBuffer(n int) []byte {
+	buf := make([]byte, n+directAlign)
+	skip := int(-uintptr(unsafe.Pointer(&buf[0])) & (directAlign - 1))
+	return buf[skip : skip+n : skip+n]
+}
+
+// Buffer holds bytes read by FileReader.ReadRange, it must be released
+// once they're no longer used
+type Buffer struct {
+	data []byte
+
+	// pooled buffer data is read into, nil if data points into contents
+	// held by reader
+	buf  *[]byte
+	pool *BufferPool
+}
+
+// Bytes returns bytes read, they're valid until Release
+func (t Buffer) Bytes() []byte {
+	return t.data
+}
+
+// Release returns buffer to its pool
+func (t Buffer) Release() {
+	if t.buf != nil {
+		t.pool.put(t.buf)
+	}
+}
+
+// preader reads ranges of a file with ReadAt
+type preader struct {
+	file   File
+	size   int64
+	direct bool
+	pool   *BufferPool
+}
+
+// read reads n bytes at off into a buffer of pool, O_DIRECT reads are
+// widened to aligned offsets & sizes
+func (t *preader) read(off int64, n int) (Buffer, error) {
+	start, end := off, off+int64(n)
+	if t.direct {
+		start = off &^ (directAlign - 1)
+		end = (end + directAlign - 1) &^ (directAlign - 1)
+	}
+
+	buf := t.pool.get(int(end - start))
+	got, err := t.file.ReadAt((*buf)[:end-start], start)
+	// reads past end of file are cut short by it
+	if int64(got) >= off+int64(n)-start && (err == nil || err == io.EOF) {
+		skip := off - start
+		return Buffer{data: (*buf)[skip : skip+int64(n)], buf: buf, pool: t.pool}, nil
+	}
+	t.pool.put(buf)
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return Buffer{}, fmt.Errorf("failed to read %d bytes at offset %d of %s, error=%w", n, off, t.file.Name(), err)
+}
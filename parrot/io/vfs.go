@@ -0,0 +1,165 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package io
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/edsrzf/mmap-go"
+)
+
+// File is a file opened through a VFS
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Seeker
+	io.Closer
+
+	Name() string
+	Stat() (fs.FileInfo, error)
+	// Sync makes written contents durable
+	Sync() error
+	Truncate(size int64) error
+}
+
+// VFS is filesystem storage keeps its files on, errors follow os package,
+// e.g a missing file is reported by an error matching fs.ErrNotExist.
+//   - OS: files on disk
+//   - MemFS: files in memory, e.g for tests or ephemeral storages
+//   - FaultFS: wraps another VFS to inject failures & crashes
+type VFS interface {
+	// OpenFile opens file as os.OpenFile, flags of os package apply
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Remove(name string) error
+	Rename(oldpath string, newpath string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+	// ReadDir returns entries of dir sorted by name
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// Map returns contents of f opened for read, they're valid until
+	// unmap is called & must not be modified
+	Map(f File) (data []byte, unmap func() error, err error)
+}
+
+// OS is VFS of files on disk, tables are mapped with mmap
+var OS VFS = osFS{}
+
+// orOS returns fs, or OS if fs is nil
+func orOS(fs VFS) VFS {
+	if fs == nil {
+		return OS
+	}
+	return fs
+}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) Rename(oldpath string, newpath string) error  { return os.Rename(oldpath, newpath) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+
+func (osFS) Map(f File) ([]byte, func() error, error) {
+	file, ok := f.(*os.File)
+	if !ok {
+		return nil, nil, fs.ErrInvalid
+	}
+	data, err := mmap.Map(file, mmap.RDONLY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, data.Unmap, nil
+}
+
+// ReadFile reads whole file at name as os.ReadFile
+func ReadFile(fs VFS, name string) ([]byte, error) {
+	f, err := orOS(fs).OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Glob returns names of files matching pattern as filepath.Glob, pattern
+// syntax is of filepath.Match
+func Glob(fs VFS, pattern string) ([]string, error) {
+	fs = orOS(fs)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err := fs.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	dir = cleanGlobPath(dir)
+	if !hasMeta(dir) {
+		return glob(fs, dir, file, nil), nil
+	}
+
+	dirs, err := Glob(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, d := range dirs {
+		matches = glob(fs, d, file, matches)
+	}
+	return matches, nil
+}
+
+// glob appends names of entries of dir matching pattern, unreadable dirs
+// are skipped like filepath.Glob does
+func glob(fs VFS, dir string, pattern string, matches []string) []string {
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if ok, _ := filepath.Match(pattern, e.Name()); ok {
+			names = append(names, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(names)
+	return append(matches, names...)
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+func cleanGlobPath(path string) string {
+	switch path {
+	case "":
+		return "."
+	case string(filepath.Separator):
+		return path
+	default:
+		return path[:len(path)-1]
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package memtable
+
+import (
+	"sync/atomic"
+
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// entryOverhead approximates memory a map entry costs beyond its key &
+// value, i.e bucket slot, tophash & value pointer
+const entryOverhead = 48
+
+// SizeOfEntry estimates memory held by an entry of memtable, it's what
+// MemtableSoftLimit & write buffer are accounted in
+func SizeOfEntry[K types.Key, V types.Value](key K, value V) int64 {
+	return int64(types.SizeOfKey(key)+value.SizeOf()) + entryOverhead
+}
+
+// rangeSize estimates memory held by a range tombstone of memtable
+func rangeSize[K types.Key](r types.KeyRange[K]) int64 {
+	return int64(types.SizeOfKey(r.Start)+types.SizeOfKey(r.End)) + entryOverhead
+}
+
+// WriteBufferManager accounts memory held by memtables of any number of
+// stores against a single budget. It only keeps count, owner of stores
+// flushes memtables once budget is exceeded, see Exceeded.
+type WriteBufferManager struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewWriteBufferManager returns manager with budget of limit bytes, a
+// non-positive limit is never exceeded
+func NewWriteBufferManager(limit int64) *WriteBufferManager {
+	return &WriteBufferManager{limit: limit}
+}
+
+// Limit returns budget in bytes
+func (t *WriteBufferManager) Limit() int64 {
+	return t.limit
+}
+
+// Usage returns memory held by all accounted memtables, in bytes
+func (t *WriteBufferManager) Usage() int64 {
+	return t.used.Load()
+}
+
+// Exceeded reports whether memtables hold more than budget
+func (t *WriteBufferManager) Exceeded() bool {
+	return t.limit > 0 && t.used.Load() > t.limit
+}
+
+func (t *WriteBufferManager) add(delta int64) {
+	if t != nil {
+		t.used.Add(delta)
+	}
+}
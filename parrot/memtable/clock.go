@@ -0,0 +1,35 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package memtable
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock hands out write times in unix nanoseconds. Times are strictly
+// increasing, even if wall clock stalls or steps back
+type Clock struct {
+	mu   sync.Mutex
+	last int64
+}
+
+// Now returns a time later than any time handed out or observed before
+func (t *Clock) Now() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = max(time.Now().UnixNano(), t.last+1)
+	return t.last
+}
+
+// Observe moves clock past ts, e.g time of a replayed write, so writes
+// made afterwards are newer
+func (t *Clock) Observe(ts int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = max(t.last, ts)
+}
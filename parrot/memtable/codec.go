@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package memtable
+
+import (
+	"encoding/binary"
+
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
+)
+
+// AppendEvent appends event encoded by c
+//   - uvarint op size | op | entry, see codec.AppendPayload
+//   - range deletes: uvarint op size | op | uvarint start size | start | uvarint end size | end [| uvarint time]
+func AppendEvent[K types.Key, V types.Value](c codec.Codec, buf []byte, event MemTableEvent[K, V]) ([]byte, error) {
+	buf = codec.AppendFrame(buf, []byte(event.Op))
+	if event.Op != DeleteRangeOperation {
+		return codec.AppendPayload(c, buf, types.Payload[K, V]{Key: event.Key, Val: event.Value, Time: event.Time})
+	}
+
+	for _, key := range []K{event.Key, event.End} {
+		keyBuf, err := c.AppendKey(nil, key)
+		if err != nil {
+			return nil, err
+		}
+		buf = codec.AppendFrame(buf, keyBuf)
+	}
+	// events written before range deletes were timed end at range bounds
+	if event.Time > 0 {
+		buf = binary.AppendUvarint(buf, uint64(event.Time))
+	}
+	return buf, nil
+}
+
+// DecodeEvent decodes event built by AppendEvent
+func DecodeEvent[K types.Key, V types.Value](c codec.Codec, data []byte) (MemTableEvent[K, V], error) {
+	op, rest, ok := codec.ReadFrame(data)
+	if !ok {
+		return MemTableEvent[K, V]{}, errors.RaiseCodecErr("truncated event op")
+	}
+	switch MemtableOperation(op) {
+	case WriteOperation, DeleteOperation, MergeOperation, DeleteRangeOperation:
+	default:
+		return MemTableEvent[K, V]{}, errors.RaiseCodecErr("unknown event op %q", op)
+	}
+	if MemtableOperation(op) != DeleteRangeOperation {
+		pl, err := codec.DecodePayload[K, V](c, rest)
+		if err != nil {
+			return MemTableEvent[K, V]{}, err
+		}
+		if pl.Ref != nil {
+			return MemTableEvent[K, V]{}, errors.RaiseCodecErr("unresolved value of key=%v", pl.Key)
+		}
+		return MemTableEvent[K, V]{Key: pl.Key, Value: pl.Val, Op: MemtableOperation(op), Time: pl.Time}, nil
+	}
+
+	event := MemTableEvent[K, V]{Op: DeleteRangeOperation}
+	for _, key := range []*K{&event.Key, &event.End} {
+		var raw []byte
+		if raw, rest, ok = codec.ReadFrame(rest); !ok {
+			return MemTableEvent[K, V]{}, errors.RaiseCodecErr("truncated range bound")
+		}
+		if err := c.DecodeKey(raw, key); err != nil {
+			return MemTableEvent[K, V]{}, err
+		}
+	}
+	if len(rest) > 0 {
+		ts, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return MemTableEvent[K, V]{}, errors.RaiseCodecErr("truncated range delete time")
+		}
+		event.Time = int64(ts)
+	}
+	return event, nil
+}
+
+// logCodec encodes memtable log events with storage codec
+type logCodec[K types.Key, V types.Value] struct {
+	c codec.Codec
+}
+
+func (t logCodec[K, V]) ID() byte { return t.c.ID() }
+
+func (t logCodec[K, V]) Encode(buf []byte, event MemTableEvent[K, V]) ([]byte, error) {
+	return AppendEvent(t.c, buf, event)
+}
+
+func (t logCodec[K, V]) Decode(data []byte) (MemTableEvent[K, V], error) {
+	return DecodeEvent[K, V](t.c, data)
+}
+
+// LogResolver resolves codec of memtable log by id recorded in its header
+func LogResolver[K types.Key, V types.Value](id byte) (wal.Codec[MemTableEvent[K, V]], error) {
+	c, err := codec.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return logCodec[K, V]{c: c}, nil
+}
+
+// ReplayLog loads events of memtable log at path of fs, whichever codec
+// wrote it
+func ReplayLog[K types.Key, V types.Value](fs io.VFS, path string) ([]MemTableEvent[K, V], error) {
+	return wal.ReplayWithCodec(fs, path, LogResolver[K, V])
+}
+
+// InspectLog decodes every record of memtable log at path of fs, see
+// wal.Inspect
+func InspectLog[K types.Key, V types.Value](fs io.VFS, path string) ([]wal.Record[MemTableEvent[K, V]], error) {
+	return wal.Inspect(fs, path, LogResolver[K, V])
+}
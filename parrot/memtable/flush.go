@@ -67,48 +67,93 @@ func (t *Flusher[K, V]) Run(ctx context.Context) {
 func (t *Flusher[K, V]) flush(mem *Memtable[K, V]) {
 	log.Infof("deleting %p \n", mem)
 
-	manager := io.GetFileManager()
+	manager := io.Manager(t.mf.VFS())
 	l0, _ := t.mf.GetLSM().GetLevel(0)
 	nextId := l0.GetNextId()
 	dbPath := t.mf.FormatDBPath(0, nextId)
 	indexPath := t.mf.FormatIndexPath(0, nextId)
 
-	dbWriter := manager.OpenForWrite(dbPath)
+	dbWriter, err := manager.OpenForWrite(dbPath)
+	if err != nil {
+		log.Panicf("failed to open table, error=%v", err)
+	}
 	defer dbWriter.Close()
 
-	indexWriter := manager.OpenForWrite(indexPath)
+	indexWriter, err := manager.OpenForWrite(indexPath)
+	if err != nil {
+		log.Panicf("failed to open table index, error=%v", err)
+	}
 	defer indexWriter.Close()
 
 	// order of update:
+	//	-	write large values to value log
 	//	-	write new table to level-0
 	//	-	update it in manifest
 	//	-	flush memtable
+	//	-	sync manifest
 	//	-	delete corresponding log file if wal turned on
 
 	// write new table to disk (level-0)
 	pls, totalSizeInBytes := mem.BuildPayloadList()
-	err := utils.Encode(dbWriter.GetFile(), indexWriter.GetFile(), pls)
+
+	// large values go to value log first, refs must point to durable records
+	var segments []int64
+	if vl := mem.opts.ValueLog; vl != nil {
+		vl.Begin()
+		defer vl.End()
+
+		segments, totalSizeInBytes, err = mem.separate(pls)
+		if err != nil {
+			log.Panicf("failed to separate values, error=%v", err)
+		}
+	}
+
+	err = utils.Encode(dbWriter.Writer(), indexWriter.Writer(), pls, mem.RangeTombstones(), mem.opts.Codec)
 	if err != nil {
 		log.Panicf("failed to encode & store, error=%v", err)
 	}
 
-	// Ensure all buffered data is flushed to disk through fsync system call
-	dbWriter.GetFile().Sync()
+	// Ensure all buffered data is flushed to disk through fsync system call,
+	// readers map both files once table is installed
+	if err := dbWriter.Sync(); err != nil {
+		log.Panicf("failed to sync table, error=%v", err)
+	}
+	if err := indexWriter.Sync(); err != nil {
+		log.Panicf("failed to sync table index, error=%v", err)
+	}
 
-	// append new table to level-0
-	lvl, _ := t.mf.GetLSM().GetLevel(0)
-	lvl.SetSSTable(nextId, metadata.NewSSTable(dbPath, indexPath, totalSizeInBytes))
+	// publishing table & clearing memtable is a single step for readers,
+	// otherwise merge operands could be read from both
+	t.mf.Install(func() {
+		// append new table to level-0
+		lvl, _ := t.mf.GetLSM().GetLevel(0)
+		table := metadata.NewSSTable(dbPath, indexPath, totalSizeInBytes)
+		table.ValueSegments = segments
+		lvl.SetSSTable(nextId, table)
 
-	mem.mu.Lock()
-	defer mem.mu.Unlock()
+		mem.mu.Lock()
+		defer mem.mu.Unlock()
 
-	for k := range mem.data {
-		delete(mem.data, k)
-	}
+		// records up to last are skipped by replay of owner's shared log
+		t.mf.GetLSM().SetFlushed(mem.last)
+
+		mem.release()
+		mem.segment = 0
+		mem.last = metadata.LogPosition{}
+	})
 
 	// delete current memetable log file if wal is turned on
 	if mem.opts.TurnOnWal {
+		// new table must be durable in manifest before its logs are dropped
+		if err := t.mf.Sync(); err != nil {
+			log.Panicf("failed to sync manifest, error=%v", err)
+		}
 		mem.wal.Delete()
+
+		// logs replayed into this memtable are persisted as well
+		for _, path := range mem.replayed {
+			manager.Delete(path)
+		}
 	}
 
 	log.Infof("deleted memtable at %s", dbPath)
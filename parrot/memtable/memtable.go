@@ -9,8 +9,8 @@ package memtable
 import (
 	"context"
 	"fmt"
+	"math"
 	"path/filepath"
-	"reflect"
 	"sort"
 	"sync"
 	"time"
@@ -20,8 +20,12 @@ import (
 	"github.com/nagarajRPoojari/orange/parrot/wal"
 
 	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/merge"
 	"github.com/nagarajRPoojari/orange/parrot/metadata"
 	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/vlog"
 
 	"github.com/nagarajRPoojari/orange/parrot/flags"
 )
@@ -31,12 +35,22 @@ type MemtableOperation string
 const (
 	WriteOperation  MemtableOperation = "WRITE"
 	DeleteOperation MemtableOperation = "DELETE"
+	MergeOperation  MemtableOperation = "MERGE"
+	// deletes keys of [Key, End), event carries no value
+	DeleteRangeOperation MemtableOperation = "DELETE_RANGE"
 )
 
 type MemTableEvent[K types.Key, V types.Value] struct {
 	Key   K
 	Value V
 	Op    MemtableOperation
+
+	// end of deleted range, set for DeleteRangeOperation only
+	End K
+
+	// time event was written at in unix nanoseconds, memtable stamps
+	// events without one. see types.Payload.Time
+	Time int64
 }
 
 type MemtableOpts struct {
@@ -60,15 +74,77 @@ type MemtableOpts struct {
 
 	// Flusher time interval
 	FlushTimeInterval time.Duration
+
+	// Operator used to fold merge operands, merges are rejected if nil
+	MergeOperator merge.Operator
+
+	// Codec of flushed tables & logs, gob if nil
+	Codec codec.Codec
+
+	// Values whose encoded size exceeds ValueLogThreshold are flushed to
+	// ValueLog & tables keep refs to them. Values stay inline if nil
+	ValueLog          *vlog.ValueLog
+	ValueLogThreshold int
+
+	// Flushing is driven by owner through FlushNext & no flusher goroutine
+	// is started, used when several stores share a flush scheduler
+	ExternalFlush bool
+
+	// Budget memory of memtables is accounted against, optional. It may be
+	// shared by several stores, owner decides which memtable to flush
+	WriteBuffer *WriteBufferManager
+
+	// VFS logs are kept on, VFS of manifest if nil
+	VFS io.VFS
+
+	// How flushed tables are read, mapped with mmap by default
+	TableRead io.ReadOpts
+
+	// Comparator types.BytesKey keys are ordered by, bytewise if nil
+	Comparator types.Comparator
+
+	// Overwritten versions are kept for reads of past times as long as
+	// they were current within Retention, only newest version is kept if 0.
+	// see MemtableStore.ReadAt
+	Retention time.Duration
+}
+
+// version is a state of key along with time it was written at
+type version[V types.Value] struct {
+	val  V
+	time int64
 }
 
 type Memtable[K types.Key, V types.Value] struct {
 	data map[K]V
+	// time newest version of each key was written at
+	times map[K]int64
+	// overwritten versions of keys newest first, kept only with
+	// MemtableOpts.Retention
+	history map[K][]version[V]
+
+	// range tombstones, they shadow older memtables & tables only. keys of
+	// data are always newer since covered ones are dropped on insertion
+	ranges []types.KeyRange[K]
 
 	// RWMutex to prevent concurrent io
 	mu   *sync.RWMutex
 	opts *MemtableOpts
+	// order of keys, see MemtableOpts.Comparator
+	less types.Order[K]
 	wal  *wal.WAL[MemTableEvent[K, V]]
+
+	// log files replayed into this memtable, deleted along with its own wal
+	replayed []string
+
+	// oldest segment of owner's shared log holding data of this memtable,
+	// 0 if none. see MemtableStore.Apply
+	segment int64
+	// newest record of owner's shared log applied to this memtable
+	last metadata.LogPosition
+
+	// estimated memory held by entries & range tombstones, in bytes
+	size int64
 }
 
 // NewMemtable initializes a new Memtable instance.
@@ -78,21 +154,26 @@ func NewMemtable[K types.Key, V types.Value](opts *MemtableOpts) *Memtable[K, V]
 
 	if opts.TurnOnWal {
 		logPath := filepath.Join(opts.WALLogDir, fmt.Sprintf("wal-%d.log", time.Now().UnixNano()))
-		wl, _ = wal.NewWAL[MemTableEvent[K, V]](
+		wl, _ = wal.NewWALWithCodec[MemTableEvent[K, V]](
 			wal.WALOpts{
 				Path:             logPath,
 				TimeInterval:     opts.WALTimeInterval,
 				EventChSize:      opts.WALEventChSize,
 				WriterBufferSize: opts.WALWriterBufferSize,
+				VFS:              opts.VFS,
 			},
+			logCodec[K, V]{c: opts.Codec},
 		)
 	}
 
 	return &Memtable[K, V]{
-		data: map[K]V{},
-		mu:   &sync.RWMutex{},
-		opts: opts,
-		wal:  wl,
+		data:    map[K]V{},
+		times:   map[K]int64{},
+		history: map[K][]version[V]{},
+		mu:      &sync.RWMutex{},
+		opts:    opts,
+		less:    types.OrderOf[K](opts.Comparator),
+		wal:     wl,
 	}
 }
 
@@ -100,71 +181,332 @@ func (t *Memtable[K, V]) GetWall() *wal.WAL[MemTableEvent[K, V]] {
 	return t.wal
 }
 
-// @todo: optimize
+// BuildPayloadList returns all versions held by memtable ordered by key,
+// versions of a key newest first, along with their size
 func (t *Memtable[K, V]) BuildPayloadList() ([]types.Payload[K, V], int64) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 	var pl []types.Payload[K, V]
 	var size int64
 	for k, v := range t.data {
-		pl = append(pl, types.Payload[K, V]{Key: k, Val: v})
+		pl = append(pl, types.Payload[K, V]{Key: k, Val: v, Time: t.times[k]})
 		size += int64(v.SizeOf())
+		for _, old := range t.history[k] {
+			pl = append(pl, types.Payload[K, V]{Key: k, Val: old.val, Time: old.time})
+			size += int64(old.val.SizeOf())
+		}
 	}
-	sort.Slice(pl, func(i, j int) bool {
-		return pl[i].Key.Less(pl[j].Key)
+	sort.SliceStable(pl, func(i, j int) bool {
+		return t.less(pl[i].Key, pl[j].Key)
 	})
 	return pl, size
 }
 
 func (t *Memtable[K, V]) Write(key K, value V) bool {
+	return t.write(key, value, time.Now().UnixNano(), t.opts.TurnOnWal)
+}
+
+// write puts key[K], value[V] written at ts & logs the event only if it's
+// applied, a rejected write is retried on next memtable which logs it there
+func (t *Memtable[K, V]) write(key K, value V, ts int64, logged bool) bool {
 	t.mu.Lock()
-	defer func() {
-		t.mu.Unlock()
-		// log the write event to wal
-		if t.opts.TurnOnWal {
-			t.wal.Append(MemTableEvent[K, V]{Key: key, Value: value, Op: WriteOperation})
-		}
-	}()
+	defer t.mu.Unlock()
 
-	// check soft threshold
-	if uintptr(len(t.data)+1)*value.SizeOf() > uintptr(t.opts.MemtableSoftLimit) {
+	old, ok := t.data[key]
+	delta := SizeOfEntry(key, value)
+	if ok && !t.retains() {
+		delta -= SizeOfEntry(key, old)
+	}
+	if !t.fits(delta) {
 		return false
 	}
+	if ok && t.retains() {
+		t.retain(key, ts)
+	}
 	t.data[key] = value
+	t.times[key] = ts
+	t.grow(delta)
+
+	// log the write event to wal
+	if logged {
+		t.wal.Append(MemTableEvent[K, V]{Key: key, Value: value, Op: WriteOperation, Time: ts})
+	}
 	return true
 }
 
 func (t *Memtable[K, V]) Delete(key K, tombstone V) {
+	t.delete(key, tombstone, time.Now().UnixNano(), t.opts.TurnOnWal)
+}
+
+func (t *Memtable[K, V]) delete(key K, tombstone V, ts int64, logged bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// tombstone replaces version held rather than marking it deleted in
+	// place, it may be retained, an operand or still referred by a log
+	if !tombstone.IsDeleted() {
+		tombstone.MarkDeleted()
+	}
+	delta := SizeOfEntry(key, tombstone)
+	if existing, ok := t.data[key]; ok && t.retains() {
+		t.retain(key, ts)
+	} else if ok {
+		delta -= SizeOfEntry(key, existing)
+	}
+	t.data[key] = tombstone
+	t.times[key] = ts
+	t.grow(delta)
+
+	// log the delete event to wal, tombstone is needed to replay it
+	if logged {
+		t.wal.Append(MemTableEvent[K, V]{Key: key, Value: tombstone, Op: DeleteOperation, Time: ts})
+	}
+}
+
+// Merge folds operand into the version held by memtable if any, otherwise
+// stores operand as is. It returns false if there is no room for a new key.
+func (t *Memtable[K, V]) Merge(key K, operand V) (bool, error) {
+	return t.merge(key, operand, time.Now().UnixNano(), t.opts.TurnOnWal)
+}
+
+func (t *Memtable[K, V]) merge(key K, operand V, ts int64, logged bool) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	existing, ok := t.data[key]
+	if !ok {
+		delta := SizeOfEntry(key, operand)
+		if !t.fits(delta) {
+			return false, nil
+		}
+		t.data[key] = operand
+		t.grow(delta)
+	} else {
+		// existing is sized before it's folded
+		old := SizeOfEntry(key, existing)
+		v, err := merge.Combine(t.opts.MergeOperator, key, existing, operand)
+		if err != nil {
+			return true, err
+		}
+		if t.retains() {
+			old = 0
+			t.retain(key, ts)
+		}
+		t.data[key] = v
+		t.grow(SizeOfEntry(key, v) - old)
+	}
+	t.times[key] = ts
+
+	// log operand rather than folded value, replay folds it again.
+	// logging only applied merges keeps replay exactly-once
+	if logged {
+		t.wal.Append(MemTableEvent[K, V]{Key: key, Value: operand, Op: MergeOperation, Time: ts})
+	}
+	return true, nil
+}
+
+// retains reports whether overwritten versions are kept
+func (t *Memtable[K, V]) retains() bool {
+	return t.opts.Retention > 0
+}
+
+// retain moves newest version of key to history as it's overwritten at ts.
+// Versions older than newest one written before retention horizon can't be
+// read anymore & are dropped. caller must hold mu
+func (t *Memtable[K, V]) retain(key K, ts int64) {
+	versions := append([]version[V]{{val: t.data[key], time: t.times[key]}}, t.history[key]...)
+
+	horizon := ts - int64(t.opts.Retention)
+	for i, v := range versions {
+		if v.time <= horizon {
+			for _, dropped := range versions[i+1:] {
+				t.grow(-SizeOfEntry(key, dropped.val))
+			}
+			versions = versions[:i+1]
+			break
+		}
+	}
+	t.history[key] = versions
+}
+
+// DeleteRange records range tombstone for keys of [start, end)
+func (t *Memtable[K, V]) DeleteRange(start K, end K) {
+	t.deleteRange(start, end, time.Now().UnixNano(), t.opts.TurnOnWal)
+}
+
+// deleteRange records range tombstone written at ts. Range shadows older
+// memtables & tables, keys it covers in this memtable are dropped or, if
+// versions are retained, deleted by a tombstone at ts so reads of earlier
+// times still see them
+func (t *Memtable[K, V]) deleteRange(start K, end K, ts int64, logged bool) {
 	t.mu.Lock()
-	defer func() {
-		t.mu.Unlock()
-		// log the delete event to wal
-		if t.opts.TurnOnWal {
-			t.wal.Append(MemTableEvent[K, V]{Key: key, Op: DeleteOperation})
+	defer t.mu.Unlock()
+
+	r := types.Between(start, end)
+	r.Time = ts
+	delta := rangeSize(r)
+	for k, v := range t.data {
+		if !r.Contains(t.less, k) {
+			continue
 		}
-	}()
+		if t.retains() {
+			tombstone := types.NewTombstone[V]()
+			t.retain(k, ts)
+			t.data[k] = tombstone
+			t.times[k] = ts
+			delta += SizeOfEntry(k, tombstone)
+			continue
+		}
+		delete(t.data, k)
+		delete(t.times, k)
+		delta -= SizeOfEntry(k, v)
+	}
+	t.ranges = append(t.ranges, r)
+	t.grow(delta)
+
+	if logged {
+		t.wal.Append(MemTableEvent[K, V]{Key: start, End: end, Op: DeleteRangeOperation, Time: ts})
+	}
+}
+
+// Size returns estimated memory held by memtable, in bytes
+func (t *Memtable[K, V]) Size() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+// fits reports whether delta bytes can be added without going over soft
+// limit, an empty memtable takes any entry so oversized ones aren't lost
+func (t *Memtable[K, V]) fits(delta int64) bool {
+	return t.size == 0 || t.size+delta <= t.opts.MemtableSoftLimit
+}
+
+// grow adds delta to size of memtable & to shared write buffer, caller must
+// hold mu
+func (t *Memtable[K, V]) grow(delta int64) {
+	t.size += delta
+	t.opts.WriteBuffer.add(delta)
+}
+
+// release drops all entries, memory is returned to shared write buffer.
+// caller must hold mu
+func (t *Memtable[K, V]) release() {
+	clear(t.data)
+	clear(t.times)
+	clear(t.history)
+	t.ranges = nil
+	t.grow(-t.size)
+}
+
+// coveredAt reports whether key is deleted for reads of ts by a range
+// tombstone of memtable
+func (t *Memtable[K, V]) coveredAt(key K, ts int64) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return types.CoversAt(t.less, t.ranges, key, ts)
+}
+
+// RangeTombstones returns range tombstones of memtable
+func (t *Memtable[K, V]) RangeTombstones() []types.KeyRange[K] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]types.KeyRange[K](nil), t.ranges...)
+}
+
+// overlaps reports whether memtable holds any key of [first, last], including
+// tombstones, operands & range tombstones
+func (t *Memtable[K, V]) overlaps(first K, last K) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
-	if _, ok := t.data[key]; !ok {
-		t.data[key] = tombstone
+	for k := range t.data {
+		if !t.less(k, first) && !t.less(last, k) {
+			return true
+		}
 	}
+	for _, r := range t.ranges {
+		if r.Overlaps(t.less, first, last) {
+			return true
+		}
+	}
+	return false
+}
 
-	t.data[key].MarkDeleted()
+// pin records record at pos as held by memtable, oldest segment is kept
+// pinned & newest position is persisted along with memtable
+func (t *Memtable[K, V]) pin(pos metadata.LogPosition) {
+	if pos.Segment == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.segment == 0 {
+		t.segment = pos.Segment
+	}
+	if t.last.Less(pos) {
+		t.last = pos
+	}
 }
 
 func (t *Memtable[K, V]) Read(key K) (V, flags.Flag) {
+	return t.ReadAt(key, math.MaxInt64)
+}
+
+// ReadAt reads version of key visible at ts, i.e newest one written at or
+// before ts
+func (t *Memtable[K, V]) ReadAt(key K, ts int64) (V, flags.Flag) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 	val, ok := t.data[key]
+	if ok && t.times[key] > ts {
+		ok = false
+		for _, old := range t.history[key] {
+			if old.time <= ts {
+				val, ok = old.val, true
+				break
+			}
+		}
+	}
 	if !ok {
-		return val, flags.KeyNotFoundFlag
+		var null V
+		return null, flags.KeyNotFoundFlag
 	}
 	if val.IsDeleted() {
 		return val, flags.KeyDeletedFlag
 	}
+	if types.IsOperand(val) {
+		return val, flags.KeyOperandFlag
+	}
 
 	return val, flags.KeyFoundFlag
 }
 
+// get returns version held for key, including tombstones & operands
+func (t *Memtable[K, V]) get(key K) (V, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	val, ok := t.data[key]
+	return val, ok
+}
+
+// scan returns entries of range in key order, including tombstones & operands
+func (t *Memtable[K, V]) scan(r types.KeyRange[K]) []types.Payload[K, V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var pl []types.Payload[K, V]
+	for k, v := range t.data {
+		if r.Contains(t.less, k) {
+			pl = append(pl, types.Payload[K, V]{Key: k, Val: v})
+		}
+	}
+	sort.Slice(pl, func(i, j int) bool {
+		return t.less(pl[i].Key, pl[j].Key)
+	})
+	return pl
+}
+
 type MemtableStore[K types.Key, V types.Value] struct {
 	mf *metadata.Manifest
 
@@ -183,10 +525,21 @@ type MemtableStore[K types.Key, V types.Value] struct {
 	// Cache for decoded values to speed up reads
 	DecoderCache *v2.CacheManager[K, V]
 
+	// stamps events applied without a write time
+	clock Clock
+
 	opts *MemtableOpts
+	// order of keys, see MemtableOpts.Comparator
+	less types.Order[K]
 }
 
 func NewMemtableStore[K types.Key, V types.Value](mf *metadata.Manifest, ctx context.Context, opts MemtableOpts) *MemtableStore[K, V] {
+	if opts.Codec == nil {
+		opts.Codec = codec.Gob
+	}
+	if opts.VFS == nil {
+		opts.VFS = mf.VFS()
+	}
 	q := NewQueue[K, V](QueueOpts{HardLimit: opts.QueueHardLimit})
 	mem := NewMemtable[K, V](&opts)
 	node := NewNode(mem)
@@ -196,7 +549,9 @@ func NewMemtableStore[K types.Key, V types.Value](mf *metadata.Manifest, ctx con
 	q.Push(node)
 
 	flusher := NewFlusher(q, mf, FlusherOpts{TimeInterval: opts.FlushTimeInterval})
-	go flusher.Run(ctx)
+	if !opts.ExternalFlush {
+		go flusher.Run(ctx)
+	}
 
 	memStore := &MemtableStore[K, V]{
 		mf:           mf,
@@ -205,7 +560,11 @@ func NewMemtableStore[K types.Key, V types.Value](mf *metadata.Manifest, ctx con
 		opts:         &opts,
 		flusher:      flusher,
 		memNode:      node,
-		DecoderCache: v2.NewCacheManager[K, V](),
+		DecoderCache: v2.NewCacheManager[K, V](v2.CacheManagerOpts{VFS: opts.VFS, Read: opts.TableRead, Comparator: opts.Comparator}),
+		less:         types.OrderOf[K](opts.Comparator),
+	}
+	if opts.ValueLog != nil {
+		memStore.DecoderCache.SetResolver(resolver[V](opts.ValueLog))
 	}
 
 	memStore.RollbackAll()
@@ -218,15 +577,24 @@ func (t *MemtableStore[K, V]) RollbackAll() error {
 		return errors.WALDisablederr
 	}
 
+	for _, file := range SortedLogs(t.opts.VFS, t.opts.WALLogDir) {
+		t.rollback(file)
+	}
+
+	return nil
+}
+
+// SortedLogs lists memtable log files at dir of fs, oldest first
+func SortedLogs(fs io.VFS, dir string) []string {
 	// List all WAL log files in the LogDir
-	files, err := filepath.Glob(filepath.Join(t.opts.WALLogDir, "*.log"))
+	files, err := io.Glob(fs, filepath.Join(dir, "*.log"))
 	if err != nil {
 		log.Infof("error listing WAL files: %v", err)
-		return err
+		return nil
 	}
 
 	// sort logs to run writes in order
-	sort.Slice(files, func(j, i int) bool {
+	sort.Slice(files, func(i, j int) bool {
 		// extract the integer part from the filename
 		getNum := func(path string) int64 {
 			base := filepath.Base(path)
@@ -236,29 +604,32 @@ func (t *MemtableStore[K, V]) RollbackAll() error {
 		}
 		return getNum(files[i]) < getNum(files[j])
 	})
-
-	for _, file := range files {
-		t.rollback(file)
-	}
-
-	return nil
+	return files
 }
 
+// rollback replays events of file without logging them again, file is
+// handed over to the active memtable & deleted once it's flushed.
 func (t *MemtableStore[K, V]) rollback(file string) {
-	events, err := wal.Replay[MemTableEvent[K, V]](file)
+	events, err := ReplayLog[K, V](t.opts.VFS, file)
 	if err != nil {
 		return
 	}
 	for _, event := range events {
-		t.Write(event.Key, event.Value)
+		if _, err := t.apply(event, false); err != nil {
+			log.Errorf("failed to replay merge for key=%v, err=%v", event.Key, err)
+		}
 	}
+
+	t.mem.mu.Lock()
+	t.mem.replayed = append(t.mem.replayed, file)
+	t.mem.mu.Unlock()
 }
 
 // warning! : helper function for unit tests
 func (t *MemtableStore[K, V]) Clear() {
-	for k := range t.mem.data {
-		delete(t.mem.data, k)
-	}
+	t.mem.mu.Lock()
+	t.mem.release()
+	t.mem.mu.Unlock()
 	t.mem = NewMemtable[K, V](t.opts)
 	t.memNode = NewNode(t.mem)
 
@@ -269,46 +640,148 @@ func (t *MemtableStore[K, V]) Clear() {
 // Write puts key[K], value[V]
 // return value will be true if it triggers flush
 func (t *MemtableStore[K, V]) Write(key K, value V) bool {
-	if ok := t.mem.Write(key, value); !ok {
+	return t.write(key, value, t.clock.Now(), t.opts.TurnOnWal)
+}
+
+func (t *MemtableStore[K, V]) write(key K, value V, ts int64, logged bool) bool {
+	if ok := t.mem.write(key, value, ts, logged); !ok {
 		log.Infof("Memtable overflow")
 
-		// create new memtable with same options
-		mem := NewMemtable[K, V](t.opts)
-		node := NewNode(mem)
+		t.rotate().write(key, value, ts, logged)
+		return true
+	}
+	return false
+}
 
-		// make current memtable non-disposable
-		node.immutable.Lock()
+// rotate makes active memtable disposable & replaces it with a new one
+func (t *MemtableStore[K, V]) rotate() *Memtable[K, V] {
+	// create new memtable with same options
+	mem := NewMemtable[K, V](t.opts)
+	node := NewNode(mem)
 
-		t.q.Push(node)
-		mem.Write(key, value)
+	// make current memtable non-disposable
+	node.immutable.Lock()
 
-		// unlock previous memtable to allow dumping
-		t.memNode.immutable.Unlock()
+	t.q.Push(node)
 
-		// update current memtable
-		t.memNode = node
-		t.mem = mem
+	// unlock previous memtable to allow dumping
+	t.memNode.immutable.Unlock()
 
-		return true
+	// update current memtable
+	t.memNode = node
+	t.mem = mem
+
+	return mem
+}
+
+// Seal makes active memtable disposable if it holds anything, so that next
+// FlushNext writes it out without waiting for it to fill up. It returns
+// false if memtable is empty.
+//   - must be serialized with writes, as Apply
+func (t *MemtableStore[K, V]) Seal() bool {
+	t.mem.mu.RLock()
+	empty := len(t.mem.data) == 0 && len(t.mem.ranges) == 0
+	t.mem.mu.RUnlock()
+
+	if empty {
+		return false
 	}
-	return false
+	t.rotate()
+	return true
+}
+
+// Release returns memory accounted for all memtables to write buffer,
+// store must not be used afterwards
+func (t *MemtableStore[K, V]) Release() {
+	t.q.Each(func(mem *Memtable[K, V]) {
+		mem.mu.Lock()
+		defer mem.mu.Unlock()
+		mem.grow(-mem.size)
+	})
+}
+
+// ActiveSize returns estimated memory held by active memtable, in bytes
+//   - must be serialized with writes, as Apply
+func (t *MemtableStore[K, V]) ActiveSize() int64 {
+	return t.mem.Size()
+}
+
+// Merge records operand for key[K], it's folded with older versions by
+// configured merge operator on reads & compaction
+func (t *MemtableStore[K, V]) Merge(key K, operand V) error {
+	if err := merge.Prepare(t.opts.MergeOperator, operand); err != nil {
+		return err
+	}
+
+	return t.merge(key, operand, t.clock.Now(), t.opts.TurnOnWal)
+}
+
+func (t *MemtableStore[K, V]) merge(key K, operand V, ts int64, logged bool) error {
+	ok, err := t.mem.merge(key, operand, ts, logged)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Infof("Memtable overflow")
+
+		_, err = t.rotate().merge(key, operand, ts, logged)
+	}
+	return err
 }
 
 // Read reads value for key[K] from memtable followed by ssts
+//   - merge operands met on the way are collected until a value, tombstone or
+//     end of ssts is reached & then folded by merge operator
 func (t *MemtableStore[K, V]) Read(key K) (V, bool) {
+	var (
+		v     V
+		found bool
+	)
+	// tables & memtables must not be swapped while operands are collected,
+	// otherwise a flushed operand might be seen twice
+	t.mf.View(func() {
+		v, found = t.read(key, math.MaxInt64)
+	})
+	return v, found
+}
+
+// ReadAt reads value key had at ts, in unix nanoseconds. In each memtable &
+// sst newest version written at or before ts is taken, older versions are
+// only available within MemtableOpts.Retention. ts older than that reads
+// whichever version survived, caller must reject it.
+//   - range tombstones delete keys for reads of their write time onwards
+func (t *MemtableStore[K, V]) ReadAt(key K, ts int64) (V, bool) {
+	var (
+		v     V
+		found bool
+	)
+	t.mf.View(func() {
+		v, found = t.read(key, ts)
+	})
+	return v, found
+}
+
+func (t *MemtableStore[K, V]) read(key K, ts int64) (V, bool) {
 	// Search backwards in Queue
 
 	log.Infof("Started reading from memtables")
-	var null V
+
+	// pending operands, newest first
+	var operands []V
 
 	node := t.q.tail
 	for node != nil {
-		v, flag := node.mem.Read(key)
+		v, flag := node.mem.ReadAt(key, ts)
 		switch flag {
 		case flags.KeyFoundFlag:
-			return v, true
+			return t.resolve(key, v, operands)
 		case flags.KeyDeletedFlag:
-			return null, false
+			return t.resolve(key, nil, operands)
+		case flags.KeyOperandFlag:
+			operands = append(operands, v)
+		}
+		if node.mem.coveredAt(key, ts) {
+			return t.resolve(key, nil, operands)
 		}
 		node = node.Prev
 	}
@@ -334,33 +807,286 @@ func (t *MemtableStore[K, V]) Read(key K) (V, bool) {
 
 		for _, mapKey := range sortedKeys {
 			table := tbls[mapKey]
-			val, err := t.DecoderCache.Get(table.DBPath, table.IndexPath, key)
+			val, err := t.DecoderCache.GetAt(table.DBPath, table.IndexPath, key, ts)
 			if err != nil {
 				switch err.(type) {
 
 				case errors.KeyNotFoundErr:
-					continue
 				case errors.KeyDeletederr:
-					return val.Val, false
+					return t.resolve(key, nil, operands)
 				}
+			} else if val.Key == key {
+				if !types.IsOperand(val.Val) {
+					return t.resolve(key, val.Val, operands)
+				}
+				operands = append(operands, val.Val)
+			}
+
+			// range tombstones of table shadow older tables only
+			if t.coveredByTable(table, key, ts) {
+				return t.resolve(key, nil, operands)
 			}
+		}
+		cnt++
+		level, _ = t.mf.GetLSM().GetLevel(cnt)
+	}
+	return t.resolve(key, nil, operands)
+}
+
+// Scan calls fn for live entries of range in key order until fn returns false.
+// Versions of a key are resolved as in Read.
+func (t *MemtableStore[K, V]) Scan(r types.KeyRange[K], fn func(key K, value V) bool) error {
+	var (
+		runs   [][]types.Payload[K, V]
+		ranges [][]types.KeyRange[K]
+		err    error
+	)
+	t.mf.View(func() {
+		runs, ranges, err = t.collect(r)
+	})
+	if err != nil {
+		return err
+	}
 
-			if val.Key == key {
-				return val.Val, true
+	// merge sorted runs, versions of a key are taken newest first
+	pos := make([]int, len(runs))
+	for {
+		var (
+			key   K
+			found bool
+		)
+		for i, run := range runs {
+			if pos[i] < len(run) && (!found || t.less(run[pos[i]].Key, key)) {
+				key, found = run[pos[i]].Key, true
 			}
+		}
+		if !found {
+			return nil
+		}
 
-			if reflect.DeepEqual(val, types.Payload[K, V]{}) {
+		var (
+			operands []V
+			base     types.Value
+			done     bool
+		)
+		for i, run := range runs {
+			if pos[i] >= len(run) || run[pos[i]].Key != key {
+				// range tombstones of source shadow older sources
+				if !done && types.Covers(t.less, ranges[i], key) {
+					done = true
+				}
 				continue
 			}
+			pl := run[pos[i]]
+			// older versions of key within source are hidden by newest one
+			for pos[i] < len(run) && run[pos[i]].Key == key {
+				pos[i]++
+			}
+
+			if done {
+				continue
+			}
+			// only live values are separated, so a ref is always a base
+			if pl.Ref != nil {
+				if err := t.DecoderCache.Resolve(&pl); err != nil {
+					return err
+				}
+				base, done = pl.Val, true
+				continue
+			}
+
+			v := pl.Val
+			switch {
+			case v.IsDeleted():
+				done = true
+			case types.IsOperand(v):
+				operands = append(operands, v)
+			default:
+				base, done = v, true
+			}
+			if !done && types.Covers(t.less, ranges[i], key) {
+				done = true
+			}
+		}
+
+		if v, ok := t.resolve(key, base, operands); ok && !fn(key, v) {
+			return nil
+		}
+	}
+}
+
+// collect loads entries of range from memtables & ssts, one sorted run per
+// source ordered newest first, along with range tombstones of each source
+func (t *MemtableStore[K, V]) collect(r types.KeyRange[K]) ([][]types.Payload[K, V], [][]types.KeyRange[K], error) {
+	var (
+		runs   [][]types.Payload[K, V]
+		ranges [][]types.KeyRange[K]
+	)
+
+	for node := t.q.tail; node != nil; node = node.Prev {
+		runs = append(runs, node.mem.scan(r))
+		ranges = append(ranges, node.mem.RangeTombstones())
+	}
+
+	for cnt := 0; ; cnt++ {
+		level, err := t.mf.GetLSM().GetLevel(cnt)
+		if err != nil {
+			break
+		}
+
+		tbls := level.GetTables()
+		ids := make([]int, 0, len(tbls))
+		for id := range tbls {
+			ids = append(ids, id)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+		for _, id := range ids {
+			table := tbls[id]
+			run, err := t.DecoderCache.GetRange(table.DBPath, table.IndexPath, r)
+			if err != nil {
+				return nil, nil, err
+			}
+			tombstones, err := t.DecoderCache.RangeTombstones(table)
+			if err != nil {
+				return nil, nil, err
+			}
+			runs = append(runs, run)
+			ranges = append(ranges, tombstones)
 		}
-		cnt++
-		level, _ = t.mf.GetLSM().GetLevel(cnt)
 	}
-	var empty V
-	return empty, false
+	return runs, ranges, nil
+}
+
+// coveredByTable reports whether key is deleted for reads of ts by a range
+// tombstone of table, tables whose tombstones can't be loaded are treated as
+// having none
+func (t *MemtableStore[K, V]) coveredByTable(table *metadata.SSTable, key K, ts int64) bool {
+	ranges, err := t.DecoderCache.RangeTombstones(table)
+	if err != nil {
+		log.Errorf("failed to load range tombstones of %s, err=%v", table.DBPath, err)
+		return false
+	}
+	return types.CoversAt(t.less, ranges, key, ts)
+}
+
+// resolve folds operands on top of base, base is nil if key has no live value
+func (t *MemtableStore[K, V]) resolve(key K, base types.Value, operands []V) (V, bool) {
+	var null V
+	if len(operands) == 0 {
+		if base == nil {
+			return null, false
+		}
+		return base.(V), true
+	}
+
+	v, err := merge.Resolve(t.opts.MergeOperator, key, base, operands)
+	if err != nil {
+		log.Errorf("failed to resolve merge operands for key=%v, err=%v", key, err)
+		return null, false
+	}
+	return v, true
 }
 
 func (t *MemtableStore[K, V]) Delete(key K, tomstone V) error {
-	t.mem.Delete(key, tomstone)
+	t.delete(key, tomstone, t.clock.Now(), t.opts.TurnOnWal)
 	return nil
 }
+
+func (t *MemtableStore[K, V]) delete(key K, tomstone V, ts int64, logged bool) {
+	t.mem.delete(key, tomstone, ts, logged)
+}
+
+// DeleteRange deletes keys of [start, end) with a single range tombstone
+func (t *MemtableStore[K, V]) DeleteRange(start K, end K) error {
+	t.deleteRange(start, end, t.clock.Now(), t.opts.TurnOnWal)
+	return nil
+}
+
+func (t *MemtableStore[K, V]) deleteRange(start K, end K, ts int64, logged bool) {
+	t.mem.deleteRange(start, end, ts, logged)
+}
+
+// Overlaps reports whether any unflushed memtable holds a key of [first, last]
+func (t *MemtableStore[K, V]) Overlaps(first K, last K) bool {
+	found := false
+	t.mf.View(func() {
+		for node := t.q.tail; node != nil && !found; node = node.Prev {
+			found = node.mem.overlaps(first, last)
+		}
+	})
+	return found
+}
+
+// Apply applies event already logged by owner at pos of its shared log,
+// segment stays pinned until memtable holding the event is flushed. Flushing
+// records pos in manifest, see metadata.LSM.SetFlushed.
+// Merge operands are expected to be prepared, see merge.Prepare.
+//
+//   - Apply calls must be serialized by owner
+//   - return value will be true if it triggers flush
+func (t *MemtableStore[K, V]) Apply(event MemTableEvent[K, V], pos metadata.LogPosition) (bool, error) {
+	flushed, err := t.apply(event, false)
+
+	// active memtable is the one holding event, even after overflow
+	t.mem.pin(pos)
+	return flushed, err
+}
+
+// apply applies event to active memtable, events written before versions
+// were kept are stamped with current time. It returns true if memtable
+// overflowed
+func (t *MemtableStore[K, V]) apply(event MemTableEvent[K, V], logged bool) (bool, error) {
+	mem := t.mem
+
+	ts := event.Time
+	if ts == 0 {
+		ts = t.clock.Now()
+	} else {
+		t.clock.Observe(ts)
+	}
+
+	var err error
+	switch event.Op {
+	case DeleteOperation:
+		t.delete(event.Key, event.Value, ts, logged)
+	case MergeOperation:
+		err = t.merge(event.Key, event.Value, ts, logged)
+	case DeleteRangeOperation:
+		t.deleteRange(event.Key, event.End, ts, logged)
+	default:
+		t.write(event.Key, event.Value, ts, logged)
+	}
+	return mem != t.mem, err
+}
+
+// Peek returns version of key held by active memtable, a merge applied
+// now is folded into it
+//   - must not run concurrently with Apply
+func (t *MemtableStore[K, V]) Peek(key K) (V, bool) {
+	t.mem.mu.RLock()
+	defer t.mem.mu.RUnlock()
+	v, ok := t.mem.data[key]
+	return v, ok
+}
+
+// OldestSegment returns oldest segment of owner's shared log which is still
+// needed by unflushed memtables
+//   - must not run concurrently with Apply
+func (t *MemtableStore[K, V]) OldestSegment() (int64, bool) {
+	var oldest int64
+	t.q.Each(func(mem *Memtable[K, V]) {
+		mem.mu.RLock()
+		defer mem.mu.RUnlock()
+		if mem.segment != 0 && (oldest == 0 || mem.segment < oldest) {
+			oldest = mem.segment
+		}
+	})
+	return oldest, oldest != 0
+}
+
+// FlushNext flushes oldest memtable if it's disposable, used with ExternalFlush
+func (t *MemtableStore[K, V]) FlushNext() bool {
+	_, ok := t.q.TryPop(t.flusher.flush)
+	return ok
+}
@@ -86,21 +86,51 @@ func (t *Queue[K, V]) Pop(callback func(*Memtable[K, V])) (*Memtable[K, V], erro
 		return nil, fmt.Errorf("head is nil")
 	}
 
-	defer t.len.Add(-1)
-
 	// head.mu.Lock defines disposability
 	// it is pre-acquired lock for active write ops & prevent flusher from disposing
 	// it will be realeased only when it is immutable/disposable (no active writes allowed)
 	t.head.immutable.Lock()
-	ret := t.head
 
-	if t.len.Load() <= 1 {
-		t.tailLock.Lock()
-		defer t.tailLock.Unlock()
+	return t.pop(callback)
+}
+
+// TryPop is non-blocking variant of Pop, it returns false if head
+// memtable isn't disposable yet
+func (t *Queue[K, V]) TryPop(callback func(*Memtable[K, V])) (*Memtable[K, V], bool) {
+	t.headLock.Lock()
+	defer t.headLock.Unlock()
+
+	if t.head == nil || !t.head.immutable.TryLock() {
+		return nil, false
 	}
 
+	mem, err := t.pop(callback)
+	return mem, err == nil
+}
+
+// Each calls fn for every memtable from oldest to newest
+//   - must not run concurrently with Push
+func (t *Queue[K, V]) Each(fn func(*Memtable[K, V])) {
+	t.headLock.Lock()
+	defer t.headLock.Unlock()
+
+	for node := t.head; node != nil; node = node.Next {
+		fn(node.mem)
+	}
+}
+
+// pop disposes head, head.immutable must be held by caller
+func (t *Queue[K, V]) pop(callback func(*Memtable[K, V])) (*Memtable[K, V], error) {
+	defer t.len.Add(-1)
+
+	ret := t.head
+
 	callback(ret.mem)
 
+	// Push reads head under tail lock, head is replaced under both
+	t.tailLock.Lock()
+	defer t.tailLock.Unlock()
+
 	if t.head == t.tail {
 		t.head = nil
 		t.tail = nil
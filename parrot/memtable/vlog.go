@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package memtable
+
+import (
+	"math"
+	"sort"
+
+	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/vlog"
+)
+
+// resolver decodes values of vl with codec of segment they're written to
+func resolver[V types.Value](vl *vlog.ValueLog) v2.Resolver[V] {
+	return func(ref types.ValueRef) (V, error) {
+		var v V
+		data, c, err := vl.Read(ref)
+		if err != nil {
+			return v, err
+		}
+		err = c.DecodeValue(data, &v)
+		return v, err
+	}
+}
+
+// separate moves large live values of pls into value log, it returns ids of
+// segments written to & size of table after separation. Tombstones &
+// merge operands stay inline, they're resolved along with older versions.
+//   - caller must hold a value log session until table is published
+func (t *Memtable[K, V]) separate(pls []types.Payload[K, V]) ([]int64, int64, error) {
+	vl := t.opts.ValueLog
+	c := vl.Codec()
+
+	var (
+		segments = map[int64]struct{}{}
+		size     int64
+		key, val []byte
+	)
+	for i := range pls {
+		pl := &pls[i]
+		if pl.Val.IsDeleted() || types.IsOperand(pl.Val) {
+			size += int64(pl.Val.SizeOf())
+			continue
+		}
+
+		var err error
+		if val, err = c.AppendValue(val[:0], pl.Val); err != nil {
+			return nil, 0, err
+		}
+		if len(val) <= t.opts.ValueLogThreshold {
+			size += int64(pl.Val.SizeOf())
+			continue
+		}
+
+		if key, err = c.AppendKey(key[:0], pl.Key); err != nil {
+			return nil, 0, err
+		}
+		ref, err := vl.Append(key, val)
+		if err != nil {
+			return nil, 0, err
+		}
+		pl.Ref = &ref
+		segments[ref.Segment] = struct{}{}
+		size += int64(ref.SizeOf())
+	}
+
+	if err := vl.Sync(); err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]int64, 0, len(segments))
+	for id := range segments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids, size, nil
+}
+
+// NewestRef returns value log ref held by newest version of key, ok is
+// false if newest version is inline or key has no live value. Merge
+// operands are skipped, a ref below them is still their base.
+func (t *MemtableStore[K, V]) NewestRef(key K) (ref types.ValueRef, ok bool) {
+	t.mf.View(func() {
+		ref, ok = t.newestRef(key)
+	})
+	return ref, ok
+}
+
+func (t *MemtableStore[K, V]) newestRef(key K) (types.ValueRef, bool) {
+	for node := t.q.tail; node != nil; node = node.Prev {
+		v, found := node.mem.get(key)
+		if found && !types.IsOperand(v) || node.mem.coveredAt(key, math.MaxInt64) {
+			// memtables hold values inline only
+			return types.ValueRef{}, false
+		}
+	}
+
+	for cnt := 0; ; cnt++ {
+		level, err := t.mf.GetLSM().GetLevel(cnt)
+		if err != nil {
+			return types.ValueRef{}, false
+		}
+
+		tbls := level.GetTables()
+		ids := make([]int, 0, len(tbls))
+		for id := range tbls {
+			ids = append(ids, id)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+		for _, id := range ids {
+			table := tbls[id]
+			pl, err := t.DecoderCache.Lookup(table.DBPath, table.IndexPath, key)
+			if err == nil {
+				if pl.Ref != nil {
+					return *pl.Ref, true
+				}
+				if !types.IsOperand(pl.Val) {
+					return types.ValueRef{}, false
+				}
+			} else if _, missing := err.(errors.KeyNotFoundErr); !missing {
+				return types.ValueRef{}, false
+			}
+			if t.coveredByTable(table, key, math.MaxInt64) {
+				return types.ValueRef{}, false
+			}
+		}
+	}
+}
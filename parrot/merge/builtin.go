@@ -0,0 +1,88 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package merge
+
+import (
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// Int64Add adds *types.Int64Value operands to the existing counter,
+// a missing counter starts at 0
+type Int64Add struct{}
+
+func (Int64Add) FullMerge(key any, existing types.Value, operands []types.Value) (types.Value, error) {
+	var sum int64
+	if existing != nil {
+		v, ok := existing.(*types.Int64Value)
+		if !ok {
+			return nil, errors.RaiseMergeErr("int64 add: unexpected value %T for key=%v", existing, key)
+		}
+		sum = v.V
+	}
+
+	for _, operand := range operands {
+		v, ok := operand.(*types.Int64Value)
+		if !ok {
+			return nil, errors.RaiseMergeErr("int64 add: unexpected operand %T for key=%v", operand, key)
+		}
+		sum += v.V
+	}
+	return &types.Int64Value{V: sum}, nil
+}
+
+func (Int64Add) PartialMerge(key any, older, newer types.Value) (types.Value, error) {
+	o, ok := older.(*types.Int64Value)
+	if !ok {
+		return nil, errors.RaiseMergeErr("int64 add: unexpected operand %T for key=%v", older, key)
+	}
+	n, ok := newer.(*types.Int64Value)
+	if !ok {
+		return nil, errors.RaiseMergeErr("int64 add: unexpected operand %T for key=%v", newer, key)
+	}
+	return &types.Int64Value{V: o.V + n.V, M: true}, nil
+}
+
+// ListAppend appends items of *types.ListValue operands to the existing list,
+// a missing list starts empty
+type ListAppend struct{}
+
+func (ListAppend) FullMerge(key any, existing types.Value, operands []types.Value) (types.Value, error) {
+	var items [][]byte
+	if existing != nil {
+		v, ok := existing.(*types.ListValue)
+		if !ok {
+			return nil, errors.RaiseMergeErr("list append: unexpected value %T for key=%v", existing, key)
+		}
+		items = append(items, v.V...)
+	}
+
+	for _, operand := range operands {
+		v, ok := operand.(*types.ListValue)
+		if !ok {
+			return nil, errors.RaiseMergeErr("list append: unexpected operand %T for key=%v", operand, key)
+		}
+		items = append(items, v.V...)
+	}
+	return &types.ListValue{V: items}, nil
+}
+
+func (ListAppend) PartialMerge(key any, older, newer types.Value) (types.Value, error) {
+	o, ok := older.(*types.ListValue)
+	if !ok {
+		return nil, errors.RaiseMergeErr("list append: unexpected operand %T for key=%v", older, key)
+	}
+	n, ok := newer.(*types.ListValue)
+	if !ok {
+		return nil, errors.RaiseMergeErr("list append: unexpected operand %T for key=%v", newer, key)
+	}
+
+	items := make([][]byte, 0, len(o.V)+len(n.V))
+	items = append(items, o.V...)
+	items = append(items, n.V...)
+	return &types.ListValue{V: items, M: true}, nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package merge
+
+import (
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// Operator folds merge operands into values. Operands are written blindly
+// through Storage.Merge & resolved lazily on reads and during compaction,
+// so read-modify-write updates need neither a read nor a lock.
+// Values passed in must not be modified, older versions stay readable
+// within retention, see storage.StorageOpts.Retention.
+//
+// Note: key is passed as `any` since types.Key is a constraint & can't be used
+// as a plain interface type; it always holds the storage's concrete key type.
+type Operator interface {
+	// FullMerge applies operands, oldest first, on top of existing.
+	// existing is nil if key has no live value.
+	FullMerge(key any, existing types.Value, operands []types.Value) (types.Value, error)
+
+	// PartialMerge combines two consecutive operands into a single operand,
+	// it's used when no base value is in reach (e.g. memtable, compaction of upper levels)
+	PartialMerge(key any, older, newer types.Value) (types.Value, error)
+}
+
+// Prepare validates operand against op & marks it as pending operand
+func Prepare(op Operator, operand types.Value) error {
+	if op == nil {
+		return errors.MergeOperatorMissingErr
+	}
+	o, ok := operand.(types.Operand)
+	if !ok {
+		return errors.RaiseMergeErr("%T can't be used as merge operand", operand)
+	}
+	o.MarkOperand()
+	return nil
+}
+
+// Resolve folds operands collected newest first (order in which sources are
+// walked) on top of base & returns the resulting value.
+func Resolve[V types.Value](op Operator, key any, base types.Value, operands []V) (V, error) {
+	var null V
+	ordered := make([]types.Value, len(operands))
+	for i, operand := range operands {
+		ordered[len(operands)-1-i] = operand
+	}
+
+	res, err := op.FullMerge(key, base, ordered)
+	if err != nil {
+		return null, err
+	}
+	v, ok := res.(V)
+	if !ok {
+		return null, errors.RaiseMergeErr("operator returned %T for key=%v", res, key)
+	}
+	return v, nil
+}
+
+// Combine merges operand with the older version found for the same key.
+//   - older operand results in a single operand
+//   - older value or tombstone results in a full value
+func Combine[V types.Value](op Operator, key any, older V, operand V) (V, error) {
+	var null V
+	var res types.Value
+	var err error
+
+	switch {
+	case older.IsDeleted():
+		res, err = op.FullMerge(key, nil, []types.Value{operand})
+	case types.IsOperand(older):
+		res, err = op.PartialMerge(key, older, operand)
+	default:
+		res, err = op.FullMerge(key, older, []types.Value{operand})
+	}
+	if err != nil {
+		return null, err
+	}
+
+	v, ok := res.(V)
+	if !ok {
+		return null, errors.RaiseMergeErr("operator returned %T for key=%v", res, key)
+	}
+	return v, nil
+}
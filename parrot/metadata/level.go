@@ -8,6 +8,7 @@ package metadata
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -45,10 +46,12 @@ func (t *Level) TablesCount() int {
 	return len(t.tables)
 }
 
+// GetTables returns a snapshot of tables of level, tables may be added
+// & removed concurrently
 func (t *Level) GetTables() map[int]*SSTable {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.tables
+	return maps.Clone(t.tables)
 }
 
 func (t *Level) GetTable(i int) (*SSTable, error) {
@@ -16,6 +16,13 @@ type LSM struct {
 	name   string
 	levels []*Level
 
+	// name of comparator ordering keys of tables, empty if keys have
+	// a fixed order
+	comparator string
+
+	// newest commit log record persisted in tables, see SetFlushed
+	flushed LogPosition
+
 	// @todo: create separate locks for each field
 	mu *sync.RWMutex
 	// mutex to lock rw on levels, one must acquire respective level lock for
@@ -49,20 +56,79 @@ func (t *LSM) GetName() string {
 	return t.name
 }
 
+func (t *LSM) GetComparator() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.comparator
+}
+
+func (t *LSM) SetComparator(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.comparator = name
+}
+
+// LogPosition locates a record of owner's commit log
+type LogPosition struct {
+	Segment int64 `json:"segment"`
+	Index   int64 `json:"index"`
+}
+
+// Less reports whether p was logged before other
+func (p LogPosition) Less(other LogPosition) bool {
+	return p.Segment < other.Segment || p.Segment == other.Segment && p.Index < other.Index
+}
+
+func (t *LSM) GetFlushed() LogPosition {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.flushed
+}
+
+// SetFlushed records that records up to pos are persisted in tables,
+// they're skipped when commit log is replayed
+func (t *LSM) SetFlushed(pos LogPosition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.flushed.Less(pos) {
+		t.flushed = pos
+	}
+}
+
 func (t *LSM) AppendLevel() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.levels = append(t.levels, NewLevel())
 }
 
+// ValueSegments returns value log segments referred by any table
+func (t *LSM) ValueSegments() map[int64]struct{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	segments := map[int64]struct{}{}
+	for _, lvl := range t.levels {
+		lvl.mu.RLock()
+		for _, tbl := range lvl.tables {
+			for _, segment := range tbl.ValueSegments {
+				segments[segment] = struct{}{}
+			}
+		}
+		lvl.mu.RUnlock()
+	}
+	return segments
+}
+
 // LSM snapshot
 // Note: snapshots are immutable, exported fields are kept
 //		 for json marshalling
 // Warning!: it is not advised to modify snapshot views
 
 type LSMView struct {
-	Name   string      `json:"name"`
-	Levels []LevelView `json:"levels"`
+	Name       string       `json:"name"`
+	Levels     []LevelView  `json:"levels"`
+	Comparator string       `json:"comparator,omitempty"`
+	Flushed    *LogPosition `json:"flushed,omitempty"`
 }
 
 func NewLSMView(name string) *LSMView {
@@ -75,9 +141,13 @@ func NewLSMView(name string) *LSMView {
 
 func (view *LSMView) ToLSM() *LSM {
 	lsm := &LSM{
-		name:   view.Name,
-		mu:     &sync.RWMutex{},
-		levels: make([]*Level, len(view.Levels)),
+		name:       view.Name,
+		mu:         &sync.RWMutex{},
+		levels:     make([]*Level, len(view.Levels)),
+		comparator: view.Comparator,
+	}
+	if view.Flushed != nil {
+		lsm.flushed = *view.Flushed
 	}
 
 	for i, lvl := range view.Levels {
@@ -87,26 +157,26 @@ func (view *LSMView) ToLSM() *LSM {
 	return lsm
 }
 
+// Clone rebuilds level from snapshot, table ids are preserved since
+// they define recency of tables within a level
 func (lv LevelView) Clone() *Level {
-	tables := make([]*SSTable, 0)
-	for _, v := range lv.Tables {
+	newLevel := NewLevel()
+	for i, v := range lv.Tables {
 		tbl := SSTable(v)
-		tables = append(tables, &tbl)
+		newLevel.SetSSTable(i, &tbl)
 	}
-	tableMap := make(map[int]*SSTable, len(tables))
-	for i, tbl := range tables {
-		tableMap[i] = tbl
-	}
-	newLevel := NewLevel()
-	newLevel.tables = tableMap
 
 	return newLevel
 }
 
-func (lvl Level) Clone() *Level {
+func (lvl *Level) Clone() *Level {
+	lvl.mu.RLock()
+	defer lvl.mu.RUnlock()
+
 	newLevel := NewLevel()
 	newLevel.tables = make(map[int]*SSTable, len(lvl.tables))
 	maps.Copy(newLevel.tables, lvl.tables)
+	newLevel.SizeInBytes.Store(lvl.SizeInBytes.Load())
 	return newLevel
 }
 
@@ -115,8 +185,13 @@ func (lsm *LSM) ToView() *LSMView {
 	defer lsm.mu.RUnlock()
 
 	view := &LSMView{
-		Name:   lsm.name,
-		Levels: make([]LevelView, len(lsm.levels)),
+		Name:       lsm.name,
+		Levels:     make([]LevelView, len(lsm.levels)),
+		Comparator: lsm.comparator,
+	}
+	if lsm.flushed != (LogPosition{}) {
+		flushed := lsm.flushed
+		view.Flushed = &flushed
 	}
 
 	for i, lvl := range lsm.levels {
@@ -131,8 +206,8 @@ func (lvl *Level) ToView() LevelView {
 	defer lvl.mu.RUnlock()
 
 	view := NewLevelView()
-	view.Tables = make(map[int]SSTableView, len(lvl.GetTables()))
-	for i, tb := range lvl.GetTables() {
+	view.Tables = make(map[int]SSTableView, len(lvl.tables))
+	for i, tb := range lvl.tables {
 		st := SSTableView(*tb)
 		view.Tables[i] = st
 	}
@@ -9,9 +9,11 @@ package metadata
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
@@ -25,12 +27,21 @@ const (
 
 type ManifestOpts struct {
 	Dir string
+	// VFS manifest & tables are kept on, files on disk if nil
+	VFS io.VFS
 }
 
 type Manifest struct {
 	Name string
 	LSM0 *LSM
 
+	// installMu serializes multi-step table installs against readers, so a
+	// read never observes both the source & destination of moved entries
+	installMu sync.RWMutex
+
+	// syncMu serializes snapshot writes
+	syncMu sync.Mutex
+
 	opts ManifestOpts
 }
 
@@ -39,19 +50,16 @@ func NewManifest(name string, opts ManifestOpts) *Manifest {
 }
 
 func (t *Manifest) Load() error {
-	filePath := path.Join(t.opts.Dir, MANIFEST, t.Name, fmt.Sprintf("%s.json", MANIFEST))
-	data, err := os.ReadFile(filePath)
+	data, err := io.Manager(t.opts.VFS).ReadFile(t.path())
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// create an empty LSM, take a snapshot & save
 			lsm := NewLSM(t.Name)
 			lsmView := lsm.ToView()
 			emptyData, _ := json.Marshal(lsmView)
-
-			fm := io.GetFileManager()
-			fw := fm.OpenForWrite(filePath)
-			fw.Write(emptyData)
-			fw.Close()
+			if err := t.write(emptyData); err != nil {
+				return err
+			}
 
 			t.LSM0 = lsm
 			return nil
@@ -62,11 +70,49 @@ func (t *Manifest) Load() error {
 
 	// load lsmview/snapshot to new LSM
 	lsmView := NewLSMView(t.Name)
-	_ = json.Unmarshal(data, lsmView)
+	if err := json.Unmarshal(data, lsmView); err != nil {
+		return fmt.Errorf("failed to parse manifest %s, error=%v", t.path(), err)
+	}
 	t.LSM0 = lsmView.ToLSM()
 	return nil
 }
 
+func (t *Manifest) path() string {
+	return path.Join(t.opts.Dir, MANIFEST, t.Name, fmt.Sprintf("%s.json", MANIFEST))
+}
+
+// write replaces manifest with data, it's written to a temp file & renamed
+// over manifest once durable, so a crash leaves either version whole
+func (t *Manifest) write(data []byte) error {
+	fm := io.Manager(t.opts.VFS)
+	tmp := t.path() + ".tmp"
+	fw, err := fm.OpenForWrite(tmp)
+	if err != nil {
+		return err
+	}
+	err = fw.Write(data)
+	fw.Close()
+	if err != nil {
+		return err
+	}
+	return fm.FS().Rename(tmp, t.path())
+}
+
+// Install runs fn exclusively of View, used to publish new tables & retire
+// their sources (memtable or older tables) as one step
+func (t *Manifest) Install(fn func()) {
+	t.installMu.Lock()
+	defer t.installMu.Unlock()
+	fn()
+}
+
+// View runs fn with a stable set of tables & memtables
+func (t *Manifest) View(fn func()) {
+	t.installMu.RLock()
+	defer t.installMu.RUnlock()
+	fn()
+}
+
 func (t *Manifest) SyncLoop(ctx context.Context) {
 	go t.sync(ctx)
 }
@@ -80,26 +126,39 @@ func (t *Manifest) sync(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			filePath := path.Join(t.opts.Dir, MANIFEST, t.Name, fmt.Sprintf("%s.json", MANIFEST))
-
-			// load consistent manifest snapshot
-			// reason: json needs struct to export fields with no locks
-			// 		   lsm is rw protected through locks, using lsm directly might lead to data race
-			lsmView := t.LSM0.ToView()
-			log.Infof("Manifest LSM: %+v. %p\n", t.LSM0.levels, t.LSM0.levels)
-
-			data, err := json.Marshal(lsmView)
-			if err != nil {
+			if err := t.Sync(); err != nil {
 				return err
 			}
-
-			fw := io.GetFileManager().OpenForWrite(filePath)
-			fw.Write(data)
-			fw.Close()
 		}
 	}
 }
 
+// Sync writes a snapshot of manifest to disk. Besides periodic sync loop, it's
+// called before deleting anything the previous snapshot might still refer to,
+// e.g wal of a flushed memtable or input tables of compaction.
+func (t *Manifest) Sync() error {
+	t.syncMu.Lock()
+	defer t.syncMu.Unlock()
+
+	// load consistent manifest snapshot
+	// reason: json needs struct to export fields with no locks
+	// 		   lsm is rw protected through locks, using lsm directly might lead to data race
+	lsmView := t.LSM0.ToView()
+	log.Infof("Manifest LSM: %+v\n", lsmView.Levels)
+
+	data, err := json.Marshal(lsmView)
+	if err != nil {
+		return err
+	}
+
+	return t.write(data)
+}
+
+// VFS returns VFS manifest & tables are kept on
+func (t *Manifest) VFS() io.VFS {
+	return io.Manager(t.opts.VFS).FS()
+}
+
 func (t *Manifest) FormatDBPath(l, i int) string {
 	if l < 0 || i < 0 {
 		return ""
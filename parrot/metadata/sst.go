@@ -10,6 +10,21 @@ type SSTable struct {
 	DBPath      string
 	IndexPath   string
 	SizeInBytes int64
+
+	// value log segments holding values of table, see vlog.ValueLog
+	ValueSegments []int64
+
+	// range tombstones of tables written before index files held them,
+	// they shadow older tables only
+	RangeTombstones []RangeTombstone
+}
+
+// RangeTombstone deletes keys of [Start, End) for reads of Time onwards,
+// bounds are encoded by codec of table. see types.KeyRange.Time
+type RangeTombstone struct {
+	Start []byte `json:"start"`
+	End   []byte `json:"end"`
+	Time  int64  `json:"time,omitempty"`
 }
 
 func NewSSTable(dBPath string, indexPath string, sizeInBytes int64) *SSTable {
@@ -25,6 +40,10 @@ type SSTableView struct {
 	DBPath      string `json:"dBPath"`
 	IndexPath   string `json:"indexPath"`
 	SizeInBytes int64  `json:"size"`
+
+	ValueSegments []int64 `json:"valueSegments,omitempty"`
+
+	RangeTombstones []RangeTombstone `json:"rangeTombstones,omitempty"`
 }
 
 func NewSSTableView(DBPath string, IndexPath string, sizeInBytes int64) SSTable {
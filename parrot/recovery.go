@@ -0,0 +1,190 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
+)
+
+// CommitLogEntry is an entry of a commit log record, see InspectCommitLog
+type CommitLogEntry[K types.Key, V types.Value] struct {
+	Family string
+	Event  memtable.MemTableEvent[K, V]
+}
+
+// InspectCommitLog decodes every record of commit log segment at path of
+// fs, see wal.Inspect
+func InspectCommitLog[K types.Key, V types.Value](fs io.VFS, path string) ([]wal.Record[[]CommitLogEntry[K, V]], error) {
+	records, err := wal.Inspect(fs, path, resolveLogCodec[K, V])
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]wal.Record[[]CommitLogEntry[K, V]], 0, len(records))
+	for _, record := range records {
+		entries := make([]CommitLogEntry[K, V], 0, len(record.Event.Entries))
+		for _, entry := range record.Event.Entries {
+			entries = append(entries, CommitLogEntry[K, V]{Family: entry.Family, Event: entry.event()})
+		}
+		res = append(res, wal.Record[[]CommitLogEntry[K, V]]{Offset: record.Offset, Event: entries, Err: record.Err})
+	}
+	return res, nil
+}
+
+// RecoveryStep is a logged entry as recovery would handle it, see PlanRecovery
+type RecoveryStep[K types.Key, V types.Value] struct {
+	// log file & position of record holding entry, segment is 0 for
+	// memtable logs
+	Path string
+	Pos  metadata.LogPosition
+
+	// family entry belongs to, empty for memtable logs
+	Family string
+	Event  memtable.MemTableEvent[K, V]
+
+	// why entry isn't applied, empty if it is
+	Skip string
+	// set if record failed to decode, Family & Event are zero then
+	Err error
+}
+
+// PlanRecovery lists entries replaying logs at dir would apply, in order,
+// without opening storage or writing anything. dir is either
+//   - commit log dir, <root>/log. Entries of families flushed past them, as
+//     recorded by manifests under <root>, are skipped
+//   - memtable log dir replayed by MemtableStore.RollbackAll
+//
+// Replay of a log stops at first record failing to decode, entries after it
+// are listed as skipped. ring decrypts logs & manifests of an encrypted
+// storage, nil if it isn't.
+func PlanRecovery[K types.Key, V types.Value](dir string, ring *io.Keyring) ([]RecoveryStep[K, V], error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	segments, err := filepath.Glob(filepath.Join(dir, "log-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	fs := io.WithKeyring(io.OS, ring)
+	if len(segments) > 0 {
+		return planCommitLog[K, V](fs, dir, segments)
+	}
+	return planMemtableLogs[K, V](fs, memtable.SortedLogs(fs, dir))
+}
+
+// planCommitLog walks segments the way Storage.recover does
+func planCommitLog[K types.Key, V types.Value](fs io.VFS, dir string, files []string) ([]RecoveryStep[K, V], error) {
+	ids := make(map[string]int64, len(files))
+	for _, file := range files {
+		var id int64
+		if _, err := fmt.Sscanf(filepath.Base(file), "log-%d.log", &id); err != nil {
+			return nil, fmt.Errorf("unexpected commit log segment %s", file)
+		}
+		ids[file] = id
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return ids[files[i]] < ids[files[j]]
+	})
+
+	root := filepath.Dir(filepath.Clean(dir))
+	flushed := map[string]metadata.LogPosition{}
+
+	var steps []RecoveryStep[K, V]
+	for _, file := range files {
+		records, err := InspectCommitLog[K, V](fs, file)
+		if err != nil {
+			return nil, err
+		}
+
+		skip := ""
+		for i, record := range records {
+			pos := metadata.LogPosition{Segment: ids[file], Index: int64(i + 1)}
+			if record.Err != nil {
+				steps = append(steps, RecoveryStep[K, V]{Path: file, Pos: pos, Skip: "undecodable record", Err: record.Err})
+				skip = "follows undecodable record"
+				continue
+			}
+
+			for _, entry := range record.Event {
+				step := RecoveryStep[K, V]{Path: file, Pos: pos, Family: entry.Family, Event: entry.Event, Skip: skip}
+				if step.Skip == "" {
+					last, ok := flushed[entry.Family]
+					if !ok {
+						if last, err = loadFlushed(fs, root, entry.Family); err != nil {
+							return nil, err
+						}
+						flushed[entry.Family] = last
+					}
+					if !last.Less(pos) {
+						step.Skip = fmt.Sprintf("flushed up to %d:%d", last.Segment, last.Index)
+					}
+				}
+				steps = append(steps, step)
+			}
+		}
+	}
+	return steps, nil
+}
+
+// loadFlushed reads position family is flushed up to from its manifest
+// under root, zero if family has none yet
+func loadFlushed(fs io.VFS, root string, family string) (metadata.LogPosition, error) {
+	// default family keeps manifest at root, see Storage.familyDir
+	for _, dir := range []string{filepath.Join(root, family), root} {
+		path := filepath.Join(dir, metadata.MANIFEST, family, metadata.MANIFEST+".json")
+		data, err := io.Manager(fs).ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return metadata.LogPosition{}, err
+		}
+
+		view := metadata.NewLSMView(family)
+		if err := json.Unmarshal(data, view); err != nil {
+			return metadata.LogPosition{}, fmt.Errorf("failed to parse %s, err=%v", path, err)
+		}
+		if view.Flushed == nil {
+			return metadata.LogPosition{}, nil
+		}
+		return *view.Flushed, nil
+	}
+	return metadata.LogPosition{}, nil
+}
+
+// planMemtableLogs walks memtable logs the way MemtableStore.RollbackAll does
+func planMemtableLogs[K types.Key, V types.Value](fs io.VFS, files []string) ([]RecoveryStep[K, V], error) {
+	var steps []RecoveryStep[K, V]
+	for _, file := range files {
+		records, err := memtable.InspectLog[K, V](fs, file)
+		if err != nil {
+			return nil, err
+		}
+
+		skip := ""
+		for i, record := range records {
+			step := RecoveryStep[K, V]{Path: file, Pos: metadata.LogPosition{Index: int64(i + 1)}, Event: record.Event, Skip: skip, Err: record.Err}
+			if record.Err != nil {
+				step.Skip = "undecodable record"
+				skip = "follows undecodable record"
+			}
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
@@ -0,0 +1,471 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
+	"github.com/nagarajRPoojari/orange/parrot/sstable"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+)
+
+// quarantineDir is directory of storage root Repair moves corrupt files to
+const quarantineDir = "quarantine"
+
+// Issue is an inconsistency found in a storage directory, see Check
+type Issue struct {
+	Family  string
+	Path    string
+	Problem string
+
+	// what Repair does about it
+	Fix string
+}
+
+// CheckReport lists what Check or Repair found in a storage directory
+type CheckReport struct {
+	Families []string
+	// tables that are referenced by manifests & decode fine
+	Tables int
+	Issues []Issue
+}
+
+// Check cross-checks manifest of every family under dir against its level
+// directories & verifies that every table decodes. Nothing is changed,
+// storage must not be open. ring decrypts files of an encrypted storage,
+// nil if it isn't.
+func Check[K types.Key, V types.Value](dir string, ring *io.Keyring) (*CheckReport, error) {
+	return scrub[K, V](dir, ring, false)
+}
+
+// Repair fixes issues Check finds, storage must not be open
+//   - tables failing to decode or missing half of their files are moved to
+//     <dir>/quarantine & dropped from manifest
+//   - tables missing on disk are dropped from manifest
+//   - files no manifest refers to are removed, they're left by interrupted
+//     flushes, compactions or ingestion
+//   - a missing or unreadable manifest is rebuilt from valid tables found,
+//     range tombstones are read back from their index files. Flushed log
+//     position is kept by manifest only, so commit log left on disk is
+//     replayed in full.
+//
+// Rebuilt manifest is encrypted with active key of ring, see Check.
+func Repair[K types.Key, V types.Value](dir string, ring *io.Keyring) (*CheckReport, error) {
+	return scrub[K, V](dir, ring, true)
+}
+
+// familyLayout locates files of a family, see Storage.familyDir
+type familyLayout struct {
+	name string
+	dir  string
+}
+
+func (f familyLayout) manifestPath() string {
+	return filepath.Join(f.dir, metadata.MANIFEST, f.name, metadata.MANIFEST+".json")
+}
+
+// tableKey identifies a table by its level & id within level
+type tableKey struct {
+	level int
+	id    int
+}
+
+// tableState is what scrub decided about files of a table
+type tableState int
+
+const (
+	tableOrphan tableState = iota
+	tableKept
+	tableQuarantined
+)
+
+// diskTable is a table found in a level directory
+type diskTable struct {
+	db, index bool
+	state     tableState
+}
+
+type scrubber[K types.Key, V types.Value] struct {
+	root string
+	fix  bool
+	// files on disk, keyed with ring scrub is given
+	fs io.VFS
+
+	// corrupt files are moved here, keeping their path relative to root
+	quarantine string
+
+	report *CheckReport
+}
+
+func scrub[K types.Key, V types.Value](root string, ring *io.Keyring, fix bool) (*CheckReport, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	families, err := findFamilies(root)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scrubber[K, V]{
+		root:       root,
+		fix:        fix,
+		fs:         io.WithKeyring(io.OS, ring),
+		quarantine: filepath.Join(root, quarantineDir, time.Now().Format("20060102-150405")),
+		report:     &CheckReport{},
+	}
+	for _, fam := range families {
+		s.report.Families = append(s.report.Families, fam.name)
+		if err := s.family(fam); err != nil {
+			return s.report, err
+		}
+	}
+	return s.report, nil
+}
+
+// findFamilies lists families having a manifest or a level directory
+//   - default family: <root>/manifest/<name>, <root>/<name>/level-N
+//   - others: <root>/<name>/manifest/<name>, <root>/<name>/<name>/level-N
+func findFamilies(root string) ([]familyLayout, error) {
+	seen := map[familyLayout]struct{}{}
+	var families []familyLayout
+	add := func(fam familyLayout) {
+		if _, ok := seen[fam]; !ok {
+			seen[fam] = struct{}{}
+			families = append(families, fam)
+		}
+	}
+
+	manifests, _ := filepath.Glob(filepath.Join(root, metadata.MANIFEST, "*", metadata.MANIFEST+".json"))
+	for _, path := range manifests {
+		add(familyLayout{name: filepath.Base(filepath.Dir(path)), dir: root})
+	}
+	levels, _ := filepath.Glob(filepath.Join(root, "*", "level-*"))
+	for _, path := range levels {
+		add(familyLayout{name: filepath.Base(filepath.Dir(path)), dir: root})
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, reserved := reservedFamilies[name]; reserved || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, name)
+		fam := familyLayout{name: name, dir: dir}
+		levels, _ := filepath.Glob(filepath.Join(dir, name, "level-*"))
+		if _, err := os.Stat(fam.manifestPath()); err == nil || len(levels) > 0 {
+			add(fam)
+		}
+	}
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+	return families, nil
+}
+
+func (s *scrubber[K, V]) issue(fam familyLayout, path string, problem string, fix string) {
+	s.report.Issues = append(s.report.Issues, Issue{Family: fam.name, Path: path, Problem: problem, Fix: fix})
+}
+
+// family checks & repairs a single family, manifest is rewritten before
+// anything it referred to is touched
+func (s *scrubber[K, V]) family(fam familyLayout) error {
+	mf := metadata.NewManifest(fam.name, metadata.ManifestOpts{Dir: fam.dir, VFS: s.fs})
+	issues := len(s.report.Issues)
+
+	tables, strays, err := listTables(mf)
+	if err != nil {
+		return err
+	}
+
+	view, readErr := readManifest(s.fs, fam)
+	rebuild := view == nil
+	switch {
+	case readErr != nil:
+		s.issue(fam, fam.manifestPath(), fmt.Sprintf("manifest is unreadable: %v", readErr), "quarantine & rebuild from valid tables")
+	case view == nil && len(tables) > 0:
+		s.issue(fam, fam.manifestPath(), "manifest is missing", "rebuild from valid tables")
+	}
+
+	// byte keys are checked against comparator recorded in manifest,
+	// bytewise order is assumed once it's lost
+	var cmp types.Comparator
+	if view != nil && view.Comparator != "" {
+		if cmp, err = types.LookupComparator(view.Comparator); err != nil {
+			return err
+		}
+	}
+
+	kept := map[tableKey]metadata.SSTableView{}
+	if !rebuild {
+		for l, level := range view.Levels {
+			ids := make([]int, 0, len(level.Tables))
+			for id := range level.Tables {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+
+			for _, id := range ids {
+				key, tbl := tableKey{level: l, id: id}, level.Tables[id]
+				dbPath, indexPath := mf.FormatDBPath(l, id), mf.FormatIndexPath(l, id)
+
+				d, ok := tables[key]
+				if !ok || !d.db || !d.index {
+					missing := dbPath
+					if ok && d.db {
+						missing = indexPath
+					}
+					fix := "drop from manifest"
+					if ok {
+						d.state = tableQuarantined
+						fix = "drop from manifest & quarantine remaining file"
+					}
+					s.issue(fam, missing, "table referenced by manifest is missing", fix)
+					continue
+				}
+
+				if _, err := verifyTable[K, V](s.fs, dbPath, cmp); err != nil {
+					d.state = tableQuarantined
+					s.issue(fam, dbPath, fmt.Sprintf("table is corrupt: %v", err), "quarantine & drop from manifest")
+					continue
+				}
+				d.state = tableKept
+				kept[key] = tbl
+			}
+		}
+	} else {
+		for _, key := range sortedTables(tables) {
+			d := tables[key]
+			if !d.db || !d.index {
+				continue
+			}
+			dbPath, indexPath := mf.FormatDBPath(key.level, key.id), mf.FormatIndexPath(key.level, key.id)
+			summary, err := verifyTable[K, V](s.fs, dbPath, cmp)
+			if err != nil {
+				d.state = tableQuarantined
+				s.issue(fam, dbPath, fmt.Sprintf("table is corrupt: %v", err), "quarantine")
+				continue
+			}
+			d.state = tableKept
+			kept[key] = metadata.SSTableView{
+				DBPath:        dbPath,
+				IndexPath:     indexPath,
+				SizeInBytes:   summary.SizeInBytes,
+				ValueSegments: summary.ValueSegments,
+			}
+		}
+	}
+	s.report.Tables += len(kept)
+
+	// whatever is left on disk isn't referenced by manifest
+	var quarantined, orphans []string
+	for _, key := range sortedTables(tables) {
+		d := tables[key]
+		var paths []string
+		if d.db {
+			paths = append(paths, mf.FormatDBPath(key.level, key.id))
+		}
+		if d.index {
+			paths = append(paths, mf.FormatIndexPath(key.level, key.id))
+		}
+
+		switch d.state {
+		case tableQuarantined:
+			quarantined = append(quarantined, paths...)
+		case tableOrphan:
+			for _, path := range paths {
+				s.issue(fam, path, "file isn't referenced by manifest", "remove")
+			}
+			orphans = append(orphans, paths...)
+		}
+	}
+	for _, path := range strays {
+		s.issue(fam, path, "file isn't referenced by manifest", "remove")
+		orphans = append(orphans, path)
+	}
+
+	if !s.fix || len(s.report.Issues) == issues {
+		return nil
+	}
+
+	if err := s.writeManifest(fam, view, readErr != nil, kept); err != nil {
+		return err
+	}
+	for _, path := range quarantined {
+		if err := s.quarantineFile(path); err != nil {
+			return err
+		}
+	}
+	for _, path := range orphans {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTables lists table files in level directories of family, files
+// staged by an interrupted ingestion are returned as strays
+func listTables(mf *metadata.Manifest) (map[tableKey]*diskTable, []string, error) {
+	tables := map[tableKey]*diskTable{}
+
+	levels, err := filepath.Glob(filepath.Join(filepath.Dir(mf.FormatLevelPath(0)), "level-*"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, dir := range levels {
+		var level int
+		if _, err := fmt.Sscanf(filepath.Base(dir), "level-%d", &level); err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			var id int
+			var ext string
+			if _, err := fmt.Sscanf(entry.Name(), "sst-%d.%s", &id, &ext); err != nil {
+				continue
+			}
+			d, ok := tables[tableKey{level: level, id: id}]
+			if !ok {
+				d = &diskTable{}
+				tables[tableKey{level: level, id: id}] = d
+			}
+			switch ext {
+			case "db":
+				d.db = true
+			case "index":
+				d.index = true
+			}
+		}
+	}
+
+	// family directory of default family is storage root, so only names
+	// written by IngestFiles are taken
+	staged, err := filepath.Glob(filepath.Join(filepath.Dir(filepath.Dir(mf.FormatLevelPath(0))), "ingest-*"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var strays []string
+	for _, path := range staged {
+		if strings.HasSuffix(path, ".db") || strings.HasSuffix(path, ".index") {
+			strays = append(strays, path)
+		}
+	}
+	return tables, strays, nil
+}
+
+func sortedTables(tables map[tableKey]*diskTable) []tableKey {
+	keys := make([]tableKey, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].level < keys[j].level || keys[i].level == keys[j].level && keys[i].id < keys[j].id
+	})
+	return keys
+}
+
+// readManifest reads manifest of family, nil if it has none
+func readManifest(fs io.VFS, fam familyLayout) (*metadata.LSMView, error) {
+	data, err := io.Manager(fs).ReadFile(fam.manifestPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	view := &metadata.LSMView{}
+	if err := json.Unmarshal(data, view); err != nil {
+		return nil, err
+	}
+	if view.Name != fam.name {
+		return nil, fmt.Errorf("manifest is named %q", view.Name)
+	}
+	return view, nil
+}
+
+func verifyTable[K types.Key, V types.Value](fs io.VFS, path string, cmp types.Comparator) (sstable.Summary[K], error) {
+	table, err := sstable.Open[K, V](fs, path, cmp)
+	if err != nil {
+		return sstable.Summary[K]{}, err
+	}
+	return table.Verify()
+}
+
+// writeManifest replaces manifest of family with one listing kept tables,
+// old is nil if manifest is rebuilt. A broken manifest is quarantined first.
+func (s *scrubber[K, V]) writeManifest(fam familyLayout, old *metadata.LSMView, broken bool, kept map[tableKey]metadata.SSTableView) error {
+	view := &metadata.LSMView{Name: fam.name}
+	levels := 1
+	if old != nil {
+		view.Comparator, view.Flushed = old.Comparator, old.Flushed
+		levels = max(levels, len(old.Levels))
+	}
+	for key := range kept {
+		levels = max(levels, key.level+1)
+	}
+
+	view.Levels = make([]metadata.LevelView, levels)
+	for l := range view.Levels {
+		view.Levels[l] = metadata.NewLevelView()
+	}
+	for key, tbl := range kept {
+		view.Levels[key.level].Tables[key.id] = tbl
+	}
+
+	data, err := json.Marshal(view)
+	if err != nil {
+		return err
+	}
+	path := fam.manifestPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := io.Manager(s.fs).WriteFile(tmp, data); err != nil {
+		return err
+	}
+	if broken {
+		if err := s.quarantineFile(path); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+// quarantineFile moves file at path under quarantine directory
+func (s *scrubber[K, V]) quarantineFile(path string) error {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	dst := filepath.Join(s.quarantine, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
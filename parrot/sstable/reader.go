@@ -0,0 +1,198 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package sstable
+
+import (
+	"sort"
+
+	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils"
+)
+
+// Table is a table file loaded for inspection, see Open. It's decoded by
+// cache unit reads go through, but nothing is trusted until Verify.
+type Table[K types.Key, V types.Value] struct {
+	Path      string
+	IndexPath string
+
+	// codec table is written with, nil for legacy gob tables
+	Codec codec.Codec
+
+	unit   *v2.CacheUnit[K, V]
+	index  []utils.IndexPayload[K, V]
+	ranges []types.KeyRange[K]
+	less   types.Order[K]
+}
+
+// Summary describes a verified table
+type Summary[K types.Key] struct {
+	Entries    int
+	Tombstones int
+	Operands   int
+	// range tombstones kept in index
+	RangeTombstones int
+	// entries whose value is kept in a value log
+	Refs int
+	// value log segments refs point to, ascending
+	ValueSegments []int64
+
+	// size of entries as accounted by storage
+	SizeInBytes int64
+
+	// smallest & largest key of table
+	First K
+	Last  K
+}
+
+// Open loads data file at path of fs & its index file, see IndexPath. Files
+// are read into memory, so tables rewritten later aren't served stale. Byte
+// keys are expected in order of cmp, bytewise if nil. nil fs is OS, see
+// io.WithKeyring for encrypted tables.
+func Open[K types.Key, V types.Value](fs io.VFS, path string, cmp types.Comparator) (*Table[K, V], error) {
+	t := &Table[K, V]{Path: path, IndexPath: IndexPath(path), less: types.OrderOf[K](cmp)}
+
+	fm := io.Manager(fs)
+	data, err := fm.ReadFile(t.Path)
+	if err != nil {
+		return nil, errors.RaiseSSTableErr("failed to read %s, err=%v", t.Path, err)
+	}
+	index, err := fm.ReadFile(t.IndexPath)
+	if err != nil {
+		return nil, errors.RaiseSSTableErr("failed to read %s, err=%v", t.IndexPath, err)
+	}
+
+	dataCodec, _, dataOk := codec.ParseHeader(data)
+	indexCodec, _, indexOk := codec.ParseHeader(index)
+	if dataOk != indexOk || dataCodec != indexCodec {
+		return nil, errors.RaiseSSTableErr("%s & its index are written by different codecs", t.Path)
+	}
+
+	t.unit = v2.NewUnit[K, V](data, index, cmp)
+	if t.index, err = t.unit.Index(); err != nil {
+		return nil, errors.RaiseSSTableErr("%s: %v", t.IndexPath, err)
+	}
+	if t.ranges, err = t.unit.RangeTombstones(); err != nil {
+		return nil, errors.RaiseSSTableErr("%s: %v", t.IndexPath, err)
+	}
+	t.Codec = t.unit.Codec()
+	return t, nil
+}
+
+// Len returns number of entries listed by index
+func (t *Table[K, V]) Len() int {
+	return len(t.index)
+}
+
+// RangeTombstones returns range tombstones kept in index, they delete keys
+// of older tables only
+func (t *Table[K, V]) RangeTombstones() []types.KeyRange[K] {
+	return t.ranges
+}
+
+// Each calls fn for entries in index order until fn returns false, value log
+// refs are left unresolved
+func (t *Table[K, V]) Each(fn func(pl types.Payload[K, V]) bool) error {
+	for _, entry := range t.index {
+		pl, err := t.decode(entry)
+		if err != nil {
+			return err
+		}
+		if !fn(pl) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (t *Table[K, V]) decode(entry utils.IndexPayload[K, V]) (types.Payload[K, V], error) {
+	if entry.Offset < 0 || entry.Size <= 0 || entry.Offset+entry.Size > t.unit.DataSize() {
+		return types.Payload[K, V]{}, errors.RaiseSSTableErr(
+			"entry of key=%v at offset=%d, size=%d is out of data file of size=%d",
+			entry.Key, entry.Offset, entry.Size, t.unit.DataSize(),
+		)
+	}
+	pl, err := t.unit.Decode(entry)
+	if err != nil {
+		return types.Payload[K, V]{}, errors.RaiseSSTableErr("%v", err)
+	}
+	return pl, nil
+}
+
+// Verify checks that table is well formed:
+//   - index keys are increasing & match keys of their entries, versions
+//     of a key are ordered newest first
+//   - every entry lies inside data file & decodes
+//   - entries are laid out back to back covering whole data file, not
+//     checked for legacy gob tables
+func (t *Table[K, V]) Verify() (Summary[K], error) {
+	var s Summary[K]
+
+	segments := map[int64]struct{}{}
+	offset := int64(0)
+	if t.Codec != nil {
+		offset = codec.HeaderSize
+	}
+	// time of previous entry
+	var last int64
+	for i, entry := range t.index {
+		if i > 0 && t.less(entry.Key, t.index[i-1].Key) {
+			return s, errors.RaiseSSTableErr("key=%v isn't sorted after key=%v", entry.Key, t.index[i-1].Key)
+		}
+		if t.Codec != nil && entry.Offset != offset {
+			return s, errors.RaiseSSTableErr("entry of key=%v at offset=%d, expected %d", entry.Key, entry.Offset, offset)
+		}
+
+		pl, err := t.decode(entry)
+		if err != nil {
+			return s, err
+		}
+		if pl.Key != entry.Key {
+			return s, errors.RaiseSSTableErr("index key=%v points to entry of key=%v", entry.Key, pl.Key)
+		}
+		if i > 0 && t.index[i-1].Key == entry.Key && pl.Time >= last {
+			return s, errors.RaiseSSTableErr("versions of key=%v aren't ordered newest first", entry.Key)
+		}
+		last = pl.Time
+
+		s.Entries++
+		switch {
+		case pl.Ref != nil:
+			s.Refs++
+			segments[pl.Ref.Segment] = struct{}{}
+			s.SizeInBytes += int64(pl.Ref.SizeOf())
+		case pl.Val.IsDeleted():
+			s.Tombstones++
+			s.SizeInBytes += int64(pl.Val.SizeOf())
+		case types.IsOperand(pl.Val):
+			s.Operands++
+			s.SizeInBytes += int64(pl.Val.SizeOf())
+		default:
+			s.SizeInBytes += int64(pl.Val.SizeOf())
+		}
+		offset = entry.Offset + entry.Size
+	}
+	if t.Codec != nil && offset != t.unit.DataSize() {
+		return s, errors.RaiseSSTableErr("%s has %d bytes not listed by index", t.Path, t.unit.DataSize()-offset)
+	}
+
+	for segment := range segments {
+		s.ValueSegments = append(s.ValueSegments, segment)
+	}
+	sort.Slice(s.ValueSegments, func(i, j int) bool {
+		return s.ValueSegments[i] < s.ValueSegments[j]
+	})
+
+	s.RangeTombstones = len(t.ranges)
+	if len(t.index) > 0 {
+		s.First, s.Last = t.index[0].Key, t.index[len(t.index)-1].Key
+	}
+	return s, nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package sstable
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils"
+)
+
+// IndexPath returns path of index file paired with data file at path,
+// e.g users.db & users.index
+func IndexPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".index"
+}
+
+// Writer builds a table outside of any storage from entries added in key
+// order, finished tables can be loaded by Storage.IngestFiles.
+//   - values are kept inline, tombstones are written as is
+//   - keys are ordered by Less, byte keys by comparator passed to NewWriter
+type Writer[K types.Key, V types.Value] struct {
+	path  string
+	db    *os.File
+	index *os.File
+	tw    *utils.TableWriter[K, V]
+
+	less  types.Order[K]
+	last  K
+	count int
+}
+
+// NewWriter creates data file at path & its index file, see IndexPath.
+// Existing files aren't overwritten. Byte keys are ordered by cmp, it must
+// match comparator of storage table is ingested into, bytewise if nil.
+func NewWriter[K types.Key, V types.Value](path string, c codec.Codec, cmp types.Comparator) (*Writer[K, V], error) {
+	if c == nil {
+		c = codec.Gob
+	}
+
+	db, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.RaiseSSTableErr("failed to create %s, err=%v", path, err)
+	}
+	index, err := os.OpenFile(IndexPath(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		db.Close()
+		os.Remove(path)
+		return nil, errors.RaiseSSTableErr("failed to create %s, err=%v", IndexPath(path), err)
+	}
+
+	t := &Writer[K, V]{path: path, db: db, index: index, less: types.OrderOf[K](cmp)}
+	if t.tw, err = utils.NewTableWriter[K, V](db, index, c); err != nil {
+		t.Abort()
+		return nil, errors.RaiseSSTableErr("%v", err)
+	}
+	return t, nil
+}
+
+// Add appends value of key, keys must be strictly increasing
+func (t *Writer[K, V]) Add(key K, value V) error {
+	if t.count > 0 && !t.less(t.last, key) {
+		return errors.RaiseSSTableErr("key=%v added after key=%v", key, t.last)
+	}
+	if err := t.tw.Append(types.Payload[K, V]{Key: key, Val: value}); err != nil {
+		return errors.RaiseSSTableErr("%v", err)
+	}
+	t.last = key
+	t.count++
+	return nil
+}
+
+// Count returns number of entries added so far
+func (t *Writer[K, V]) Count() int {
+	return t.count
+}
+
+// Finish makes table durable & closes its files. An empty table is
+// removed & reported as error, it can't be ingested.
+func (t *Writer[K, V]) Finish() error {
+	if t.count == 0 {
+		t.Abort()
+		return errors.RaiseSSTableErr("table %s has no entries", t.path)
+	}
+
+	if err := t.tw.Flush(); err != nil {
+		t.Abort()
+		return errors.RaiseSSTableErr("%v", err)
+	}
+	for _, f := range []*os.File{t.db, t.index} {
+		if err := f.Sync(); err != nil {
+			t.Abort()
+			return errors.RaiseSSTableErr("failed to sync %s, err=%v", f.Name(), err)
+		}
+	}
+	t.db.Close()
+	t.index.Close()
+	return nil
+}
+
+// Abort closes & removes files of unfinished table
+func (t *Writer[K, V]) Abort() {
+	t.db.Close()
+	t.index.Close()
+	os.Remove(t.path)
+	os.Remove(IndexPath(t.path))
+}
@@ -9,14 +9,19 @@ package storage
 import (
 	"context"
 	"path/filepath"
+	"sync"
 	"time"
 
-	v2 "github.com/nagarajRPoojari/orange/parrot/cache/v2"
+	"github.com/nagarajRPoojari/orange/parrot/codec"
 	"github.com/nagarajRPoojari/orange/parrot/compactor"
 	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
 	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/merge"
 	"github.com/nagarajRPoojari/orange/parrot/metadata"
 	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+	"github.com/nagarajRPoojari/orange/parrot/wal"
 )
 
 // StorageOpts defines configuration options for the storage engine.
@@ -41,8 +46,21 @@ type StorageOpts struct {
 	MemtableWALEventChSize int32
 	// writer buffer size
 	MemtableWALWriterBufferSize int
-	// Directory path where WAL files will be stored
+	// Directory path of per-memtable WAL files written by older versions,
+	// they are migrated into commit log on open
 	MemtableWALLogDir string
+	// Directory of commit log shared by all families
+	commitLogDir string
+	// Window commit log segments are kept for after they're closed, so
+	// subscribers can resume reading changes from them, see Subscribe.
+	// Segments are deleted once flushed if 0
+	ChangeRetention time.Duration
+	// Budget (in bytes) for memtables of all families, largest memtable is
+	// flushed once they hold more. Turned off if 0
+	WriteBufferSize int64
+	// Budget shared with other storages, takes precedence over
+	// WriteBufferSize when set
+	WriteBuffer *memtable.WriteBufferManager
 
 	// Compaction configuration
 	// Enables background compaction and garbage collection
@@ -62,18 +80,103 @@ type StorageOpts struct {
 	CompactionWALWriterBufferSize int
 	// Directory to store compaction-related WALs or logs
 	compactionWALLogDir string
+	// Number of workers compacting families concurrently, defaults to 1
+	CompactionWorkers int
+	// Scheduler shared with other storages, families are compacted on its
+	// workers if set, CompactionWorkers & CompactionTimeInterval are unused
+	// then. Caller runs it, see compactor.Scheduler.Run
+	CompactionScheduler *compactor.Scheduler
+	// Maximum number of key ranges a compaction is split into & merged in
+	// parallel, see compactor.SizeTiredCompactionOpts
+	MaxSubcompactions int
+	// Minimum number of entries per subcompaction, defaults to 4096
+	SubcompactionMinEntries int
+	// Optional hook to drop or rewrite entries while compacting
+	CompactionFilter compactor.CompactionFilter
+
+	// Window old versions of documents are kept for, so they can be read
+	// with GetAt. Overwritten versions are dropped right away if 0
+	Retention time.Duration
+
+	// Name of registered comparator ordering types.BytesKey keys, bytewise
+	// order is used if empty. It's recorded in manifest of each family &
+	// opening a family with a different one fails. It orders keys of this
+	// storage only, see types.RegisterComparator
+	Comparator string
+
+	// Codec serializing keys & values of tables & logs, gob if nil. It's
+	// registered for reads, see codec.Register; files written by other
+	// registered codecs stay readable
+	Codec codec.Codec
+
+	// Keys tables, logs, manifests & value log segments are encrypted at
+	// rest with, files are written in plaintext if nil. It's kept by this
+	// storage, see io.WithKeyring
+	Keyring *io.Keyring
+
+	// VFS all files are kept on, files on disk if nil. e.g io.NewMemFS()
+	// keeps storage in memory & io.FaultFS injects failures
+	VFS io.VFS
+
+	// How tables are read: mapped with mmap by default, or read entry by
+	// entry with pread into pooled buffers, optionally with O_DIRECT. see
+	// io.ReadOpts
+	TableRead io.ReadOpts
+
+	// Operator folding values written through Storage.Merge, e.g merge.Int64Add{}.
+	// Merge is rejected if not set
+	MergeOperator merge.Operator
+
+	// Value separation
+	// Values whose encoded size exceeds threshold (in bytes) are flushed to a
+	// value log of family & tables keep refs to them, so compaction doesn't
+	// rewrite them. Turned off if 0
+	ValueLogThreshold int
+	// Size (in bytes) after which a value log segment is sealed, defaults to 64MB
+	ValueLogSegmentSize int64
+	// Fraction of dead records after which live values of a sealed segment
+	// are rewritten, so it can be reclaimed. defaults to 0.5
+	ValueLogGCRatio float64
 }
 
 type Storage[K types.Key, V types.Value] struct {
-	name     string
-	store    *memtable.MemtableStore[K, V]
-	manifest *metadata.Manifest
+	name string
 
-	reader *Reader[K, V]
-	writer *Writer[K, V]
+	// default family, named after storage
+	def *Family[K, V]
+
+	families map[string]*Family[K, V]
+	familyMu sync.Mutex
+
+	// commit log shared by all families, nil if wal is turned off
+	log *commitLog[K, V]
+	// compaction log shared by all families, nil if compaction is turned off
+	gcLog *wal.WAL[compactor.Event]
+	// schedules compaction of families, nil if compaction is turned off
+	scheduler *compactor.Scheduler
+
+	// memory budget of memtables of all families, nil if there is none
+	buffer *memtable.WriteBufferManager
+	// wakes flush scheduler before next tick, see flushLoop
+	flushCh chan struct{}
+
+	// mu serializes writes (commit log append & memtable apply), reads share
+	// it so that a batch spanning families is never observed partially
+	mu sync.RWMutex
+	// stamps write times of batches
+	clock memtable.Clock
+
+	// comparator byte keys are ordered by & order of keys it implies,
+	// see StorageOpts.Comparator
+	comparator types.Comparator
+	less       types.Order[K]
 
 	// context for smooth teardown
 	context context.Context
+	cancel  context.CancelFunc
+	// tracks background loops, see Close
+	wg        sync.WaitGroup
+	closeOnce sync.Once
 
 	opts *StorageOpts
 }
@@ -81,68 +184,504 @@ type Storage[K types.Key, V types.Value] struct {
 func NewStorage[K types.Key, V types.Value](name string, ctx context.Context, opts StorageOpts) *Storage[K, V] {
 	opts.compactionWALLogDir = filepath.Join(opts.Directory, "gc")
 	opts.MemtableWALLogDir = filepath.Join(opts.Directory, "wal")
-	v := &Storage[K, V]{name: name, context: ctx, opts: &opts}
-	v.createOrLoadCollection()
-	v.reader = NewReader(v.store, ReaderOpts{})
-	v.writer = NewWriter(v.store, WriterOpts{})
+	opts.commitLogDir = filepath.Join(opts.Directory, "log")
+	if opts.Codec == nil {
+		opts.Codec = codec.Gob
+	}
+	if err := codec.Register(opts.Codec); err != nil {
+		log.Panicf("failed to register codec, err=%v", err)
+	}
+	if opts.VFS == nil {
+		opts.VFS = io.OS
+	}
+	// components get keyed VFS, so keys stay with this storage
+	opts.VFS = io.WithKeyring(opts.VFS, opts.Keyring)
+	if opts.ValueLogSegmentSize <= 0 {
+		opts.ValueLogSegmentSize = 64 << 20
+	}
+	if opts.ValueLogGCRatio <= 0 {
+		opts.ValueLogGCRatio = 0.5
+	}
+
+	comparator := types.BytewiseComparator
+	if opts.Comparator != "" {
+		var err error
+		if comparator, err = types.LookupComparator(opts.Comparator); err != nil {
+			log.Panicf("failed to set comparator, err=%v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	v := &Storage[K, V]{
+		name:       name,
+		families:   map[string]*Family[K, V]{},
+		context:    ctx,
+		cancel:     cancel,
+		opts:       &opts,
+		flushCh:    make(chan struct{}, 1),
+		comparator: comparator,
+		less:       types.OrderOf[K](comparator),
+	}
+
+	v.buffer = opts.WriteBuffer
+	if v.buffer == nil && opts.WriteBufferSize > 0 {
+		v.buffer = memtable.NewWriteBufferManager(opts.WriteBufferSize)
+	}
 
 	if opts.TurnOnCompaction {
-		gc := compactor.NewGC(
-			v.manifest,
-			(*v2.CacheManager[K, V])(v.store.DecoderCache),
-			&compactor.SizeTiredCompaction[K, V]{
-				Opts: compactor.SizeTiredCompactionOpts{
-					Level0MaxSizeInBytes:       opts.Level0MaxSizeInBytes,
-					MaxSizeInBytesGrowthFactor: opts.MaxSizeInBytesGrowthFactor,
-				},
-			},
-			compactor.GCOpts{
-				TimeInterval:        opts.CompactionTimeInterval,
-				WALTimeInterval:     opts.CompactionWALTimeInterval,
-				WALEventChSize:      opts.CompactionWALEventChSize,
-				WALWriterBufferSize: opts.CompactionWALWriterBufferSize,
-			},
-		)
-		go gc.Run(ctx)
+		v.gcLog = compactor.OpenLog(compactor.GCOpts{
+			WALTimeInterval:     opts.CompactionWALTimeInterval,
+			WALEventChSize:      opts.CompactionWALEventChSize,
+			WALWriterBufferSize: opts.CompactionWALWriterBufferSize,
+			WALLogDir:           opts.compactionWALLogDir,
+			VFS:                 opts.VFS,
+		})
+
+		// families register as they're opened, see Family
+		v.scheduler = opts.CompactionScheduler
+		if v.scheduler == nil {
+			v.scheduler = compactor.NewScheduler(compactor.SchedulerOpts{
+				Workers:      opts.CompactionWorkers,
+				TimeInterval: opts.CompactionTimeInterval,
+			})
+		}
+	}
+
+	if opts.TurnOnMemtableWal {
+		v.log = openCommitLog[K, V](opts.commitLogDir, wal.WALOpts{
+			TimeInterval:     opts.MemtableWALTimeInterval,
+			EventChSize:      opts.MemtableWALEventChSize,
+			WriterBufferSize: opts.MemtableWALWriterBufferSize,
+			VFS:              opts.VFS,
+		}, opts.Codec)
+	}
+
+	def, err := v.Family(name)
+	if err != nil {
+		log.Panicf("failed to open default family, err=%v", err)
+	}
+	v.def = def
+
+	if v.log != nil {
+		v.recover()
+	}
+
+	v.wg.Add(2)
+	go v.syncLoop()
+	go v.flushLoop()
+	if opts.TurnOnCompaction && opts.CompactionScheduler == nil {
+		v.wg.Add(1)
+		go func() {
+			defer v.wg.Done()
+			v.scheduler.Run(v.context)
+		}()
 	}
 
 	return v
 }
 
-func (t *Storage[K, V]) createOrLoadCollection() {
-	mf := metadata.NewManifest(t.name, metadata.ManifestOpts{Dir: t.opts.Directory})
-	mf.Load()
-
-	mf.SyncLoop(t.context)
-
-	mt := memtable.NewMemtableStore[K, V](
-		mf,
-		t.context,
-		memtable.MemtableOpts{
-			MemtableSoftLimit:   int64(t.opts.MemtableThreshold),
-			QueueHardLimit:      t.opts.QueueHardLimit,
-			QueueSoftLimit:      t.opts.QueueSoftLimit,
-			WALLogDir:           t.opts.MemtableWALLogDir,
-			WALTimeInterval:     t.opts.MemtableWALTimeInterval,
-			WALEventChSize:      t.opts.MemtableWALEventChSize,
-			WALWriterBufferSize: t.opts.MemtableWALWriterBufferSize,
-			TurnOnWal:           t.opts.TurnOnMemtableWal,
-			FlushTimeInterval:   t.opts.FlushTimeInterval,
-		})
-	t.store = mt
-	t.manifest = mf
+// recover replays commit log segments left by previous run, families are
+// opened as they are met. Replayed segments stay pinned until flushed.
+//   - a segment may hold records of memtables flushed already, those are
+//     skipped as merge operands aren't idempotent
+func (t *Storage[K, V]) recover() {
+	t.log.replay(func(pos metadata.LogPosition, rec logRecord[K, V]) {
+		for _, entry := range rec.Entries {
+			fam, err := t.Family(entry.Family)
+			if err != nil {
+				log.Errorf("failed to replay entry for family=%s, err=%v", entry.Family, err)
+				continue
+			}
+			// writes made after recovery must be newer than replayed ones,
+			// flushed ones included
+			t.clock.Observe(entry.Time)
+			if !fam.manifest.GetLSM().GetFlushed().Less(pos) {
+				continue
+			}
+			if _, err := fam.store.Apply(entry.event(), pos); err != nil {
+				log.Errorf("failed to replay entry for key=%v, err=%v", entry.Key, err)
+			}
+		}
+	})
+}
+
+// syncLoop periodically persists manifests of all families
+func (t *Storage[K, V]) syncLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.context.Done():
+			return
+		case <-ticker.C:
+			for _, fam := range t.snapshot() {
+				if err := fam.manifest.Sync(); err != nil {
+					log.Errorf("failed to sync manifest of family=%s, err=%v", fam.name, err)
+				}
+			}
+		}
+	}
+}
+
+// flushLoop is the flush scheduler shared by all families, every tick
+// or once memtables go over write buffer budget, it flushes all disposable
+// memtables & releases commit log segments no longer needed
+func (t *Storage[K, V]) flushLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.opts.FlushTimeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.context.Done():
+			log.Infof("Shutting down flusher")
+			return
+		case <-ticker.C:
+		case <-t.flushCh:
+			// memtables went over write buffer budget, see relieveBuffer
+		}
+
+		flushed := false
+		for _, fam := range t.snapshot() {
+			for fam.store.FlushNext() {
+				flushed = true
+			}
+		}
+		if t.log != nil {
+			t.releaseLog()
+		}
+		// new level-0 tables might be due for compaction
+		if flushed && t.scheduler != nil {
+			t.scheduler.Notify()
+		}
+	}
+}
+
+// releaseLog deletes commit log segments older than oldest segment
+// pinned by any memtable & closed more than ChangeRetention ago
+func (t *Storage[K, V]) releaseLog() {
+	horizon := time.Now().Add(-t.opts.ChangeRetention).UnixNano()
+
+	t.mu.Lock()
+	// taken under mu, so no family can log without being considered
+	fams := t.snapshot()
+	oldest := t.log.segment
+	for _, fam := range fams {
+		if segment, ok := fam.store.OldestSegment(); ok && segment < oldest {
+			oldest = segment
+		}
+	}
+	obsolete := t.log.obsolete(oldest, horizon)
+	t.mu.Unlock()
+
+	if len(obsolete) == 0 {
+		return
+	}
+
+	// flushed tables must be durable in manifest before their logs are dropped
+	for _, fam := range fams {
+		if err := fam.manifest.Sync(); err != nil {
+			log.Errorf("failed to sync manifest of family=%s, err=%v", fam.name, err)
+			return
+		}
+	}
+
+	t.mu.Lock()
+	t.log.release(obsolete)
+	t.mu.Unlock()
+}
+
+// compactionTask schedules compaction of a family, see compactor.Task
+type compactionTask[K types.Key, V types.Value] struct {
+	fam *Family[K, V]
+}
+
+// Score is highest level score of family, families with sealed value log
+// segments are always due so they're checked for garbage
+func (t *compactionTask[K, V]) Score() float64 {
+	score := t.fam.gc.Score()
+	if t.fam.vlog != nil && len(t.fam.vlog.Sealed()) > 0 {
+		score = max(score, 1)
+	}
+	return score
+}
+
+// Compact runs a compaction pass & value log gc of family, unless it's
+// paused, see pauseCompaction
+func (t *compactionTask[K, V]) Compact() {
+	fam := t.fam
+	if !fam.compacting.CompareAndSwap(false, true) {
+		return
+	}
+	defer fam.compacting.Store(false)
+
+	ctx := fam.storage.context
+	if ctx.Err() == nil {
+		fam.gc.RunOnce()
+	}
+	if ctx.Err() == nil && fam.vlog != nil {
+		fam.storage.collectValueLog(fam)
+	}
+}
+
+// Close stops background flush, compaction & sync loops, waiting for
+// in-flight runs to finish, and persists logs & manifests. Memtables aren't
+// flushed, their content is recovered from commit log on next open.
+// Cancelling context passed to NewStorage stops loops as well, but doesn't wait.
+// Storage must not be used after Close.
+func (t *Storage[K, V]) Close() {
+	t.closeOnce.Do(func() {
+		t.cancel()
+		// waits for running compactions, scheduler may outlive storage
+		if t.scheduler != nil {
+			for _, fam := range t.snapshot() {
+				t.scheduler.Unregister(fam.task)
+			}
+		}
+		t.wg.Wait()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if t.log != nil {
+			t.log.wal.Close()
+		}
+		if t.gcLog != nil {
+			t.gcLog.Close()
+		}
+		for _, fam := range t.snapshot() {
+			if err := fam.manifest.Sync(); err != nil {
+				log.Errorf("failed to sync manifest of family=%s, err=%v", fam.name, err)
+			}
+			// write buffer may be shared with storages still running
+			fam.store.Release()
+			if fam.vlog != nil {
+				if err := fam.vlog.Close(); err != nil {
+					log.Errorf("failed to close value log of family=%s, err=%v", fam.name, err)
+				}
+			}
+		}
+	})
 }
 
 func (t *Storage[K, V]) Get(key K) ReadStatus[V] {
-	return t.reader.Get(key)
+	return t.def.Get(key)
+}
+
+// GetAt returns value key had at ts. Versions overwritten more than
+// Retention ago are dropped, so it fails with errors.RetentionErr if
+// Retention is 0 or ts is older than Retention.
+//   - keys deleted by DeleteRange are found at times before it
+func (t *Storage[K, V]) GetAt(key K, ts time.Time) ReadStatus[V] {
+	return t.def.GetAt(key, ts)
 }
 
 func (t *Storage[K, V]) Put(key K, value V) WriteStatus {
-	return t.writer.Put(key, value)
+	return t.def.Put(key, value)
 }
 
 func (t *Storage[K, V]) Delete(key K, tomstone V) WriteStatus {
-	return t.writer.Delete(key, tomstone)
+	return t.def.Delete(key, tomstone)
+}
+
+// DeleteRange deletes keys of [start, end) with a single range tombstone,
+// no tombstone value is needed per key. Covered keys are dropped by compaction
+func (t *Storage[K, V]) DeleteRange(start K, end K) WriteStatus {
+	return t.def.DeleteRange(start, end)
+}
+
+// Scan calls fn for live entries of range in key order until fn returns false
+func (t *Storage[K, V]) Scan(r types.KeyRange[K], fn func(key K, value V) bool) error {
+	return t.def.Scan(r, fn)
+}
+
+// Merge records operand for key without reading current value, operand is
+// folded by configured merge operator on reads & compaction
+func (t *Storage[K, V]) Merge(key K, operand V) WriteStatus {
+	return t.def.Merge(key, operand)
+}
+
+// Write applies all entries of batch atomically, entries may span families.
+// Batch is logged as a single commit log record, so it's either replayed
+// whole or not at all, and readers never observe it partially applied.
+func (t *Storage[K, V]) Write(batch *WriteBatch[K, V]) WriteStatus {
+	if batch.Len() == 0 {
+		return WriteStatus{}
+	}
+
+	// resolve families & validate operands before anything is logged
+	fams := make([]*Family[K, V], len(batch.entries))
+	for i, entry := range batch.entries {
+		fam, err := t.Family(entry.Family)
+		if err != nil {
+			return WriteStatus{Err: err}
+		}
+		fams[i] = fam
+
+		switch entry.Op {
+		case memtable.MergeOperation:
+			if err := merge.Prepare(t.opts.MergeOperator, entry.Value); err != nil {
+				return WriteStatus{Err: err}
+			}
+		case memtable.DeleteRangeOperation:
+			if !t.less(entry.Key, entry.End) {
+				return WriteStatus{Err: errors.RaiseKeyRangeErr("start=%v must sort before end=%v", entry.Key, entry.End)}
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return WriteStatus{Err: t.apply(batch.entries, fams)}
+}
+
+// apply logs entries as a single record & applies them to their families.
+// Entries are checked first, a batch failing to apply is neither logged nor
+// applied partially. caller must hold mu
+func (t *Storage[K, V]) apply(entries []logEntry[K, V], fams []*Family[K, V]) error {
+	if err := t.check(entries, fams); err != nil {
+		return err
+	}
+
+	// entries of a batch share write time, so reads of any time see all or none
+	ts := t.clock.Now()
+	for i := range entries {
+		entries[i].Time = ts
+	}
+
+	var pos metadata.LogPosition
+	if t.log != nil {
+		var err error
+		if pos, err = t.log.append(logRecord[K, V]{Entries: entries}); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	overflow := false
+	for i, entry := range entries {
+		flushed, err := fams[i].store.Apply(entry.event(), pos)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		overflow = overflow || flushed
+	}
+
+	if t.buffer != nil && t.buffer.Exceeded() {
+		overflow = t.relieveBuffer() || overflow
+	}
+
+	// memtable switch starts a new segment, so segments can be released
+	// as memtables get flushed
+	if overflow && t.log != nil {
+		t.log.rotate()
+	}
+
+	return firstErr
+}
+
+// check folds merge operands of entries into versions apply would fold them
+// into, without applying anything: values written by earlier entries of
+// batch or held by active memtables. Merges are the only entries which can
+// fail to apply. caller must hold mu
+func (t *Storage[K, V]) check(entries []logEntry[K, V], fams []*Family[K, V]) error {
+	type slot struct {
+		fam *Family[K, V]
+		key K
+	}
+	// version of key after earlier entries of batch, not present once a
+	// range of batch deletes it
+	type version struct {
+		val     V
+		present bool
+	}
+
+	// writes, deletes & range deletes can't fail on their own
+	if len(entries) == 1 && entries[0].Op != memtable.MergeOperation {
+		return nil
+	}
+
+	staged := map[slot]version{}
+	ranges := map[*Family[K, V]][]types.KeyRange[K]{}
+	for i, entry := range entries {
+		s := slot{fam: fams[i], key: entry.Key}
+		switch entry.Op {
+		case memtable.MergeOperation:
+		case memtable.DeleteRangeOperation:
+			r := types.Between(entry.Key, entry.End)
+			ranges[s.fam] = append(ranges[s.fam], r)
+			for other := range staged {
+				if other.fam == s.fam && r.Contains(t.less, other.key) {
+					staged[other] = version{}
+				}
+			}
+			continue
+		case memtable.DeleteOperation:
+			staged[s] = version{val: types.NewTombstone[V](), present: true}
+			continue
+		default:
+			staged[s] = version{val: entry.Value, present: true}
+			continue
+		}
+
+		older, ok := staged[s]
+		if !ok {
+			deleted := false
+			for _, r := range ranges[s.fam] {
+				deleted = deleted || r.Contains(t.less, entry.Key)
+			}
+			if !deleted {
+				older.val, older.present = s.fam.store.Peek(entry.Key)
+			}
+		}
+
+		folded := entry.Value
+		if older.present {
+			v, err := merge.Combine(t.opts.MergeOperator, entry.Key, older.val, entry.Value)
+			if err != nil {
+				return err
+			}
+			folded = v
+		}
+		staged[s] = version{val: folded, present: true}
+	}
+	return nil
+}
+
+// relieveBuffer seals largest active memtable once memtables hold more than
+// write buffer budget & wakes flush scheduler, it returns true if a memtable
+// was sealed. Sealed memtables pending flush are left to free memory if they
+// hold most of it. caller must hold mu
+func (t *Storage[K, V]) relieveBuffer() bool {
+	var (
+		largest *Family[K, V]
+		size    int64
+		active  int64
+	)
+	for _, fam := range t.snapshot() {
+		s := fam.store.ActiveSize()
+		active += s
+		if s > size {
+			largest, size = fam, s
+		}
+	}
+
+	sealed := false
+	if largest != nil && 2*active >= t.buffer.Limit() {
+		sealed = largest.store.Seal()
+		log.Infof("write buffer exceeded, usage=%d limit=%d, sealed memtable of family=%s", t.buffer.Usage(), t.buffer.Limit(), largest.name)
+	}
+
+	select {
+	case t.flushCh <- struct{}{}:
+	default:
+	}
+	return sealed
 }
 
 type ReadStatus[V types.Value] struct {
@@ -178,6 +717,18 @@ func (t *Reader[K, V]) Get(key K) ReadStatus[V] {
 	return ReadStatus[V]{Value: val}
 }
 
+func (t *Reader[K, V]) GetAt(key K, ts time.Time) ReadStatus[V] {
+	val, ok := t.store.ReadAt(key, ts.UnixNano())
+	if !ok {
+		return ReadStatus[V]{Err: errors.RaiseKeyNotFoundErr("key=%v", key)}
+	}
+	return ReadStatus[V]{Value: val}
+}
+
+func (t *Reader[K, V]) Scan(r types.KeyRange[K], fn func(key K, value V) bool) error {
+	return t.store.Scan(r, fn)
+}
+
 type WriteStatus struct {
 	Err error
 }
@@ -185,16 +736,19 @@ type WriteStatus struct {
 type WriterOpts struct {
 }
 
+// Writer writes to a single family through storage, see Storage.Write
 type Writer[K types.Key, V types.Value] struct {
-	store *memtable.MemtableStore[K, V]
+	storage *Storage[K, V]
+	family  string
 
 	opts WriterOpts
 }
 
-func NewWriter[K types.Key, V types.Value](store *memtable.MemtableStore[K, V], opts WriterOpts) *Writer[K, V] {
+func NewWriter[K types.Key, V types.Value](storage *Storage[K, V], family string, opts WriterOpts) *Writer[K, V] {
 	r := &Writer[K, V]{
-		store: store,
-		opts:  opts,
+		storage: storage,
+		family:  family,
+		opts:    opts,
 	}
 	r.opts = opts
 
@@ -202,11 +756,17 @@ func NewWriter[K types.Key, V types.Value](store *memtable.MemtableStore[K, V],
 }
 
 func (t *Writer[K, V]) Put(key K, value V) WriteStatus {
-	_ = t.store.Write(key, value)
-	return WriteStatus{Err: nil}
+	return t.storage.Write(NewWriteBatch[K, V]().Put(t.family, key, value))
 }
 
 func (t *Writer[K, V]) Delete(key K, tomstone V) WriteStatus {
-	_ = t.store.Delete(key, tomstone)
-	return WriteStatus{Err: nil}
+	return t.storage.Write(NewWriteBatch[K, V]().Delete(t.family, key, tomstone))
+}
+
+func (t *Writer[K, V]) DeleteRange(start K, end K) WriteStatus {
+	return t.storage.Write(NewWriteBatch[K, V]().DeleteRange(t.family, start, end))
+}
+
+func (t *Writer[K, V]) Merge(key K, operand V) WriteStatus {
+	return t.storage.Write(NewWriteBatch[K, V]().Merge(t.family, key, operand))
 }
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+)
+
+// BytesKey is an opaque byte string key, storages order it by their
+// comparator, see OrderOf. Composite keys are built with EncodeTuple.
+//
+// Note: bytes are held in a string to keep key comparable
+type BytesKey struct {
+	K string
+}
+
+func NewBytesKey(b []byte) BytesKey {
+	return BytesKey{K: string(b)}
+}
+
+func (t BytesKey) SizeOf() uintptr {
+	return unsafe.Sizeof(t) + uintptr(len(t.K))
+}
+
+// Bytes returns a copy of key bytes
+func (t BytesKey) Bytes() []byte {
+	return []byte(t.K)
+}
+
+// Less orders keys bytewise, regardless of comparator of any storage
+func (t BytesKey) Less(other any) bool {
+	otherBytes, ok := other.(BytesKey)
+	if !ok {
+		return false
+	}
+	return t.K < otherBytes.K
+}
+
+func (t BytesKey) String() string {
+	return fmt.Sprintf("%x", t.K)
+}
+
+// Comparator defines order of BytesKey keys
+type Comparator interface {
+	// Name identifies comparator, it's recorded in manifest so it must
+	// never change for a comparator with same order
+	Name() string
+
+	// Compare returns -1, 0 or +1. It must return 0 only for identical
+	// byte strings, keys are matched by equality elsewhere. a & b share
+	// memory of keys, they must not be modified or retained
+	Compare(a, b []byte) int
+}
+
+type bytewiseComparator struct{}
+
+func (bytewiseComparator) Name() string { return "parrot.bytewise" }
+
+func (bytewiseComparator) Compare(a, b []byte) int { return bytes.Compare(a, b) }
+
+type reverseBytewiseComparator struct{}
+
+func (reverseBytewiseComparator) Name() string { return "parrot.reverse-bytewise" }
+
+func (reverseBytewiseComparator) Compare(a, b []byte) int { return bytes.Compare(b, a) }
+
+var (
+	// BytewiseComparator orders keys lexicographically, it's the default one
+	BytewiseComparator Comparator = bytewiseComparator{}
+
+	// ReverseBytewiseComparator orders keys in reverse lexicographic order
+	ReverseBytewiseComparator Comparator = reverseBytewiseComparator{}
+)
+
+var (
+	comparatorsMu sync.Mutex
+	comparators   = map[string]Comparator{
+		BytewiseComparator.Name():        BytewiseComparator,
+		ReverseBytewiseComparator.Name(): ReverseBytewiseComparator,
+	}
+)
+
+// RegisterComparator makes c available to LookupComparator under its name
+func RegisterComparator(c Comparator) error {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+
+	if _, ok := comparators[c.Name()]; ok {
+		return errors.RaiseComparatorErr("%q already registered", c.Name())
+	}
+	comparators[c.Name()] = c
+	return nil
+}
+
+// LookupComparator returns comparator registered under name
+func LookupComparator(name string) (Comparator, error) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+
+	c, ok := comparators[name]
+	if !ok {
+		return nil, errors.RaiseComparatorErr("%q not registered", name)
+	}
+	return c, nil
+}
+
+// Order reports whether key a sorts before key b. Components of a storage
+// order keys by the same Order, see OrderOf
+type Order[K Key] func(a, b K) bool
+
+// OrderOf returns order of keys of type K: BytesKey keys are ordered by c,
+// bytewise if c is nil, other keys by their Less
+func OrderOf[K Key](c Comparator) Order[K] {
+	if _, ok := any(*new(K)).(BytesKey); !ok || c == nil || c == BytewiseComparator {
+		return func(a, b K) bool { return a.Less(b) }
+	}
+	return func(a, b K) bool {
+		return c.Compare(view(any(a).(BytesKey).K), view(any(b).(BytesKey).K)) < 0
+	}
+}
+
+// view returns bytes of s without copying them
+func view(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
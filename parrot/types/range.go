@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package types
+
+// KeyRange is a half open range of keys [Start, End), a missing bound
+// leaves that side unbounded
+type KeyRange[K Key] struct {
+	Start    K
+	End      K
+	HasStart bool
+	HasEnd   bool
+
+	// time range tombstone was written at in unix nanoseconds, it deletes
+	// keys for reads of that time onwards. 0 for ranges which aren't
+	// tombstones & tombstones written before they were timed, those delete
+	// keys for reads of any time
+	Time int64
+}
+
+// Between returns range [start, end)
+func Between[K Key](start, end K) KeyRange[K] {
+	return KeyRange[K]{Start: start, End: end, HasStart: true, HasEnd: true}
+}
+
+// From returns range of keys starting at start
+func From[K Key](start K) KeyRange[K] {
+	return KeyRange[K]{Start: start, HasStart: true}
+}
+
+// AllKeys returns unbounded range
+func AllKeys[K Key]() KeyRange[K] {
+	return KeyRange[K]{}
+}
+
+// Prefix returns range of keys starting with prefix under bytewise order,
+// e.g. all tuples sharing leading elements
+func Prefix(prefix BytesKey) KeyRange[BytesKey] {
+	r := From(prefix)
+
+	// smallest key greater than every key with prefix: drop trailing 0xff
+	// & increment last byte, none exists if prefix is all 0xff
+	end := []byte(prefix.K)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) > 0 {
+		end[len(end)-1]++
+		r.End, r.HasEnd = NewBytesKey(end), true
+	}
+	return r
+}
+
+// Before reports whether key sorts before start of range under order less
+func (r KeyRange[K]) Before(less Order[K], key K) bool {
+	return r.HasStart && less(key, r.Start)
+}
+
+// After reports whether key sorts at or after end of range under order less
+func (r KeyRange[K]) After(less Order[K], key K) bool {
+	return r.HasEnd && !less(key, r.End)
+}
+
+// Contains reports whether key falls in range under order less
+func (r KeyRange[K]) Contains(less Order[K], key K) bool {
+	return !r.Before(less, key) && !r.After(less, key)
+}
+
+// Covers reports whether key falls in any of ranges under order less
+func Covers[K Key](less Order[K], ranges []KeyRange[K], key K) bool {
+	for _, r := range ranges {
+		if r.Contains(less, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversAt reports whether key falls in any of ranges written at or before
+// ts, see KeyRange.Time
+func CoversAt[K Key](less Order[K], ranges []KeyRange[K], key K, ts int64) bool {
+	for _, r := range ranges {
+		if r.Time <= ts && r.Contains(less, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps reports whether range holds any key of [first, last] under
+// order less
+func (r KeyRange[K]) Overlaps(less Order[K], first, last K) bool {
+	return !r.After(less, first) && !r.Before(less, last)
+}
+
+// Intersect returns range of keys falling in both r & o under order less,
+// ok is false if no key does
+func (r KeyRange[K]) Intersect(less Order[K], o KeyRange[K]) (res KeyRange[K], ok bool) {
+	res = r
+	if o.HasStart && (!res.HasStart || less(res.Start, o.Start)) {
+		res.Start, res.HasStart = o.Start, true
+	}
+	if o.HasEnd && (!res.HasEnd || less(o.End, res.End)) {
+		res.End, res.HasEnd = o.End, true
+	}
+	if res.HasStart && res.HasEnd && !less(res.Start, res.End) {
+		return res, false
+	}
+	return res, true
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+)
+
+// tuple element tags, tag order defines order between element types
+const (
+	tupleBytes  byte = 0x01
+	tupleString byte = 0x02
+	tupleInt    byte = 0x03
+	tupleTime   byte = 0x04
+)
+
+// EncodeTuple encodes elements into a key whose bytewise order matches
+// element-wise order of tuples, e.g. (tenant, timestamp, id).
+// Supported elements are int, int32, int64, string, []byte & time.Time.
+//
+// Encoding of a tuple is a prefix of encoding of every tuple extending
+// it, see Prefix.
+//
+//   - ints & times are tagged, sign flipped 8 byte big endian values
+//   - strings & bytes are tagged, 0x00 is escaped as 0x00 0xff & value is
+//     terminated by 0x00 0x01
+func EncodeTuple(elems ...any) (BytesKey, error) {
+	var buf []byte
+	for _, elem := range elems {
+		switch v := elem.(type) {
+		case int:
+			buf = appendInt(buf, tupleInt, int64(v))
+		case int32:
+			buf = appendInt(buf, tupleInt, int64(v))
+		case int64:
+			buf = appendInt(buf, tupleInt, v)
+		case time.Time:
+			buf = appendInt(buf, tupleTime, v.UnixNano())
+		case string:
+			buf = appendBytes(buf, tupleString, []byte(v))
+		case []byte:
+			buf = appendBytes(buf, tupleBytes, v)
+		default:
+			return BytesKey{}, errors.RaiseKeyEncodingErr("unsupported tuple element %T", elem)
+		}
+	}
+	return NewBytesKey(buf), nil
+}
+
+// DecodeTuple decodes key built by EncodeTuple. Ints are decoded as int64
+// & times as UTC time.Time.
+func DecodeTuple(key BytesKey) ([]any, error) {
+	buf := []byte(key.K)
+
+	var elems []any
+	for len(buf) > 0 {
+		tag := buf[0]
+		buf = buf[1:]
+
+		switch tag {
+		case tupleInt, tupleTime:
+			if len(buf) < 8 {
+				return nil, errors.RaiseKeyEncodingErr("truncated tuple element at %d", len(key.K)-len(buf))
+			}
+			v := int64(binary.BigEndian.Uint64(buf) ^ (1 << 63))
+			buf = buf[8:]
+			if tag == tupleTime {
+				elems = append(elems, time.Unix(0, v).UTC())
+			} else {
+				elems = append(elems, v)
+			}
+		case tupleString, tupleBytes:
+			v, rest, ok := readBytes(buf)
+			if !ok {
+				return nil, errors.RaiseKeyEncodingErr("malformed tuple element at %d", len(key.K)-len(buf))
+			}
+			buf = rest
+			if tag == tupleString {
+				elems = append(elems, string(v))
+			} else {
+				elems = append(elems, v)
+			}
+		default:
+			return nil, errors.RaiseKeyEncodingErr("unknown tuple tag 0x%02x", tag)
+		}
+	}
+	return elems, nil
+}
+
+// MustEncodeTuple is like EncodeTuple but panics on unsupported elements
+func MustEncodeTuple(elems ...any) BytesKey {
+	key, err := EncodeTuple(elems...)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func appendInt(buf []byte, tag byte, v int64) []byte {
+	buf = append(buf, tag)
+	// flipping sign bit orders negative values before positive ones
+	return binary.BigEndian.AppendUint64(buf, uint64(v)^(1<<63))
+}
+
+func appendBytes(buf []byte, tag byte, v []byte) []byte {
+	buf = append(buf, tag)
+	for _, b := range v {
+		buf = append(buf, b)
+		if b == 0x00 {
+			buf = append(buf, 0xff)
+		}
+	}
+	return append(buf, 0x00, 0x01)
+}
+
+// readBytes unescapes string or bytes element, it returns false if element
+// is unterminated or badly escaped
+func readBytes(buf []byte) ([]byte, []byte, bool) {
+	v := []byte{}
+	for {
+		i := bytes.IndexByte(buf, 0x00)
+		if i < 0 || i+1 >= len(buf) {
+			return nil, nil, false
+		}
+		v = append(v, buf[:i]...)
+		switch buf[i+1] {
+		case 0x01:
+			return v, buf[i+2:], true
+		case 0xff:
+			v = append(v, 0x00)
+			buf = buf[i+2:]
+		default:
+			return nil, nil, false
+		}
+	}
+}
@@ -6,7 +6,10 @@
 
 package types
 
-import "unsafe"
+import (
+	"reflect"
+	"unsafe"
+)
 
 // Key types
 type Key interface {
@@ -14,10 +17,28 @@ type Key interface {
 	Less(other any) bool
 }
 
+// Sizer is implemented by keys reporting memory they hold, see SizeOfKey
+type Sizer interface {
+	SizeOf() uintptr
+}
+
+// SizeOfKey estimates memory held by key, keys not implementing Sizer are
+// accounted by their shallow size
+func SizeOfKey[K Key](key K) uintptr {
+	if s, ok := any(key).(Sizer); ok {
+		return s.SizeOf()
+	}
+	return unsafe.Sizeof(key)
+}
+
 type IntKey struct {
 	K int
 }
 
+func (t IntKey) SizeOf() uintptr {
+	return unsafe.Sizeof(t)
+}
+
 func (t IntKey) Less(other any) bool {
 	otherInt, ok := other.(IntKey)
 	if !ok {
@@ -30,6 +51,10 @@ type StringKey struct {
 	K string
 }
 
+func (t StringKey) SizeOf() uintptr {
+	return unsafe.Sizeof(t) + uintptr(len(t.K))
+}
+
 func (t StringKey) Less(other any) bool {
 	otherStr, ok := other.(StringKey)
 	if !ok {
@@ -84,9 +109,113 @@ func (t *StringValue) SizeOf() uintptr {
 	return uintptr(len(t.V))
 }
 
+// Operand is implemented by values which can be stored as pending merge
+// operands, operands are folded by a merge operator on reads & compaction
+type Operand interface {
+	Value
+	MarkOperand()
+	IsOperand() bool
+}
+
+// NewTombstone returns a new tombstone of value type V, values are marked
+// deleted in place so V is expected to be a pointer type
+func NewTombstone[V Value]() V {
+	var v V
+	if t := reflect.TypeOf(&v).Elem(); t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem()).Interface().(V)
+	}
+	v.MarkDeleted()
+	return v
+}
+
+// IsOperand reports whether v is a pending merge operand
+func IsOperand(v Value) bool {
+	op, ok := v.(Operand)
+	return ok && op.IsOperand()
+}
+
+// Int64Value is an int64 counter, supports merge operands
+type Int64Value struct {
+	V int64
+	D bool
+	M bool
+}
+
+func (t *Int64Value) SizeOf() uintptr {
+	return 8
+}
+
+func (t *Int64Value) MarkDeleted() {
+	t.D = true
+}
+
+func (t *Int64Value) IsDeleted() bool {
+	return t.D
+}
+
+func (t *Int64Value) MarkOperand() {
+	t.M = true
+}
+
+func (t *Int64Value) IsOperand() bool {
+	return t.M
+}
+
+// ListValue is an append-only list of opaque items, supports merge operands
+type ListValue struct {
+	V [][]byte
+	D bool
+	M bool
+}
+
+func (t *ListValue) SizeOf() uintptr {
+	var size uintptr
+	for _, item := range t.V {
+		size += uintptr(len(item))
+	}
+	return size
+}
+
+func (t *ListValue) MarkDeleted() {
+	t.D = true
+}
+
+func (t *ListValue) IsDeleted() bool {
+	return t.D
+}
+
+func (t *ListValue) MarkOperand() {
+	t.M = true
+}
+
+func (t *ListValue) IsOperand() bool {
+	return t.M
+}
+
 // Payload
 
 type Payload[K Key, V Value] struct {
 	Key K
 	Val V
+
+	// Ref points to value kept in value log, Val is unset until it's resolved
+	Ref *ValueRef
+
+	// Time entry was written at in unix nanoseconds, 0 for entries written
+	// before versions were kept, they're older than any other version
+	Time int64
+}
+
+// ValueRef locates a value separated from its table into value log
+type ValueRef struct {
+	// id of value log segment
+	Segment int64
+	// offset & size of record within segment
+	Offset int64
+	Size   int64
+}
+
+// SizeOf returns size accounted for a separated value, tables hold only its ref
+func (t ValueRef) SizeOf() uintptr {
+	return unsafe.Sizeof(t)
 }
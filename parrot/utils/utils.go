@@ -7,12 +7,13 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/gob"
+	"bufio"
+	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/metadata"
 	"github.com/nagarajRPoojari/orange/parrot/types"
 )
 
@@ -22,44 +23,200 @@ type IndexPayload[K types.Key, V types.Value] struct {
 	Size   int64
 }
 
+// Encode writes sorted kv & range tombstones as a table, entries & index
+// are encoded by c
+//   - data file: header | entries, see codec.AppendPayload
+//   - index file: header | size prefixed records of uvarint key size, key,
+//     uvarint offset & uvarint size of entry. If table holds range
+//     tombstones, an empty record follows & then a size prefixed record
+//     per range of size prefixed start, size prefixed end & uvarint time
 func Encode[K types.Key, V types.Value](
-	dbFile *os.File,
-	indexFile *os.File,
+	dbFile io.Writer,
+	indexFile io.Writer,
 	kv []types.Payload[K, V],
+	ranges []types.KeyRange[K],
+	c codec.Codec,
 ) error {
-	indexEncoder := gob.NewEncoder(indexFile)
-
+	tw, err := NewTableWriter[K, V](dbFile, indexFile, c)
+	if err != nil {
+		return err
+	}
 	for _, item := range kv {
-		offset, err := dbFile.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return fmt.Errorf("failed to get offset: %w", err)
+		if err := tw.Append(item); err != nil {
+			return err
 		}
+	}
+	if err := tw.AppendRangeTombstones(ranges); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
 
-		var buf bytes.Buffer
-		tmpEncoder := gob.NewEncoder(&buf)
-		if err := tmpEncoder.Encode(item); err != nil {
-			return fmt.Errorf("failed to encode to buffer: %w", err)
-		}
+// TableWriter streams sorted entries into data & index files of a table,
+// format is described at Encode
+type TableWriter[K types.Key, V types.Value] struct {
+	c     codec.Codec
+	db    *bufio.Writer
+	index *bufio.Writer
 
-		n, err := dbFile.Write(buf.Bytes())
-		if err != nil {
-			return fmt.Errorf("failed to write to db file: %w", err)
-		}
+	// offset of next entry in data file
+	offset int64
+
+	// reused encoding buffers
+	entry, key, record []byte
+}
+
+// NewTableWriter writes headers of both files, entries are added by Append
+func NewTableWriter[K types.Key, V types.Value](db io.Writer, index io.Writer, c codec.Codec) (*TableWriter[K, V], error) {
+	t := &TableWriter[K, V]{c: c, db: bufio.NewWriter(db), index: bufio.NewWriter(index)}
+
+	header := codec.AppendHeader(nil, c.ID())
+	if _, err := t.db.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write db header: %w", err)
+	}
+	if _, err := t.index.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write index header: %w", err)
+	}
+	t.offset = int64(len(header))
+	return t, nil
+}
+
+// Append writes entry, entries must be appended in key order
+func (t *TableWriter[K, V]) Append(item types.Payload[K, V]) error {
+	var err error
+	t.entry, err = codec.AppendPayload(t.c, t.entry[:0], item)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	if _, err := t.db.Write(t.entry); err != nil {
+		return fmt.Errorf("failed to write to db file: %w", err)
+	}
+
+	t.key, err = t.c.AppendKey(t.key[:0], item.Key)
+	if err != nil {
+		return fmt.Errorf("failed to encode index key: %w", err)
+	}
+	t.record = binary.AppendUvarint(t.record[:0], uint64(len(t.key)))
+	t.record = append(t.record, t.key...)
+	t.record = binary.AppendUvarint(t.record, uint64(t.offset))
+	t.record = binary.AppendUvarint(t.record, uint64(len(t.entry)))
+	if _, err := t.index.Write(codec.AppendFrame(nil, t.record)); err != nil {
+		return fmt.Errorf("failed to write index payload: %w", err)
+	}
 
-		indexPayload := IndexPayload[K, V]{
-			Key:    item.Key,
-			Offset: offset,
-			Size:   int64(n),
+	t.offset += int64(len(t.entry))
+	return nil
+}
+
+// AppendRangeTombstones writes ranges to index, it must follow all
+// entries & be called at most once
+func (t *TableWriter[K, V]) AppendRangeTombstones(ranges []types.KeyRange[K]) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+	// empty record ends entries, no index record is empty
+	if _, err := t.index.Write(codec.AppendFrame(nil, nil)); err != nil {
+		return fmt.Errorf("failed to write index payload: %w", err)
+	}
+	for _, r := range ranges {
+		var err error
+		if t.key, err = t.c.AppendKey(t.key[:0], r.Start); err != nil {
+			return fmt.Errorf("failed to encode range tombstone: %w", err)
+		}
+		t.record = codec.AppendFrame(t.record[:0], t.key)
+		if t.key, err = t.c.AppendKey(t.key[:0], r.End); err != nil {
+			return fmt.Errorf("failed to encode range tombstone: %w", err)
 		}
-		if err := indexEncoder.Encode(indexPayload); err != nil {
-			return fmt.Errorf("failed to encode index payload: %w", err)
+		t.record = codec.AppendFrame(t.record, t.key)
+		t.record = binary.AppendUvarint(t.record, uint64(r.Time))
+		if _, err := t.index.Write(codec.AppendFrame(nil, t.record)); err != nil {
+			return fmt.Errorf("failed to write index payload: %w", err)
 		}
 	}
-	if _, err := dbFile.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to reset dbFile seek: %w", err)
+	return nil
+}
+
+// Flush writes buffered entries to underlying files
+func (t *TableWriter[K, V]) Flush() error {
+	if err := t.db.Flush(); err != nil {
+		return fmt.Errorf("failed to flush db file: %w", err)
 	}
-	if _, err := indexFile.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to reset indexFile seek: %w", err)
+	if err := t.index.Flush(); err != nil {
+		return fmt.Errorf("failed to flush index file: %w", err)
 	}
 	return nil
 }
+
+// DecodeIndex decodes index record written by Encode
+func DecodeIndex[K types.Key, V types.Value](c codec.Codec, record []byte) (IndexPayload[K, V], error) {
+	var entry IndexPayload[K, V]
+
+	keySize, n := binary.Uvarint(record)
+	if n <= 0 || uint64(len(record)-n) < keySize {
+		return entry, fmt.Errorf("malformed index record")
+	}
+	record = record[n:]
+	if err := c.DecodeKey(record[:keySize], &entry.Key); err != nil {
+		return entry, err
+	}
+	record = record[keySize:]
+
+	offset, n := binary.Uvarint(record)
+	if n <= 0 {
+		return entry, fmt.Errorf("malformed index record")
+	}
+	size, m := binary.Uvarint(record[n:])
+	if m <= 0 {
+		return entry, fmt.Errorf("malformed index record")
+	}
+	entry.Offset, entry.Size = int64(offset), int64(size)
+	return entry, nil
+}
+
+// DecodeRangeTombstone decodes range tombstone record written by Encode
+func DecodeRangeTombstone[K types.Key](c codec.Codec, record []byte) (types.KeyRange[K], error) {
+	var r types.KeyRange[K]
+
+	start, rest, ok := codec.ReadFrame(record)
+	if !ok {
+		return r, fmt.Errorf("malformed range tombstone")
+	}
+	end, rest, ok := codec.ReadFrame(rest)
+	if !ok {
+		return r, fmt.Errorf("malformed range tombstone")
+	}
+	ts, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return r, fmt.Errorf("malformed range tombstone")
+	}
+
+	var lo, hi K
+	if err := c.DecodeKey(start, &lo); err != nil {
+		return r, err
+	}
+	if err := c.DecodeKey(end, &hi); err != nil {
+		return r, err
+	}
+	r = types.Between(lo, hi)
+	r.Time = int64(ts)
+	return r, nil
+}
+
+// DecodeRangeTombstones decodes ranges kept in manifest by tables written
+// before range tombstones were kept in index files
+func DecodeRangeTombstones[K types.Key](c codec.Codec, tombstones []metadata.RangeTombstone) ([]types.KeyRange[K], error) {
+	ranges := make([]types.KeyRange[K], 0, len(tombstones))
+	for _, tombstone := range tombstones {
+		var start, end K
+		if err := c.DecodeKey(tombstone.Start, &start); err != nil {
+			return nil, err
+		}
+		if err := c.DecodeKey(tombstone.End, &end); err != nil {
+			return nil, err
+		}
+		r := types.Between(start, end)
+		r.Time = tombstone.Time
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
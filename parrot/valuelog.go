@@ -0,0 +1,128 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package storage
+
+import (
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+// collectValueLog runs a value log gc pass on family, it's run by compaction
+// workers after compaction.
+//   - one sealed segment is checked per pass, if its share of dead records
+//     reached ValueLogGCRatio live values are written again, so compaction
+//     eventually drops all refs to it
+//   - sealed segments no table refers to are deleted
+func (t *Storage[K, V]) collectValueLog(fam *Family[K, V]) {
+	if segment, ok := fam.nextValueSegment(); ok {
+		t.rewriteValueSegment(fam, segment)
+	}
+
+	// readers hold mu for the whole read, so no read can be left holding
+	// a ref to a deleted segment
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deleted, err := fam.vlog.Reclaim(func() (map[int64]struct{}, error) {
+		// manifest on disk must not refer to deleted segments either
+		if err := fam.manifest.Sync(); err != nil {
+			return nil, err
+		}
+		var segments map[int64]struct{}
+		fam.manifest.View(func() {
+			segments = fam.manifest.GetLSM().ValueSegments()
+		})
+		return segments, nil
+	})
+	if err != nil {
+		log.Errorf("failed to reclaim value log of family=%s, err=%v", fam.name, err)
+	}
+	if len(deleted) > 0 {
+		log.Infof("reclaimed value log segments %v of family=%s", deleted, fam.name)
+	}
+}
+
+// nextValueSegment returns sealed segment to be checked by next gc pass,
+// segments are checked round robin
+func (t *Family[K, V]) nextValueSegment() (int64, bool) {
+	sealed := t.vlog.Sealed()
+	if len(sealed) == 0 {
+		return 0, false
+	}
+
+	next := sealed[0]
+	for _, segment := range sealed {
+		if segment > t.vlogCursor {
+			next = segment
+			break
+		}
+	}
+	t.vlogCursor = next
+	return next, true
+}
+
+// rewriteValueSegment writes live values of segment again if enough of
+// its records are dead
+func (t *Storage[K, V]) rewriteValueSegment(fam *Family[K, V], segment int64) {
+	type record struct {
+		key K
+		ref types.ValueRef
+	}
+
+	var (
+		live  []record
+		total int
+	)
+	err := fam.vlog.Each(segment, func(c codec.Codec, raw []byte, ref types.ValueRef) bool {
+		total++
+
+		var key K
+		if err := c.DecodeKey(raw, &key); err != nil {
+			log.Errorf("failed to decode value log key of segment=%d, err=%v", segment, err)
+			return false
+		}
+		// a record is live while newest version of its key refers to it
+		if current, ok := fam.store.NewestRef(key); ok && current == ref {
+			live = append(live, record{key: key, ref: ref})
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("failed to scan value log segment=%d, err=%v", segment, err)
+		return
+	}
+
+	if total == 0 || float64(total-len(live)) < t.opts.ValueLogGCRatio*float64(total) {
+		return
+	}
+	for _, rec := range live {
+		t.relocate(fam, rec.key, rec.ref)
+	}
+}
+
+// relocate writes current value of key again if it's still kept at ref,
+// value goes to active segment on next flush
+func (t *Storage[K, V]) relocate(fam *Family[K, V], key K, ref types.ValueRef) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// key might have been written since segment was scanned
+	if current, ok := fam.store.NewestRef(key); !ok || current != ref {
+		return
+	}
+	value, ok := fam.store.Read(key)
+	if !ok {
+		return
+	}
+
+	entry := logEntry[K, V]{Family: fam.name, Key: key, Value: value, Op: memtable.WriteOperation}
+	if err := t.apply([]logEntry[K, V]{entry}, []*Family[K, V]{fam}); err != nil {
+		log.Errorf("failed to relocate value of key=%v, err=%v", key, err)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package vlog
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/codec"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+type Opts struct {
+	// Directory holding segments
+	Dir string
+
+	// Active segment is sealed & a new one started once it grows past
+	// SegmentSize (in bytes)
+	SegmentSize int64
+
+	// Codec of values appended to new segments. Segments record codec
+	// they're written by, like tables
+	Codec codec.Codec
+
+	// VFS segments are kept on, files on disk if nil. Segments are
+	// encrypted if it's keyed, see io.WithKeyring
+	VFS io.VFS
+}
+
+// ValueLog is an append-only log of values separated from tables, tables keep
+// only a types.ValueRef to them. Compaction moves refs around without
+// rewriting values, so large values are written once.
+//
+// Log is split into segments, only the newest one is appended to. A sealed
+// segment is deleted once no table refers to it, see Reclaim.
+//   - segment: header | (uvarint key size | key | uvarint value size | value)...
+type ValueLog struct {
+	opts Opts
+
+	// guards segments & head, reads hold it shared so a segment
+	// isn't closed under them
+	mu       sync.RWMutex
+	segments map[int64]*segment
+	head     *segment
+
+	// writers of refs hold it shared from first append until tables holding
+	// refs are published, Reclaim holds it exclusively
+	session sync.RWMutex
+}
+
+type segment struct {
+	id int64
+	// appends to active segment, nil once it's sealed
+	w *io.FileWriter
+	r *io.FileReader
+
+	codec codec.Codec
+	// size of contents, as read by r
+	size int64
+}
+
+// Open loads segments at opts.Dir, a new segment is started on first append
+func Open(opts Opts) (*ValueLog, error) {
+	if opts.Codec == nil {
+		opts.Codec = codec.Gob
+	}
+	if opts.VFS == nil {
+		opts.VFS = io.OS
+	}
+	if err := opts.VFS.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, errors.RaiseValueLogErr("failed to create dir=%s, err=%v", opts.Dir, err)
+	}
+
+	t := &ValueLog{opts: opts, segments: map[int64]*segment{}}
+
+	files, err := io.Glob(opts.VFS, filepath.Join(opts.Dir, "vlog-*.log"))
+	if err != nil {
+		return nil, errors.RaiseValueLogErr("failed to list segments, err=%v", err)
+	}
+	for _, file := range files {
+		var id int64
+		if _, err := fmt.Sscanf(filepath.Base(file), "vlog-%d.log", &id); err != nil {
+			continue
+		}
+		seg, err := openSegment(io.Manager(opts.VFS), id, file)
+		if err != nil {
+			return nil, err
+		}
+		if seg == nil {
+			// crashed before header was written, nothing can refer to it
+			if err := opts.VFS.Remove(file); err != nil {
+				log.Errorf("failed to delete empty value log segment, err=%v", err)
+			}
+			continue
+		}
+		t.segments[id] = seg
+	}
+	return t, nil
+}
+
+// openSegment opens sealed segment for reads, it returns nil if
+// segment has no header
+func openSegment(fm *io.FileManager, id int64, path string) (*segment, error) {
+	stat, err := fm.FS().Stat(path)
+	if err != nil {
+		return nil, errors.RaiseValueLogErr("failed to stat segment=%d, err=%v", id, err)
+	}
+	if stat.Size() == 0 {
+		return nil, nil
+	}
+	r, err := fm.OpenForTail(path)
+	if err != nil {
+		return nil, errors.RaiseValueLogErr("failed to open segment=%d, err=%v", id, err)
+	}
+	if r.Size() < codec.HeaderSize {
+		r.Close()
+		return nil, nil
+	}
+
+	header, err := r.ReadRange(0, codec.HeaderSize)
+	if err != nil {
+		r.Close()
+		return nil, errors.RaiseValueLogErr("failed to read header of segment=%d, err=%v", id, err)
+	}
+	codecId, _, ok := codec.ParseHeader(header.Bytes())
+	header.Release()
+	if !ok {
+		r.Close()
+		return nil, errors.RaiseValueLogErr("segment=%d has no header", id)
+	}
+	c, err := codec.Lookup(codecId)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &segment{id: id, r: r, codec: c, size: r.Size()}, nil
+}
+
+func (t *ValueLog) path(id int64) string {
+	return filepath.Join(t.opts.Dir, fmt.Sprintf("vlog-%d.log", id))
+}
+
+// Codec returns codec values must be encoded with before Append
+func (t *ValueLog) Codec() codec.Codec {
+	return t.opts.Codec
+}
+
+// Begin starts a session, segments appended to in a session aren't
+// reclaimed until End
+func (t *ValueLog) Begin() {
+	t.session.RLock()
+}
+
+// End ends session started by Begin
+func (t *ValueLog) End() {
+	t.session.RUnlock()
+}
+
+// Append writes encoded key & value to active segment, record is durable
+// only after Sync. Each record is flushed on its own, so it can be read
+// back right away.
+func (t *ValueLog) Append(key []byte, value []byte) (types.ValueRef, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.head == nil || t.head.size >= t.opts.SegmentSize {
+		if err := t.rotate(); err != nil {
+			return types.ValueRef{}, err
+		}
+	}
+
+	record := codec.AppendFrame(nil, key)
+	record = codec.AppendFrame(record, value)
+
+	if _, err := t.head.w.Writer().Write(record); err != nil {
+		return types.ValueRef{}, errors.RaiseValueLogErr("failed to append to segment=%d, err=%v", t.head.id, err)
+	}
+	if err := t.head.w.Flush(); err != nil {
+		return types.ValueRef{}, errors.RaiseValueLogErr("failed to append to segment=%d, err=%v", t.head.id, err)
+	}
+	if err := t.head.r.Refresh(); err != nil {
+		return types.ValueRef{}, errors.RaiseValueLogErr("failed to refresh segment=%d, err=%v", t.head.id, err)
+	}
+	ref := types.ValueRef{Segment: t.head.id, Offset: t.head.size, Size: int64(len(record))}
+	t.head.size += int64(len(record))
+	return ref, nil
+}
+
+// rotate seals active segment & starts a new one
+func (t *ValueLog) rotate() error {
+	next := time.Now().UnixNano()
+	for id := range t.segments {
+		next = max(next, id+1)
+	}
+
+	fm := io.Manager(t.opts.VFS)
+	w, err := fm.OpenForWrite(t.path(next))
+	if err != nil {
+		return errors.RaiseValueLogErr("failed to create segment=%d, err=%v", next, err)
+	}
+	header := codec.AppendHeader(nil, t.opts.Codec.ID())
+	if _, err = w.Writer().Write(header); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		w.Close()
+		return errors.RaiseValueLogErr("failed to write header of segment=%d, err=%v", next, err)
+	}
+	r, err := fm.OpenForTail(t.path(next))
+	if err != nil {
+		w.Close()
+		return errors.RaiseValueLogErr("failed to open segment=%d, err=%v", next, err)
+	}
+
+	if t.head != nil {
+		// sealed segment must be durable, tables may refer to it already
+		if err := t.head.w.Sync(); err != nil {
+			log.Errorf("failed to sync value log segment=%d, err=%v", t.head.id, err)
+		}
+		t.head.w.Close()
+		t.head.w = nil
+	}
+	t.head = &segment{id: next, w: w, r: r, codec: t.opts.Codec, size: r.Size()}
+	t.segments[next] = t.head
+	return nil
+}
+
+// Sync makes appended records durable, it must be called before
+// tables referring to them are published
+func (t *ValueLog) Sync() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.head == nil {
+		return nil
+	}
+	if err := t.head.w.Sync(); err != nil {
+		return errors.RaiseValueLogErr("failed to sync segment=%d, err=%v", t.head.id, err)
+	}
+	return nil
+}
+
+// Read returns value pointed by ref along with codec it's encoded by
+func (t *ValueLog) Read(ref types.ValueRef) ([]byte, codec.Codec, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	seg, ok := t.segments[ref.Segment]
+	if !ok {
+		return nil, nil, errors.RaiseValueLogErr("segment=%d not found", ref.Segment)
+	}
+	if ref.Offset+ref.Size > seg.size {
+		return nil, nil, errors.RaiseValueLogErr("ref=%+v out of segment bounds", ref)
+	}
+
+	record, err := seg.r.ReadRange(ref.Offset, int(ref.Size))
+	if err != nil {
+		return nil, nil, errors.RaiseValueLogErr("failed to read ref=%+v, err=%v", ref, err)
+	}
+	defer record.Release()
+	_, value, ok := parseRecord(record.Bytes())
+	if !ok {
+		return nil, nil, errors.RaiseValueLogErr("corrupt record at ref=%+v", ref)
+	}
+	return bytes.Clone(value), seg.codec, nil
+}
+
+// parseRecord splits record into key & value
+func parseRecord(record []byte) (key []byte, value []byte, ok bool) {
+	key, rest, ok := codec.ReadFrame(record)
+	if !ok {
+		return nil, nil, false
+	}
+	value, rest, ok = codec.ReadFrame(rest)
+	if !ok || len(rest) != 0 {
+		return nil, nil, false
+	}
+	return key, value, true
+}
+
+// Each calls fn for records of segment in order until fn returns false,
+// keys are decoded by codec passed to fn. A torn tail is skipped.
+func (t *ValueLog) Each(id int64, fn func(c codec.Codec, key []byte, ref types.ValueRef) bool) error {
+	t.mu.RLock()
+	seg, ok := t.segments[id]
+	t.mu.RUnlock()
+	if !ok {
+		return errors.RaiseValueLogErr("segment=%d not found", id)
+	}
+
+	data, err := io.Manager(t.opts.VFS).ReadFile(t.path(id))
+	if err != nil {
+		return errors.RaiseValueLogErr("failed to read segment=%d, err=%v", id, err)
+	}
+
+	offset := int64(codec.HeaderSize)
+	data = data[min(len(data), codec.HeaderSize):]
+	for len(data) > 0 {
+		key, rest, ok := codec.ReadFrame(data)
+		if !ok {
+			return nil
+		}
+		_, rest, ok = codec.ReadFrame(rest)
+		if !ok {
+			return nil
+		}
+
+		size := int64(len(data) - len(rest))
+		if !fn(seg.codec, key, types.ValueRef{Segment: id, Offset: offset, Size: size}) {
+			return nil
+		}
+		offset += size
+		data = rest
+	}
+	return nil
+}
+
+// Sealed returns ids of segments no longer appended to, oldest first
+func (t *ValueLog) Sealed() []int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	ids := make([]int64, 0, len(t.segments))
+	for id := range t.segments {
+		if t.head == nil || id != t.head.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// Reclaim deletes sealed segments not returned by referenced, which must
+// list segments referred by durable tables. It runs exclusively of sessions
+// & returns ids of deleted segments.
+func (t *ValueLog) Reclaim(referenced func() (map[int64]struct{}, error)) ([]int64, error) {
+	t.session.Lock()
+	defer t.session.Unlock()
+
+	live, err := referenced()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []int64
+	for _, id := range t.Sealed() {
+		if _, ok := live[id]; ok {
+			continue
+		}
+
+		t.mu.Lock()
+		seg := t.segments[id]
+		delete(t.segments, id)
+		t.mu.Unlock()
+
+		seg.r.Close()
+		if err := t.opts.VFS.Remove(t.path(id)); err != nil {
+			return deleted, errors.RaiseValueLogErr("failed to delete segment=%d, err=%v", id, err)
+		}
+		deleted = append(deleted, id)
+	}
+	return deleted, nil
+}
+
+// Close syncs active segment & closes all segments
+func (t *ValueLog) Close() error {
+	err := t.Sync()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, seg := range t.segments {
+		if seg.w != nil {
+			seg.w.Close()
+		}
+		seg.r.Close()
+	}
+	t.segments = map[int64]*segment{}
+	t.head = nil
+	return err
+}
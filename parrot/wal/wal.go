@@ -9,11 +9,13 @@ package wal
 import (
 	"bufio"
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"io"
 	"time"
 
+	"github.com/nagarajRPoojari/orange/parrot/codec"
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 
 	"sync"
@@ -26,6 +28,43 @@ import (
 type Event interface {
 }
 
+// Codec encodes log events, its id is recorded in log header
+type Codec[E Event] interface {
+	ID() byte
+	Encode(buf []byte, event E) ([]byte, error)
+	Decode(data []byte) (E, error)
+}
+
+// Resolver returns codec of a log by id recorded in its header
+type Resolver[E Event] func(id byte) (Codec[E], error)
+
+// GobCodec encodes each event as a self describing gob message
+type GobCodec[E Event] struct{}
+
+func (GobCodec[E]) ID() byte { return codec.GobID }
+
+func (GobCodec[E]) Encode(buf []byte, event E) ([]byte, error) {
+	w := bytes.NewBuffer(buf)
+	if err := gob.NewEncoder(w).Encode(event); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func (GobCodec[E]) Decode(data []byte) (E, error) {
+	var event E
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&event)
+	return event, err
+}
+
+// GobResolver resolves gob codec, the only codec of logs written by NewWAL
+func GobResolver[E Event](id byte) (Codec[E], error) {
+	if id != codec.GobID {
+		return nil, customerr.RaiseCodecErr("unknown log codec id %d", id)
+	}
+	return GobCodec[E]{}, nil
+}
+
 type WALOpts struct {
 	// Path to the WAL file on disk
 	Path string
@@ -37,6 +76,9 @@ type WALOpts struct {
 
 	// writer buffer size
 	WriterBufferSize int
+
+	// VFS log is kept on, files on disk if nil
+	VFS fio.VFS
 }
 
 // WAL implements a Write-Ahead Log to ensure durability of events.
@@ -48,30 +90,59 @@ type WAL[E Event] struct {
 	// Channel used to signal WAL shutdown
 	closeCh chan struct{}
 
+	// Channel of Sync requests, each is answered on its channel
+	syncCh chan chan error
+
 	// WaitGroup to wait for all background goroutines to finish during shutdown
 	wg sync.WaitGroup
 
 	fileWriter     *fio.FileWriter
-	encoder        *gob.Encoder
+	codec          Codec[E]
 	bufferedWriter *bufio.Writer
 
+	// scratch buffer reused across records
+	buf []byte
+
 	mu sync.Mutex
 
 	//opts
 	opts *WALOpts
 }
 
-// NewWAL returns new WAL instance
+// NewWAL returns new WAL instance encoding events with gob
 func NewWAL[E Event](opts WALOpts) (*WAL[E], error) {
-	fm := fio.GetFileManager()
-	fw := fm.OpenForAppend(opts.Path)
+	return NewWALWithCodec[E](opts, GobCodec[E]{})
+}
+
+// NewWALWithCodec returns new WAL instance encoding events with c.
+// An existing log is appended to only if it's written by same codec.
+func NewWALWithCodec[E Event](opts WALOpts, c Codec[E]) (*WAL[E], error) {
+	fm := fio.Manager(opts.VFS)
+	fw, err := fm.OpenForAppend(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	empty, err := fw.Empty()
+	if err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	bw := bufio.NewWriterSize(fw.Writer(), opts.WriterBufferSize)
+	if empty {
+		bw.Write(codec.AppendHeader(nil, c.ID()))
+	} else if err := checkHeader(fm, opts.Path, c); err != nil {
+		fw.Close()
+		return nil, err
+	}
 
-	bw := bufio.NewWriterSize(fw.GetFile(), opts.WriterBufferSize)
 	w := &WAL[E]{
 		fileWriter:     fw,
 		eventCh:        make(chan E, opts.EventChSize),
 		closeCh:        make(chan struct{}),
-		encoder:        gob.NewEncoder(bw),
+		syncCh:         make(chan chan error),
+		codec:          c,
 		bufferedWriter: bw,
 		opts:           &opts,
 	}
@@ -82,21 +153,204 @@ func NewWAL[E Event](opts WALOpts) (*WAL[E], error) {
 	return w, nil
 }
 
-// Replay loads logs from give path & rebuilds event list
-func Replay[E Event](path string) ([]E, error) {
-	fm := fio.GetFileManager()
+// checkHeader verifies that existing log at path is written by c
+func checkHeader[E Event](fm *fio.FileManager, path string, c Codec[E]) error {
+	data, err := fm.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(data) < codec.HeaderSize {
+		return customerr.RaiseCodecErr("unreadable log header, file=%s", path)
+	}
+	if id, _, ok := codec.ParseHeader(data[:codec.HeaderSize]); !ok || id != c.ID() {
+		return customerr.RaiseCodecErr("log written by another codec, file=%s", path)
+	}
+	return nil
+}
+
+// Replay loads logs from give path of fs & rebuilds event list, log must be
+// written by gob codec. Nil fs is files on disk.
+func Replay[E Event](fs fio.VFS, path string) ([]E, error) {
+	return ReplayWithCodec(fs, path, GobResolver[E])
+}
+
+// ReplayWithCodec loads logs from given path of fs decoding events with
+// codec named by log header
+func ReplayWithCodec[E Event](fs fio.VFS, path string, resolve Resolver[E]) ([]E, error) {
+	records, err := Inspect(fs, path, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]E, 0, len(records))
+	for _, record := range records {
+		if record.Err != nil {
+			// nothing after a bad record can be trusted
+			log.Errorf("failed to decode, file=%s, err=%v", path, record.Err)
+			break
+		}
+		events = append(events, record.Event)
+	}
+	return events, nil
+}
+
+// Record is a log record as found on disk, see Inspect
+type Record[E Event] struct {
+	// byte offset of record in log, -1 for logs written before logs had headers
+	Offset int64
+	Event  E
+
+	// set if record failed to decode, Event is zero then
+	Err error
+}
+
+// Inspect decodes every record of log at path of fs. Unlike ReplayWithCodec
+// it doesn't stop at a record failing to decode, only a torn record (the
+// last one reported) ends it.
+func Inspect[E Event](fs fio.VFS, path string, resolve Resolver[E]) ([]Record[E], error) {
+	fm := fio.Manager(fs)
 	if !fm.Exists(path) {
-		return nil, customerr.FileNotFounderr("file=%v", fm)
+		return nil, customerr.FileNotFounderr("file=%v", path)
+	}
+
+	// log files keep growing & might be replayed more than once, so they are
+	// read fresh instead of through shared (cached) mmap readers
+	data, err := fm.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	id, body, ok := codec.ParseHeader(data)
+	if !ok {
+		return inspectLegacy[E](data), nil
+	}
+	c, err := resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record[E]
+	for len(body) > 0 {
+		offset := int64(len(data) - len(body))
+		raw, rest, ok := codec.ReadFrame(body)
+		if !ok {
+			// a torn record at the tail is expected after crash
+			records = append(records, Record[E]{Offset: offset, Err: customerr.RaiseCodecErr("torn log record")})
+			break
+		}
+		body = rest
+
+		event, err := c.Decode(raw)
+		records = append(records, Record[E]{Offset: offset, Event: event, Err: err})
+	}
+	return records, nil
+}
+
+// Tail reads records of a log as they're appended to it, see OpenTail
+type Tail[E Event] struct {
+	fs      fio.VFS
+	path    string
+	resolve Resolver[E]
+
+	// opened once log has a header
+	r     *fio.FileReader
+	codec Codec[E]
+	// offset of first record not read yet
+	offset int64
+}
+
+// OpenTail returns tail of log at path of fs, starting at its first record.
+// Log might still be empty, it's opened by first Read finding its header.
+func OpenTail[E Event](fs fio.VFS, path string, resolve Resolver[E]) (*Tail[E], error) {
+	if !fio.Manager(fs).Exists(path) {
+		return nil, customerr.FileNotFounderr("file=%v", path)
+	}
+	return &Tail[E]{fs: fs, path: path, resolve: resolve}, nil
+}
+
+// Read decodes records appended since last Read, only bytes following them
+// are read. A record still being written out is returned once it's whole.
+func (t *Tail[E]) Read() ([]Record[E], error) {
+	if t.r == nil {
+		if err := t.open(); err != nil || t.r == nil {
+			return nil, err
+		}
+	} else if err := t.r.Refresh(); err != nil {
+		return nil, err
 	}
 
-	fr, err := fm.OpenForRead(path)
+	size := t.r.Size()
+	if size == t.offset {
+		return nil, nil
+	}
+	buf, err := t.r.ReadRange(t.offset, int(size-t.offset))
 	if err != nil {
 		return nil, err
 	}
-	defer fr.Close()
+	defer buf.Release()
 
-	var events []E
-	decoder := gob.NewDecoder(bytes.NewReader(fr.GetPayload()))
+	var records []Record[E]
+	body := buf.Bytes()
+	for len(body) > 0 {
+		raw, rest, ok := codec.ReadFrame(body)
+		if !ok {
+			break
+		}
+		event, err := t.codec.Decode(raw)
+		records = append(records, Record[E]{Offset: t.offset, Event: event, Err: err})
+		t.offset += int64(len(body) - len(rest))
+		body = rest
+	}
+	return records, nil
+}
+
+// open opens log once its header is written out, r is left nil until then
+func (t *Tail[E]) open() error {
+	fm := fio.Manager(t.fs)
+	if info, err := t.fs.Stat(t.path); err != nil || info.Size() == 0 {
+		return err
+	}
+	r, err := fm.OpenForTail(t.path)
+	if err != nil {
+		return err
+	}
+	if r.Size() < codec.HeaderSize {
+		r.Close()
+		return nil
+	}
+
+	header, err := r.ReadRange(0, codec.HeaderSize)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	id, _, ok := codec.ParseHeader(header.Bytes())
+	header.Release()
+	if !ok {
+		r.Close()
+		return customerr.RaiseCodecErr("log has no header, file=%s", t.path)
+	}
+	if t.codec, err = t.resolve(id); err != nil {
+		r.Close()
+		return err
+	}
+	t.r, t.offset = r, codec.HeaderSize
+	return nil
+}
+
+// Close closes log read
+func (t *Tail[E]) Close() {
+	if t.r != nil {
+		t.r.Close()
+	}
+}
+
+// inspectLegacy decodes logs written as a single gob stream, before
+// logs had headers. Stream can't be resynced, so it ends at first bad record.
+func inspectLegacy[E Event](data []byte) []Record[E] {
+	var records []Record[E]
+	decoder := gob.NewDecoder(bytes.NewReader(data))
 
 	for {
 		var entry E
@@ -105,12 +359,13 @@ func Replay[E Event](path string) ([]E, error) {
 			break
 		}
 		if err != nil {
-			log.Errorf("failed to decode, err=%v", err)
+			records = append(records, Record[E]{Offset: -1, Err: err})
+			break
 		}
-		events = append(events, entry)
+		records = append(records, Record[E]{Offset: -1, Event: entry})
 	}
 
-	return events, nil
+	return records
 }
 
 func (w *WAL[E]) Append(entry E) {
@@ -118,6 +373,8 @@ func (w *WAL[E]) Append(entry E) {
 }
 
 func (t *WAL[E]) run() {
+	defer t.wg.Done()
+
 	flushTicker := time.NewTicker(t.opts.TimeInterval)
 	defer flushTicker.Stop()
 
@@ -130,30 +387,62 @@ func (t *WAL[E]) run() {
 			if err := t.flush(); err != nil {
 			}
 
+		case done := <-t.syncCh:
+			done <- t.sync()
+
 		case <-t.closeCh:
-			t.drain()
-			t.flush() // final flush before exiting
+			// final sync before exiting, logs are durable once closed
+			if err := t.sync(); err != nil {
+				log.Errorf("failed to sync log, file=%s, err=%v", t.opts.Path, err)
+			}
+			t.fileWriter.Close()
 			return
 		}
 	}
 }
 
-// write writes a single entry to the WAL[E].
-func (t *WAL[E]) write(entry Event) {
+// write writes a single entry to the WAL[E], framed by its size
+func (t *WAL[E]) write(entry E) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if err := t.encoder.Encode(entry); err != nil {
+	record, err := t.codec.Encode(t.buf[:0], entry)
+	if err != nil {
 		log.Fatalf("failed to encode log event %v", err)
 	}
+	t.buf = record
 
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(record)))
+	t.bufferedWriter.Write(size[:n])
+	if _, err := t.bufferedWriter.Write(record); err != nil {
+		log.Fatalf("failed to write log event %v", err)
+	}
 }
 
 func (t *WAL[E]) flush() error {
 	if err := t.bufferedWriter.Flush(); err != nil {
 		return err
 	}
-	return nil
+	// encrypted logs are sealed in frames of what's flushed
+	return t.fileWriter.Flush()
+}
+
+// sync writes out & fsyncs events appended so far
+func (t *WAL[E]) sync() error {
+	t.drain()
+	if err := t.flush(); err != nil {
+		return err
+	}
+	return t.fileWriter.Sync()
+}
+
+// Sync returns once events appended before it are durable. Otherwise
+// they're written out every TimeInterval & made durable on Close.
+func (t *WAL[E]) Sync() error {
+	done := make(chan error, 1)
+	t.syncCh <- done
+	return <-done
 }
 
 func (t *WAL[E]) Truncate() {
@@ -161,7 +450,7 @@ func (t *WAL[E]) Truncate() {
 }
 
 func (t *WAL[E]) Delete() {
-	fm := fio.GetFileManager()
+	fm := fio.Manager(t.opts.VFS)
 	fm.Delete(t.opts.Path)
 }
 
@@ -171,7 +460,6 @@ func (t *WAL[E]) drain() {
 		case event := <-t.eventCh:
 			t.write(event)
 		default:
-			t.fileWriter.Close()
 			return
 		}
 	}
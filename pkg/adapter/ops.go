@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 
 	"github.com/nagarajRPoojari/orange/pkg/oql"
 	pb "github.com/nagarajRPoojari/orange/pkg/proto/ops"
@@ -41,20 +42,32 @@ type InsertOpAdapter struct {
 }
 
 func (t *InsertOpAdapter) ToProtobuf() *pb.InsertReq {
-	valPb, err := structpb.NewStruct(t.Native.Value)
+	value := t.Native.Value
+	// int64 _IDs are sent as is, Struct numbers are float64
+	id, ok := value["_ID"].(int64)
+	if ok && id != 0 {
+		value = maps.Clone(value)
+		delete(value, "_ID")
+	}
+	valPb, err := structpb.NewStruct(value)
 	if err != nil {
 		log.Fatalf("failed to create structpb.Struct: %v", err)
 	}
 	return &pb.InsertReq{
 		Document: t.Native.Document,
 		Value:    valPb,
+		Id:       id,
 	}
 }
 
 func (t *InsertOpAdapter) ToNative() *oql.InsertOp {
+	value := t.Pb.Value.AsMap()
+	if t.Pb.Id != 0 {
+		value["_ID"] = t.Pb.Id
+	}
 	return &oql.InsertOp{
 		Document: t.Pb.Document,
-		Value:    t.Pb.Value.AsMap(),
+		Value:    value,
 	}
 }
 
@@ -88,6 +101,8 @@ func (t *DeleteOpAdapter) ToProtobuf() *pb.DeleteReq {
 	return &pb.DeleteReq{
 		Document: t.Native.Document,
 		Id:       t.Native.ID,
+		EndId:    t.Native.EndID,
+		Range:    t.Native.Range,
 	}
 }
 
@@ -95,6 +110,46 @@ func (t *DeleteOpAdapter) ToNative() *oql.DeleteOp {
 	return &oql.DeleteOp{
 		Document: t.Pb.Document,
 		ID:       t.Pb.Id,
+		EndID:    t.Pb.EndId,
+		Range:    t.Pb.Range,
+	}
+}
+
+type AlterOpAdapter struct {
+	Native *oql.AlterOp
+	Pb     *pb.AlterReq
+}
+
+func (t *AlterOpAdapter) ToProtobuf() *pb.AlterReq {
+	var defPb *structpb.Value
+	if t.Native.Default != nil {
+		var err error
+		if defPb, err = structpb.NewValue(t.Native.Default); err != nil {
+			log.Fatalf("failed to create structpb.Value: %v", err)
+		}
+	}
+	return &pb.AlterReq{
+		Document: t.Native.Document,
+		Action:   t.Native.Action,
+		Field:    t.Native.Field,
+		Type:     t.Native.Type,
+		Default:  defPb,
+		To:       t.Native.To,
+	}
+}
+
+func (t *AlterOpAdapter) ToNative() *oql.AlterOp {
+	var def interface{}
+	if t.Pb.Default != nil {
+		def = t.Pb.Default.AsInterface()
+	}
+	return &oql.AlterOp{
+		Document: t.Pb.Document,
+		Action:   t.Pb.Action,
+		Field:    t.Pb.Field,
+		Type:     t.Pb.Type,
+		Default:  def,
+		To:       t.Pb.To,
 	}
 }
 
@@ -51,3 +51,20 @@ func MissingFields(msg string, args ...any) error {
 func TypeCastError(msg string, args ...any) error {
 	return SchemaError(fmt.Sprintf("missing fields: "+msg, args...))
 }
+
+// FieldViolation is returned for a value of a document breaking a
+// constraint of its field
+type FieldViolation struct {
+	// path of field, e.g address.city
+	Path string
+	// constraint broken: required, nullable, type, min, max, maxLength,
+	// pattern or enum
+	Constraint string
+	Value      any
+	// what constraint expects of value, e.g <= 120
+	Want string
+}
+
+func (e FieldViolation) Error() string {
+	return fmt.Sprintf("Schema error: %s violation: %s=%v, want %s", e.Constraint, e.Path, e.Value, e.Want)
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nagarajRPoojari/orange/pkg/internal/errors"
 )
@@ -19,7 +20,7 @@ func NewParser(input string) *Parser {
 }
 
 // Build parses query and builds respective QueryOp
-//   - supports INSERT, CREATE, SELECT queries
+//   - supports INSERT, CREATE, CREATE INDEX, ALTER, SELECT, DELETE queries
 func (t *Parser) Build() (Query, error) {
 	return t.parse()
 
@@ -33,8 +34,12 @@ func (t Parser) parse() (Query, error) {
 		return t.ParseSelectQuery()
 	case strings.HasPrefix(input, string(T_INSERT)):
 		return t.ParseInsertQuery()
+	case regexp.MustCompile(fmt.Sprintf(`^%s\s+%s\b`, T_CREATE, T_INDEX)).MatchString(input):
+		return t.ParseCreateIndexQuery()
 	case strings.HasPrefix(input, string(T_CREATE)):
 		return t.ParseCreateQuery()
+	case strings.HasPrefix(input, string(T_ALTER)):
+		return t.ParseAlterQuery()
 	case strings.HasPrefix(input, string(T_DELETE)):
 		return t.ParseDeleteQuery()
 	default:
@@ -106,6 +111,66 @@ func unmarshallSchema(input JSONString) (Schema, error) {
 	return schema, nil
 }
 
+// ParseCreateIndexQuery parses a CREATE INDEX query and returns a CreateIndexOp.
+//
+// Expected format:
+//
+//	CREATE INDEX <name> ON <document>(<field>)
+//
+// Returns an error if parsing fails.
+func (t *Parser) ParseCreateIndexQuery() (CreateIndexOp, error) {
+	re := regexp.MustCompile(fmt.Sprintf(`(?i)^\s*%s\s+%s\s+(\w+)\s+%s\s+(\w+)\s*\(\s*(\w+)\s*\)\s*;?\s*$`, T_CREATE, T_INDEX, T_ON))
+	match := re.FindStringSubmatch(t.input)
+	if len(match) < 4 {
+		return CreateIndexOp{}, errors.OQLSyntaxError("expected CREATE INDEX <name> ON <document>(<field>)")
+	}
+	return CreateIndexOp{
+		Name:     match[1],
+		Document: match[2],
+		Field:    match[3],
+	}, nil
+}
+
+// ParseAlterQuery parses an ALTER DOCUMENT query and returns an AlterOp.
+//
+// Expected format:
+//
+//	ALTER DOCUMENT <document> ADD FIELD <field> <type> [DEFAULT <value>]
+//	ALTER DOCUMENT <document> DROP FIELD <field>
+//	ALTER DOCUMENT <document> RENAME FIELD <field> TO <new field>
+//
+// Default values are quoted strings or JSON literals, e.g numbers.
+// Returns an error if parsing fails.
+func (t *Parser) ParseAlterQuery() (AlterOp, error) {
+	prefix := fmt.Sprintf(`(?i)^\s*%s\s+%s\s+(\w+)\s+`, T_ALTER, T_DOCUMENT)
+	suffix := `\s*;?\s*$`
+
+	add := regexp.MustCompile(prefix + fmt.Sprintf(`%s\s+%s\s+(\w+)\s+(\w+)(?:\s+%s\s+('[^']*'|[^\s;']+))?`, T_ADD, T_FIELD, T_DEFAULT) + suffix)
+	if match := add.FindStringSubmatch(t.input); match != nil {
+		op := AlterOp{Document: match[1], Action: AlterAdd, Field: match[2], Type: strings.ToUpper(match[3])}
+		if match[4] != "" {
+			value, err := parseLiteral(match[4])
+			if err != nil {
+				return AlterOp{}, errors.OQLSyntaxError("failed to parse default of %s, expected a quoted string or literal", match[2])
+			}
+			op.Default = value
+		}
+		return op, nil
+	}
+
+	drop := regexp.MustCompile(prefix + fmt.Sprintf(`%s\s+%s\s+(\w+)`, T_DROP, T_FIELD) + suffix)
+	if match := drop.FindStringSubmatch(t.input); match != nil {
+		return AlterOp{Document: match[1], Action: AlterDrop, Field: match[2]}, nil
+	}
+
+	rename := regexp.MustCompile(prefix + fmt.Sprintf(`%s\s+%s\s+(\w+)\s+%s\s+(\w+)`, T_RENAME, T_FIELD, T_TO) + suffix)
+	if match := rename.FindStringSubmatch(t.input); match != nil {
+		return AlterOp{Document: match[1], Action: AlterRename, Field: match[2], To: match[3]}, nil
+	}
+
+	return AlterOp{}, errors.OQLSyntaxError("expected ALTER DOCUMENT <document> ADD FIELD <field> <type> [DEFAULT <value>], DROP FIELD <field> or RENAME FIELD <field> TO <new field>")
+}
+
 // ParseInsertQuery parses an INSERT VALUE query and returns an InsertOp.
 //
 // Expected format:
@@ -165,9 +230,13 @@ func unmarshallValue(input JSONString) (Value, error) {
 // Expected format:
 //
 //	SELECT <columns> FROM <document> WITH _ID=<key>
+//	SELECT <columns> FROM <document> WITH _ID=<key> AS OF '<RFC3339 time>'
+//	SELECT <columns> FROM <document> WHERE <field> = <value>
 //
-// Extracts the document name, selected columns, and required _ID filter.
-// Returns an error if parsing fails or if _ID is missing.
+// Extracts the document name, selected columns, required _ID or field
+// filter and optional time document is read as of. Field values are
+// quoted strings or JSON literals, e.g numbers.
+// Returns an error if parsing fails or if both _ID & field are missing.
 func (t *Parser) ParseSelectQuery() (SelectOp, error) {
 	name, err := extractDocumentNameFromSelectQuery(t.input)
 	if err != nil {
@@ -175,20 +244,92 @@ func (t *Parser) ParseSelectQuery() (SelectOp, error) {
 		return null, err
 	}
 
+	field, value, ok, err := extractFieldFilter(t.input)
+	if err != nil {
+		var null SelectOp
+		return null, err
+	}
+	if ok {
+		asOf, err := extractAsOf(t.input)
+		if err != nil {
+			var null SelectOp
+			return null, err
+		}
+		return SelectOp{
+			Document: name,
+			Columns:  extractColumnNames(t.input),
+			AsOf:     asOf,
+			Field:    field,
+			Value:    value,
+		}, nil
+	}
+
 	_id, err := extractID(t.input)
 	if err != nil {
 		var null SelectOp
 		return null, err
 	}
 
+	asOf, err := extractAsOf(t.input)
+	if err != nil {
+		var null SelectOp
+		return null, err
+	}
+
 	cols := extractColumnNames(t.input)
 	return SelectOp{
 		Document: name,
 		Columns:  cols,
 		ID:       _id,
+		AsOf:     asOf,
 	}, nil
 }
 
+// extractFieldFilter extracts field & value of WHERE <field> = <value>,
+// ok is false if input has no such clause or filters on _ID
+func extractFieldFilter(input string) (string, interface{}, bool, error) {
+	re := regexp.MustCompile(fmt.Sprintf(`(?i)\b%s\s+(\w+)\s*=\s*('[^']*'|[^\s;']+)`, T_WHERE))
+	match := re.FindStringSubmatch(input)
+	if len(match) < 3 || match[1] == "_ID" {
+		return "", nil, false, nil
+	}
+
+	value, err := parseLiteral(match[2])
+	if err != nil {
+		return "", nil, false, errors.OQLSyntaxError("failed to parse value of %s, expected a quoted string or literal", match[1])
+	}
+	return match[1], value, true, nil
+}
+
+// parseLiteral parses a single quoted string or a JSON literal
+func parseLiteral(raw string) (interface{}, error) {
+	if strings.HasPrefix(raw, "'") {
+		return raw[1 : len(raw)-1], nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// extractAsOf extracts time of AS OF '<time>' clause, zero if input has none
+func extractAsOf(input string) (time.Time, error) {
+	re := regexp.MustCompile(`(?i)\bAS\s+OF\s+'([^']*)'`)
+	match := re.FindStringSubmatch(input)
+	if len(match) < 2 {
+		if regexp.MustCompile(`(?i)\bAS\s+OF\b`).MatchString(input) {
+			return time.Time{}, errors.OQLSyntaxError("AS OF expects a quoted time")
+		}
+		return time.Time{}, nil
+	}
+	ts, err := time.Parse(time.RFC3339, match[1])
+	if err != nil {
+		return time.Time{}, errors.OQLSyntaxError("failed to parse AS OF time, expected RFC3339")
+	}
+	return ts, nil
+}
+
 func extractID(input string) (int64, error) {
 	re := regexp.MustCompile(`_ID\s*=\s*(\d+)`)
 	match := re.FindStringSubmatch(input)
@@ -227,13 +368,14 @@ func extractDocumentNameFromSelectQuery(input string) (string, error) {
 	return "", errors.OQLSyntaxError("failed to extract document name")
 }
 
-// ParseSelectQuery parses a DELETE query and returns a SelectOp.
+// ParseDeleteQuery parses a DELETE query and returns a DeleteOp.
 //
 // Expected format:
 //
 //	DELETE DOCUMENT FROM <document> WHERE _ID=<key>
+//	DELETE DOCUMENT FROM <document> WHERE _ID BETWEEN <start> AND <end>
 //
-// Returns an error if parsing fails or if _ID is missing.
+// Returns an error if parsing fails, if _ID is missing or if range is empty.
 func (t *Parser) ParseDeleteQuery() (DeleteOp, error) {
 	var null DeleteOp
 	name, err := extractDocumentNameFromDeleteQuery(t.input)
@@ -241,6 +383,18 @@ func (t *Parser) ParseDeleteQuery() (DeleteOp, error) {
 		return null, err
 	}
 
+	if start, end, ok, err := extractIDRange(t.input); ok || err != nil {
+		if err != nil {
+			return null, err
+		}
+		return DeleteOp{
+			Document: name,
+			ID:       start,
+			EndID:    end,
+			Range:    true,
+		}, nil
+	}
+
 	_id, err := extractID(t.input)
 	if err != nil {
 		return null, err
@@ -251,8 +405,30 @@ func (t *Parser) ParseDeleteQuery() (DeleteOp, error) {
 	}, nil
 }
 
+// extractIDRange extracts bounds of _ID BETWEEN <start> AND <end>, ok is
+// false if input has no BETWEEN clause
+func extractIDRange(input string) (int64, int64, bool, error) {
+	re := regexp.MustCompile(`(?i)_ID\s+BETWEEN\s+(\d+)\s+AND\s+(\d+)`)
+	match := re.FindStringSubmatch(input)
+	if len(match) < 3 {
+		return 0, 0, false, nil
+	}
+	start, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, 0, false, errors.OQLSyntaxError("failed to parse _ID range start to int64")
+	}
+	end, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return 0, 0, false, errors.OQLSyntaxError("failed to parse _ID range end to int64")
+	}
+	if start > end {
+		return 0, 0, false, errors.OQLSyntaxError("empty _ID range")
+	}
+	return start, end, true, nil
+}
+
 func extractDocumentNameFromDeleteQuery(input string) (string, error) {
-	re := regexp.MustCompile(`(?i)DELETE\s+DOCUMENT\s+FROM\s+(\w+)\s+WHERE\s+_ID\s*=\s*[\w\d]+`)
+	re := regexp.MustCompile(`(?i)DELETE\s+DOCUMENT\s+FROM\s+(\w+)\s+WHERE\s+_ID\s*(=\s*[\w\d]+|\s+BETWEEN\s+)`)
 	match := re.FindStringSubmatch(input)
 	if len(match) > 1 {
 		return match[1], nil
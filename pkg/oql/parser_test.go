@@ -3,6 +3,7 @@ package oql
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParser_ParseCreateQuery(t *testing.T) {
@@ -145,6 +146,69 @@ func TestParser_ParseSelectQuery(t *testing.T) {
 			want:    SelectOp{},
 			wantErr: true,
 		},
+		{
+			name: "valid select query as of time",
+			fields: fields{
+				input: `SELECT * FROM x WHERE _ID = 5 AS OF '2026-10-01T10:00:00Z'`,
+			},
+			want: SelectOp{
+				Document: "x",
+				Columns:  []string{"*"},
+				ID:       5,
+				AsOf:     time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid select query (malformed AS OF time)",
+			fields: fields{
+				input: `SELECT * FROM x WHERE _ID = 5 AS OF '2026-10-01 10:00'`,
+			},
+			want:    SelectOp{},
+			wantErr: true,
+		},
+		{
+			name: "invalid select query (unquoted AS OF time)",
+			fields: fields{
+				input: `SELECT * FROM x WHERE _ID = 5 AS OF 2026-10-01T10:00:00Z`,
+			},
+			want:    SelectOp{},
+			wantErr: true,
+		},
+		{
+			name: "valid select query by quoted field value",
+			fields: fields{
+				input: `SELECT * FROM users WHERE email = 'Alice@example.com'`,
+			},
+			want: SelectOp{
+				Document: "users",
+				Columns:  []string{"*"},
+				Field:    "email",
+				Value:    "Alice@example.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid select query by numeric field value",
+			fields: fields{
+				input: `SELECT name FROM users WHERE age=30;`,
+			},
+			want: SelectOp{
+				Document: "users",
+				Columns:  []string{"name"},
+				Field:    "age",
+				Value:    float64(30),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid select query (unquoted string field value)",
+			fields: fields{
+				input: `SELECT * FROM users WHERE email = alice`,
+			},
+			want:    SelectOp{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -163,6 +227,110 @@ func TestParser_ParseSelectQuery(t *testing.T) {
 	}
 }
 
+func TestParser_ParseCreateIndexQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    CreateIndexOp
+		wantErr bool
+	}{
+		{
+			name:  "valid create index query",
+			input: `CREATE INDEX idx ON users(email)`,
+			want:  CreateIndexOp{Name: "idx", Document: "users", Field: "email"},
+		},
+		{
+			name:  "valid create index query with spaces",
+			input: `create index by_age on users ( age );`,
+			want:  CreateIndexOp{Name: "by_age", Document: "users", Field: "age"},
+		},
+		{
+			name:    "invalid create index query (missing field)",
+			input:   `CREATE INDEX idx ON users`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid create index query (several fields)",
+			input:   `CREATE INDEX idx ON users(email, age)`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(tt.input).Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parser.Build() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parser.Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseAlterQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    AlterOp
+		wantErr bool
+	}{
+		{
+			name:  "add field",
+			input: `ALTER DOCUMENT users ADD FIELD email STRING`,
+			want:  AlterOp{Document: "users", Action: AlterAdd, Field: "email", Type: "STRING"},
+		},
+		{
+			name:  "add field with quoted default",
+			input: `alter document users add field country string default 'in';`,
+			want:  AlterOp{Document: "users", Action: AlterAdd, Field: "country", Type: "STRING", Default: "in"},
+		},
+		{
+			name:  "add field with literal default",
+			input: `ALTER DOCUMENT users ADD FIELD age INT64 DEFAULT 18`,
+			want:  AlterOp{Document: "users", Action: AlterAdd, Field: "age", Type: "INT64", Default: float64(18)},
+		},
+		{
+			name:  "drop field",
+			input: `ALTER DOCUMENT users DROP FIELD age`,
+			want:  AlterOp{Document: "users", Action: AlterDrop, Field: "age"},
+		},
+		{
+			name:  "rename field",
+			input: `ALTER DOCUMENT users RENAME FIELD name TO full_name`,
+			want:  AlterOp{Document: "users", Action: AlterRename, Field: "name", To: "full_name"},
+		},
+		{
+			name:    "add field without type",
+			input:   `ALTER DOCUMENT users ADD FIELD email`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid default",
+			input:   `ALTER DOCUMENT users ADD FIELD age INT64 DEFAULT eighteen`,
+			wantErr: true,
+		},
+		{
+			name:    "rename without new name",
+			input:   `ALTER DOCUMENT users RENAME FIELD name`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(tt.input).Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parser.Build() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parser.Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParser_ParseDeleteQuery(t *testing.T) {
 	type fields struct {
 		input string
@@ -189,6 +357,22 @@ func TestParser_ParseDeleteQuery(t *testing.T) {
 			want:    DeleteOp{},
 			wantErr: true,
 		},
+		{
+			name: "valid range delete query",
+			fields: fields{
+				input: `DELETE DOCUMENT FROM user WHERE _ID BETWEEN 10 AND 20`,
+			},
+			want:    DeleteOp{Document: "user", ID: 10, EndID: 20, Range: true},
+			wantErr: false,
+		},
+		{
+			name: "invalid range delete query (empty range)",
+			fields: fields{
+				input: `DELETE DOCUMENT FROM user WHERE _ID BETWEEN 20 AND 10`,
+			},
+			want:    DeleteOp{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
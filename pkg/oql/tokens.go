@@ -12,6 +12,16 @@ const (
 	T_SELECT   TokenType = "SELECT"
 	T_FROM     TokenType = "FROM"
 	T_DELETE   TokenType = "DELETE"
+	T_INDEX    TokenType = "INDEX"
+	T_ON       TokenType = "ON"
+	T_WHERE    TokenType = "WHERE"
+	T_ALTER    TokenType = "ALTER"
+	T_ADD      TokenType = "ADD"
+	T_DROP     TokenType = "DROP"
+	T_RENAME   TokenType = "RENAME"
+	T_FIELD    TokenType = "FIELD"
+	T_DEFAULT  TokenType = "DEFAULT"
+	T_TO       TokenType = "TO"
 
 	// Symbols
 	T_LPAREN         TokenType = "("
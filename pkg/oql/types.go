@@ -1,5 +1,7 @@
 package oql
 
+import "time"
+
 // JSONString represents a raw JSON string input.
 type JSONString string
 
@@ -27,17 +29,51 @@ type InsertOp struct {
 	Value    Value
 }
 
+// CreateIndexOp represents a parsed CREATE INDEX ... ON ...(field) operation.
+type CreateIndexOp struct {
+	Name     string
+	Document string
+	Field    string
+}
+
+// actions of AlterOp
+const (
+	AlterAdd    = "ADD"
+	AlterDrop   = "DROP"
+	AlterRename = "RENAME"
+)
+
+// AlterOp represents a parsed ALTER DOCUMENT ... ADD|DROP|RENAME FIELD operation.
+// Type & Default are set for AlterAdd, Default is nil if none is given. To
+// is new name of field for AlterRename.
+type AlterOp struct {
+	Document string
+	Action   string
+	Field    string
+	Type     string
+	Default  interface{}
+	To       string
+}
+
 // SelectOp represents a parsed SELECT ... FROM ... WITH _ID= operation.
+// AsOf is zero unless document is read as of a past time. Documents are
+// looked up by Field equal to Value instead of ID if Field is set.
 type SelectOp struct {
 	Document string
 	Columns  []string
 	ID       int64
+	AsOf     time.Time
+	Field    string
+	Value    interface{}
 }
 
 // DeleteOp represents a parsed DELETE DOCUMENT FROM ... operation.
+// Range deletes are inclusive of both ID & EndID.
 type DeleteOp struct {
 	Document string
 	ID       int64
+	EndID    int64
+	Range    bool
 }
 
 // Query is a generic interface for all query operation types (CreateOp, InsertOp, etc.).
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.6
+// 	protoc-gen-go v1.36.7
 // 	protoc        v5.29.3
 // source: pkg/proto/ops.proto
 
@@ -130,6 +130,7 @@ type InsertReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
 	Value         *structpb.Struct       `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Id            int64                  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"` // _ID if non zero, Struct numbers can't hold every int64
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -178,9 +179,17 @@ func (x *InsertReq) GetValue() *structpb.Struct {
 	return nil
 }
 
+func (x *InsertReq) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
 type InsertRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Id            int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"` // _ID of document, generated if it had none
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -222,6 +231,13 @@ func (x *InsertRes) GetStatus() bool {
 	return false
 }
 
+func (x *InsertRes) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
 type CreateReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
@@ -318,17 +334,147 @@ func (x *CreatRes) GetStatus() bool {
 	return false
 }
 
+type AlterReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"` // ADD, DROP or RENAME
+	Field         string                 `protobuf:"bytes,3,opt,name=field,proto3" json:"field,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`       // type of added field
+	Default       *structpb.Value        `protobuf:"bytes,5,opt,name=default,proto3" json:"default,omitempty"` // default of added field, none if unset
+	To            string                 `protobuf:"bytes,6,opt,name=to,proto3" json:"to,omitempty"`           // new name of renamed field
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlterReq) Reset() {
+	*x = AlterReq{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlterReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlterReq) ProtoMessage() {}
+
+func (x *AlterReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlterReq.ProtoReflect.Descriptor instead.
+func (*AlterReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AlterReq) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *AlterReq) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *AlterReq) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *AlterReq) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AlterReq) GetDefault() *structpb.Value {
+	if x != nil {
+		return x.Default
+	}
+	return nil
+}
+
+func (x *AlterReq) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type AlterRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlterRes) Reset() {
+	*x = AlterRes{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlterRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlterRes) ProtoMessage() {}
+
+func (x *AlterRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlterRes.ProtoReflect.Descriptor instead.
+func (*AlterRes) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AlterRes) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
 type DeleteReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
 	Id            int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	EndId         int64                  `protobuf:"varint,3,opt,name=end_id,json=endId,proto3" json:"end_id,omitempty"`
+	Range         bool                   `protobuf:"varint,4,opt,name=range,proto3" json:"range,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeleteReq) Reset() {
 	*x = DeleteReq{}
-	mi := &file_pkg_proto_ops_proto_msgTypes[6]
+	mi := &file_pkg_proto_ops_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -340,7 +486,7 @@ func (x *DeleteReq) String() string {
 func (*DeleteReq) ProtoMessage() {}
 
 func (x *DeleteReq) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_ops_proto_msgTypes[6]
+	mi := &file_pkg_proto_ops_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -353,7 +499,7 @@ func (x *DeleteReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteReq.ProtoReflect.Descriptor instead.
 func (*DeleteReq) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{6}
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DeleteReq) GetDocument() string {
@@ -370,6 +516,20 @@ func (x *DeleteReq) GetId() int64 {
 	return 0
 }
 
+func (x *DeleteReq) GetEndId() int64 {
+	if x != nil {
+		return x.EndId
+	}
+	return 0
+}
+
+func (x *DeleteReq) GetRange() bool {
+	if x != nil {
+		return x.Range
+	}
+	return false
+}
+
 type DeleteRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
@@ -379,7 +539,7 @@ type DeleteRes struct {
 
 func (x *DeleteRes) Reset() {
 	*x = DeleteRes{}
-	mi := &file_pkg_proto_ops_proto_msgTypes[7]
+	mi := &file_pkg_proto_ops_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -391,7 +551,7 @@ func (x *DeleteRes) String() string {
 func (*DeleteRes) ProtoMessage() {}
 
 func (x *DeleteRes) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_ops_proto_msgTypes[7]
+	mi := &file_pkg_proto_ops_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -404,7 +564,7 @@ func (x *DeleteRes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRes.ProtoReflect.Descriptor instead.
 func (*DeleteRes) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{7}
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *DeleteRes) GetStatus() bool {
@@ -414,6 +574,438 @@ func (x *DeleteRes) GetStatus() bool {
 	return false
 }
 
+type FlushReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"` // all documents if empty
+	Wait          bool                   `protobuf:"varint,2,opt,name=wait,proto3" json:"wait,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushReq) Reset() {
+	*x = FlushReq{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushReq) ProtoMessage() {}
+
+func (x *FlushReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushReq.ProtoReflect.Descriptor instead.
+func (*FlushReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FlushReq) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *FlushReq) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+type FlushRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushRes) Reset() {
+	*x = FlushRes{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushRes) ProtoMessage() {}
+
+func (x *FlushRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushRes.ProtoReflect.Descriptor instead.
+func (*FlushRes) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FlushRes) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+type CompactRangeReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	StartId       int64                  `protobuf:"varint,2,opt,name=start_id,json=startId,proto3" json:"start_id,omitempty"`
+	EndId         int64                  `protobuf:"varint,3,opt,name=end_id,json=endId,proto3" json:"end_id,omitempty"` // exclusive
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompactRangeReq) Reset() {
+	*x = CompactRangeReq{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompactRangeReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompactRangeReq) ProtoMessage() {}
+
+func (x *CompactRangeReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompactRangeReq.ProtoReflect.Descriptor instead.
+func (*CompactRangeReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CompactRangeReq) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *CompactRangeReq) GetStartId() int64 {
+	if x != nil {
+		return x.StartId
+	}
+	return 0
+}
+
+func (x *CompactRangeReq) GetEndId() int64 {
+	if x != nil {
+		return x.EndId
+	}
+	return 0
+}
+
+type CompactRangeRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompactRangeRes) Reset() {
+	*x = CompactRangeRes{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompactRangeRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompactRangeRes) ProtoMessage() {}
+
+func (x *CompactRangeRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompactRangeRes.ProtoReflect.Descriptor instead.
+func (*CompactRangeRes) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CompactRangeRes) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+type RewriteReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	Wait          bool                   `protobuf:"varint,2,opt,name=wait,proto3" json:"wait,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RewriteReq) Reset() {
+	*x = RewriteReq{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RewriteReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RewriteReq) ProtoMessage() {}
+
+func (x *RewriteReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RewriteReq.ProtoReflect.Descriptor instead.
+func (*RewriteReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RewriteReq) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *RewriteReq) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+type RewriteRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        bool                   `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RewriteRes) Reset() {
+	*x = RewriteRes{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RewriteRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RewriteRes) ProtoMessage() {}
+
+func (x *RewriteRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RewriteRes.ProtoReflect.Descriptor instead.
+func (*RewriteRes) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RewriteRes) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+type SubscribeReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Document      string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	FromPosition  string                 `protobuf:"bytes,2,opt,name=from_position,json=fromPosition,proto3" json:"from_position,omitempty"` // changes committed from now on if empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeReq) Reset() {
+	*x = SubscribeReq{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeReq) ProtoMessage() {}
+
+func (x *SubscribeReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeReq.ProtoReflect.Descriptor instead.
+func (*SubscribeReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SubscribeReq) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *SubscribeReq) GetFromPosition() string {
+	if x != nil {
+		return x.FromPosition
+	}
+	return ""
+}
+
+type SubscribeRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Position      string                 `protobuf:"bytes,1,opt,name=position,proto3" json:"position,omitempty"` // pass as from_position to resume after change
+	Op            string                 `protobuf:"bytes,2,opt,name=op,proto3" json:"op,omitempty"`             // insert, delete or delete_range
+	Id            int64                  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	EndId         int64                  `protobuf:"varint,4,opt,name=end_id,json=endId,proto3" json:"end_id,omitempty"` // inclusive, set for delete_range
+	Data          []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`                 // inserted document in json bytes
+	Time          int64                  `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`                // commit time in unix nanoseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRes) Reset() {
+	*x = SubscribeRes{}
+	mi := &file_pkg_proto_ops_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRes) ProtoMessage() {}
+
+func (x *SubscribeRes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_ops_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRes.ProtoReflect.Descriptor instead.
+func (*SubscribeRes) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_ops_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SubscribeRes) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+func (x *SubscribeRes) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *SubscribeRes) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SubscribeRes) GetEndId() int64 {
+	if x != nil {
+		return x.EndId
+	}
+	return 0
+}
+
+func (x *SubscribeRes) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SubscribeRes) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
 var File_pkg_proto_ops_proto protoreflect.FileDescriptor
 
 const file_pkg_proto_ops_proto_rawDesc = "" +
@@ -424,29 +1016,76 @@ const file_pkg_proto_ops_proto_rawDesc = "" +
 	"\acolumns\x18\x02 \x03(\tR\acolumns\x12\x0e\n" +
 	"\x02id\x18\x03 \x01(\x03R\x02id\"\x1f\n" +
 	"\tSelectRes\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data\"V\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"f\n" +
 	"\tInsertReq\x12\x1a\n" +
 	"\bdocument\x18\x01 \x01(\tR\bdocument\x12-\n" +
-	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value\"#\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\x03R\x02id\"3\n" +
 	"\tInsertRes\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\bR\x06status\"X\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\"X\n" +
 	"\tCreateReq\x12\x1a\n" +
 	"\bdocument\x18\x01 \x01(\tR\bdocument\x12/\n" +
 	"\x06schema\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x06schema\"\"\n" +
 	"\bCreatRes\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\bR\x06status\"7\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\"\xaa\x01\n" +
+	"\bAlterReq\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x14\n" +
+	"\x05field\x18\x03 \x01(\tR\x05field\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x120\n" +
+	"\adefault\x18\x05 \x01(\v2\x16.google.protobuf.ValueR\adefault\x12\x0e\n" +
+	"\x02to\x18\x06 \x01(\tR\x02to\"\"\n" +
+	"\bAlterRes\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\"d\n" +
 	"\tDeleteReq\x12\x1a\n" +
 	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x0e\n" +
-	"\x02id\x18\x02 \x01(\x03R\x02id\"#\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06end_id\x18\x03 \x01(\x03R\x05endId\x12\x14\n" +
+	"\x05range\x18\x04 \x01(\bR\x05range\"#\n" +
 	"\tDeleteRes\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\bR\x06status2\x92\x02\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\":\n" +
+	"\bFlushReq\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x12\n" +
+	"\x04wait\x18\x02 \x01(\bR\x04wait\"\"\n" +
+	"\bFlushRes\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\"_\n" +
+	"\x0fCompactRangeReq\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x19\n" +
+	"\bstart_id\x18\x02 \x01(\x03R\astartId\x12\x15\n" +
+	"\x06end_id\x18\x03 \x01(\x03R\x05endId\")\n" +
+	"\x0fCompactRangeRes\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\"<\n" +
+	"\n" +
+	"RewriteReq\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x12\n" +
+	"\x04wait\x18\x02 \x01(\bR\x04wait\"$\n" +
+	"\n" +
+	"RewriteRes\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\bR\x06status\"O\n" +
+	"\fSubscribeReq\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12#\n" +
+	"\rfrom_position\x18\x02 \x01(\tR\ffromPosition\"\x89\x01\n" +
+	"\fSubscribeRes\x12\x1a\n" +
+	"\bposition\x18\x01 \x01(\tR\bposition\x12\x0e\n" +
+	"\x02op\x18\x02 \x01(\tR\x02op\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06end_id\x18\x04 \x01(\x03R\x05endId\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\fR\x04data\x12\x12\n" +
+	"\x04time\x18\x06 \x01(\x03R\x04time2\xee\x02\n" +
 	"\x03Ops\x12'\n" +
-	"\x06Create\x12\x0e.ops.CreateReq\x1a\r.ops.CreatRes\x12(\n" +
+	"\x06Create\x12\x0e.ops.CreateReq\x1a\r.ops.CreatRes\x12%\n" +
+	"\x05Alter\x12\r.ops.AlterReq\x1a\r.ops.AlterRes\x12(\n" +
 	"\x06Insert\x12\x0e.ops.InsertReq\x1a\x0e.ops.InsertRes\x121\n" +
 	"\x0fSecondaryInsert\x12\x0e.ops.InsertReq\x1a\x0e.ops.InsertRes\x12(\n" +
 	"\x06Delete\x12\x0e.ops.DeleteReq\x1a\x0e.ops.DeleteRes\x121\n" +
 	"\x0fSecondaryDelete\x12\x0e.ops.DeleteReq\x1a\x0e.ops.DeleteRes\x12(\n" +
-	"\x06Select\x12\x0e.ops.SelectReq\x1a\x0e.ops.SelectResB\x0fZ\rpkg/proto/opsb\x06proto3"
+	"\x06Select\x12\x0e.ops.SelectReq\x1a\x0e.ops.SelectRes\x123\n" +
+	"\tSubscribe\x12\x11.ops.SubscribeReq\x1a\x11.ops.SubscribeRes0\x012\x97\x01\n" +
+	"\x05Admin\x12%\n" +
+	"\x05Flush\x12\r.ops.FlushReq\x1a\r.ops.FlushRes\x12:\n" +
+	"\fCompactRange\x12\x14.ops.CompactRangeReq\x1a\x14.ops.CompactRangeRes\x12+\n" +
+	"\aRewrite\x12\x0f.ops.RewriteReq\x1a\x0f.ops.RewriteResB\x0fZ\rpkg/proto/opsb\x06proto3"
 
 var (
 	file_pkg_proto_ops_proto_rawDescOnce sync.Once
@@ -460,7 +1099,7 @@ func file_pkg_proto_ops_proto_rawDescGZIP() []byte {
 	return file_pkg_proto_ops_proto_rawDescData
 }
 
-var file_pkg_proto_ops_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_pkg_proto_ops_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_pkg_proto_ops_proto_goTypes = []any{
 	(*SelectReq)(nil),       // 0: ops.SelectReq
 	(*SelectRes)(nil),       // 1: ops.SelectRes
@@ -468,30 +1107,52 @@ var file_pkg_proto_ops_proto_goTypes = []any{
 	(*InsertRes)(nil),       // 3: ops.InsertRes
 	(*CreateReq)(nil),       // 4: ops.CreateReq
 	(*CreatRes)(nil),        // 5: ops.CreatRes
-	(*DeleteReq)(nil),       // 6: ops.DeleteReq
-	(*DeleteRes)(nil),       // 7: ops.DeleteRes
-	(*structpb.Struct)(nil), // 8: google.protobuf.Struct
+	(*AlterReq)(nil),        // 6: ops.AlterReq
+	(*AlterRes)(nil),        // 7: ops.AlterRes
+	(*DeleteReq)(nil),       // 8: ops.DeleteReq
+	(*DeleteRes)(nil),       // 9: ops.DeleteRes
+	(*FlushReq)(nil),        // 10: ops.FlushReq
+	(*FlushRes)(nil),        // 11: ops.FlushRes
+	(*CompactRangeReq)(nil), // 12: ops.CompactRangeReq
+	(*CompactRangeRes)(nil), // 13: ops.CompactRangeRes
+	(*RewriteReq)(nil),      // 14: ops.RewriteReq
+	(*RewriteRes)(nil),      // 15: ops.RewriteRes
+	(*SubscribeReq)(nil),    // 16: ops.SubscribeReq
+	(*SubscribeRes)(nil),    // 17: ops.SubscribeRes
+	(*structpb.Struct)(nil), // 18: google.protobuf.Struct
+	(*structpb.Value)(nil),  // 19: google.protobuf.Value
 }
 var file_pkg_proto_ops_proto_depIdxs = []int32{
-	8, // 0: ops.InsertReq.value:type_name -> google.protobuf.Struct
-	8, // 1: ops.CreateReq.schema:type_name -> google.protobuf.Struct
-	4, // 2: ops.Ops.Create:input_type -> ops.CreateReq
-	2, // 3: ops.Ops.Insert:input_type -> ops.InsertReq
-	2, // 4: ops.Ops.SecondaryInsert:input_type -> ops.InsertReq
-	6, // 5: ops.Ops.Delete:input_type -> ops.DeleteReq
-	6, // 6: ops.Ops.SecondaryDelete:input_type -> ops.DeleteReq
-	0, // 7: ops.Ops.Select:input_type -> ops.SelectReq
-	5, // 8: ops.Ops.Create:output_type -> ops.CreatRes
-	3, // 9: ops.Ops.Insert:output_type -> ops.InsertRes
-	3, // 10: ops.Ops.SecondaryInsert:output_type -> ops.InsertRes
-	7, // 11: ops.Ops.Delete:output_type -> ops.DeleteRes
-	7, // 12: ops.Ops.SecondaryDelete:output_type -> ops.DeleteRes
-	1, // 13: ops.Ops.Select:output_type -> ops.SelectRes
-	8, // [8:14] is the sub-list for method output_type
-	2, // [2:8] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	18, // 0: ops.InsertReq.value:type_name -> google.protobuf.Struct
+	18, // 1: ops.CreateReq.schema:type_name -> google.protobuf.Struct
+	19, // 2: ops.AlterReq.default:type_name -> google.protobuf.Value
+	4,  // 3: ops.Ops.Create:input_type -> ops.CreateReq
+	6,  // 4: ops.Ops.Alter:input_type -> ops.AlterReq
+	2,  // 5: ops.Ops.Insert:input_type -> ops.InsertReq
+	2,  // 6: ops.Ops.SecondaryInsert:input_type -> ops.InsertReq
+	8,  // 7: ops.Ops.Delete:input_type -> ops.DeleteReq
+	8,  // 8: ops.Ops.SecondaryDelete:input_type -> ops.DeleteReq
+	0,  // 9: ops.Ops.Select:input_type -> ops.SelectReq
+	16, // 10: ops.Ops.Subscribe:input_type -> ops.SubscribeReq
+	10, // 11: ops.Admin.Flush:input_type -> ops.FlushReq
+	12, // 12: ops.Admin.CompactRange:input_type -> ops.CompactRangeReq
+	14, // 13: ops.Admin.Rewrite:input_type -> ops.RewriteReq
+	5,  // 14: ops.Ops.Create:output_type -> ops.CreatRes
+	7,  // 15: ops.Ops.Alter:output_type -> ops.AlterRes
+	3,  // 16: ops.Ops.Insert:output_type -> ops.InsertRes
+	3,  // 17: ops.Ops.SecondaryInsert:output_type -> ops.InsertRes
+	9,  // 18: ops.Ops.Delete:output_type -> ops.DeleteRes
+	9,  // 19: ops.Ops.SecondaryDelete:output_type -> ops.DeleteRes
+	1,  // 20: ops.Ops.Select:output_type -> ops.SelectRes
+	17, // 21: ops.Ops.Subscribe:output_type -> ops.SubscribeRes
+	11, // 22: ops.Admin.Flush:output_type -> ops.FlushRes
+	13, // 23: ops.Admin.CompactRange:output_type -> ops.CompactRangeRes
+	15, // 24: ops.Admin.Rewrite:output_type -> ops.RewriteRes
+	14, // [14:25] is the sub-list for method output_type
+	3,  // [3:14] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_pkg_proto_ops_proto_init() }
@@ -505,9 +1166,9 @@ func file_pkg_proto_ops_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_ops_proto_rawDesc), len(file_pkg_proto_ops_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   18,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_pkg_proto_ops_proto_goTypes,
 		DependencyIndexes: file_pkg_proto_ops_proto_depIdxs,
@@ -20,11 +20,13 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Ops_Create_FullMethodName          = "/ops.Ops/Create"
+	Ops_Alter_FullMethodName           = "/ops.Ops/Alter"
 	Ops_Insert_FullMethodName          = "/ops.Ops/Insert"
 	Ops_SecondaryInsert_FullMethodName = "/ops.Ops/SecondaryInsert"
 	Ops_Delete_FullMethodName          = "/ops.Ops/Delete"
 	Ops_SecondaryDelete_FullMethodName = "/ops.Ops/SecondaryDelete"
 	Ops_Select_FullMethodName          = "/ops.Ops/Select"
+	Ops_Subscribe_FullMethodName       = "/ops.Ops/Subscribe"
 )
 
 // OpsClient is the client API for Ops service.
@@ -32,11 +34,13 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OpsClient interface {
 	Create(ctx context.Context, in *CreateReq, opts ...grpc.CallOption) (*CreatRes, error)
+	Alter(ctx context.Context, in *AlterReq, opts ...grpc.CallOption) (*AlterRes, error)
 	Insert(ctx context.Context, in *InsertReq, opts ...grpc.CallOption) (*InsertRes, error)
 	SecondaryInsert(ctx context.Context, in *InsertReq, opts ...grpc.CallOption) (*InsertRes, error)
 	Delete(ctx context.Context, in *DeleteReq, opts ...grpc.CallOption) (*DeleteRes, error)
 	SecondaryDelete(ctx context.Context, in *DeleteReq, opts ...grpc.CallOption) (*DeleteRes, error)
 	Select(ctx context.Context, in *SelectReq, opts ...grpc.CallOption) (*SelectRes, error)
+	Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeRes], error)
 }
 
 type opsClient struct {
@@ -57,6 +61,16 @@ func (c *opsClient) Create(ctx context.Context, in *CreateReq, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *opsClient) Alter(ctx context.Context, in *AlterReq, opts ...grpc.CallOption) (*AlterRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlterRes)
+	err := c.cc.Invoke(ctx, Ops_Alter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *opsClient) Insert(ctx context.Context, in *InsertReq, opts ...grpc.CallOption) (*InsertRes, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(InsertRes)
@@ -107,16 +121,37 @@ func (c *opsClient) Select(ctx context.Context, in *SelectReq, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *opsClient) Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeRes], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ops_ServiceDesc.Streams[0], Ops_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeReq, SubscribeRes]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ops_SubscribeClient = grpc.ServerStreamingClient[SubscribeRes]
+
 // OpsServer is the server API for Ops service.
 // All implementations must embed UnimplementedOpsServer
 // for forward compatibility.
 type OpsServer interface {
 	Create(context.Context, *CreateReq) (*CreatRes, error)
+	Alter(context.Context, *AlterReq) (*AlterRes, error)
 	Insert(context.Context, *InsertReq) (*InsertRes, error)
 	SecondaryInsert(context.Context, *InsertReq) (*InsertRes, error)
 	Delete(context.Context, *DeleteReq) (*DeleteRes, error)
 	SecondaryDelete(context.Context, *DeleteReq) (*DeleteRes, error)
 	Select(context.Context, *SelectReq) (*SelectRes, error)
+	Subscribe(*SubscribeReq, grpc.ServerStreamingServer[SubscribeRes]) error
 	mustEmbedUnimplementedOpsServer()
 }
 
@@ -130,6 +165,9 @@ type UnimplementedOpsServer struct{}
 func (UnimplementedOpsServer) Create(context.Context, *CreateReq) (*CreatRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
 }
+func (UnimplementedOpsServer) Alter(context.Context, *AlterReq) (*AlterRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Alter not implemented")
+}
 func (UnimplementedOpsServer) Insert(context.Context, *InsertReq) (*InsertRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
 }
@@ -145,6 +183,9 @@ func (UnimplementedOpsServer) SecondaryDelete(context.Context, *DeleteReq) (*Del
 func (UnimplementedOpsServer) Select(context.Context, *SelectReq) (*SelectRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Select not implemented")
 }
+func (UnimplementedOpsServer) Subscribe(*SubscribeReq, grpc.ServerStreamingServer[SubscribeRes]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
 func (UnimplementedOpsServer) mustEmbedUnimplementedOpsServer() {}
 func (UnimplementedOpsServer) testEmbeddedByValue()             {}
 
@@ -184,6 +225,24 @@ func _Ops_Create_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ops_Alter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlterReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpsServer).Alter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ops_Alter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpsServer).Alter(ctx, req.(*AlterReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Ops_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(InsertReq)
 	if err := dec(in); err != nil {
@@ -274,6 +333,17 @@ func _Ops_Select_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ops_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OpsServer).Subscribe(m, &grpc.GenericServerStream[SubscribeReq, SubscribeRes]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ops_SubscribeServer = grpc.ServerStreamingServer[SubscribeRes]
+
 // Ops_ServiceDesc is the grpc.ServiceDesc for Ops service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -285,6 +355,10 @@ var Ops_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Create",
 			Handler:    _Ops_Create_Handler,
 		},
+		{
+			MethodName: "Alter",
+			Handler:    _Ops_Alter_Handler,
+		},
 		{
 			MethodName: "Insert",
 			Handler:    _Ops_Insert_Handler,
@@ -306,6 +380,190 @@ var Ops_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Ops_Select_Handler,
 		},
 	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Ops_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/proto/ops.proto",
+}
+
+const (
+	Admin_Flush_FullMethodName        = "/ops.Admin/Flush"
+	Admin_CompactRange_FullMethodName = "/ops.Admin/CompactRange"
+	Admin_Rewrite_FullMethodName      = "/ops.Admin/Rewrite"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	Flush(ctx context.Context, in *FlushReq, opts ...grpc.CallOption) (*FlushRes, error)
+	CompactRange(ctx context.Context, in *CompactRangeReq, opts ...grpc.CallOption) (*CompactRangeRes, error)
+	Rewrite(ctx context.Context, in *RewriteReq, opts ...grpc.CallOption) (*RewriteRes, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Flush(ctx context.Context, in *FlushReq, opts ...grpc.CallOption) (*FlushRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FlushRes)
+	err := c.cc.Invoke(ctx, Admin_Flush_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CompactRange(ctx context.Context, in *CompactRangeReq, opts ...grpc.CallOption) (*CompactRangeRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompactRangeRes)
+	err := c.cc.Invoke(ctx, Admin_CompactRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Rewrite(ctx context.Context, in *RewriteReq, opts ...grpc.CallOption) (*RewriteRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RewriteRes)
+	err := c.cc.Invoke(ctx, Admin_Rewrite_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+type AdminServer interface {
+	Flush(context.Context, *FlushReq) (*FlushRes, error)
+	CompactRange(context.Context, *CompactRangeReq) (*CompactRangeRes, error)
+	Rewrite(context.Context, *RewriteReq) (*RewriteRes, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) Flush(context.Context, *FlushReq) (*FlushRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedAdminServer) CompactRange(context.Context, *CompactRangeReq) (*CompactRangeRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompactRange not implemented")
+}
+func (UnimplementedAdminServer) Rewrite(context.Context, *RewriteReq) (*RewriteRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rewrite not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_Flush_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Flush(ctx, req.(*FlushReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CompactRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactRangeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CompactRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CompactRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CompactRange(ctx, req.(*CompactRangeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Rewrite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RewriteReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Rewrite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_Rewrite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Rewrite(ctx, req.(*RewriteReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ops.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Flush",
+			Handler:    _Admin_Flush_Handler,
+		},
+		{
+			MethodName: "CompactRange",
+			Handler:    _Admin_CompactRange_Handler,
+		},
+		{
+			MethodName: "Rewrite",
+			Handler:    _Admin_Rewrite_Handler,
+		},
+	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/proto/ops.proto",
 }
@@ -0,0 +1,258 @@
+package schema
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path"
+
+	"github.com/nagarajRPoojari/orange/internal/types"
+	"github.com/nagarajRPoojari/orange/pkg/internal/errors"
+	"github.com/nagarajRPoojari/orange/pkg/oql"
+)
+
+// Schema of a document is versioned, it's version 1 when created & every
+// alteration makes a new one. Catalog keeps current schema of document
+// along with versions following first one, so documents stored with an
+// older version are upgraded to current one as they're read.
+
+// VersionField holds version of schema a document is stored with, it is
+// set by storage & isn't a field of schema. Documents without it are of
+// version 1
+const VersionField = "_V"
+
+// Alteration changes a top level field of schema, see Alter
+type Alteration struct {
+	// oql.AlterAdd, oql.AlterDrop or oql.AlterRename
+	Action string `json:"action"`
+	Field  string `json:"field"`
+	// type & default of added field
+	Type    string      `json:"type,omitempty"`
+	Default interface{} `json:"default,omitempty"`
+	// new name of renamed field
+	To string `json:"to,omitempty"`
+}
+
+// SchemaVersion is schema of document made by alteration of previous one
+type SchemaVersion struct {
+	Version    int        `json:"version"`
+	Schema     oql.Schema `json:"schema"`
+	Alteration Alteration `json:"alteration"`
+}
+
+// versionCatalogPath returns path versions of document are saved at, next
+// to its schema
+func (t *SchemaHandler) versionCatalogPath(docName string) string {
+	return path.Join(t.opts.Dir, docName+".versions")
+}
+
+// LoadVersions loads versions of schema of document following first one,
+// in order. Documents never altered have none
+func (t *SchemaHandler) LoadVersions(docName string) ([]SchemaVersion, error) {
+	t.versionMu.Lock()
+	defer t.versionMu.Unlock()
+	return t.loadVersions(docName)
+}
+
+func (t *SchemaHandler) loadVersions(docName string) ([]SchemaVersion, error) {
+	if versions, ok := t.versions[docName]; ok {
+		return versions, nil
+	}
+
+	if t.versions == nil {
+		t.versions = map[string][]SchemaVersion{}
+	}
+	data, err := os.ReadFile(t.versionCatalogPath(docName))
+	if os.IsNotExist(err) {
+		t.versions[docName] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.SchemaError("failed to load schema versions")
+	}
+
+	var versions []SchemaVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, errors.SchemaJSONUnmarshallError("%v", err)
+	}
+	t.versions[docName] = versions
+	return versions, nil
+}
+
+// LoadVersion loads current schema of document along with its version
+func (t *SchemaHandler) LoadVersion(docName string) (oql.Schema, int, error) {
+	schema, err := t.LoadFromCatalog(docName)
+	if err != nil {
+		return nil, 0, err
+	}
+	versions, err := t.LoadVersions(docName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return schema, len(versions) + 1, nil
+}
+
+// Alter applies alteration to schema of document & saves it as a new
+// version, which is returned. Indexes on a dropped field are dropped &
+// ones on a renamed field follow it. Caller must make sure document isn't
+// written to meanwhile.
+func (t *SchemaHandler) Alter(docName string, alteration Alteration) (int, error) {
+	current, version, err := t.LoadVersion(docName)
+	if err != nil {
+		return 0, err
+	}
+
+	schema, err := alter(current, alteration)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.VerifySchema(schema); err != nil {
+		return 0, err
+	}
+
+	t.indexMu.Lock()
+	defer t.indexMu.Unlock()
+	indexes, err := t.loadIndexes(docName)
+	if err != nil {
+		return 0, err
+	}
+	altered := make([]Index, 0, len(indexes))
+	for _, idx := range indexes {
+		switch {
+		case idx.Field != alteration.Field:
+		case alteration.Action == oql.AlterDrop:
+			continue
+		case alteration.Action == oql.AlterRename:
+			idx.Field = alteration.To
+		}
+		altered = append(altered, idx)
+	}
+
+	t.versionMu.Lock()
+	defer t.versionMu.Unlock()
+	versions, err := t.loadVersions(docName)
+	if err != nil {
+		return 0, err
+	}
+	versions = append(append([]SchemaVersion(nil), versions...), SchemaVersion{
+		Version:    version + 1,
+		Schema:     schema,
+		Alteration: alteration,
+	})
+
+	// versions are source of current schema, schema file is rewritten
+	// after them only to stay readable
+	bytes, err := json.Marshal(versions)
+	if err != nil {
+		return 0, errors.SchemaJSONMarshallError("%v", err)
+	}
+	if err := writeCatalogFile(t.versionCatalogPath(docName), bytes); err != nil {
+		return 0, errors.SchemaError("failed to save schema versions")
+	}
+	t.versions[docName] = versions
+
+	if len(altered) != len(indexes) || alteration.Action == oql.AlterRename {
+		if err := t.saveIndexes(docName, altered); err != nil {
+			return 0, err
+		}
+	}
+
+	if bytes, err = json.Marshal(schema); err != nil {
+		return 0, errors.SchemaJSONMarshallError("%v", err)
+	}
+	if err := writeCatalogFile(path.Join(t.opts.Dir, docName), bytes); err != nil {
+		return 0, errors.SchemaError("failed to save schema to catalog")
+	}
+	return version + 1, nil
+}
+
+// alter returns copy of schema with alteration applied
+func alter(schema oql.Schema, alteration Alteration) (oql.Schema, error) {
+	field := alteration.Field
+	if field == "_ID" || field == VersionField {
+		return nil, errors.SchemaValidationError("%s can't be altered", field)
+	}
+	_, exists := schema[field]
+	altered := maps.Clone(schema)
+
+	switch alteration.Action {
+	case oql.AlterAdd:
+		if exists {
+			return nil, errors.SchemaValidationError("field %s already exists", field)
+		}
+		if _, ok := types.AllTypes[alteration.Type]; !ok {
+			return nil, errors.SchemaValidationError("invalid data type %v of %s", alteration.Type, field)
+		}
+		// documents stored before have no value for field unless it has a
+		// default, so it's nullable without one. nullable is always set to
+		// keep it a descriptor, see fieldDescriptor
+		desc := map[string]interface{}{"type": alteration.Type, "nullable": alteration.Default == nil}
+		if alteration.Default != nil {
+			desc["default"] = alteration.Default
+		}
+		altered[field] = desc
+	case oql.AlterDrop:
+		if !exists {
+			return nil, errors.SchemaValidationError("field %s doesn't exist", field)
+		}
+		delete(altered, field)
+	case oql.AlterRename:
+		if !exists {
+			return nil, errors.SchemaValidationError("field %s doesn't exist", field)
+		}
+		if _, ok := schema[alteration.To]; ok || alteration.To == "" || alteration.To == "_ID" || alteration.To == VersionField {
+			return nil, errors.SchemaValidationError("field %s can't be renamed to %q", field, alteration.To)
+		}
+		altered[alteration.To] = altered[field]
+		delete(altered, field)
+	default:
+		return nil, errors.SchemaValidationError("unknown alteration %q", alteration.Action)
+	}
+	return altered, nil
+}
+
+// writeCatalogFile replaces file at path with data, readers see either
+// old or new contents
+func writeCatalogFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Upgrade applies alterations made to schema of document after version to
+// doc, a top level copy of a document stored with version. Added fields
+// take their default, cast to their type.
+func (t *SchemaHandler) Upgrade(docName string, doc map[string]interface{}, version int) error {
+	versions, err := t.LoadVersions(docName)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		if v.Version <= version {
+			continue
+		}
+		a := v.Alteration
+		switch a.Action {
+		case oql.AlterAdd:
+			if _, ok := doc[a.Field]; ok || a.Default == nil {
+				continue
+			}
+			value, err := types.TypeCast(a.Type, a.Default)
+			if err != nil {
+				return err
+			}
+			doc[a.Field] = value
+		case oql.AlterDrop:
+			delete(doc, a.Field)
+		case oql.AlterRename:
+			if value, ok := doc[a.Field]; ok {
+				doc[a.To] = value
+				delete(doc, a.Field)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nagarajRPoojari/orange/internal/types"
+	"github.com/nagarajRPoojari/orange/pkg/internal/errors"
+)
+
+// Field descriptors may constrain values of a field besides its type, e.g
+// {"type": "INT", "nullable": true, "min": 0, "max": 120}
+//   - nullable fields may be null or missing, others are required unless
+//     they've a default
+//   - min & max bound values of numeric fields
+//   - pattern & maxLength constrain values of STRING fields
+//   - enum lists values a field may take
+//
+// Values breaking a constraint are rejected with a FieldViolation.
+
+// FieldViolation is returned by VerifyAndCastData for a value breaking a
+// constraint of its field
+type FieldViolation = errors.FieldViolation
+
+// numericTypes are types min & max apply to
+var numericTypes = map[string]struct{}{
+	"INT": {}, "INT64": {}, "INT32": {}, "INT16": {}, "INT8": {},
+	"FLOAT": {}, "FLOAT32": {}, "FLOAT64": {}, "DECIMAL": {}, "BYTE": {},
+}
+
+// compiled patterns of field descriptors, keyed by pattern
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// number returns v, a value cast to a numeric type or an option of a
+// descriptor, as float64. ok is false if it isn't a number
+func number(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		// DECIMAL values are strings
+		if _, ok := v.(types.DECIMAL); ok {
+			f, err := strconv.ParseFloat(rv.String(), 64)
+			return f, err == nil
+		}
+	}
+	return 0, false
+}
+
+// verifyConstraints validates constraint options of descriptor of field
+func verifyConstraints(field string, desc map[string]interface{}) error {
+	typ := desc["type"].(string)
+	_, numeric := numericTypes[typ]
+
+	if nullable, ok := desc["nullable"]; ok {
+		if _, ok := nullable.(bool); !ok {
+			return errors.SchemaValidationError("nullable field of %s should be bool", field)
+		}
+	}
+
+	for _, opt := range []string{"min", "max"} {
+		v, ok := desc[opt]
+		if !ok {
+			continue
+		}
+		if !numeric {
+			return errors.SchemaValidationError("%s isn't supported on %s field %s", opt, typ, field)
+		}
+		if _, ok := number(v); !ok {
+			return errors.SchemaValidationError("%s of %s should be a number", opt, field)
+		}
+	}
+	if _, ok := desc["min"]; ok {
+		if _, ok := desc["max"]; ok {
+			minimum, _ := number(desc["min"])
+			maximum, _ := number(desc["max"])
+			if minimum > maximum {
+				return errors.SchemaValidationError("min of %s is greater than its max", field)
+			}
+		}
+	}
+
+	if maxLength, ok := desc["maxLength"]; ok {
+		if typ != "STRING" {
+			return errors.SchemaValidationError("maxLength isn't supported on %s field %s", typ, field)
+		}
+		n, ok := number(maxLength)
+		if !ok || n < 0 || n != float64(int64(n)) {
+			return errors.SchemaValidationError("maxLength of %s should be a non negative integer", field)
+		}
+	}
+
+	if pattern, ok := desc["pattern"]; ok {
+		if typ != "STRING" {
+			return errors.SchemaValidationError("pattern isn't supported on %s field %s", typ, field)
+		}
+		p, ok := pattern.(string)
+		if !ok {
+			return errors.SchemaValidationError("pattern of %s should be a string", field)
+		}
+		if _, err := compilePattern(p); err != nil {
+			return errors.SchemaValidationError("invalid pattern of %s, err=%v", field, err)
+		}
+	}
+
+	if enum, ok := desc["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok || len(values) == 0 {
+			return errors.SchemaValidationError("enum of %s should be a non empty list", field)
+		}
+		for _, v := range values {
+			if _, err := types.TypeCast(typ, v); err != nil {
+				return errors.SchemaValidationError("invalid enum value %v of %s, err=%v", v, field, err)
+			}
+		}
+	}
+
+	if def, ok := desc["default"]; ok {
+		value, err := types.TypeCast(typ, def)
+		if err != nil {
+			return errors.SchemaValidationError("invalid default %v of %s, err=%v", def, field, err)
+		}
+		if err := checkConstraints(field, desc, value); err != nil {
+			return errors.SchemaValidationError("invalid default %v of %s, err=%v", def, field, err)
+		}
+	}
+	return nil
+}
+
+// checkConstraints fails with a FieldViolation if value of field at path,
+// cast to its type, breaks a constraint of descriptor desc
+func checkConstraints(path string, desc map[string]interface{}, value interface{}) error {
+	if n, ok := number(value); ok {
+		if minimum, ok := number(desc["min"]); ok && n < minimum {
+			return FieldViolation{Path: path, Constraint: "min", Value: value, Want: fmt.Sprintf(">= %v", desc["min"])}
+		}
+		if maximum, ok := number(desc["max"]); ok && n > maximum {
+			return FieldViolation{Path: path, Constraint: "max", Value: value, Want: fmt.Sprintf("<= %v", desc["max"])}
+		}
+	}
+
+	if s, ok := value.(types.STRING); ok {
+		if maxLength, ok := number(desc["maxLength"]); ok && float64(utf8.RuneCountInString(string(s))) > maxLength {
+			return FieldViolation{Path: path, Constraint: "maxLength", Value: value, Want: fmt.Sprintf("at most %v characters", desc["maxLength"])}
+		}
+		if pattern, ok := desc["pattern"].(string); ok {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				return err
+			}
+			if !re.MatchString(string(s)) {
+				return FieldViolation{Path: path, Constraint: "pattern", Value: value, Want: fmt.Sprintf("match of %q", pattern)}
+			}
+		}
+	}
+
+	if enum, ok := desc["enum"].([]interface{}); ok {
+		for _, v := range enum {
+			if allowed, err := types.TypeCast(desc["type"].(string), v); err == nil && reflect.DeepEqual(allowed, value) {
+				return nil
+			}
+		}
+		return FieldViolation{Path: path, Constraint: "enum", Value: value, Want: fmt.Sprintf("one of %v", enum)}
+	}
+	return nil
+}
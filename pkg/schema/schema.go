@@ -5,6 +5,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/nagarajRPoojari/orange/internal/types"
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
@@ -21,15 +23,33 @@ type SchemaHandler struct {
 	// cache of loaded schema
 	cache map[string]oql.Schema
 
+	// cache of loaded indexes, see LoadIndexes
+	indexes map[string][]Index
+	indexMu sync.Mutex
+
+	// cache of loaded schema versions, see LoadVersions
+	versions  map[string][]SchemaVersion
+	versionMu sync.Mutex
+
 	opts *SchemaHandlerOpts
 }
 
+// Index is a secondary index of a document on one of its top level fields.
+// Documents of a unique index have distinct values, see UniqueFields
+type Index struct {
+	Name   string `json:"name"`
+	Field  string `json:"field"`
+	Unique bool   `json:"unique,omitempty"`
+}
+
 // NewSchemaHandler creates a new SchemaHandler with the given options.
 // Initializes an empty in-memory schema cache
 func NewSchemaHandler(opts *SchemaHandlerOpts) *SchemaHandler {
 	return &SchemaHandler{
-		cache: map[string]oql.Schema{},
-		opts:  opts,
+		cache:    map[string]oql.Schema{},
+		indexes:  map[string][]Index{},
+		versions: map[string][]SchemaVersion{},
+		opts:     opts,
 	}
 }
 
@@ -45,26 +65,63 @@ func (t *SchemaHandler) VerifySchema(schema oql.Schema) error {
 	return recursiveSchemaVerifier(schema)
 }
 
+// strategies _IDs of documents inserted without one are generated with,
+// set as "strategy" of _ID along with "auto_increment": true
+const (
+	// 1, 2, 3, ... following greatest _ID inserted
+	SequentialID = "sequential"
+	// time ordered _IDs: ms since epoch, node id & sequence number
+	SnowflakeID = "snowflake"
+)
+
 func loadSchemaId(schema oql.Schema) (map[string]interface{}, error) {
-	var id_map map[string]interface{}
-	if _id := schema["_ID"]; _id != nil {
-		id_map, ok := _id.(map[string]interface{})
-		if !ok {
-			return nil, errors.SchemaValidationError("_ID properties missing")
+	_id := schema["_ID"]
+	if _id == nil {
+		return nil, nil
+	}
+	id_map, ok := _id.(map[string]interface{})
+	if !ok {
+		return nil, errors.SchemaValidationError("_ID properties missing")
+	}
+	ai := false
+	if v, ok := id_map["auto_increment"]; ok {
+		if ai, ok = v.(bool); !ok {
+			return nil, errors.SchemaValidationError("auto_increment field should be bool")
 		}
-		if ai, ok := id_map["auto_increment"]; ok {
-			_, ok := ai.(bool)
-			if !ok {
-				return nil, errors.SchemaValidationError("auto_increment field should be bool")
-			}
+	}
+	if v, ok := id_map["strategy"]; ok {
+		if strategy, _ := v.(string); strategy != SequentialID && strategy != SnowflakeID {
+			return nil, errors.SchemaValidationError("invalid _ID strategy %v, expected %s or %s", v, SequentialID, SnowflakeID)
+		}
+		if !ai {
+			return nil, errors.SchemaValidationError("_ID strategy needs auto_increment")
 		}
 	}
 	return id_map, nil
 }
 
+// IDStrategy returns strategy _IDs of documents inserted without one are
+// generated with, empty if they aren't generated. schema must be verified
+func IDStrategy(schema oql.Schema) string {
+	id_map, _ := loadSchemaId(schema)
+	if ai, _ := id_map["auto_increment"].(bool); !ai {
+		return ""
+	}
+	if strategy, ok := id_map["strategy"].(string); ok {
+		return strategy
+	}
+	return SequentialID
+}
+
 // recursiveSchemaVerifier recursively scans schema and validates give type
 // and ensures it is supported natively by parrot
 func recursiveSchemaVerifier(schema map[string]interface{}) error {
+	return verifyFields(schema, "")
+}
+
+// verifyFields verifies fields of schema of a document nested at prefix,
+// empty for top level fields
+func verifyFields(schema map[string]interface{}, prefix string) error {
 	if len(schema) == 0 {
 		return errors.SchemaValidationError("missing data type")
 	}
@@ -73,6 +130,15 @@ func recursiveSchemaVerifier(schema map[string]interface{}) error {
 		if key == "_ID" {
 			continue
 		}
+		if key == VersionField {
+			return errors.SchemaValidationError("%s is a reserved field", key)
+		}
+		if desc, ok := fieldDescriptor(v); ok {
+			if err := verifyDescriptor(prefix+key, desc, prefix != ""); err != nil {
+				return err
+			}
+			continue
+		}
 		// try to cast to string
 		vString, ok := v.(string)
 		if ok {
@@ -82,14 +148,110 @@ func recursiveSchemaVerifier(schema map[string]interface{}) error {
 		} else {
 			vMap, ok := v.(map[string]interface{})
 			if !ok {
-				return errors.SchemaValidationError("invalid data type %v", vMap)
+				return errors.SchemaValidationError("invalid data type %v", v)
+			}
+			if err := verifyFields(vMap, prefix+key+"."); err != nil {
+				return err
 			}
-			return recursiveSchemaVerifier(vMap)
 		}
 	}
 	return nil
 }
 
+// descriptorOptions are keys a field descriptor may have besides type
+var descriptorOptions = map[string]struct{}{
+	"unique":    {},
+	"default":   {},
+	"nullable":  {},
+	"min":       {},
+	"max":       {},
+	"pattern":   {},
+	"enum":      {},
+	"maxLength": {},
+}
+
+// fieldDescriptor returns v as a field descriptor, e.g
+// {"type": "STRING", "unique": true}, ok is false if it isn't one. A map is
+// a descriptor if its type is a string, its other keys are options & one
+// of them at least holds a value a nested document can't, i.e neither a
+// type name nor a map. So schemas stored before descriptors keep their
+// meaning: {"type": "INT"} & {"type": "STRING", "default": "INT"} are
+// nested documents, latter is declared as descriptor by adding an option
+// e.g "nullable": false.
+func fieldDescriptor(v interface{}) (map[string]interface{}, bool) {
+	desc, ok := v.(map[string]interface{})
+	if !ok || len(desc) < 2 {
+		return nil, false
+	}
+	if _, ok := desc["type"].(string); !ok {
+		return nil, false
+	}
+	nested := true
+	for key, opt := range desc {
+		if key == "type" {
+			continue
+		}
+		if _, ok := descriptorOptions[key]; !ok {
+			return nil, false
+		}
+		switch opt := opt.(type) {
+		case map[string]interface{}:
+		case string:
+			if _, ok := types.AllTypes[opt]; !ok {
+				nested = false
+			}
+		default:
+			nested = false
+		}
+	}
+	if nested {
+		return nil, false
+	}
+	return desc, true
+}
+
+// verifyDescriptor validates type & options of descriptor of field
+func verifyDescriptor(field string, desc map[string]interface{}, nested bool) error {
+	if _, ok := types.AllTypes[desc["type"].(string)]; !ok {
+		return errors.SchemaValidationError("invalid data type %v of %s", desc["type"], field)
+	}
+	if unique, ok := desc["unique"]; ok {
+		if _, ok := unique.(bool); !ok {
+			return errors.SchemaValidationError("unique field of %s should be bool", field)
+		}
+		if nested && unique == true {
+			return errors.SchemaValidationError("unique isn't supported on nested field %s", field)
+		}
+	}
+	return verifyConstraints(field, desc)
+}
+
+// FieldType returns type of a top level field of schema, declared either
+// as type name or by a field descriptor. ok is false if schema has no such
+// field or it's a nested document
+func FieldType(schema oql.Schema, field string) (string, bool) {
+	if field == "_ID" {
+		return "", false
+	}
+	if desc, ok := fieldDescriptor(schema[field]); ok {
+		return desc["type"].(string), true
+	}
+	name, ok := schema[field].(string)
+	return name, ok
+}
+
+// UniqueFields returns top level fields of schema marked unique
+func UniqueFields(schema oql.Schema) []string {
+	var fields []string
+	for field, v := range schema {
+		if desc, ok := fieldDescriptor(v); ok && desc["unique"] == true {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 // SavetoCatalog saves schema to catalog directory,
 // might throw error if duplicate document name found
 func (t *SchemaHandler) SavetoCatalog(docName string, schema oql.Schema) error {
@@ -108,6 +270,20 @@ func (t *SchemaHandler) SavetoCatalog(docName string, schema oql.Schema) error {
 	}
 
 	if _, err := os.Stat(catalogPath); err != nil {
+		// unique fields are enforced by indexes, they are saved ahead of
+		// schema so document never exists without them
+		var indexes []Index
+		for _, field := range UniqueFields(schema) {
+			indexes = append(indexes, Index{Name: "unique_" + field, Field: field, Unique: true})
+		}
+		if len(indexes) > 0 {
+			t.indexMu.Lock()
+			err := t.saveIndexes(docName, indexes)
+			t.indexMu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
 
 		if err := os.WriteFile(catalogPath, bytes, 0600); err != nil {
 			return errors.SchemaError("failed to save schema to catalog")
@@ -128,6 +304,15 @@ func (t *SchemaHandler) LoadFromCatalog(docName string) (oql.Schema, error) {
 		return schema, nil
 	}
 
+	// altered schema is last of its versions, see Alter
+	versions, err := t.LoadVersions(docName)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > 0 {
+		return versions[len(versions)-1].Schema, nil
+	}
+
 	catalogPath := path.Join(t.opts.Dir, docName)
 	data, err := os.ReadFile(catalogPath)
 	if err != nil {
@@ -142,47 +327,116 @@ func (t *SchemaHandler) LoadFromCatalog(docName string) (oql.Schema, error) {
 	return schema, nil
 }
 
-// VerifyAndCastData verifies strict schema and tries for
-// possible type conversion,
-//
-// catches:
-//   - missing fields
-//   - invalid data type
-//   - @todo: constraint
-func (t *SchemaHandler) VerifyAndCastData(schema, data map[string]interface{}) error {
-	err := recursiveDataCaster(schema, data)
+// indexCatalogPath returns path indexes of document are saved at, next to
+// its schema
+func (t *SchemaHandler) indexCatalogPath(docName string) string {
+	return path.Join(t.opts.Dir, docName+".indexes")
+}
+
+// SaveIndex records index of document in catalog. Indexed field must be a
+// top level field of a scalar type, might throw error if document already
+// has an index of same name or on same field
+func (t *SchemaHandler) SaveIndex(docName string, index Index) error {
+	schema, err := t.LoadFromCatalog(docName)
 	if err != nil {
 		return err
 	}
-
-	missing := make([]string, 0)
-	// for now: user must provide id
-	if _, ok := data["_ID"]; !ok {
-		missing = append(missing, "_ID")
+	if _, ok := FieldType(schema, index.Field); !ok {
+		return errors.SchemaValidationError("field %q can't be indexed, expected a top level field of a scalar type", index.Field)
 	}
 
-	for key := range schema {
-		if _, ok := data[key]; !ok {
-			missing = append(missing, key)
+	t.indexMu.Lock()
+	defer t.indexMu.Unlock()
+
+	indexes, err := t.loadIndexes(docName)
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		if idx.Name == index.Name || idx.Field == index.Field {
+			return errors.DuplicateSchemaError("index %s on %s(%s) already exists", idx.Name, docName, idx.Field)
 		}
 	}
+	return t.saveIndexes(docName, append(append([]Index(nil), indexes...), index))
+}
 
-	if len(missing) > 0 {
-		return errors.MissingFields("%v", missing)
+// saveIndexes replaces indexes of document in catalog, caller must hold indexMu
+func (t *SchemaHandler) saveIndexes(docName string, indexes []Index) error {
+	bytes, err := json.Marshal(indexes)
+	if err != nil {
+		return errors.SchemaJSONMarshallError("%v", err)
 	}
-
+	if err := os.WriteFile(t.indexCatalogPath(docName), bytes, 0600); err != nil {
+		return errors.SchemaError("failed to save index to catalog")
+	}
+	if t.indexes == nil {
+		t.indexes = map[string][]Index{}
+	}
+	t.indexes[docName] = indexes
 	return nil
+}
 
+// LoadIndexes loads indexes of document from catalog, in order they were
+// created. Documents without indexes have none
+func (t *SchemaHandler) LoadIndexes(docName string) ([]Index, error) {
+	t.indexMu.Lock()
+	defer t.indexMu.Unlock()
+	return t.loadIndexes(docName)
 }
 
-func recursiveDataCaster(schema, data map[string]interface{}) error {
+func (t *SchemaHandler) loadIndexes(docName string) ([]Index, error) {
+	if indexes, ok := t.indexes[docName]; ok {
+		return indexes, nil
+	}
+
+	if t.indexes == nil {
+		t.indexes = map[string][]Index{}
+	}
+	data, err := os.ReadFile(t.indexCatalogPath(docName))
+	if os.IsNotExist(err) {
+		t.indexes[docName] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.SchemaError("failed to load indexes")
+	}
 
-	for key, v := range data {
-		if key == "_ID" {
-			// idMap, err := loadSchemaId(schema)
-			// ai, _ := idMap["auto_increment"].(bool)
-			// @todo: support auto increment id
+	var indexes []Index
+	if err := json.Unmarshal(data, &indexes); err != nil {
+		return nil, errors.SchemaJSONUnmarshallError("%v", err)
+	}
+	t.indexes[docName] = indexes
+	return indexes, nil
+}
+
+// VerifyAndCastData verifies strict schema and tries for
+// possible type conversion,
+//
+// catches:
+//   - missing fields, missing ones with a default take it
+//   - invalid data type
+//   - constraints of field descriptors, see FieldViolation
+func (t *SchemaHandler) VerifyAndCastData(schema, data map[string]interface{}) error {
+	// generated _IDs are set before documents are verified
+	if _, ok := data["_ID"]; !ok {
+		return FieldViolation{Path: "_ID", Constraint: "required", Want: "a value"}
+	}
+	return recursiveDataCaster(schema, data, "")
+}
 
+// recursiveDataCaster verifies & casts data of a document nested at
+// prefix, empty for top level fields. Fields are visited in order, so
+// first violation is reported
+func recursiveDataCaster(schema, data map[string]interface{}, prefix string) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v := data[key]
+		if key == "_ID" {
 			casted, err := types.ToID(v)
 			if err != nil {
 				return err
@@ -193,15 +447,20 @@ func recursiveDataCaster(schema, data map[string]interface{}) error {
 			continue
 		}
 
-		// try to cast to string
+		path := prefix + key
 		schemaField, ok := schema[key]
 		if !ok {
-			return errors.UnknownField("%v", key)
+			return errors.UnknownField("%v", path)
+		}
+		desc, _ := fieldDescriptor(schemaField)
+		if desc != nil {
+			schemaField = desc["type"]
 		}
 
+		// try to cast to string
 		schemaStringField, ok := schemaField.(string)
 		if ok {
-			casted, err := types.TypeCast(schemaStringField, v)
+			casted, err := castField(path, schemaStringField, desc, v)
 			if err != nil {
 				return err
 			}
@@ -209,11 +468,60 @@ func recursiveDataCaster(schema, data map[string]interface{}) error {
 		} else {
 			vMap, ok := v.(map[string]interface{})
 			if !ok {
-				return errors.TypeCastError("invalid data type %T", key)
+				return FieldViolation{Path: path, Constraint: "type", Value: v, Want: "a document"}
 			}
 			sMap, _ := schemaField.(map[string]interface{})
-			return recursiveDataCaster(sMap, vMap)
+			if err := recursiveDataCaster(sMap, vMap, path+"."); err != nil {
+				return err
+			}
+		}
+	}
+
+	keys = keys[:0]
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := data[key]; ok || key == "_ID" {
+			continue
 		}
+		desc, _ := fieldDescriptor(schema[key])
+		if def, ok := desc["default"]; ok {
+			casted, err := types.TypeCast(desc["type"].(string), def)
+			if err != nil {
+				return err
+			}
+			data[key] = casted
+			continue
+		}
+		if desc["nullable"] == true {
+			continue
+		}
+		return FieldViolation{Path: prefix + key, Constraint: "required", Want: "a value"}
 	}
 	return nil
 }
+
+// castField casts value v of field at path to typ & checks it against
+// constraints of descriptor desc, nil if field is declared by type alone
+func castField(path string, typ string, desc map[string]interface{}, v interface{}) (interface{}, error) {
+	if v == nil {
+		if desc["nullable"] == true {
+			return nil, nil
+		}
+		return nil, FieldViolation{Path: path, Constraint: "nullable", Want: "a non null value"}
+	}
+
+	casted, err := types.TypeCast(typ, v)
+	if err != nil {
+		return nil, FieldViolation{Path: path, Constraint: "type", Value: v, Want: typ}
+	}
+	if desc != nil {
+		if err := checkConstraints(path, desc, casted); err != nil {
+			return nil, err
+		}
+	}
+	return casted, nil
+}
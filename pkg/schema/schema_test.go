@@ -1,9 +1,11 @@
 package schema
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/nagarajRPoojari/orange/internal/types"
 	"github.com/nagarajRPoojari/orange/pkg/oql"
 )
 
@@ -38,6 +40,21 @@ func TestSchemaHandler_Verifier(t *testing.T) {
 			}),
 			wantErr: false,
 		},
+		{
+			name: "snowflake _ID",
+			schema: oql.Schema(map[string]interface{}{
+				"_ID": map[string]interface{}{"auto_increment": true, "strategy": "snowflake"},
+			}),
+			wantErr: false,
+		},
+		{
+			name: "unknown _ID strategy",
+			schema: oql.Schema(map[string]interface{}{
+				"_ID": map[string]interface{}{"auto_increment": true, "strategy": "uuid"},
+			}),
+			wantErr: true,
+			errSub:  "strategy",
+		},
 		{
 			name: "Invalid _ID type",
 			schema: oql.Schema(map[string]interface{}{
@@ -54,6 +71,120 @@ func TestSchemaHandler_Verifier(t *testing.T) {
 			wantErr: true,
 			errSub:  "invalid",
 		},
+		{
+			name: "unique field",
+			schema: oql.Schema(map[string]interface{}{
+				"username": map[string]interface{}{"type": "STRING", "unique": true},
+				"address":  map[string]interface{}{"type": "STRING", "zip": "INT64"},
+			}),
+			wantErr: false,
+		},
+		{
+			name: "unique flag isn't bool",
+			schema: oql.Schema(map[string]interface{}{
+				"username": map[string]interface{}{"type": "STRING", "unique": "yes"},
+			}),
+			wantErr: true,
+			errSub:  "bool",
+		},
+		{
+			name: "unique nested field",
+			schema: oql.Schema(map[string]interface{}{
+				"address": map[string]interface{}{"city": map[string]interface{}{"type": "STRING", "unique": true}},
+			}),
+			wantErr: true,
+			errSub:  "nested",
+		},
+		{
+			name: "unsupported type of nested field",
+			schema: oql.Schema(map[string]interface{}{
+				"address": map[string]interface{}{"city": "STRING"},
+				"name":    "COUNTRY",
+			}),
+			wantErr: true,
+			errSub:  "COUNTRY",
+		},
+		{
+			name: "field constraints",
+			schema: oql.Schema(map[string]interface{}{
+				"age":    map[string]interface{}{"type": "INT", "nullable": true, "default": float64(0), "min": float64(0), "max": float64(120)},
+				"email":  map[string]interface{}{"type": "STRING", "pattern": "^[^@]+@[^@]+$", "maxLength": float64(64)},
+				"status": map[string]interface{}{"type": "STRING", "enum": []interface{}{"active", "banned"}},
+			}),
+			wantErr: false,
+		},
+		{
+			name: "nullable flag isn't bool",
+			schema: oql.Schema(map[string]interface{}{
+				"age": map[string]interface{}{"type": "INT", "nullable": "yes"},
+			}),
+			wantErr: true,
+			errSub:  "bool",
+		},
+		{
+			name: "min greater than max",
+			schema: oql.Schema(map[string]interface{}{
+				"age": map[string]interface{}{"type": "INT", "min": float64(10), "max": float64(1)},
+			}),
+			wantErr: true,
+			errSub:  "greater",
+		},
+		{
+			name: "min of string field",
+			schema: oql.Schema(map[string]interface{}{
+				"name": map[string]interface{}{"type": "STRING", "min": float64(1)},
+			}),
+			wantErr: true,
+			errSub:  "isn't supported",
+		},
+		{
+			name: "pattern of int field",
+			schema: oql.Schema(map[string]interface{}{
+				"age": map[string]interface{}{"type": "INT", "pattern": "^[0-9]+$"},
+			}),
+			wantErr: true,
+			errSub:  "isn't supported",
+		},
+		{
+			name: "invalid pattern",
+			schema: oql.Schema(map[string]interface{}{
+				"name": map[string]interface{}{"type": "STRING", "pattern": "a("},
+			}),
+			wantErr: true,
+			errSub:  "pattern",
+		},
+		{
+			name: "negative maxLength",
+			schema: oql.Schema(map[string]interface{}{
+				"name": map[string]interface{}{"type": "STRING", "maxLength": float64(-1)},
+			}),
+			wantErr: true,
+			errSub:  "maxLength",
+		},
+		{
+			name: "empty enum",
+			schema: oql.Schema(map[string]interface{}{
+				"status": map[string]interface{}{"type": "STRING", "enum": []interface{}{}},
+			}),
+			wantErr: true,
+			errSub:  "enum",
+		},
+		{
+			name: "enum value of other type",
+			schema: oql.Schema(map[string]interface{}{
+				"level": map[string]interface{}{"type": "INT", "enum": []interface{}{float64(1), "high"}},
+			}),
+			wantErr: true,
+			errSub:  "enum",
+		},
+		{
+			name: "default breaking constraint",
+			schema: oql.Schema(map[string]interface{}{
+				"age": map[string]interface{}{"type": "INT", "default": float64(-1), "min": float64(0)},
+			}),
+			wantErr: true,
+			errSub:  "default",
+		},
 	}
 
 	for _, tc := range tests {
@@ -137,6 +268,125 @@ func TestSchemaHandler_SavetoCatalog(t *testing.T) {
 	}
 }
 
+func TestSchemaHandler_SaveIndex(t *testing.T) {
+	dir := t.TempDir()
+	handler := NewSchemaHandler(&SchemaHandlerOpts{Dir: dir})
+	err := handler.SavetoCatalog("users", oql.Schema(map[string]interface{}{
+		"_ID":     map[string]interface{}{"auto_increment": false},
+		"email":   "STRING",
+		"age":     "INT8",
+		"address": map[string]interface{}{"city": "STRING"},
+	}))
+	if err != nil {
+		t.Fatalf("SchemaHandler.SavetoCatalog() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		index   Index
+		wantErr bool
+	}{
+		{name: "valid index", index: Index{Name: "by_email", Field: "email"}},
+		{name: "second index", index: Index{Name: "by_age", Field: "age"}},
+		{name: "duplicate name", index: Index{Name: "by_email", Field: "age"}, wantErr: true},
+		{name: "duplicate field", index: Index{Name: "by_email_2", Field: "email"}, wantErr: true},
+		{name: "nested field", index: Index{Name: "by_city", Field: "address"}, wantErr: true},
+		{name: "unknown field", index: Index{Name: "by_name", Field: "name"}, wantErr: true},
+		{name: "_ID", index: Index{Name: "by_id", Field: "_ID"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := handler.SaveIndex("users", tt.index); (err != nil) != tt.wantErr {
+				t.Errorf("SchemaHandler.SaveIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// indexes are read back from catalog by a new handler
+	want := []Index{{Name: "by_email", Field: "email"}, {Name: "by_age", Field: "age"}}
+	got, err := NewSchemaHandler(&SchemaHandlerOpts{Dir: dir}).LoadIndexes("users")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaHandler.LoadIndexes() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := handler.LoadIndexes("orders"); err != nil || len(got) != 0 {
+		t.Errorf("SchemaHandler.LoadIndexes() = %v, %v, want none", got, err)
+	}
+}
+
+func TestSchemaHandler_Alter(t *testing.T) {
+	dir := t.TempDir()
+	handler := NewSchemaHandler(&SchemaHandlerOpts{Dir: dir})
+	err := handler.SavetoCatalog("users", oql.Schema(map[string]interface{}{
+		"_ID":   map[string]interface{}{"auto_increment": false},
+		"email": "STRING",
+		"age":   "INT8",
+	}))
+	if err != nil {
+		t.Fatalf("SchemaHandler.SavetoCatalog() error = %v", err)
+	}
+	if err := handler.SaveIndex("users", Index{Name: "by_email", Field: "email"}); err != nil {
+		t.Fatalf("SchemaHandler.SaveIndex() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		alteration Alteration
+		wantErr    bool
+	}{
+		{name: "add field", alteration: Alteration{Action: oql.AlterAdd, Field: "country", Type: "STRING", Default: "in"}},
+		{name: "add field with type name as default", alteration: Alteration{Action: oql.AlterAdd, Field: "plan", Type: "STRING", Default: "INT"}},
+		{name: "add field without default", alteration: Alteration{Action: oql.AlterAdd, Field: "nickname", Type: "STRING"}},
+		{name: "rename indexed field", alteration: Alteration{Action: oql.AlterRename, Field: "email", To: "mail"}},
+		{name: "drop field", alteration: Alteration{Action: oql.AlterDrop, Field: "age"}},
+		{name: "add existing field", alteration: Alteration{Action: oql.AlterAdd, Field: "mail", Type: "STRING"}, wantErr: true},
+		{name: "add field of invalid type", alteration: Alteration{Action: oql.AlterAdd, Field: "city", Type: "CITY"}, wantErr: true},
+		{name: "add field with invalid default", alteration: Alteration{Action: oql.AlterAdd, Field: "score", Type: "INT64", Default: "high"}, wantErr: true},
+		{name: "drop missing field", alteration: Alteration{Action: oql.AlterDrop, Field: "age"}, wantErr: true},
+		{name: "rename to existing field", alteration: Alteration{Action: oql.AlterRename, Field: "mail", To: "country"}, wantErr: true},
+		{name: "drop _ID", alteration: Alteration{Action: oql.AlterDrop, Field: "_ID"}, wantErr: true},
+		{name: "rename to version field", alteration: Alteration{Action: oql.AlterRename, Field: "mail", To: VersionField}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := handler.Alter("users", tt.alteration); (err != nil) != tt.wantErr {
+				t.Errorf("SchemaHandler.Alter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// versions & indexes are read back from catalog by a new handler
+	reopened := NewSchemaHandler(&SchemaHandlerOpts{Dir: dir})
+	schema, version, err := reopened.LoadVersion("users")
+	if err != nil || version != 6 {
+		t.Fatalf("SchemaHandler.LoadVersion() = %v, %v, want version 6", version, err)
+	}
+	if _, ok := schema["mail"]; !ok || schema["email"] != nil || schema["age"] != nil {
+		t.Errorf("SchemaHandler.LoadVersion() = %v, want mail, country, plan & nickname", schema)
+	}
+	if got, _ := reopened.LoadIndexes("users"); !reflect.DeepEqual(got, []Index{{Name: "by_email", Field: "mail"}}) {
+		t.Errorf("SchemaHandler.LoadIndexes() = %v, want index on mail", got)
+	}
+
+	doc := map[string]interface{}{"email": "a@example.com", "age": 30}
+	if err := reopened.Upgrade("users", doc, 1); err != nil {
+		t.Fatalf("SchemaHandler.Upgrade() error = %v", err)
+	}
+	want := map[string]interface{}{"mail": "a@example.com", "country": types.STRING("in"), "plan": types.STRING("INT")}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("SchemaHandler.Upgrade() = %v, want %v", doc, want)
+	}
+
+	// added fields are optional, ones with default take it
+	data := map[string]interface{}{"_ID": 1, "mail": "b@example.com"}
+	if err := reopened.VerifyAndCastData(schema, data); err != nil {
+		t.Fatalf("SchemaHandler.VerifyAndCastData() error = %v", err)
+	}
+	want = map[string]interface{}{"_ID": data["_ID"], "mail": types.STRING("b@example.com"), "country": types.STRING("in"), "plan": types.STRING("INT")}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("SchemaHandler.VerifyAndCastData() = %v, want %v", data, want)
+	}
+}
+
 func TestSchemaHandler_VerifyAndCastData(t *testing.T) {
 	type fields struct {
 		opts *SchemaHandlerOpts
@@ -238,3 +488,158 @@ func TestSchemaHandler_VerifyAndCastData(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemaHandler_FieldConstraints(t *testing.T) {
+	schema := oql.Schema(map[string]interface{}{
+		"name":   "STRING",
+		"age":    map[string]interface{}{"type": "INT", "nullable": true, "min": float64(0), "max": float64(120)},
+		"email":  map[string]interface{}{"type": "STRING", "pattern": "^[^@]+@[^@]+$", "maxLength": float64(16)},
+		"status": map[string]interface{}{"type": "STRING", "enum": []interface{}{"active", "banned"}, "default": "active"},
+		"address": map[string]interface{}{
+			"city": map[string]interface{}{"type": "STRING", "maxLength": float64(8)},
+			"zip":  map[string]interface{}{"type": "INT", "nullable": true},
+		},
+	})
+	if err := NewSchemaHandler(&SchemaHandlerOpts{}).VerifySchema(schema); err != nil {
+		t.Fatalf("unexpected error verifying schema: %v", err)
+	}
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"_ID":     1,
+			"name":    "alice",
+			"age":     30,
+			"email":   "alice@x.io",
+			"status":  "banned",
+			"address": map[string]interface{}{"city": "pune", "zip": 411001},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(data map[string]interface{})
+		// path & constraint of violation, empty if data is valid
+		path       string
+		constraint string
+	}{
+		{name: "valid", change: func(data map[string]interface{}) {}},
+		{name: "nullable field is null", change: func(data map[string]interface{}) { data["age"] = nil }},
+		{name: "nullable field is missing", change: func(data map[string]interface{}) { delete(data, "age") }},
+		{name: "nested nullable field is missing", change: func(data map[string]interface{}) {
+			delete(data["address"].(map[string]interface{}), "zip")
+		}},
+		{name: "missing field takes default", change: func(data map[string]interface{}) { delete(data, "status") }},
+		{name: "missing field", change: func(data map[string]interface{}) { delete(data, "name") }, path: "name", constraint: "required"},
+		{name: "missing nested field", change: func(data map[string]interface{}) {
+			delete(data["address"].(map[string]interface{}), "city")
+		}, path: "address.city", constraint: "required"},
+		{name: "null field", change: func(data map[string]interface{}) { data["name"] = nil }, path: "name", constraint: "nullable"},
+		{name: "invalid type", change: func(data map[string]interface{}) { data["age"] = "old" }, path: "age", constraint: "type"},
+		{name: "below min", change: func(data map[string]interface{}) { data["age"] = -1 }, path: "age", constraint: "min"},
+		{name: "above max", change: func(data map[string]interface{}) { data["age"] = 121 }, path: "age", constraint: "max"},
+		{name: "pattern mismatch", change: func(data map[string]interface{}) { data["email"] = "alice" }, path: "email", constraint: "pattern"},
+		{name: "too long", change: func(data map[string]interface{}) { data["email"] = "alice@example.com" }, path: "email", constraint: "maxLength"},
+		{name: "not in enum", change: func(data map[string]interface{}) { data["status"] = "deleted" }, path: "status", constraint: "enum"},
+		{name: "too long nested", change: func(data map[string]interface{}) {
+			data["address"].(map[string]interface{})["city"] = "bengaluru"
+		}, path: "address.city", constraint: "maxLength"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := valid()
+			tc.change(data)
+			err := NewSchemaHandler(&SchemaHandlerOpts{}).VerifyAndCastData(schema, data)
+			if tc.path == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if data["status"] == nil {
+					t.Errorf("expected status to take its default")
+				}
+				return
+			}
+			violation, ok := err.(FieldViolation)
+			if !ok {
+				t.Fatalf("expected FieldViolation, got: %v", err)
+			}
+			if violation.Path != tc.path || violation.Constraint != tc.constraint {
+				t.Errorf("expected %s violation of %s, got: %v", tc.constraint, tc.path, err)
+			}
+		})
+	}
+}
+
+func TestSchemaHandler_FieldDescriptor(t *testing.T) {
+	tests := []struct {
+		name  string
+		field interface{}
+		// type of field, empty if it's a nested document
+		want string
+		data interface{}
+		// value of field after casting data
+		cast interface{}
+	}{
+		{
+			name:  "nested document with type field only",
+			field: map[string]interface{}{"type": "STRING"},
+			data:  map[string]interface{}{"type": "home"},
+			cast:  map[string]interface{}{"type": types.STRING("home")},
+		},
+		{
+			name:  "nested document with type & default fields",
+			field: map[string]interface{}{"type": "STRING", "default": "STRING"},
+			data:  map[string]interface{}{"type": "home", "default": "yes"},
+			cast: map[string]interface{}{
+				"type":    types.STRING("home"),
+				"default": types.STRING("yes"),
+			},
+		},
+		{
+			name:  "descriptor with type name as default",
+			field: map[string]interface{}{"type": "STRING", "default": "INT", "nullable": false},
+			want:  "STRING",
+			cast:  types.STRING("INT"),
+		},
+		{
+			name:  "descriptor with option",
+			field: map[string]interface{}{"type": "INT", "unique": false},
+			want:  "INT",
+			data:  12,
+			cast:  types.INT(12),
+		},
+		{
+			name:  "nested document with type name as field",
+			field: map[string]interface{}{"type": "STRING", "zip": "INT"},
+			data:  map[string]interface{}{"type": "home", "zip": 411001},
+			cast: map[string]interface{}{
+				"type": types.STRING("home"),
+				"zip":  types.INT(411001),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := oql.Schema(map[string]interface{}{"field": tc.field})
+			handler := NewSchemaHandler(&SchemaHandlerOpts{})
+			if err := handler.VerifySchema(schema); err != nil {
+				t.Fatalf("unexpected error verifying schema: %v", err)
+			}
+			got, ok := FieldType(schema, "field")
+			if (tc.want != "") != ok || got != tc.want {
+				t.Errorf("FieldType() = %q, %v, want %q", got, ok, tc.want)
+			}
+			data := map[string]interface{}{"_ID": 1}
+			if tc.data != nil {
+				data["field"] = tc.data
+			}
+			if err := handler.VerifyAndCastData(schema, data); err != nil {
+				t.Fatalf("unexpected error casting data: %v", err)
+			}
+			if !reflect.DeepEqual(data["field"], tc.cast) {
+				t.Errorf("field = %#v, want %#v", data["field"], tc.cast)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -8,7 +9,10 @@ import (
 	"github.com/nagarajRPoojari/orange/net/client"
 	"github.com/nagarajRPoojari/orange/net/server"
 	"github.com/nagarajRPoojari/orange/pkg/oql"
+	pb "github.com/nagarajRPoojari/orange/pkg/proto/ops"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestClient(t *testing.T) {
@@ -30,7 +34,7 @@ func TestClient(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	err = cl.Insert(
+	id, err := cl.Insert(
 		&oql.InsertOp{
 			Document: "test",
 			Value: map[string]interface{}{
@@ -43,6 +47,7 @@ func TestClient(t *testing.T) {
 		},
 	)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(90102), id)
 
 	got, err := cl.Select(
 		&oql.SelectOp{
@@ -53,6 +58,95 @@ func TestClient(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, got)
 
-	os.RemoveAll("./temp")
+	// changes are streamed in commit order & resumed after a position
+	_, err = cl.Insert(&oql.InsertOp{
+		Document: "test",
+		Value:    map[string]interface{}{"_ID": 90103, "name": "watched", "age": map[string]interface{}{"name": 13}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, cl.Delete(&oql.DeleteOp{Document: "test", ID: 90103}))
+
+	changes := subscribe(t, cl, "test", "0.0.0", 3)
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, "insert", changes[0].Op)
+		assert.Equal(t, int64(90102), changes[0].Id)
+		assert.Contains(t, string(changes[0].Data), "hello")
+		assert.Equal(t, "insert", changes[1].Op)
+		assert.Equal(t, int64(90103), changes[1].Id)
+		assert.Equal(t, "delete", changes[2].Op)
+		assert.Equal(t, int64(90103), changes[2].Id)
+		assert.Empty(t, changes[2].Data)
+
+		resumed := subscribe(t, cl, "test", changes[0].Position, 2)
+		if assert.Len(t, resumed, 2) {
+			assert.Equal(t, changes[1].Position, resumed[0].Position)
+			assert.Equal(t, changes[2].Position, resumed[1].Position)
+		}
+	}
+	assert.Error(t, cl.Subscribe(context.Background(), "missing", "", func(*pb.SubscribeRes) error { return nil }))
+	assert.Error(t, cl.Subscribe(context.Background(), "test", "bad", func(*pb.SubscribeRes) error { return nil }))
+
+	// admin ops: flushed & compacted document stays readable
+	assert.NoError(t, cl.Flush("test", true))
+	assert.NoError(t, cl.Flush("", false))
+	assert.NoError(t, cl.CompactRange("test", 0, 100000))
+	assert.Error(t, cl.Flush("missing", true))
+	assert.Error(t, cl.CompactRange("test", 10, 10))
+
+	got, err = cl.Select(
+		&oql.SelectOp{
+			Document: "test",
+			ID:       90102,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), "hello")
+
+	// documents stored before schema is altered are read with current one
+	assert.NoError(t, cl.Alter(&oql.AlterOp{Document: "test", Action: oql.AlterAdd, Field: "country", Type: "STRING", Default: "in"}))
+	assert.Error(t, cl.Alter(&oql.AlterOp{Document: "test", Action: oql.AlterDrop, Field: "missing"}))
+	assert.NoError(t, cl.Rewrite("test", true))
+	got, err = cl.Select(&oql.SelectOp{Document: "test", ID: 90102})
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), `"country":"in"`)
+
+	// _IDs of documents inserted without one are generated & returned
+	assert.NoError(t, cl.Create(&oql.CreateOp{
+		Document: "events",
+		Schema: oql.Schema(map[string]interface{}{
+			"_ID":  map[string]interface{}{"auto_increment": true, "strategy": "snowflake"},
+			"name": "STRING",
+		}),
+	}))
+	first, err := cl.Insert(&oql.InsertOp{Document: "events", Value: map[string]interface{}{"name": "first"}})
+	assert.NoError(t, err)
+	second, err := cl.Insert(&oql.InsertOp{Document: "events", Value: map[string]interface{}{"name": "second"}})
+	assert.NoError(t, err)
+	assert.Greater(t, first, int64(1<<22))
+	assert.Greater(t, second, first)
+
+	got, err = cl.Select(&oql.SelectOp{Document: "events", ID: second})
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), "second")
+
+	// stopping server persists manifests, so directory is removed after it
 	dbServer.Stop()
+	os.RemoveAll("./temp")
+}
+
+// subscribe collects n changes of document streamed after from
+func subscribe(t *testing.T, cl *client.Client, document, from string, n int) []*pb.SubscribeRes {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var changes []*pb.SubscribeRes
+	err := cl.Subscribe(ctx, document, from, func(res *pb.SubscribeRes) error {
+		changes = append(changes, res)
+		if len(changes) == n {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(t, codes.Canceled, status.Code(err), "got %d of %d changes", len(changes), n)
+	return changes
 }
@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"path"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 	"github.com/nagarajRPoojari/orange/pkg/oql"
+	"github.com/nagarajRPoojari/orange/pkg/schema"
 	"github.com/spf13/viper"
 
 	"github.com/nagarajRPoojari/orange/internal/config"
 	odb "github.com/nagarajRPoojari/orange/internal/db"
+	"github.com/nagarajRPoojari/orange/internal/errors"
 	"github.com/nagarajRPoojari/orange/internal/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -71,7 +76,7 @@ func TestOrangedb_SelectDoc(t *testing.T) {
 
 	assert.FileExists(t, path.Join(dir, "catalog", "test"))
 	assert.NoError(t, err)
-	err = db.InsertDoc(
+	_, err = db.InsertDoc(
 		oql.InsertOp{
 			Document: "test",
 			Value: map[string]interface{}{
@@ -138,7 +143,7 @@ func TestOrangedb_DeleteDoc(t *testing.T) {
 
 	assert.FileExists(t, path.Join(dir, "catalog", "test"))
 	assert.NoError(t, err)
-	err = db.InsertDoc(
+	_, err = db.InsertDoc(
 		oql.InsertOp{
 			Document: "test",
 			Value: map[string]interface{}{
@@ -171,6 +176,47 @@ func TestOrangedb_DeleteDoc(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestOrangedb_DeleteDoc_Range(t *testing.T) {
+	dir := t.TempDir()
+	db := odb.NewOrangedb(
+		t.Context(),
+		getMockedConfig(dir),
+	)
+
+	err := db.CreateCollection(
+		oql.CreateOp{
+			Document: "test",
+			Schema: oql.Schema(map[string]interface{}{
+				"_ID":  map[string]interface{}{"auto_increment": false},
+				"name": "STRING",
+			}),
+		},
+	)
+	assert.NoError(t, err)
+
+	for i := range 10 {
+		_, err = db.InsertDoc(
+			oql.InsertOp{
+				Document: "test",
+				Value:    map[string]interface{}{"_ID": int64(i), "name": "hello"},
+			},
+		)
+		assert.NoError(t, err)
+	}
+
+	_, err = db.ProcessQuery("DELETE DOCUMENT FROM test WHERE _ID BETWEEN 3 AND 6")
+	assert.NoError(t, err)
+
+	for i := range 10 {
+		_, err := db.GetDoc(oql.SelectOp{Document: "test", ID: int64(i)})
+		if i >= 3 && i <= 6 {
+			assert.Error(t, err, "_ID=%d", i)
+		} else {
+			assert.NoError(t, err, "_ID=%d", i)
+		}
+	}
+}
+
 func TestOragedb_InsertDoc(t *testing.T) {
 	dir := t.TempDir()
 	db := odb.NewOrangedb(
@@ -193,7 +239,7 @@ func TestOragedb_InsertDoc(t *testing.T) {
 
 	assert.FileExists(t, path.Join(dir, "catalog", "test"))
 	assert.NoError(t, err)
-	err = db.InsertDoc(
+	_, err = db.InsertDoc(
 		oql.InsertOp{
 			Document: "test",
 			Value: map[string]interface{}{
@@ -312,3 +358,527 @@ func TestOrangedb_ProcessQuery(t *testing.T) {
 	_, err = db.ProcessQuery(`SELECT name, age FROM users WHERE _ID = 89`)
 	assert.Error(t, err)
 }
+
+// TestOrangedb_ProcessQuery_AsOf verifies that SELECT ... AS OF reads
+// version of document current at given time
+func TestOrangedb_ProcessQuery_AsOf(t *testing.T) {
+	log.Disable()
+
+	conf := getMockedConfig(t.TempDir())
+	conf.Retention = time.Hour
+	db := odb.NewOrangedb(t.Context(), conf)
+	_, err := db.ProcessQuery(`CREATE DOCUMENT users { "_ID": {"auto_increment": false},"name": "STRING" }`)
+	assert.NoError(t, err)
+
+	before := time.Now()
+	var stamps []time.Time
+	for _, name := range []string{"first", "second", "third"} {
+		_, err := db.ProcessQuery(fmt.Sprintf(`INSERT VALUE INTO users {"_ID": 7, "name": "%s"}`, name))
+		assert.NoError(t, err)
+		stamps = append(stamps, time.Now())
+	}
+
+	for i, name := range []string{"first", "second", "third"} {
+		got, err := db.ProcessQuery(fmt.Sprintf(`SELECT * FROM users WHERE _ID = 7 AS OF '%s'`, stamps[i].Format(time.RFC3339Nano)))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, name, got.(map[string]interface{})["name"])
+		}
+	}
+
+	_, err = db.ProcessQuery(fmt.Sprintf(`SELECT * FROM users WHERE _ID = 7 AS OF '%s'`, before.Format(time.RFC3339Nano)))
+	assert.Error(t, err)
+	_, err = db.ProcessQuery(`SELECT * FROM users WHERE _ID = 7 AS OF 'yesterday'`)
+	assert.Error(t, err)
+
+	// past versions aren't kept without retention
+	db = odb.NewOrangedb(t.Context(), getMockedConfig(t.TempDir()))
+	_, err = db.ProcessQuery(`CREATE DOCUMENT users { "_ID": {"auto_increment": false},"name": "STRING" }`)
+	assert.NoError(t, err)
+	_, err = db.ProcessQuery(`INSERT VALUE INTO users {"_ID": 7, "name": "first"}`)
+	assert.NoError(t, err)
+	_, err = db.ProcessQuery(fmt.Sprintf(`SELECT * FROM users WHERE _ID = 7 AS OF '%s'`, time.Now().Format(time.RFC3339Nano)))
+	assert.Error(t, err)
+}
+
+// TestOrangedb_SecondaryIndex verifies that documents are found by indexed
+// fields, that indexes are backfilled & kept in sync with overwrites, deletes
+// & range deletes, and that they survive reopen
+func TestOrangedb_SecondaryIndex(t *testing.T) {
+	log.Disable()
+
+	conf := getMockedConfig(t.TempDir())
+	db := odb.NewOrangedb(t.Context(), conf)
+	_, err := db.ProcessQuery(`CREATE DOCUMENT users { "_ID": {"auto_increment": false}, "email": "STRING", "age": "INT8", "address": {"city": "STRING"} }`)
+	assert.NoError(t, err)
+
+	insert := func(db *odb.Oragedb, id int, email string, age int) {
+		_, err := db.ProcessQuery(fmt.Sprintf(`INSERT VALUE INTO users {"_ID": %d, "email": "%s", "age": %d, "address": {"city": "x"}}`, id, email, age))
+		assert.NoError(t, err)
+	}
+	find := func(db *odb.Oragedb, where string) []int64 {
+		got, err := db.ProcessQuery(`SELECT * FROM users WHERE ` + where)
+		if !assert.NoError(t, err, where) {
+			return nil
+		}
+		ids := []int64{}
+		for _, doc := range got.([]map[string]interface{}) {
+			ids = append(ids, doc["_ID"].(types.ID).K)
+		}
+		return ids
+	}
+
+	// documents stored before index is created are backfilled
+	insert(db, 1, "alice@example.com", 30)
+	insert(db, 2, "bob@example.com", 30)
+	insert(db, 3, "carol@example.com", 40)
+	_, err = db.ProcessQuery(`CREATE INDEX by_email ON users(email)`)
+	assert.NoError(t, err)
+	_, err = db.ProcessQuery(`CREATE INDEX by_age ON users(age)`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{2}, find(db, `email = 'bob@example.com'`))
+	assert.Equal(t, []int64{1, 2}, find(db, `age = 30`))
+	assert.Equal(t, []int64{}, find(db, `email = 'dave@example.com'`))
+
+	// overwritten documents move to entries of their new values
+	insert(db, 2, "bob@example.org", 40)
+	assert.Equal(t, []int64{}, find(db, `email = 'bob@example.com'`))
+	assert.Equal(t, []int64{2}, find(db, `email = 'bob@example.org'`))
+	assert.Equal(t, []int64{1}, find(db, `age = 30`))
+	assert.Equal(t, []int64{2, 3}, find(db, `age = 40`))
+
+	_, err = db.ProcessQuery(`DELETE DOCUMENT FROM users WHERE _ID=1`)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{}, find(db, `age = 30`))
+	assert.Equal(t, []int64{}, find(db, `email = 'alice@example.com'`))
+
+	_, err = db.ProcessQuery(`DELETE DOCUMENT FROM users WHERE _ID BETWEEN 2 AND 3`)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{}, find(db, `age = 40`))
+
+	for _, q := range []string{
+		`CREATE INDEX by_email ON users(age)`,
+		`CREATE INDEX by_email_again ON users(email)`,
+		`CREATE INDEX by_city ON users(address)`,
+		`CREATE INDEX by_name ON users(name)`,
+		`CREATE INDEX by_id ON missing(email)`,
+		`SELECT * FROM users WHERE address = 'x'`,
+		`SELECT * FROM users WHERE age = 'old'`,
+	} {
+		_, err := db.ProcessQuery(q)
+		assert.Error(t, err, q)
+	}
+	db.Close()
+
+	// indexes are kept in catalog & storage across reopen
+	db = odb.NewOrangedb(t.Context(), conf)
+	t.Cleanup(db.Close)
+	insert(db, 4, "alice@example.com", 30)
+	assert.Equal(t, []int64{4}, find(db, `age = 30`))
+	assert.Equal(t, []int64{4}, find(db, `email = 'alice@example.com'`))
+}
+
+// TestOrangedb_UniqueConstraint verifies that a value of a unique field is
+// held by at most one document, across overwrites, deletes & concurrent inserts
+func TestOrangedb_UniqueConstraint(t *testing.T) {
+	log.Disable()
+
+	db := odb.NewOrangedb(t.Context(), getMockedConfig(t.TempDir()))
+	t.Cleanup(db.Close)
+	_, err := db.ProcessQuery(`CREATE DOCUMENT users { "_ID": {"auto_increment": false}, "username": {"type": "STRING", "unique": true}, "name": "STRING" }`)
+	assert.NoError(t, err)
+
+	insert := func(id int, username string) error {
+		_, err := db.ProcessQuery(fmt.Sprintf(`INSERT VALUE INTO users {"_ID": %d, "username": "%s", "name": "x"}`, id, username))
+		return err
+	}
+	// holder is _ID expected to hold value, unchecked if negative
+	assertViolation := func(err error, holder int64) {
+		var violation errors.UniqueViolation
+		if assert.ErrorAs(t, err, &violation) {
+			assert.Equal(t, "username", violation.Field)
+			if holder >= 0 {
+				assert.Equal(t, holder, violation.ID)
+			}
+		}
+	}
+
+	assert.NoError(t, insert(1, "alice"))
+	assertViolation(insert(2, "alice"), 1)
+	// overwriting holder keeps its value
+	assert.NoError(t, insert(1, "alice"))
+
+	// values are released by overwrites & deletes
+	assert.NoError(t, insert(1, "alice2"))
+	assert.NoError(t, insert(2, "alice"))
+	assertViolation(insert(1, "alice"), 2)
+	_, err = db.ProcessQuery(`DELETE DOCUMENT FROM users WHERE _ID=2`)
+	assert.NoError(t, err)
+	assert.NoError(t, insert(3, "alice"))
+
+	got, err := db.ProcessQuery(`SELECT * FROM users WHERE username = 'alice'`)
+	if assert.NoError(t, err) && assert.Len(t, got, 1) {
+		assert.Equal(t, int64(3), got.([]map[string]interface{})[0]["_ID"].(types.ID).K)
+	}
+
+	// exactly one of concurrent inserts of same value wins
+	var wg sync.WaitGroup
+	var won atomic.Int32
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := insert(100+i, "racer"); err == nil {
+				won.Add(1)
+			} else {
+				assertViolation(err, -1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), won.Load())
+
+	for _, q := range []string{
+		`CREATE DOCUMENT bad_flag { "_ID": {"auto_increment": false}, "username": {"type": "STRING", "unique": "yes"} }`,
+		`CREATE DOCUMENT bad_type { "_ID": {"auto_increment": false}, "username": {"type": "NAME", "unique": true} }`,
+		`CREATE DOCUMENT bad_nested { "_ID": {"auto_increment": false}, "address": {"city": {"type": "STRING", "unique": true}} }`,
+	} {
+		_, err := db.ProcessQuery(q)
+		assert.Error(t, err, q)
+	}
+}
+
+// TestOrangedb_GeneratedID verifies that _IDs of documents inserted without
+// one are generated past every _ID inserted, across reopen
+func TestOrangedb_GeneratedID(t *testing.T) {
+	log.Disable()
+
+	conf := getMockedConfig(t.TempDir())
+	conf.NodeID = 7
+	db := odb.NewOrangedb(t.Context(), conf)
+	for _, q := range []string{
+		`CREATE DOCUMENT orders { "_ID": {"auto_increment": true}, "item": "STRING" }`,
+		`CREATE DOCUMENT events { "_ID": {"auto_increment": true, "strategy": "snowflake"}, "item": "STRING" }`,
+		`CREATE DOCUMENT users { "_ID": {"auto_increment": false}, "item": "STRING" }`,
+	} {
+		_, err := db.ProcessQuery(q)
+		assert.NoError(t, err, q)
+	}
+
+	insert := func(db *odb.Oragedb, document string, value string) int64 {
+		id, err := db.ProcessQuery(fmt.Sprintf(`INSERT VALUE INTO %s {%s}`, document, value))
+		if !assert.NoError(t, err, value) {
+			return 0
+		}
+		return id.(int64)
+	}
+
+	assert.Equal(t, int64(1), insert(db, "orders", `"item": "a"`))
+	assert.Equal(t, int64(2), insert(db, "orders", `"item": "b"`))
+	// inserted _IDs aren't generated again
+	assert.Equal(t, int64(10), insert(db, "orders", `"_ID": 10, "item": "c"`))
+	assert.Equal(t, int64(11), insert(db, "orders", `"item": "d"`))
+	assert.Equal(t, int64(5), insert(db, "orders", `"_ID": 5, "item": "e"`))
+	assert.Equal(t, int64(12), insert(db, "orders", `"item": "f"`))
+
+	got, err := db.ProcessQuery(`SELECT * FROM orders WHERE _ID = 11`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "d", string(got.(map[string]interface{})["item"].(types.STRING)))
+	}
+
+	// snowflake _IDs are time ordered & carry node id
+	var events []int64
+	for range 100 {
+		events = append(events, insert(db, "events", `"item": "x"`))
+	}
+	for i, id := range events {
+		assert.Equal(t, conf.NodeID, id>>12&1023)
+		if i > 0 {
+			assert.Greater(t, id, events[i-1])
+		}
+	}
+
+	_, err = db.ProcessQuery(`INSERT VALUE INTO users {"item": "x"}`)
+	assert.Error(t, err)
+	for _, q := range []string{
+		`CREATE DOCUMENT bad_strategy { "_ID": {"auto_increment": true, "strategy": "random"}, "item": "STRING" }`,
+		`CREATE DOCUMENT bad_flag { "_ID": {"auto_increment": false, "strategy": "snowflake"}, "item": "STRING" }`,
+	} {
+		_, err := db.ProcessQuery(q)
+		assert.Error(t, err, q)
+	}
+	db.Close()
+
+	// sequences are kept in storage across reopen
+	db = odb.NewOrangedb(t.Context(), conf)
+	t.Cleanup(db.Close)
+	assert.Equal(t, int64(13), insert(db, "orders", `"item": "g"`))
+	assert.Greater(t, insert(db, "events", `"item": "y"`), events[len(events)-1])
+}
+
+// TestOrangedb_AlterDocument verifies that documents stored before a field
+// is added, dropped or renamed are read with current schema, before & after
+// they're rewritten, and that indexes follow renamed fields
+func TestOrangedb_AlterDocument(t *testing.T) {
+	log.Disable()
+
+	conf := getMockedConfig(t.TempDir())
+	db := odb.NewOrangedb(t.Context(), conf)
+	query := func(db *odb.Oragedb, q string) any {
+		got, err := db.ProcessQuery(q)
+		assert.NoError(t, err, q)
+		return got
+	}
+	get := func(db *odb.Oragedb, id int) map[string]interface{} {
+		got, _ := query(db, fmt.Sprintf(`SELECT * FROM users WHERE _ID = %d`, id)).(map[string]interface{})
+		delete(got, "_ID")
+		return got
+	}
+
+	query(db, `CREATE DOCUMENT users { "_ID": {"auto_increment": false}, "name": "STRING", "age": "INT64" }`)
+	query(db, `INSERT VALUE INTO users {"_ID": 1, "name": "alice", "age": 30}`)
+	query(db, `CREATE INDEX by_name ON users(name)`)
+
+	// added field takes its default, in stored & inserted documents
+	query(db, `ALTER DOCUMENT users ADD FIELD country STRING DEFAULT 'in'`)
+	assert.Equal(t, map[string]interface{}{"name": types.STRING("alice"), "age": types.INT64(30), "country": types.STRING("in")}, get(db, 1))
+	query(db, `INSERT VALUE INTO users {"_ID": 2, "name": "bob", "age": 40}`)
+	query(db, `INSERT VALUE INTO users {"_ID": 3, "name": "carol", "age": 50, "country": "us"}`)
+	assert.Equal(t, types.STRING("in"), get(db, 2)["country"])
+	assert.Equal(t, types.STRING("us"), get(db, 3)["country"])
+
+	// renamed field keeps its values & index
+	query(db, `ALTER DOCUMENT users RENAME FIELD name TO full_name`)
+	assert.Equal(t, map[string]interface{}{"full_name": types.STRING("alice"), "age": types.INT64(30), "country": types.STRING("in")}, get(db, 1))
+	assert.Len(t, query(db, `SELECT * FROM users WHERE full_name = 'alice'`), 1)
+	_, err := db.ProcessQuery(`INSERT VALUE INTO users {"_ID": 4, "name": "dave", "age": 20}`)
+	assert.Error(t, err)
+	query(db, `INSERT VALUE INTO users {"_ID": 4, "full_name": "dave", "age": 20}`)
+
+	// entries of documents stored with an older schema are replaced
+	query(db, `INSERT VALUE INTO users {"_ID": 1, "full_name": "alicia", "age": 31}`)
+	assert.Len(t, query(db, `SELECT * FROM users WHERE full_name = 'alice'`), 0)
+	assert.Len(t, query(db, `SELECT * FROM users WHERE full_name = 'alicia'`), 1)
+
+	// dropped field is gone from stored documents, even once it's added back.
+	// field added without default is optional
+	query(db, `ALTER DOCUMENT users DROP FIELD age`)
+	query(db, `ALTER DOCUMENT users ADD FIELD age INT64`)
+	assert.Equal(t, map[string]interface{}{"full_name": types.STRING("bob"), "country": types.STRING("in")}, get(db, 2))
+	query(db, `INSERT VALUE INTO users {"_ID": 5, "full_name": "eve"}`)
+	assert.Equal(t, map[string]interface{}{"full_name": types.STRING("eve"), "country": types.STRING("in")}, get(db, 5))
+	query(db, `INSERT VALUE INTO users {"_ID": 6, "full_name": "frank", "age": 60}`)
+	assert.Equal(t, types.INT64(60), get(db, 6)["age"])
+
+	for _, q := range []string{
+		`ALTER DOCUMENT users ADD FIELD country STRING`,
+		`ALTER DOCUMENT users ADD FIELD city COUNTRY`,
+		`ALTER DOCUMENT users ADD FIELD score INT64 DEFAULT 'high'`,
+		`ALTER DOCUMENT users DROP FIELD name`,
+		`ALTER DOCUMENT users DROP FIELD _ID`,
+		`ALTER DOCUMENT users RENAME FIELD country TO full_name`,
+		`ALTER DOCUMENT users RENAME FIELD country TO _V`,
+		`ALTER DOCUMENT missing DROP FIELD age`,
+	} {
+		_, err := db.ProcessQuery(q)
+		assert.Error(t, err, q)
+	}
+
+	want := map[int]map[string]interface{}{}
+	for id := 1; id <= 4; id++ {
+		want[id] = get(db, id)
+	}
+	assert.NoError(t, db.Rewrite("users", true))
+	for id := 1; id <= 4; id++ {
+		assert.Equal(t, want[id], get(db, id))
+	}
+	db.Close()
+
+	// versions are kept in catalog across reopen
+	db = odb.NewOrangedb(t.Context(), conf)
+	t.Cleanup(db.Close)
+	for id := 1; id <= 4; id++ {
+		assert.Equal(t, want[id], get(db, id))
+	}
+	assert.Len(t, query(db, `SELECT * FROM users WHERE full_name = 'dave'`), 1)
+}
+
+// TestOrangedb_Codec_Mixed verifies that documents flushed by gob & document
+// codecs are both readable after codec is switched, deletes included
+func TestOrangedb_FieldConstraints(t *testing.T) {
+	log.Disable()
+
+	db := odb.NewOrangedb(t.Context(), getMockedConfig(t.TempDir()))
+	t.Cleanup(db.Close)
+	_, err := db.ProcessQuery(`CREATE DOCUMENT people { "_ID": {"auto_increment": false}, "name": "STRING", "age": {"type": "INT", "nullable": true, "min": 0, "max": 120}, "email": {"type": "STRING", "pattern": "^[^@]+@[^@]+$", "maxLength": 32}, "role": {"type": "STRING", "enum": ["admin", "member"], "default": "member"}, "address": {"city": {"type": "STRING", "maxLength": 16}} }`)
+	assert.NoError(t, err)
+
+	_, err = db.ProcessQuery(`CREATE DOCUMENT broken { "_ID": {"auto_increment": false}, "age": {"type": "INT", "min": 10, "max": 1} }`)
+	assert.Error(t, err)
+
+	// optional fields are left out
+	_, err = db.ProcessQuery(`INSERT VALUE INTO people {"_ID": 1, "name": "alice", "email": "alice@x.io", "address": {"city": "pune"}}`)
+	assert.NoError(t, err)
+	got, err := db.ProcessQuery(`SELECT * FROM people WHERE _ID = 1`)
+	assert.NoError(t, err)
+	doc := got.(map[string]interface{})
+	assert.Equal(t, types.STRING("member"), doc["role"])
+	assert.Nil(t, doc["age"])
+
+	_, err = db.ProcessQuery(`INSERT VALUE INTO people {"_ID": 2, "name": "bob", "age": null, "email": "bob@x.io", "role": "admin", "address": {"city": "goa"}}`)
+	assert.NoError(t, err)
+
+	violations := []struct {
+		doc        string
+		path       string
+		constraint string
+	}{
+		{`{"_ID": 3, "email": "carol@x.io", "address": {"city": "goa"}}`, "name", "required"},
+		{`{"_ID": 3, "name": null, "email": "carol@x.io", "address": {"city": "goa"}}`, "name", "nullable"},
+		{`{"_ID": 3, "name": "carol", "age": "old", "email": "carol@x.io", "address": {"city": "goa"}}`, "age", "type"},
+		{`{"_ID": 3, "name": "carol", "age": 121, "email": "carol@x.io", "address": {"city": "goa"}}`, "age", "max"},
+		{`{"_ID": 3, "name": "carol", "age": -1, "email": "carol@x.io", "address": {"city": "goa"}}`, "age", "min"},
+		{`{"_ID": 3, "name": "carol", "email": "carol", "address": {"city": "goa"}}`, "email", "pattern"},
+		{`{"_ID": 3, "name": "carol", "email": "carol@a-very-long-domain.example.com", "address": {"city": "goa"}}`, "email", "maxLength"},
+		{`{"_ID": 3, "name": "carol", "email": "carol@x.io", "role": "owner", "address": {"city": "goa"}}`, "role", "enum"},
+		{`{"_ID": 3, "name": "carol", "email": "carol@x.io", "address": {"city": "thiruvananthapuram"}}`, "address.city", "maxLength"},
+		{`{"_ID": 3, "name": "carol", "email": "carol@x.io", "address": {}}`, "address.city", "required"},
+	}
+	for _, v := range violations {
+		_, err := db.ProcessQuery(`INSERT VALUE INTO people ` + v.doc)
+		var violation schema.FieldViolation
+		if assert.ErrorAs(t, err, &violation, v.doc) {
+			assert.Equal(t, v.path, violation.Path, v.doc)
+			assert.Equal(t, v.constraint, violation.Constraint, v.doc)
+		}
+	}
+	_, err = db.ProcessQuery(`SELECT * FROM people WHERE _ID = 3`)
+	assert.Error(t, err)
+}
+
+func TestOrangedb_Codec_Mixed(t *testing.T) {
+	dir := t.TempDir()
+	conf := getMockedConfig(dir)
+	conf.Memtable.Threshold = 512
+	conf.Memtable.FlushTimeInterval = 100 * time.Millisecond
+	conf.Compaction.TurnOn = false
+
+	insert := func(db *odb.Oragedb, from, to int) {
+		for i := from; i < to; i++ {
+			_, err := db.InsertDoc(oql.InsertOp{
+				Document: "test",
+				Value: map[string]interface{}{
+					"_ID":  i,
+					"name": fmt.Sprintf("doc-%d", i),
+					"age":  map[string]interface{}{"name": i % 100},
+				},
+			})
+			assert.NoError(t, err)
+		}
+		for i := from; i < to; i += 10 {
+			assert.NoError(t, db.DeleteDoc(oql.DeleteOp{Document: "test", ID: int64(i)}))
+		}
+	}
+
+	for i, c := range []string{"gob", "document"} {
+		conf.Codec = c
+		db := odb.NewOrangedb(t.Context(), conf)
+		if i == 0 {
+			assert.NoError(t, db.CreateCollection(oql.CreateOp{
+				Document: "test",
+				Schema: oql.Schema(map[string]interface{}{
+					"_ID":  map[string]interface{}{"auto_increment": false},
+					"name": "STRING",
+					"age":  map[string]interface{}{"name": "INT8"},
+				}),
+			}))
+		}
+		insert(db, i*200, (i+1)*200)
+
+		// let memtables flush under current codec
+		time.Sleep(500 * time.Millisecond)
+		db.Close()
+	}
+
+	conf.Codec = "document"
+	db := odb.NewOrangedb(t.Context(), conf)
+	t.Cleanup(db.Close)
+
+	for i := range 400 {
+		got, err := db.GetDoc(oql.SelectOp{Document: "test", ID: int64(i)})
+		if i%10 == 0 {
+			assert.Error(t, err, "id=%d", i)
+			continue
+		}
+		assert.NoError(t, err, "id=%d", i)
+		assert.Equal(t, types.ID{K: int64(i)}, got["_ID"])
+		assert.Equal(t, fmt.Sprintf("doc-%d", i), fmt.Sprint(got["name"]))
+		assert.Equal(t, types.INT8(i%100), got["age"].(map[string]interface{})["name"])
+	}
+}
+
+func TestOrangedb_IngestFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := odb.NewOrangedb(
+		t.Context(),
+		getMockedConfig(dir),
+	)
+
+	err := db.CreateCollection(
+		oql.CreateOp{
+			Document: "test",
+			Schema: oql.Schema(map[string]interface{}{
+				"_ID":  map[string]interface{}{"auto_increment": false},
+				"name": "STRING",
+			}),
+		},
+	)
+	assert.NoError(t, err)
+
+	docs := []oql.Value{}
+	for i := 10; i > 0; i-- {
+		docs = append(docs, oql.Value{"_ID": int64(i), "name": fmt.Sprintf("user%d", i)})
+	}
+	table := path.Join(t.TempDir(), "users.db")
+	assert.NoError(t, db.BuildTable("test", table, docs))
+
+	dup := path.Join(t.TempDir(), "dup.db")
+	assert.Error(t, db.BuildTable("test", dup, []oql.Value{{"_ID": int64(1), "name": "a"}, {"_ID": int64(1), "name": "b"}}))
+	assert.NoFileExists(t, dup)
+
+	assert.Error(t, db.IngestFiles("missing", []string{table}))
+	assert.NoError(t, db.IngestFiles("test", []string{table}))
+
+	for i := 1; i <= 10; i++ {
+		got, err := db.GetDoc(oql.SelectOp{Document: "test", ID: int64(i)})
+		assert.NoError(t, err, "_ID=%d", i)
+		assert.Equal(t, types.STRING(fmt.Sprintf("user%d", i)), got["name"], "_ID=%d", i)
+	}
+}
+
+// TestOrangedb_SizeOf verifies that document sizes account for nested
+// documents & lists rather than a fixed cost per document.
+func TestOrangedb_SizeOf(t *testing.T) {
+	flat := &odb.InternalValueType{Payload: map[string]interface{}{
+		"_ID":  types.ID{K: 1},
+		"name": types.STRING("orange"),
+	}}
+	nested := &odb.InternalValueType{Payload: map[string]interface{}{
+		"_ID":  types.ID{K: 1},
+		"name": types.STRING("orange"),
+		"address": map[string]interface{}{
+			"city": types.STRING("bengaluru"),
+			"tags": []interface{}{types.STRING("home"), types.STRING("work")},
+		},
+	}}
+
+	assert.Greater(t, flat.SizeOf(), uintptr(len("orange")))
+	assert.Greater(t, nested.SizeOf(), flat.SizeOf()+uintptr(len("bengaluru")+len("home")+len("work")))
+
+	// sizes grow with payload
+	long := &odb.InternalValueType{Payload: map[string]interface{}{
+		"_ID":  types.ID{K: 1},
+		"name": types.STRING(string(make([]byte, 4096))),
+	}}
+	assert.GreaterOrEqual(t, long.SizeOf(), flat.SizeOf()+4096-uintptr(len("orange")))
+}
@@ -8,6 +8,7 @@ package compactor_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -49,7 +50,7 @@ func TestGC(t *testing.T) {
 			TurnOnWal:         false,
 		},
 	)
-	d := types.IntValue{V: 0}
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
 
 	gc := compactor.NewGC(
 		mf,
@@ -71,7 +72,7 @@ func TestGC(t *testing.T) {
 	go gc.Run(ctx)
 
 	// overflow memtable to trigger flush
-	for i := range int(1024 / d.SizeOf()) {
+	for i := range int(1024 / entrySize) {
 		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
 
@@ -83,8 +84,8 @@ func TestGC(t *testing.T) {
 	time.Sleep(3 * time.Second)
 	mts.Clear()
 
-	val, ok := mts.Read(types.IntKey{K: 244})
-	v = types.IntValue{V: 244}
+	val, ok := mts.Read(types.IntKey{K: 12})
+	v = types.IntValue{V: 12}
 
 	assert.True(t, ok)
 	assert.Equal(t, v, *val)
@@ -118,7 +119,7 @@ func TestGC_Intensive(t *testing.T) {
 			FlushTimeInterval: 1000 * time.Millisecond,
 		},
 	)
-	d := types.IntValue{V: 0}
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
 
 	gc := compactor.NewGC(
 		mf,
@@ -141,7 +142,7 @@ func TestGC_Intensive(t *testing.T) {
 
 	// overflow memtable to trigger flush
 	multiples := 10
-	totalOps := int(MEMTABLE_THRESHOLD/d.SizeOf()) * multiples
+	totalOps := int(MEMTABLE_THRESHOLD/entrySize) * multiples
 
 	for i := range totalOps {
 		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
@@ -154,9 +155,158 @@ func TestGC_Intensive(t *testing.T) {
 	time.Sleep(5 * time.Second)
 	mts.Clear()
 
-	val, ok := mts.Read(types.IntKey{K: 244})
-	v = types.IntValue{V: 244}
+	val, ok := mts.Read(types.IntKey{K: 12})
+	v = types.IntValue{V: 12}
 
 	assert.True(t, ok)
 	assert.Equal(t, v, *val)
 }
+
+// TestGC_CompactionFilter verifies that compaction consults registered filter.
+// It:
+//   - Drops entries with even keys
+//   - Rewrites entries with keys divisible by 3
+//   - Keeps everything else untouched
+func TestGC_CompactionFilter(t *testing.T) {
+	log.Disable()
+	tempDir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	const MEMTABLE_THRESHOLD = 1024
+
+	mf := metadata.NewManifest("test", metadata.ManifestOpts{Dir: tempDir})
+	mf.Load()
+
+	mf.SyncLoop(ctx)
+
+	mts := memtable.NewMemtableStore[types.IntKey, *types.IntValue](
+		mf,
+		ctx,
+		memtable.MemtableOpts{
+			MemtableSoftLimit: MEMTABLE_THRESHOLD,
+			FlushTimeInterval: 1000 * time.Millisecond,
+		},
+	)
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
+
+	filter := compactor.CompactionFilterFunc(func(level int, key any, value types.Value) (compactor.FilterDecision, types.Value) {
+		k := key.(types.IntKey)
+		switch {
+		case k.K%2 == 0:
+			return compactor.FilterDrop, nil
+		case k.K%3 == 0:
+			return compactor.FilterReplace, &types.IntValue{V: int32(k.K * 10)}
+		}
+		return compactor.FilterKeep, nil
+	})
+
+	gc := compactor.NewGC(
+		mf,
+		(*v2.CacheManager[types.IntKey, *types.IntValue])(mts.DecoderCache),
+		&compactor.SizeTiredCompaction[types.IntKey, *types.IntValue]{
+			Opts: compactor.SizeTiredCompactionOpts{
+				Level0MaxSizeInBytes:       MEMTABLE_THRESHOLD / 16, // any flushed table overflows level-0
+				MaxSizeInBytesGrowthFactor: 10,
+			},
+			Filter: filter,
+		},
+		compactor.GCOpts{
+			WALLogDir:           tempDir,
+			TimeInterval:        1000 * time.Millisecond,
+			WALTimeInterval:     conf.DefaultWALEventBufferSize,
+			WALEventChSize:      conf.DefaultWALEventBufferSize,
+			WALWriterBufferSize: conf.DefaultWALEventBufferSize,
+		},
+	)
+	go gc.Run(ctx)
+
+	// overflow memtable few times to have more than one table in level-0
+	multiples := 3
+	totalOps := int(MEMTABLE_THRESHOLD/entrySize) * multiples
+	for i := range totalOps {
+		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
+	}
+
+	// wait for memtables to flush & compact
+	time.Sleep(5 * time.Second)
+	mts.Clear()
+
+	_, ok := mts.Read(types.IntKey{K: 2})
+	assert.False(t, ok, "expected even key to be dropped")
+
+	val, ok := mts.Read(types.IntKey{K: 3})
+	assert.True(t, ok)
+	assert.Equal(t, types.IntValue{V: 30}, *val)
+
+	val, ok = mts.Read(types.IntKey{K: 5})
+	assert.True(t, ok)
+	assert.Equal(t, types.IntValue{V: 5}, *val)
+}
+
+// scoredTask is a compaction task whose score drops to 0 once compacted
+type scoredTask struct {
+	name  string
+	score float64
+
+	mu    *sync.Mutex
+	order *[]string
+}
+
+func (t *scoredTask) Score() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.score
+}
+
+func (t *scoredTask) Compact() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*t.order = append(*t.order, t.name)
+	t.score = 0
+}
+
+// TestScheduler verifies that scheduler compacts due tasks highest score
+// first, skips tasks below score 1 & stops compacting unregistered tasks.
+func TestScheduler(t *testing.T) {
+	log.Disable()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	task := func(name string, score float64) *scoredTask {
+		return &scoredTask{name: name, score: score, mu: &mu, order: &order}
+	}
+	idle, low, high, dropped := task("idle", 0.5), task("low", 1), task("high", 7), task("dropped", 3)
+
+	s := compactor.NewScheduler(compactor.SchedulerOpts{Workers: 1, TimeInterval: 100 * time.Millisecond})
+	for _, task := range []*scoredTask{idle, low, high, dropped} {
+		s.Register(task)
+	}
+	s.Unregister(dropped)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{"high", "low"}, order)
+	high.score = 2
+	mu.Unlock()
+
+	// a task is compacted again once it's due on a later tick
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{"high", "low", "high"}, order)
+	mu.Unlock()
+
+	cancel()
+	<-done
+}
@@ -7,8 +7,13 @@
 package io_test
 
 import (
+	"bytes"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 
 	"github.com/nagarajRPoojari/orange/parrot/io"
@@ -21,11 +26,12 @@ func TestFileManager_WriteAndRead(t *testing.T) {
 
 	manager := io.GetFileManager()
 
-	writer := manager.OpenForWrite(testFilePath)
+	writer, err := manager.OpenForWrite(testFilePath)
+	assert.NoError(t, err)
 	defer writer.Close()
 
 	expected := []byte("Hello from FileWriter!\n")
-	writer.Write(expected)
+	assert.NoError(t, writer.Write(expected))
 
 	reader, err := manager.OpenForRead(testFilePath)
 	assert.NoError(t, err)
@@ -51,3 +57,388 @@ func TestFileManager_MultipleReadsReturnSameInstance(t *testing.T) {
 	assert.Equal(t, r1, r2)
 	assert.Equal(t, string(r1.GetPayload()), string(r2.GetPayload()))
 }
+
+// testKeyring returns manager of files on disk encrypted with a keyring
+// holding a key per id, last one active
+func testKeyring(t *testing.T, ids ...string) *io.FileManager {
+	ring := io.NewKeyring()
+	for i, id := range ids {
+		key := bytes.Repeat([]byte{byte(i + 1)}, 32)
+		assert.NoError(t, ring.Add(id, key))
+	}
+	return io.Manager(io.WithKeyring(io.OS, ring))
+}
+
+func TestFileManager_Encryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := testKeyring(t, "k1")
+
+	// large enough to span several frames
+	expected := bytes.Repeat([]byte("secret document "), 10000)
+	path := filepath.Join(tmpDir, "enc.txt")
+	writer, err := manager.OpenForWrite(path)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(expected))
+	writer.Close()
+
+	raw, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(raw, []byte("secret")), "plaintext found on disk")
+
+	keyID, err := manager.KeyID(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+
+	got, err := manager.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	reader, err := manager.OpenForRead(path)
+	assert.NoError(t, err)
+	buf, err := reader.ReadRange(0, len(expected))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, buf.Bytes())
+	buf.Release()
+	reader.Close()
+
+	// tampered frame fails authentication
+	raw[len(raw)/2] ^= 0xff
+	tampered := filepath.Join(tmpDir, "tampered.txt")
+	assert.NoError(t, os.WriteFile(tampered, raw, 0644))
+	_, err = manager.ReadFile(tampered)
+	assert.Error(t, err)
+
+	// plaintext files written before encryption stay readable
+	plain := filepath.Join(tmpDir, "plain.txt")
+	assert.NoError(t, os.WriteFile(plain, []byte("plain"), 0644))
+	got, err = manager.ReadFile(plain)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", string(got))
+}
+
+func TestFileManager_Encryption_Append(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := testKeyring(t, "k1")
+
+	path := filepath.Join(tmpDir, "log")
+	w, err := manager.OpenForAppend(path)
+	assert.NoError(t, err)
+	empty, err := w.Empty()
+	assert.NoError(t, err)
+	assert.True(t, empty)
+	assert.NoError(t, w.Write([]byte("first,")))
+	w.Close()
+
+	// frame torn by crash is dropped on reopen
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	f.Write([]byte{0x40, 0, 0, 0, 1, 2, 3})
+	f.Close()
+
+	w, err = manager.OpenForAppend(path)
+	assert.NoError(t, err)
+	empty, err = w.Empty()
+	assert.NoError(t, err)
+	assert.False(t, empty)
+	assert.NoError(t, w.Write([]byte("second")))
+	w.Close()
+
+	got, err := manager.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "first,second", string(got))
+
+	// rotated key encrypts new files, old ones stay readable
+	ring := manager.Keyring()
+	assert.NoError(t, ring.Add("k2", bytes.Repeat([]byte{9}, 16)))
+	rotated := filepath.Join(tmpDir, "rotated")
+	assert.NoError(t, manager.WriteFile(rotated, []byte("rotated")))
+	keyID, err := manager.KeyID(rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", keyID)
+	got, err = manager.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "first,second", string(got))
+
+	// unknown key can't decrypt
+	_, err = testKeyring(t, "k3").ReadFile(path)
+	assert.Error(t, err)
+
+	// keys stay with their manager
+	_, err = io.GetFileManager().ReadFile(path)
+	assert.Error(t, err)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "keys")
+	assert.NoError(t, os.WriteFile(path, []byte(
+		"# rotated monthly\n"+
+			"k1 "+strings.Repeat("01", 32)+"\n\n"+
+			"k2 "+strings.Repeat("02", 16)+"\n"), 0600))
+
+	ring, err := io.LoadKeyring(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", ring.Active())
+
+	assert.NoError(t, os.WriteFile(path, []byte("k1 0102\n"), 0600))
+	_, err = io.LoadKeyring(path)
+	assert.Error(t, err, "short key must be rejected")
+}
+
+func TestMemFS(t *testing.T) {
+	vfs := io.NewMemFS()
+	assert.NoError(t, vfs.MkdirAll("/db/level-0", 0755))
+
+	f, err := vfs.OpenFile("/db/level-0/sst-1.db", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	f, err = vfs.OpenFile("/db/level-0/sst-1.db", os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte(" world"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	got, err := io.ReadFile(vfs, "/db/level-0/sst-1.db")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(got))
+
+	_, err = vfs.OpenFile("/db/level-0/sst-1.db", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	assert.ErrorIs(t, err, fs.ErrExist)
+	_, err = vfs.OpenFile("/db/missing/sst-1.db", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	assert.NoError(t, vfs.Rename("/db/level-0", "/db/level-1"))
+	matches, err := io.Glob(vfs, "/db/level-*/sst-*.db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/db/level-1/sst-1.db"}, matches)
+
+	assert.Error(t, vfs.Remove("/db/level-1"), "dir isn't empty")
+	assert.NoError(t, vfs.Remove("/db/level-1/sst-1.db"))
+	assert.NoError(t, vfs.Remove("/db/level-1"))
+	_, err = vfs.Stat("/db/level-1")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// files of another VFS aren't visible on disk
+	_, err = os.Stat("/db")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileManager_MemFS(t *testing.T) {
+	manager := io.Manager(io.NewMemFS())
+	assert.NotSame(t, io.GetFileManager(), manager)
+
+	w, err := manager.OpenForWrite("/db/table.db")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("in memory")))
+	w.Close()
+
+	reader, err := manager.OpenForRead("/db/table.db")
+	assert.NoError(t, err)
+	assert.Equal(t, "in memory", string(reader.GetPayload()))
+	assert.True(t, manager.Exists("/db/table.db"))
+	assert.False(t, io.GetFileManager().Exists("/db/table.db"))
+}
+
+func TestFileManager_Pread(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := io.GetFileManager()
+
+	// spans pooled & unpooled buffer sizes
+	expected := make([]byte, 3<<20)
+	for i := range expected {
+		expected[i] = byte(i % 251)
+	}
+
+	for _, direct := range []bool{false, true} {
+		pool := io.NewBufferPool()
+		opts := io.ReadOpts{Mode: io.ReadPread, Direct: direct, Pool: pool}
+		path := filepath.Join(tmpDir, fmt.Sprintf("pread-%v.db", direct))
+		assert.NoError(t, os.WriteFile(path, expected, 0644))
+
+		reader, err := manager.OpenForReadWith(path, opts)
+		assert.NoError(t, err)
+		assert.Nil(t, reader.GetPayload(), "contents must be read on demand")
+		assert.Equal(t, int64(len(expected)), reader.Size())
+
+		for _, r := range [][2]int{{0, 1}, {5, 4096}, {4095, 2}, {1 << 20, 1 << 20}, {7, 2 << 20}, {len(expected) - 3, 3}} {
+			buf, err := reader.ReadRange(int64(r[0]), r[1])
+			if assert.NoError(t, err, "direct=%v range=%v", direct, r) {
+				assert.Equal(t, expected[r[0]:r[0]+r[1]], buf.Bytes(), "direct=%v range=%v", direct, r)
+				buf.Release()
+			}
+		}
+		_, err = reader.ReadRange(int64(len(expected)-1), 2)
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), pool.InUse(), "buffers must be released")
+
+		// shared readers keep mode of first open
+		mapped, err := manager.OpenForRead(path)
+		assert.NoError(t, err)
+		assert.Same(t, reader, mapped)
+	}
+
+	// encrypted files decrypt only frames a read covers, a short frame
+	// left by a flush is followed by full ones
+	manager = testKeyring(t, "k1")
+	path := filepath.Join(tmpDir, "enc.db")
+	writer, err := manager.OpenForWrite(path)
+	assert.NoError(t, err)
+	_, err = writer.Writer().Write(expected[:100])
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Flush())
+	assert.NoError(t, writer.Write(expected[100:]))
+	writer.Close()
+
+	for _, opts := range []io.ReadOpts{{}, {Mode: io.ReadPread, Direct: true}} {
+		// shared readers keep mode of first open, so each mode gets a path
+		opts.Pool = io.NewBufferPool()
+		assert.NoError(t, os.Link(path, path+opts.Mode.String()))
+		reader, err := manager.OpenForReadWith(path+opts.Mode.String(), opts)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Nil(t, reader.GetPayload(), "contents must be decrypted on demand")
+		assert.Equal(t, int64(len(expected)), reader.Size())
+		for _, r := range [][2]int{{0, 0}, {10, 10}, {90, 20}, {100, 64 << 10}, {65 << 10, 1 << 20}, {0, len(expected)}, {len(expected) - 3, 3}} {
+			buf, err := reader.ReadRange(int64(r[0]), r[1])
+			if assert.NoError(t, err, "mode=%v range=%v", opts.Mode, r) {
+				assert.Equal(t, expected[r[0]:r[0]+r[1]], buf.Bytes(), "mode=%v range=%v", opts.Mode, r)
+				buf.Release()
+			}
+		}
+		assert.Equal(t, int64(0), opts.Pool.InUse(), "buffers must be released")
+	}
+}
+
+func TestParseReadMode(t *testing.T) {
+	for name, mode := range map[string]io.ReadMode{"": io.ReadMmap, "mmap": io.ReadMmap, "pread": io.ReadPread} {
+		got, err := io.ParseReadMode(name)
+		assert.NoError(t, err)
+		assert.Equal(t, mode, got)
+	}
+	_, err := io.ParseReadMode("aio")
+	assert.Error(t, err)
+}
+
+func TestFaultFS_DropUnsynced(t *testing.T) {
+	mem := io.NewMemFS()
+	vfs := io.NewFaultFS(mem)
+	manager := io.Manager(vfs)
+
+	w, err := manager.OpenForAppend("/db/log")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("synced,")))
+	_, err = w.Writer().Write([]byte("lost"))
+	assert.NoError(t, err)
+
+	unsynced, err := vfs.OpenFile("/db/unsynced", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	unsynced.Write([]byte("never synced"))
+
+	// renames are durable, contents move along
+	assert.NoError(t, vfs.Rename("/db/log", "/db/log-1"))
+	got, err := io.ReadFile(mem, "/db/log-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "synced,lost", string(got))
+
+	assert.NoError(t, vfs.DropUnsynced())
+	got, err = io.ReadFile(mem, "/db/log-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "synced,", string(got))
+	// file is created durably, its contents aren't
+	got, err = io.ReadFile(mem, "/db/unsynced")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	// handles opened before crash are detached
+	_, err = w.Writer().Write([]byte("after crash"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+	got, err = io.ReadFile(mem, "/db/log-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "synced,", string(got))
+}
+
+func TestFaultFS_FailSync(t *testing.T) {
+	vfs := io.NewFaultFS(io.NewMemFS())
+	manager := io.Manager(vfs)
+
+	w, err := manager.OpenForWrite("/db/table.db")
+	assert.NoError(t, err)
+	defer w.Close()
+
+	vfs.FailSync(syscall.EIO)
+	assert.ErrorIs(t, w.Write([]byte("data")), syscall.EIO)
+
+	// failed sync leaves contents unsynced
+	assert.NoError(t, vfs.DropUnsynced())
+	got, err := io.ReadFile(vfs, "/db/table.db")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	vfs.FailSync(nil)
+	w, err = manager.OpenForWrite("/db/table.db")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("data")))
+	w.Close()
+}
+
+func TestFaultFS_NoSpace(t *testing.T) {
+	vfs := io.NewFaultFS(io.NewMemFS())
+	manager := io.Manager(vfs)
+
+	vfs.SetSpace(4)
+	w, err := manager.OpenForWrite("/db/table.db")
+	assert.NoError(t, err)
+	err = w.Write([]byte("too large"))
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	w.Close()
+
+	got, err := io.ReadFile(vfs, "/db/table.db")
+	assert.NoError(t, err)
+	assert.Equal(t, "too ", string(got), "write is cut where space ran out")
+
+	vfs.SetSpace(-1)
+	w, err = manager.OpenForAppend("/db/table.db")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("large")))
+	w.Close()
+}
+
+func TestFaultFS_CrashAfter(t *testing.T) {
+	vfs := io.NewFaultFS(io.NewMemFS())
+	manager := io.Manager(vfs)
+
+	w, err := manager.OpenForWrite("/db/manifest")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("v1")))
+	w.Close()
+
+	// rewriting in place truncates durably before new contents are synced,
+	// crash right after open (mkdir & open) finds file empty
+	vfs.CrashAfter(2, "/db")
+	w, err = manager.OpenForWrite("/db/manifest")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte("v2")))
+	w.Close()
+
+	image, err := vfs.Crashed()
+	assert.NoError(t, err)
+	if assert.NotNil(t, image) {
+		got, err := io.ReadFile(image, "/db/manifest")
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	}
+
+	// written to a temp file & renamed, new version is durable
+	assert.NoError(t, manager.WriteFile("/db/manifest.tmp", []byte("v3")))
+	assert.NoError(t, vfs.Rename("/db/manifest.tmp", "/db/manifest"))
+	image, err = vfs.Durable("/db")
+	assert.NoError(t, err)
+	got, err := io.ReadFile(image, "/db/manifest")
+	assert.NoError(t, err)
+	assert.Equal(t, "v3", string(got))
+}
@@ -60,10 +60,10 @@ func TestMemtable_Write_Overflow_Trigger_Flush(t *testing.T) {
 		ctx,
 		memtable.MemtableOpts{MemtableSoftLimit: 1024, FlushTimeInterval: conf.DefaultFlusherTimeInterval},
 	)
-	d := types.IntValue{V: 0}
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
 
 	// overflow memtable to trigger flush
-	for i := range int(1024 / d.SizeOf()) {
+	for i := range int(1024 / entrySize) {
 		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
 
@@ -71,13 +71,14 @@ func TestMemtable_Write_Overflow_Trigger_Flush(t *testing.T) {
 	ok := mts.Write(k, &v)
 	assert.True(t, ok, "Expected to trigger flush")
 
-	// wait for memtable to flush & clear both memtable
-	time.Sleep(3 * time.Second)
+	// wait for memtable to flush & clear both memtable, kept off manifest
+	// sync ticks so test doesn't end while a sync writes to temp dir
+	time.Sleep(2500 * time.Millisecond)
 
 	mts.Clear()
 
-	val, ok := mts.Read(types.IntKey{K: 244})
-	v = types.IntValue{V: 244}
+	val, ok := mts.Read(types.IntKey{K: 12})
+	v = types.IntValue{V: 12}
 
 	assert.True(t, ok)
 	assert.Equal(t, v, *val)
@@ -113,25 +114,26 @@ func TestMemtable_Write_With_Multiple_Reader(t *testing.T) {
 	// Perform enough writes to trigger approximately 2 memtable flushes.
 	// Each flush occurs after reaching MEMTABLE_THRESHOLD bytes.
 	// totalOps is calculated based on the size of each entry and number of flushes.
-	d := types.IntValue{V: 0}
-	for i := range int(MEMTABLE_THRESHOLD / d.SizeOf()) {
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
+	for i := range int(MEMTABLE_THRESHOLD / entrySize) {
 		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
-	offset := int(MEMTABLE_THRESHOLD / d.SizeOf())
-	for i := range int(MEMTABLE_THRESHOLD / d.SizeOf()) {
+	offset := int(MEMTABLE_THRESHOLD / entrySize)
+	for i := range int(MEMTABLE_THRESHOLD / entrySize) {
 		mts.Write(types.IntKey{K: i + offset}, &types.IntValue{V: int32(i + offset)})
 	}
 
 	// A small gap to let it flush to disk & erase
-	// further read should come from disk sst
-	time.Sleep(3 * time.Second)
+	// further read should come from disk sst, kept off manifest sync
+	// ticks so test doesn't end while a sync writes to temp dir
+	time.Sleep(2500 * time.Millisecond)
 	wg := sync.WaitGroup{}
 
 	// clear in-memory memtables to read from disk
 	mts.Clear()
 
 	ticket := make(chan struct{}, MAX_CONCURRENT_READ_ROUTINES)
-	for i := range int(MEMTABLE_THRESHOLD / d.SizeOf()) {
+	for i := range int(MEMTABLE_THRESHOLD / entrySize) {
 		wg.Add(1)
 		ticket <- struct{}{} // acquire a ticket
 		func(i int) {
@@ -176,13 +178,13 @@ func TestMemtable_Intensive_Write_And_Read(t *testing.T) {
 		ctx,
 		memtable.MemtableOpts{MemtableSoftLimit: MEMTABLE_THRESHOLD, FlushTimeInterval: conf.DefaultFlusherTimeInterval},
 	)
-	d := types.IntValue{V: 0}
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
 
 	// Perform enough writes to trigger approximately 10 memtable flushes.
 	// Each flush occurs after reaching MEMTABLE_THRESHOLD bytes.
 	// totalOps is calculated based on the size of each entry and number of flushes.
 	multiples := 10
-	totalOps := int(MEMTABLE_THRESHOLD/d.SizeOf()) * multiples
+	totalOps := int(MEMTABLE_THRESHOLD/entrySize) * multiples
 	for i := range totalOps {
 		mts.Write(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
@@ -372,10 +374,10 @@ func TestMemtable_Delete_On_Disk(t *testing.T) {
 		ctx,
 		memtable.MemtableOpts{MemtableSoftLimit: MEMTABLE_THRESHOLD, FlushTimeInterval: conf.DefaultFlusherTimeInterval},
 	)
-	d := types.IntValue{V: 0}
+	entrySize := uintptr(memtable.SizeOfEntry(types.IntKey{}, &types.IntValue{}))
 
 	multiples := 10
-	totalOps := int(MEMTABLE_THRESHOLD/d.SizeOf()) * multiples
+	totalOps := int(MEMTABLE_THRESHOLD/entrySize) * multiples
 
 	// Perform a sequence of operations where delete actions are sandwiched between writes.
 	// This pattern ensures the following:
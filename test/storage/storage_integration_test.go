@@ -7,12 +7,26 @@
 package storage_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	parrot "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/compactor"
 	"github.com/nagarajRPoojari/orange/parrot/conf"
+	"github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/memtable"
+	"github.com/nagarajRPoojari/orange/parrot/merge"
+	"github.com/nagarajRPoojari/orange/parrot/sstable"
 	"github.com/nagarajRPoojari/orange/parrot/types"
 	"github.com/nagarajRPoojari/orange/parrot/utils/log"
 	"github.com/stretchr/testify/assert"
@@ -101,7 +115,7 @@ func TestStorage_Delete(t *testing.T) {
 	// Each flush occurs after reaching MEMTABLE_THRESHOLD bytes.
 	// totalOps is calculated based on the size of each entry and number of flushes.
 	multiples := 10
-	totalOps := int(MEMTABLE_THRESHOLD/v.SizeOf()) * multiples
+	totalOps := int(MEMTABLE_THRESHOLD/memtable.SizeOfEntry(k, &v)) * multiples
 	for i := range totalOps {
 		db.Put(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
@@ -151,13 +165,17 @@ func TestStorage_Load_DB(t *testing.T) {
 	// Each flush occurs after reaching MEMTABLE_THRESHOLD bytes.
 	// totalOps is calculated based on the size of each entry and number of flushes.
 	multiples := 10
-	totalOps := int(MEMTABLE_THRESHOLD/v.SizeOf()) * multiples
+	totalOps := int(MEMTABLE_THRESHOLD/memtable.SizeOfEntry(k, &v)) * multiples
 	for i := range totalOps {
 		db1.Put(types.IntKey{K: i}, &types.IntValue{V: int32(i)})
 	}
 
 	time.Sleep(2 * time.Second)
 
+	// db1 must be stopped before db2 takes over the directory, background
+	// flush & compaction of both must not run on same files
+	db1.Close()
+
 	// creating one more db to load from same directory
 	db2 := parrot.NewStorage[types.IntKey, *types.IntValue](
 		dbName,
@@ -189,3 +207,1819 @@ func TestStorage_Load_DB(t *testing.T) {
 	assert.NoError(t, readRes.Err, "failed to get key")
 	assert.Equal(t, v, *readRes.Value)
 }
+
+// counterOpts returns storage options with int64 add merge operator
+func counterOpts(dir string) parrot.StorageOpts {
+	return parrot.StorageOpts{
+		Directory:                     dir,
+		MemtableThreshold:             1024 * 2,
+		TurnOnMemtableWal:             true,
+		FlushTimeInterval:             conf.DefaultFlusherTimeInterval,
+		MemtableWALTimeInterval:       conf.DefaultWALTimeInterval,
+		MemtableWALEventChSize:        conf.DefaultWALEventBufferSize,
+		MemtableWALWriterBufferSize:   conf.DefaultWALEventBufferSize,
+		TurnOnCompaction:              true,
+		CompactionTimeInterval:        conf.DefaultCompactionTimeInterval,
+		CompactionWALTimeInterval:     conf.DefaultWALTimeInterval,
+		CompactionWALEventChSize:      conf.DefaultWALEventBufferSize,
+		CompactionWALWriterBufferSize: conf.DefaultWriterBufferSize,
+		Level0MaxSizeInBytes:          1024 * 2,
+		MaxSizeInBytesGrowthFactor:    2,
+		MergeOperator:                 merge.Int64Add{},
+	}
+}
+
+// TestStorage_Merge_Int64Add verifies that counter increments written through
+// Merge are folded correctly while operands are spread across memtables,
+// level-0 tables & compacted levels.
+func TestStorage_Merge_Int64Add(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+
+	counters := 5
+	rounds := 20
+	filler := 1024
+
+	// base value for one counter, others start from scratch
+	db.Put(types.IntKey{K: 0}, &types.Int64Value{V: 100})
+
+	for r := range rounds {
+		for c := range counters {
+			res := db.Merge(types.IntKey{K: c}, &types.Int64Value{V: int64(r + 1)})
+			assert.NoError(t, res.Err)
+		}
+		// unrelated writes push operands out of memtable
+		for i := range filler / rounds {
+			db.Put(types.IntKey{K: counters + r*filler + i}, &types.Int64Value{V: 1})
+		}
+	}
+
+	// counter deleted in between only sums operands written after tombstone
+	db.Delete(types.IntKey{K: 1}, &types.Int64Value{})
+	db.Merge(types.IntKey{K: 1}, &types.Int64Value{V: 7})
+
+	expected := int64(rounds * (rounds + 1) / 2)
+	check := func() {
+		for c := range counters {
+			readRes := db.Get(types.IntKey{K: c})
+			assert.NoError(t, readRes.Err)
+			switch c {
+			case 0:
+				assert.Equal(t, 100+expected, readRes.Value.V)
+			case 1:
+				assert.Equal(t, int64(7), readRes.Value.V)
+			default:
+				assert.Equal(t, expected, readRes.Value.V)
+			}
+			assert.False(t, readRes.Value.IsOperand())
+		}
+	}
+
+	check()
+
+	// let flusher & compaction fold operands on disk
+	time.Sleep(5 * time.Second)
+	check()
+
+	// let in-flight compaction finish before temp dir is removed
+	db.Close()
+}
+
+// TestStorage_Merge_ListAppend verifies list append operator along with
+// rejection of merges when no operator is configured.
+func TestStorage_Merge_ListAppend(t *testing.T) {
+	log.Disable()
+
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir1)
+	opts.MergeOperator = merge.ListAppend{}
+	db := parrot.NewStorage[types.StringKey, *types.ListValue]("test", ctx, opts)
+
+	k := types.StringKey{K: "events"}
+	db.Merge(k, &types.ListValue{V: [][]byte{[]byte("a")}})
+	db.Merge(k, &types.ListValue{V: [][]byte{[]byte("b"), []byte("c")}})
+
+	readRes := db.Get(k)
+	assert.NoError(t, readRes.Err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, readRes.Value.V)
+
+	opts = counterOpts(dir2)
+	opts.MergeOperator = nil
+	plain := parrot.NewStorage[types.StringKey, *types.ListValue]("test", ctx, opts)
+	assert.Error(t, plain.Merge(k, &types.ListValue{}).Err)
+}
+
+// TestStorage_Merge_Reload verifies that merge operands replayed from wal
+// are applied exactly once, across consecutive restarts.
+func TestStorage_Merge_Reload(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	k := types.IntKey{K: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db1 := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+	for range 10 {
+		db1.Merge(k, &types.Int64Value{V: 1})
+	}
+	db1.Close()
+
+	for range 2 {
+		db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+
+		readRes := db.Get(k)
+		assert.NoError(t, readRes.Err)
+		assert.Equal(t, int64(10), readRes.Value.V)
+
+		time.Sleep(1 * time.Second)
+		db.Close()
+	}
+}
+
+// TestStorage_Merge_Reload_Flushed verifies that commit log records already
+// flushed to tables aren't applied again on restart.
+func TestStorage_Merge_Reload_Flushed(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	k := types.IntKey{K: -1}
+	db1 := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+	for round := range 3 {
+		db1.Merge(k, &types.Int64Value{V: 1})
+		// overflow memtable holding operand, so its log segment is
+		// shared with next memtable
+		for i := range 256 {
+			db1.Put(types.IntKey{K: round*256 + i}, &types.Int64Value{V: int64(i)})
+		}
+	}
+	time.Sleep(2 * time.Second)
+	db1.Close()
+
+	for range 2 {
+		db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+
+		readRes := db.Get(k)
+		assert.NoError(t, readRes.Err)
+		assert.Equal(t, int64(3), readRes.Value.V)
+
+		time.Sleep(1 * time.Second)
+		db.Close()
+	}
+}
+
+// TestStorage_PlanRecovery verifies that planning recovery lists logged
+// entries in order & skips entries already persisted in tables, as reopening
+// storage would
+func TestStorage_PlanRecovery(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+	for round := range 3 {
+		db.Merge(types.IntKey{K: -1}, &types.Int64Value{V: 1})
+		for i := range 256 {
+			db.Put(types.IntKey{K: round*256 + i}, &types.Int64Value{V: int64(i)})
+		}
+	}
+	time.Sleep(2 * time.Second)
+	db.Close()
+
+	steps, err := parrot.PlanRecovery[types.IntKey, *types.Int64Value](filepath.Join(dir, "log"), nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, steps)
+
+	skipped := 0
+	for i, step := range steps {
+		assert.NoError(t, step.Err)
+		assert.Equal(t, "test", step.Family)
+		if i > 0 {
+			assert.True(t, steps[i-1].Pos.Less(step.Pos), "steps out of order at %d", i)
+		}
+		if step.Skip != "" {
+			assert.True(t, strings.HasPrefix(step.Skip, "flushed up to"), step.Skip)
+			skipped++
+		}
+	}
+	// memtables share log segments, so flushed records stay on disk
+	assert.NotZero(t, skipped)
+}
+
+// TestStorage_Families verifies that families are independent keyspaces
+// & that a batch spanning families survives restart as a whole.
+func TestStorage_Families(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+
+	users, err := db.Family("users")
+	assert.NoError(t, err)
+	orders, err := db.Family("orders")
+	assert.NoError(t, err)
+
+	_, err = db.Family("../users")
+	assert.Error(t, err)
+	_, err = db.Family("log")
+	assert.Error(t, err)
+
+	k := types.IntKey{K: 1}
+	users.Put(k, &types.Int64Value{V: 10})
+	orders.Put(k, &types.Int64Value{V: 20})
+
+	assert.Equal(t, int64(10), users.Get(k).Value.V)
+	assert.Equal(t, int64(20), orders.Get(k).Value.V)
+	assert.Error(t, db.Get(k).Err)
+
+	batch := parrot.NewWriteBatch[types.IntKey, *types.Int64Value]().
+		Put("users", types.IntKey{K: 2}, &types.Int64Value{V: 1}).
+		Merge("orders", k, &types.Int64Value{V: 5}).
+		Delete("users", k, &types.Int64Value{})
+	assert.NoError(t, db.Write(batch).Err)
+	db.Close()
+
+	// families are discovered from commit log on open
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, counterOpts(dir))
+	t.Cleanup(db.Close)
+	assert.ElementsMatch(t, []string{"test", "users", "orders"}, db.Families())
+
+	users, _ = db.Family("users")
+	orders, _ = db.Family("orders")
+	assert.Error(t, users.Get(k).Err)
+	assert.Equal(t, int64(1), users.Get(types.IntKey{K: 2}).Value.V)
+	assert.Equal(t, int64(25), orders.Get(k).Value.V)
+}
+
+// cappedAdd is an int64 add operator refusing to fold counters past max
+type cappedAdd struct {
+	merge.Int64Add
+	max int64
+}
+
+func (t cappedAdd) FullMerge(key any, existing types.Value, operands []types.Value) (types.Value, error) {
+	v, err := t.Int64Add.FullMerge(key, existing, operands)
+	if err != nil {
+		return nil, err
+	}
+	if v.(*types.Int64Value).V > t.max {
+		return nil, errors.RaiseMergeErr("counter of key=%v exceeds %d", key, t.max)
+	}
+	return v, nil
+}
+
+// TestStorage_Write_Atomic verifies that a batch whose merge fails to fold
+// is rejected as a whole, neither its earlier entries are visible nor is it
+// replayed from commit log on next open.
+func TestStorage_Write_Atomic(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.MergeOperator = cappedAdd{max: 10}
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+
+	counter, other := types.IntKey{K: 1}, types.IntKey{K: 2}
+	assert.NoError(t, db.Put(counter, &types.Int64Value{V: 5}).Err)
+
+	batch := parrot.NewWriteBatch[types.IntKey, *types.Int64Value]().
+		Put("test", other, &types.Int64Value{V: 1}).
+		Merge("test", counter, &types.Int64Value{V: 2}).
+		Merge("test", counter, &types.Int64Value{V: 4})
+	assert.Error(t, db.Write(batch).Err)
+
+	check := func() {
+		assert.Error(t, db.Get(other).Err)
+		readRes := db.Get(counter)
+		assert.NoError(t, readRes.Err)
+		assert.Equal(t, int64(5), readRes.Value.V)
+	}
+	check()
+
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	check()
+}
+
+// TestStorage_Families_Flush verifies that data of flushed families is
+// served from disk & commit log segments are released once flushed.
+func TestStorage_Families_Flush(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.TurnOnCompaction = false
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+
+	names := []string{"a", "b", "c"}
+	totalOps := 1024
+	for i := range totalOps {
+		batch := parrot.NewWriteBatch[types.IntKey, *types.Int64Value]()
+		for j, name := range names {
+			batch.Put(name, types.IntKey{K: i}, &types.Int64Value{V: int64(i * (j + 1))})
+		}
+		assert.NoError(t, db.Write(batch).Err)
+	}
+
+	// let shared flusher dump all disposable memtables
+	time.Sleep(2500 * time.Millisecond)
+
+	for j, name := range names {
+		fam, _ := db.Family(name)
+		for _, i := range []int{0, totalOps / 2, totalOps - 1} {
+			readRes := fam.Get(types.IntKey{K: i})
+			assert.NoError(t, readRes.Err)
+			assert.Equal(t, int64(i*(j+1)), readRes.Value.V)
+		}
+	}
+
+	// only segments pinned by active memtables are left
+	segments, _ := filepath.Glob(filepath.Join(dir, "log", "*.log"))
+	assert.LessOrEqual(t, len(segments), 2)
+}
+
+// TestStorage_Scan_Tuples verifies that scans over tuple keys return live
+// entries in key order while versions are spread across memtables & tables,
+// and that a family can't be reopened under a different comparator.
+func TestStorage_Scan_Tuples(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.MergeOperator = nil
+	db := parrot.NewStorage[types.BytesKey, *types.StringValue]("test", ctx, opts)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := func(tenant string, i int) types.BytesKey {
+		return types.MustEncodeTuple(tenant, base.Add(time.Duration(i)*time.Second), int64(i))
+	}
+
+	totalOps := 512
+	for i := totalOps - 1; i >= 0; i-- {
+		for _, tenant := range []string{"acme", "globex"} {
+			assert.NoError(t, db.Put(key(tenant, i), &types.StringValue{V: fmt.Sprintf("%s-%d", tenant, i)}).Err)
+		}
+	}
+	// let older versions settle in tables
+	time.Sleep(2 * time.Second)
+
+	for i := 0; i < totalOps; i += 4 {
+		assert.NoError(t, db.Delete(key("acme", i), &types.StringValue{}).Err)
+		assert.NoError(t, db.Put(key("acme", i+1), &types.StringValue{V: fmt.Sprintf("new-%d", i+1)}).Err)
+	}
+
+	scanAcme := func(db *parrot.Storage[types.BytesKey, *types.StringValue]) {
+		var got []string
+		err := db.Scan(types.Prefix(types.MustEncodeTuple("acme")), func(k types.BytesKey, v *types.StringValue) bool {
+			got = append(got, v.V)
+			return true
+		})
+		assert.NoError(t, err)
+
+		var want []string
+		for i := range totalOps {
+			switch i % 4 {
+			case 0:
+			case 1:
+				want = append(want, fmt.Sprintf("new-%d", i))
+			default:
+				want = append(want, fmt.Sprintf("acme-%d", i))
+			}
+		}
+		assert.Equal(t, want, got)
+	}
+	scanAcme(db)
+
+	// bounded range stops at end & early return stops scan
+	var got []string
+	err := db.Scan(types.Between(key("globex", 10), key("globex", 20)), func(k types.BytesKey, v *types.StringValue) bool {
+		got = append(got, v.V)
+		return len(got) < 5
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"globex-10", "globex-11", "globex-12", "globex-13", "globex-14"}, got)
+
+	db.Close()
+
+	reverse := opts
+	reverse.Comparator = types.ReverseBytewiseComparator.Name()
+	assert.Panics(t, func() {
+		parrot.NewStorage[types.BytesKey, *types.StringValue]("test", ctx, reverse)
+	})
+
+	opts.Comparator = types.BytewiseComparator.Name()
+	db = parrot.NewStorage[types.BytesKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	scanAcme(db)
+}
+
+// TestStorage_Comparator_PerStorage verifies that storages opened in one
+// process keep their own order of byte keys, in memtables & tables alike.
+func TestStorage_Comparator_PerStorage(t *testing.T) {
+	log.Disable()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	open := func(comparator string) *parrot.Storage[types.BytesKey, *types.StringValue] {
+		opts := counterOpts(t.TempDir())
+		opts.MergeOperator = nil
+		opts.TurnOnCompaction = false
+		opts.Comparator = comparator
+		db := parrot.NewStorage[types.BytesKey, *types.StringValue]("test", ctx, opts)
+		t.Cleanup(db.Close)
+		return db
+	}
+	forward := open("")
+	reverse := open(types.ReverseBytewiseComparator.Name())
+
+	scan := func(db *parrot.Storage[types.BytesKey, *types.StringValue]) []string {
+		var got []string
+		assert.NoError(t, db.Scan(types.AllKeys[types.BytesKey](), func(k types.BytesKey, v *types.StringValue) bool {
+			got = append(got, v.V)
+			return true
+		}))
+		return got
+	}
+
+	for _, db := range []*parrot.Storage[types.BytesKey, *types.StringValue]{forward, reverse} {
+		for _, v := range []string{"b", "a", "c"} {
+			assert.NoError(t, db.Put(types.NewBytesKey([]byte(v)), &types.StringValue{V: v}).Err)
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, scan(forward))
+	assert.Equal(t, []string{"c", "b", "a"}, scan(reverse))
+
+	// tables are sorted & searched by comparator of their storage
+	assert.NoError(t, forward.Flush(true))
+	assert.NoError(t, reverse.Flush(true))
+	assert.Equal(t, []string{"a", "b", "c"}, scan(forward))
+	assert.Equal(t, []string{"c", "b", "a"}, scan(reverse))
+	for _, v := range []string{"a", "b", "c"} {
+		assert.Equal(t, v, reverse.Get(types.NewBytesKey([]byte(v))).Value.V)
+	}
+}
+
+// valueLogOpts returns storage options separating values larger than 256 bytes
+func valueLogOpts(dir string) parrot.StorageOpts {
+	opts := counterOpts(dir)
+	opts.MergeOperator = nil
+	opts.MemtableThreshold = 16 * 1024
+	opts.Level0MaxSizeInBytes = 512
+	opts.ValueLogThreshold = 256
+	opts.ValueLogSegmentSize = 8 * 1024
+	return opts
+}
+
+// TestStorage_ValueLog verifies that large values are kept in value log
+// across flush, compaction & reload while small ones stay inline.
+func TestStorage_ValueLog(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := valueLogOpts(dir)
+	db := parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+
+	value := func(i int) string {
+		if i%5 == 0 {
+			return fmt.Sprintf("small-%d", i)
+		}
+		return fmt.Sprintf("%04d-%s", i, strings.Repeat("x", 512))
+	}
+
+	totalOps := 256
+	for i := range totalOps {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.StringValue{V: value(i)}).Err)
+	}
+	for i := 0; i < totalOps; i += 7 {
+		assert.NoError(t, db.Delete(types.IntKey{K: i}, &types.StringValue{}).Err)
+	}
+	// let memtables flush & tables compact
+	time.Sleep(3 * time.Second)
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "vlog", "vlog-*.log"))
+	assert.NotEmpty(t, segments)
+
+	check := func(db *parrot.Storage[types.IntKey, *types.StringValue]) {
+		for i := range totalOps {
+			readRes := db.Get(types.IntKey{K: i})
+			if i%7 == 0 {
+				assert.Error(t, readRes.Err, "key=%d", i)
+				continue
+			}
+			assert.NoError(t, readRes.Err, "key=%d", i)
+			if readRes.Err == nil {
+				assert.Equal(t, value(i), readRes.Value.V)
+			}
+		}
+
+		count := 0
+		err := db.Scan(types.AllKeys[types.IntKey](), func(k types.IntKey, v *types.StringValue) bool {
+			assert.Equal(t, value(k.K), v.V)
+			count++
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, totalOps-(totalOps+6)/7, count)
+	}
+	check(db)
+
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	check(db)
+}
+
+// TestStorage_ValueLog_GC verifies that value log segments holding only
+// overwritten values are reclaimed & live values survive their relocation.
+func TestStorage_ValueLog_GC(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := valueLogOpts(dir)
+	db := parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+
+	value := func(round, i int) string {
+		return fmt.Sprintf("%d-%04d-%s", round, i, strings.Repeat("x", 512))
+	}
+
+	keys, rounds := 64, 6
+	var first []string
+	for round := range rounds {
+		for i := range keys {
+			assert.NoError(t, db.Put(types.IntKey{K: i}, &types.StringValue{V: value(round, i)}).Err)
+		}
+		time.Sleep(1500 * time.Millisecond)
+
+		if round == 0 {
+			first, _ = filepath.Glob(filepath.Join(dir, "vlog", "vlog-*.log"))
+			assert.NotEmpty(t, first)
+		}
+	}
+	// let compaction drop old versions & value log gc reclaim their segments
+	time.Sleep(5 * time.Second)
+
+	reclaimed := 0
+	for _, segment := range first {
+		if _, err := os.Stat(segment); os.IsNotExist(err) {
+			reclaimed++
+		}
+	}
+	assert.Positive(t, reclaimed, "no segment of first round was reclaimed")
+
+	check := func(db *parrot.Storage[types.IntKey, *types.StringValue]) {
+		for i := range keys {
+			readRes := db.Get(types.IntKey{K: i})
+			assert.NoError(t, readRes.Err, "key=%d", i)
+			if readRes.Err == nil {
+				assert.Equal(t, value(rounds-1, i), readRes.Value.V)
+			}
+		}
+	}
+	check(db)
+
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	check(db)
+}
+
+// TestStorage_DeleteRange verifies that a range tombstone shadows older
+// versions spread across memtables & tables but not newer writes, and that
+// it survives compaction & reload.
+func TestStorage_DeleteRange(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+
+	totalOps := 1024
+	for i := range totalOps {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	// let older versions settle in tables
+	time.Sleep(2 * time.Second)
+
+	start, end := 100, 700
+	assert.NoError(t, db.DeleteRange(types.IntKey{K: start}, types.IntKey{K: end}).Err)
+	assert.Error(t, db.DeleteRange(types.IntKey{K: end}, types.IntKey{K: start}).Err)
+
+	// newer writes inside range stay visible, merges start from scratch
+	assert.NoError(t, db.Put(types.IntKey{K: 200}, &types.Int64Value{V: -1}).Err)
+	assert.NoError(t, db.Merge(types.IntKey{K: 300}, &types.Int64Value{V: 5}).Err)
+
+	want := func(i int) (int64, bool) {
+		switch {
+		case i == 200:
+			return -1, true
+		case i == 300:
+			return 5, true
+		case i >= start && i < end:
+			return 0, false
+		}
+		return int64(i), true
+	}
+
+	check := func(db *parrot.Storage[types.IntKey, *types.Int64Value]) {
+		for i := range totalOps {
+			readRes := db.Get(types.IntKey{K: i})
+			v, ok := want(i)
+			if !ok {
+				assert.Error(t, readRes.Err, "key=%d", i)
+				continue
+			}
+			assert.NoError(t, readRes.Err, "key=%d", i)
+			if readRes.Err == nil {
+				assert.Equal(t, v, readRes.Value.V, "key=%d", i)
+			}
+		}
+
+		count := 0
+		err := db.Scan(types.AllKeys[types.IntKey](), func(k types.IntKey, v *types.Int64Value) bool {
+			w, ok := want(k.K)
+			assert.True(t, ok, "key=%d", k.K)
+			assert.Equal(t, w, v.V, "key=%d", k.K)
+			count++
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, totalOps-(end-start)+2, count)
+	}
+	check(db)
+
+	// push tombstone through flush & compaction
+	for i := range totalOps {
+		if i < start || i >= end {
+			assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+		}
+	}
+	time.Sleep(3 * time.Second)
+	check(db)
+
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	check(db)
+}
+
+// TestStorage_IngestFiles verifies that tables built offline become visible
+// as newest versions of their keys, survive reload, and that files
+// overlapping each other or unflushed writes are rejected.
+func TestStorage_IngestFiles(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	src := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	build := func(name string, start, end int, v int64) string {
+		path := filepath.Join(src, name+".db")
+		w, err := sstable.NewWriter[types.IntKey, *types.Int64Value](path, nil, nil)
+		assert.NoError(t, err)
+		for i := start; i < end; i++ {
+			assert.NoError(t, w.Add(types.IntKey{K: i}, &types.Int64Value{V: v}))
+		}
+		assert.NoError(t, w.Finish())
+		return path
+	}
+
+	w, err := sstable.NewWriter[types.IntKey, *types.Int64Value](filepath.Join(src, "unsorted.db"), nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Add(types.IntKey{K: 2}, &types.Int64Value{V: 1}))
+	assert.Error(t, w.Add(types.IntKey{K: 1}, &types.Int64Value{V: 1}))
+	w.Abort()
+
+	opts := counterOpts(dir)
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+
+	totalOps := 1024
+	for i := range totalOps {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	// let older versions settle in tables
+	time.Sleep(2 * time.Second)
+
+	// newer than flushed versions & disjoint from unflushed ones
+	older := build("older", 100, 200, -1)
+	fresh := build("fresh", 5000, 6000, -2)
+	assert.NoError(t, db.IngestFiles([]string{fresh, older}))
+
+	assert.Error(t, db.IngestFiles([]string{build("a", 7000, 7100, 0), build("b", 7050, 7200, 0)}))
+	assert.Error(t, db.IngestFiles([]string{build("unflushed", totalOps-1, totalOps, 0)}))
+
+	check := func(db *parrot.Storage[types.IntKey, *types.Int64Value]) {
+		for i := range totalOps {
+			want := int64(i)
+			if i >= 100 && i < 200 {
+				want = -1
+			}
+			readRes := db.Get(types.IntKey{K: i})
+			assert.NoError(t, readRes.Err, "key=%d", i)
+			if readRes.Err == nil {
+				assert.Equal(t, want, readRes.Value.V, "key=%d", i)
+			}
+		}
+		for i := 5000; i < 6000; i++ {
+			readRes := db.Get(types.IntKey{K: i})
+			assert.NoError(t, readRes.Err, "key=%d", i)
+			if readRes.Err == nil {
+				assert.Equal(t, int64(-2), readRes.Value.V, "key=%d", i)
+			}
+		}
+		assert.Error(t, db.Get(types.IntKey{K: 7000}).Err)
+	}
+	check(db)
+
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	check(db)
+
+	// source files are left in place
+	assert.FileExists(t, fresh)
+	assert.FileExists(t, sstable.IndexPath(fresh))
+}
+
+// TestStorage_Table_Verify verifies that tables are inspected through same
+// decoding as reads & that damaged tables are reported.
+// It ensures that:
+//   - Verify of an intact table summarises its key range & counts
+//   - Overwritten or truncated data files fail Verify
+func TestStorage_Table_Verify(t *testing.T) {
+	log.Disable()
+
+	path := filepath.Join(t.TempDir(), "table.db")
+	w, err := sstable.NewWriter[types.IntKey, *types.Int64Value](path, nil, nil)
+	assert.NoError(t, err)
+	for i := 10; i < 110; i++ {
+		assert.NoError(t, w.Add(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}))
+	}
+	assert.NoError(t, w.Finish())
+
+	table, err := sstable.Open[types.IntKey, *types.Int64Value](nil, path, nil)
+	assert.NoError(t, err)
+	s, err := table.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, 100, s.Entries)
+	assert.Equal(t, 0, s.Tombstones)
+	assert.Equal(t, types.IntKey{K: 10}, s.First)
+	assert.Equal(t, types.IntKey{K: 109}, s.Last)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path, data[:len(data)-3], 0644))
+	table, err = sstable.Open[types.IntKey, *types.Int64Value](nil, path, nil)
+	assert.NoError(t, err)
+	_, err = table.Verify()
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, append(data, 0, 0, 0), 0644))
+	table, err = sstable.Open[types.IntKey, *types.Int64Value](nil, path, nil)
+	assert.NoError(t, err)
+	_, err = table.Verify()
+	assert.Error(t, err)
+}
+
+// TestStorage_Check_Repair verifies that offline check finds tables missing,
+// corrupt or unreferenced & that repair leaves a directory storage opens
+// with data of intact tables.
+func TestStorage_Check_Repair(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.TurnOnCompaction = false
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	totalOps := 1024
+	for i := range totalOps {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	time.Sleep(2 * time.Second)
+	db.Close()
+
+	report, err := parrot.Check[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test"}, report.Families)
+	assert.Empty(t, report.Issues)
+
+	tables, err := filepath.Glob(filepath.Join(dir, "test", "level-0", "*.db"))
+	assert.NoError(t, err)
+	if !assert.GreaterOrEqual(t, len(tables), 3) {
+		return
+	}
+
+	assert.NoError(t, os.Remove(tables[0]))
+	data, err := os.ReadFile(tables[1])
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(tables[1], data[:len(data)-4], 0644))
+	orphan := filepath.Join(dir, "test", "level-0", "sst-99999.db")
+	assert.NoError(t, os.WriteFile(orphan, data, 0644))
+
+	report, err = parrot.Check[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, report.Issues, 3)
+	assert.Equal(t, len(tables)-2, report.Tables)
+
+	_, err = parrot.Repair[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, orphan)
+	assert.NoFileExists(t, tables[1])
+
+	quarantined, err := filepath.Glob(filepath.Join(dir, "quarantine", "*", "test", "level-0", filepath.Base(tables[1])))
+	assert.NoError(t, err)
+	assert.Len(t, quarantined, 1)
+
+	report, err = parrot.Check[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Issues)
+
+	// a lost manifest is rebuilt from tables left
+	manifest := filepath.Join(dir, "manifest", "test", "manifest.json")
+	before, err := os.ReadFile(manifest)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Remove(manifest))
+
+	report, err = parrot.Repair[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, len(tables)-2, report.Tables)
+
+	after, err := os.ReadFile(manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, countTables(t, before), countTables(t, after))
+
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	found := 0
+	for i := range totalOps {
+		if readRes := db.Get(types.IntKey{K: i}); readRes.Err == nil {
+			assert.Equal(t, int64(i), readRes.Value.V)
+			found++
+		}
+	}
+	assert.NotZero(t, found)
+}
+
+// TestStorage_Repair_DeleteRange verifies that range tombstones are kept in
+// table files, so keys they delete stay deleted once a lost manifest is
+// rebuilt by repair.
+func TestStorage_Repair_DeleteRange(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.TurnOnCompaction = false
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+
+	keys := 64
+	start, end := types.IntKey{K: 16}, types.IntKey{K: 48}
+	for i := range keys {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.DeleteRange(start, end).Err)
+	assert.NoError(t, db.Flush(true))
+	db.Close()
+
+	// range tombstone is listed by index of table it's flushed to
+	tables, err := filepath.Glob(filepath.Join(dir, "test", "level-0", "*.db"))
+	assert.NoError(t, err)
+	ranges := 0
+	for _, path := range tables {
+		table, err := sstable.Open[types.IntKey, *types.Int64Value](nil, path, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		s, err := table.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, len(table.RangeTombstones()), s.RangeTombstones)
+		ranges += s.RangeTombstones
+	}
+	assert.Equal(t, 1, ranges)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "manifest", "test", "manifest.json")))
+	report, err := parrot.Repair[types.IntKey, *types.Int64Value](dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, report.Issues, 1)
+
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	for i := range keys {
+		res := db.Get(types.IntKey{K: i})
+		if i >= start.K && i < end.K {
+			assert.Error(t, res.Err, "key=%d", i)
+		} else if assert.NoError(t, res.Err, "key=%d", i) {
+			assert.Equal(t, int64(i), res.Value.V)
+		}
+	}
+}
+
+// countTables returns number of tables listed by manifest data
+func countTables(t *testing.T, data []byte) int {
+	var view struct {
+		Levels []struct {
+			Tables map[string]any `json:"tables"`
+		} `json:"levels"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &view))
+
+	count := 0
+	for _, level := range view.Levels {
+		count += len(level.Tables)
+	}
+	return count
+}
+
+// TestStorage_Flush_CompactRange verifies that memtables are flushed &
+// levels compacted on demand, well below size thresholds.
+// It ensures that:
+//   - Flush with wait leaves written keys in a level-0 table
+//   - CompactRange merges levels into a single table, dropping deleted keys
+//     & tombstones once nothing older is left
+func TestStorage_Flush_CompactRange(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.MemtableThreshold = 1 << 20
+	opts.Level0MaxSizeInBytes = 1 << 30
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+
+	tables := func() []string {
+		paths, err := filepath.Glob(filepath.Join(dir, "test", "level-*", "*.db"))
+		assert.NoError(t, err)
+		return paths
+	}
+
+	for i := range 200 {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	assert.Len(t, tables(), 1)
+
+	// flushing an empty memtable is a no-op
+	assert.NoError(t, db.Flush(true))
+	assert.Len(t, tables(), 1)
+
+	assert.NoError(t, db.DeleteRange(types.IntKey{K: 0}, types.IntKey{K: 100}).Err)
+	for i := 100; i < 150; i++ {
+		assert.NoError(t, db.Delete(types.IntKey{K: i}, &types.Int64Value{}).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	assert.Len(t, tables(), 2)
+
+	assert.Error(t, db.CompactRange(types.IntKey{K: 10}, types.IntKey{K: 10}))
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: 1000}))
+
+	left := tables()
+	if assert.Len(t, left, 1) {
+		table, err := sstable.Open[types.IntKey, *types.Int64Value](nil, left[0], nil)
+		assert.NoError(t, err)
+		s, err := table.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, 50, s.Entries)
+		assert.Equal(t, 0, s.Tombstones)
+		assert.Equal(t, types.IntKey{K: 150}, s.First)
+	}
+
+	for i := range 200 {
+		readRes := db.Get(types.IntKey{K: i})
+		if i < 150 {
+			assert.Error(t, readRes.Err, "key=%d", i)
+			continue
+		}
+		assert.NoError(t, readRes.Err, "key=%d", i)
+		if readRes.Err == nil {
+			assert.Equal(t, int64(i), readRes.Value.V)
+		}
+	}
+}
+
+// TestStorage_CompactionFilter_Shadow verifies that a version dropped by
+// compaction filter keeps shadowing older versions of its key held by
+// deeper levels, dropped keys never come back.
+func TestStorage_CompactionFilter_Shadow(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.MemtableThreshold = 1 << 20
+	opts.Level0MaxSizeInBytes = 1 << 30
+	// drops versions holding 100 only, older versions are kept
+	opts.CompactionFilter = compactor.CompactionFilterFunc(func(level int, key any, value types.Value) (compactor.FilterDecision, types.Value) {
+		if value.(*types.Int64Value).V == 100 {
+			return compactor.FilterDrop, nil
+		}
+		return compactor.FilterKeep, nil
+	})
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+
+	// older versions end up in level-1
+	assert.NoError(t, db.Put(types.IntKey{K: 1}, &types.Int64Value{V: 1}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.Put(types.IntKey{K: 2}, &types.Int64Value{V: 2}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: 1000}))
+
+	// newer version is dropped by level-0 compaction, while level-1 still
+	// holds the older one
+	assert.NoError(t, db.Put(types.IntKey{K: 1}, &types.Int64Value{V: 100}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 1}, types.IntKey{K: 2}))
+
+	assert.Error(t, db.Get(types.IntKey{K: 1}).Err)
+	readRes := db.Get(types.IntKey{K: 2})
+	if assert.NoError(t, readRes.Err) {
+		assert.Equal(t, int64(2), readRes.Value.V)
+	}
+}
+
+// TestStorage_Subcompactions verifies that a large compaction is split into
+// key ranges written to tables of disjoint keys, and that range tombstones
+// carried down by a split compaction only shadow keys of their own table.
+func TestStorage_Subcompactions(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.MemtableThreshold = 1 << 20
+	opts.Level0MaxSizeInBytes = 1 << 30
+	opts.MaxSubcompactions = 4
+	opts.SubcompactionMinEntries = 256
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+
+	const n = 4000
+	for range 2 {
+		for i := range n {
+			assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+		}
+		assert.NoError(t, db.Flush(true))
+	}
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: n}))
+
+	paths, err := filepath.Glob(filepath.Join(dir, "test", "level-1", "*.db"))
+	assert.NoError(t, err)
+	assert.Len(t, paths, 4)
+
+	var summaries []sstable.Summary[types.IntKey]
+	entries := 0
+	for _, path := range paths {
+		table, err := sstable.Open[types.IntKey, *types.Int64Value](nil, path, nil)
+		assert.NoError(t, err)
+		s, err := table.Verify()
+		assert.NoError(t, err)
+		summaries = append(summaries, s)
+		entries += s.Entries
+	}
+	assert.Equal(t, n, entries)
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].First.Less(summaries[j].First)
+	})
+	for i := 1; i < len(summaries); i++ {
+		assert.True(t, summaries[i-1].Last.Less(summaries[i].First), "tables %d & %d overlap", i-1, i)
+	}
+
+	// range tombstone spans all subcompactions of next level-0 compaction,
+	// key written after it must survive in whichever table it lands
+	for i := range n {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(2 * i)}).Err)
+	}
+	assert.NoError(t, db.DeleteRange(types.IntKey{K: 1000}, types.IntKey{K: 3000}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.Put(types.IntKey{K: 2000}, &types.Int64Value{V: -1}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: n}))
+
+	for i := range n {
+		readRes := db.Get(types.IntKey{K: i})
+		switch {
+		case i == 2000:
+			if assert.NoError(t, readRes.Err, "key=%d", i) {
+				assert.Equal(t, int64(-1), readRes.Value.V)
+			}
+		case i >= 1000 && i < 3000:
+			assert.Error(t, readRes.Err, "key=%d", i)
+		default:
+			if assert.NoError(t, readRes.Err, "key=%d", i) {
+				assert.Equal(t, int64(2*i), readRes.Value.V)
+			}
+		}
+	}
+}
+
+// TestStorage_WriteBuffer verifies that memtables of all families are kept
+// within a shared write buffer budget long before any of them reaches its
+// own threshold, and that flushed memtables give their memory back.
+func TestStorage_WriteBuffer(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	const WRITE_BUFFER_SIZE = 16 * 1024
+
+	buffer := memtable.NewWriteBufferManager(WRITE_BUFFER_SIZE)
+	opts := counterOpts(dir)
+	opts.TurnOnCompaction = false
+	opts.MemtableThreshold = 1024 * 1024
+	opts.WriteBuffer = buffer
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+
+	names := []string{"a", "b", "c"}
+	totalOps := 1024
+	for i := range totalOps {
+		batch := parrot.NewWriteBatch[types.IntKey, *types.Int64Value]()
+		for j, name := range names {
+			batch.Put(name, types.IntKey{K: i}, &types.Int64Value{V: int64(i * (j + 1))})
+		}
+		assert.NoError(t, db.Write(batch).Err)
+	}
+	assert.Greater(t, int64(len(names)*totalOps)*memtable.SizeOfEntry(types.IntKey{}, &types.Int64Value{}), int64(WRITE_BUFFER_SIZE))
+
+	// let shared flusher dump sealed memtables
+	time.Sleep(2500 * time.Millisecond)
+	assert.LessOrEqual(t, buffer.Usage(), int64(WRITE_BUFFER_SIZE))
+
+	for j, name := range names {
+		fam, _ := db.Family(name)
+		for _, i := range []int{0, totalOps / 2, totalOps - 1} {
+			readRes := fam.Get(types.IntKey{K: i})
+			if assert.NoError(t, readRes.Err) {
+				assert.Equal(t, int64(i*(j+1)), readRes.Value.V)
+			}
+		}
+	}
+
+	assert.NoError(t, db.Flush(true))
+	assert.Zero(t, buffer.Usage())
+}
+
+// TestStorage_Encryption verifies that tables, commit log & manifest are
+// encrypted at rest, that a reopened storage reads them back, and that
+// compaction re-encrypts tables once a new key is rotated in.
+func TestStorage_Encryption(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ring := io.NewKeyring()
+	assert.NoError(t, ring.Add("k1", bytes.Repeat([]byte{1}, 32)))
+
+	opts := counterOpts(dir)
+	opts.MergeOperator = nil
+	opts.MemtableThreshold = 1 << 20
+	opts.Level0MaxSizeInBytes = 1 << 30
+	opts.Keyring = ring
+	db := parrot.NewStorage[types.StringKey, *types.StringValue]("test", ctx, opts)
+
+	n := 200
+	for i := range n {
+		k, v := types.StringKey{K: fmt.Sprintf("key-%d", i)}, types.StringValue{V: fmt.Sprintf("secret-%d", i)}
+		assert.NoError(t, db.Put(k, &v).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	db.Close()
+
+	files := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() == 0 {
+			return err
+		}
+		files++
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.False(t, bytes.Contains(data, []byte("secret")), "plaintext found in %s", path)
+		assert.False(t, bytes.Contains(data, []byte("level-0")), "plaintext manifest %s", path)
+		keyID, err := io.GetFileManager().KeyID(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", keyID, path)
+		return nil
+	})
+	assert.Greater(t, files, 0)
+
+	db = parrot.NewStorage[types.StringKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	for i := range n {
+		readRes := db.Get(types.StringKey{K: fmt.Sprintf("key-%d", i)})
+		if assert.NoError(t, readRes.Err, "key=%d", i) {
+			assert.Equal(t, fmt.Sprintf("secret-%d", i), readRes.Value.V)
+		}
+	}
+
+	// rotated key is picked up by tables rewritten by compaction
+	assert.NoError(t, ring.Add("k2", bytes.Repeat([]byte{2}, 32)))
+	assert.NoError(t, db.CompactRange(types.StringKey{K: ""}, types.StringKey{K: "~"}))
+
+	tables, err := filepath.Glob(filepath.Join(dir, "test", "level-*", "*.db"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tables)
+	for _, path := range tables {
+		keyID, err := io.GetFileManager().KeyID(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "k2", keyID, path)
+
+		table, err := sstable.Open[types.StringKey, *types.StringValue](io.WithKeyring(io.OS, ring), path, nil)
+		if assert.NoError(t, err) {
+			s, err := table.Verify()
+			assert.NoError(t, err)
+			assert.Equal(t, n, s.Entries)
+		}
+	}
+	for i := range n {
+		readRes := db.Get(types.StringKey{K: fmt.Sprintf("key-%d", i)})
+		if assert.NoError(t, readRes.Err, "key=%d", i) {
+			assert.Equal(t, fmt.Sprintf("secret-%d", i), readRes.Value.V)
+		}
+	}
+}
+
+// TestStorage_MemFS verifies that storage kept on an in-memory VFS flushes,
+// compacts & reopens without touching disk.
+func TestStorage_MemFS(t *testing.T) {
+	log.Disable()
+
+	dir := filepath.Join(t.TempDir(), "mem")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.VFS = io.NewMemFS()
+	opts.ValueLogThreshold = 64
+
+	db := parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+	totalOps := 1024
+	for i := range totalOps {
+		v := types.StringValue{V: fmt.Sprintf("value-%d", i)}
+		if i%8 == 0 {
+			v.V = strings.Repeat("x", 128)
+		}
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &v).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: totalOps}))
+	db.Close()
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "storage must not touch disk")
+	tables, err := io.Glob(opts.VFS, filepath.Join(dir, "test", "level-*", "*.db"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tables)
+
+	db = parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	for i := range totalOps {
+		readRes := db.Get(types.IntKey{K: i})
+		if assert.NoError(t, readRes.Err, "key=%d", i) && i%8 != 0 {
+			assert.Equal(t, fmt.Sprintf("value-%d", i), readRes.Value.V)
+		}
+	}
+}
+
+func TestStorage_Pread(t *testing.T) {
+	log.Disable()
+
+	for _, direct := range []bool{false, true} {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		opts := counterOpts(dir)
+		opts.TableRead = io.ReadOpts{Mode: io.ReadPread, Direct: direct}
+
+		db := parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+		totalOps := 1024
+		for i := range totalOps {
+			assert.NoError(t, db.Put(types.IntKey{K: i}, &types.StringValue{V: fmt.Sprintf("value-%d", i)}).Err)
+		}
+		assert.NoError(t, db.Flush(true))
+		assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: totalOps}))
+		db.Close()
+
+		db = parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+		for i := range totalOps {
+			readRes := db.Get(types.IntKey{K: i})
+			if assert.NoError(t, readRes.Err, "direct=%v key=%d", direct, i) {
+				assert.Equal(t, fmt.Sprintf("value-%d", i), readRes.Value.V)
+			}
+		}
+		db.Close()
+		assert.Equal(t, int64(0), io.DefaultBufferPool.InUse(), "buffers must be released")
+	}
+}
+
+// TestStorage_GetAt verifies that past versions, tombstones & merge operands
+// are read as of a time while they sit in memtables, level-0 tables,
+// compacted levels & after reopening, and that compaction drops versions
+// older than retention.
+func TestStorage_GetAt(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.Retention = time.Hour
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	before := time.Now()
+
+	keys, rounds := 64, 5
+	counter, deleted := types.IntKey{K: keys}, types.IntKey{K: 1}
+	stamps := make([]time.Time, rounds)
+	for r := range rounds {
+		for i := range keys {
+			if r > 2 && i == deleted.K {
+				continue
+			}
+			assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(r*1000 + i)}).Err)
+		}
+		assert.NoError(t, db.Merge(counter, &types.Int64Value{V: 1}).Err)
+		if r == 2 {
+			assert.NoError(t, db.Delete(deleted, &types.Int64Value{}).Err)
+		}
+		stamps[r] = time.Now()
+	}
+
+	check := func(stage string) {
+		for r, ts := range stamps {
+			for i := range keys {
+				res := db.GetAt(types.IntKey{K: i}, ts)
+				if r >= 2 && i == deleted.K {
+					assert.Error(t, res.Err, "%s: round=%d key=%d", stage, r, i)
+					continue
+				}
+				if assert.NoError(t, res.Err, "%s: round=%d key=%d", stage, r, i) {
+					assert.Equal(t, int64(r*1000+i), res.Value.V, "%s: round=%d key=%d", stage, r, i)
+				}
+			}
+			res := db.GetAt(counter, ts)
+			if assert.NoError(t, res.Err, "%s: round=%d counter", stage, r) {
+				assert.Equal(t, int64(r+1), res.Value.V, "%s: round=%d counter", stage, r)
+			}
+		}
+		assert.Error(t, db.GetAt(types.IntKey{K: 0}, before).Err, "%s: key is read before it's written", stage)
+		res := db.Get(types.IntKey{K: 0})
+		if assert.NoError(t, res.Err, stage) {
+			assert.Equal(t, int64((rounds-1)*1000), res.Value.V, stage)
+		}
+	}
+
+	check("memtable")
+	assert.NoError(t, db.Flush(true))
+	check("level-0")
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: keys + 1}))
+	check("compacted")
+	db.Close()
+
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	check("reopened")
+	db.Close()
+
+	// versions overwritten before horizon are dropped by compaction, newest
+	// of them stays readable
+	dir = t.TempDir()
+	opts = counterOpts(dir)
+	opts.Retention = time.Second
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	key := types.IntKey{K: 0}
+	for r := range 3 {
+		assert.NoError(t, db.Put(key, &types.Int64Value{V: int64(r)}).Err)
+		stamps[r] = time.Now()
+		assert.NoError(t, db.Flush(true))
+	}
+	time.Sleep(1100 * time.Millisecond)
+	horizon := time.Now()
+	assert.NoError(t, db.Put(key, &types.Int64Value{V: 3}).Err)
+	assert.NoError(t, db.Flush(true))
+
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: 1}))
+	if res := db.GetAt(key, horizon); assert.NoError(t, res.Err) {
+		assert.Equal(t, int64(2), res.Value.V)
+	}
+	assert.Equal(t, int64(3), db.Get(key).Value.V)
+
+	// reads past horizon fail rather than return whichever version survived
+	for _, ts := range stamps[:3] {
+		res := db.GetAt(key, ts)
+		assert.IsType(t, errors.RetentionErr(""), res.Err)
+	}
+	db.Close()
+
+	// without retention only newest version is kept & nothing is read as of
+	// a time
+	opts = counterOpts(t.TempDir())
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	assert.NoError(t, db.Put(key, &types.Int64Value{V: 1}).Err)
+	assert.IsType(t, errors.RetentionErr(""), db.GetAt(key, time.Now()).Err)
+	assert.Equal(t, int64(1), db.Get(key).Value.V)
+	db.Close()
+}
+
+// TestStorage_GetAt_DeleteRange verifies that keys deleted by a range
+// tombstone are still read as of times before it, while range shadows older
+// memtables & tables as well as versions in its own memtable.
+func TestStorage_GetAt_DeleteRange(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.Retention = time.Hour
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+
+	keys := 64
+	start, end := types.IntKey{K: 16}, types.IntKey{K: 48}
+	stamps := make([]time.Time, 4)
+	for i := range keys {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+	stamps[0] = time.Now()
+	assert.NoError(t, db.Flush(true))
+
+	// overwritten in memtable range tombstone is written to
+	assert.NoError(t, db.Put(types.IntKey{K: 40}, &types.Int64Value{V: 1040}).Err)
+	stamps[1] = time.Now()
+	assert.NoError(t, db.DeleteRange(start, end).Err)
+	stamps[2] = time.Now()
+	assert.NoError(t, db.Put(types.IntKey{K: 20}, &types.Int64Value{V: 2020}).Err)
+	stamps[3] = time.Now()
+
+	// value of key i as of stamps[r], -1 if it's deleted
+	want := func(r int, i int) int64 {
+		switch {
+		case r >= 3 && i == 20:
+			return 2020
+		case r >= 2 && i >= start.K && i < end.K:
+			return -1
+		case r >= 1 && i == 40:
+			return 1040
+		}
+		return int64(i)
+	}
+
+	check := func(stage string) {
+		for r, ts := range stamps {
+			for i := range keys {
+				res := db.GetAt(types.IntKey{K: i}, ts)
+				if want(r, i) < 0 {
+					assert.Error(t, res.Err, "%s: round=%d key=%d", stage, r, i)
+				} else if assert.NoError(t, res.Err, "%s: round=%d key=%d", stage, r, i) {
+					assert.Equal(t, want(r, i), res.Value.V, "%s: round=%d key=%d", stage, r, i)
+				}
+			}
+		}
+		for i := range keys {
+			res := db.Get(types.IntKey{K: i})
+			if want(len(stamps), i) < 0 {
+				assert.Error(t, res.Err, "%s: key=%d", stage, i)
+			} else if assert.NoError(t, res.Err, "%s: key=%d", stage, i) {
+				assert.Equal(t, want(len(stamps), i), res.Value.V, "%s: key=%d", stage, i)
+			}
+		}
+	}
+
+	check("memtable")
+	assert.NoError(t, db.Flush(true))
+	check("level-0")
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: keys}))
+	check("compacted")
+	db.Close()
+
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	check("reopened")
+	db.Close()
+
+	// versions deleted before horizon are dropped by compaction
+	opts = counterOpts(t.TempDir())
+	opts.Retention = time.Second
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	key := types.IntKey{K: 20}
+	assert.NoError(t, db.Put(key, &types.Int64Value{V: 1}).Err)
+	assert.NoError(t, db.Flush(true))
+	assert.NoError(t, db.DeleteRange(start, end).Err)
+	assert.NoError(t, db.Flush(true))
+	time.Sleep(1100 * time.Millisecond)
+
+	assert.NoError(t, db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: keys}))
+	assert.Error(t, db.Get(key).Err)
+	assert.Error(t, db.GetAt(key, time.Now()).Err)
+	db.Close()
+}
+
+// watch collects n changes of family logged after from, failing once they
+// don't show up in time
+func watch(t *testing.T, db *parrot.Storage[types.IntKey, *types.Int64Value], family string, from parrot.ChangePosition, n int) []parrot.Change[types.IntKey, *types.Int64Value] {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var changes []parrot.Change[types.IntKey, *types.Int64Value]
+	err := db.Subscribe(ctx, family, from, func(c parrot.Change[types.IntKey, *types.Int64Value]) error {
+		changes = append(changes, c)
+		if len(changes) == n {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled, "got %d of %d changes", len(changes), n)
+	return changes
+}
+
+// TestStorage_Subscribe verifies that changes of a family are read back from
+// commit log in commit order across segments, that subscribers resume after a
+// position, also after reopen, and that positions of dropped segments fail.
+func TestStorage_Subscribe(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts := counterOpts(dir)
+	opts.ChangeRetention = time.Hour
+
+	db := parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	docs, err := db.Family("docs")
+	assert.NoError(t, err)
+
+	// memtables overflow every few dozen writes, so changes span segments
+	total := 512
+	for i := range total {
+		switch {
+		case i%64 == 63:
+			batch := parrot.NewWriteBatch[types.IntKey, *types.Int64Value]().
+				Put("test", types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).
+				DeleteRange("docs", types.IntKey{K: i - 8}, types.IntKey{K: i})
+			assert.NoError(t, db.Write(batch).Err)
+		case i%8 == 7:
+			assert.NoError(t, docs.Delete(types.IntKey{K: i - 1}, &types.Int64Value{}).Err)
+		default:
+			assert.NoError(t, docs.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+		}
+		// writes of other families are not changes of docs
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.Int64Value{V: int64(i)}).Err)
+	}
+
+	changes := watch(t, db, "docs", parrot.ChangePosition{}, total)
+	for i, c := range changes {
+		if i > 0 {
+			assert.True(t, changes[i-1].Position.Less(c.Position), "changes must be in commit order")
+		}
+		assert.NotZero(t, c.Time)
+		switch {
+		case i%64 == 63:
+			assert.Equal(t, memtable.DeleteRangeOperation, c.Op)
+			assert.Equal(t, types.IntKey{K: i - 8}, c.Key)
+			assert.Equal(t, types.IntKey{K: i}, c.End)
+		case i%8 == 7:
+			assert.Equal(t, memtable.DeleteOperation, c.Op)
+			assert.Equal(t, types.IntKey{K: i - 1}, c.Key)
+		default:
+			assert.Equal(t, memtable.WriteOperation, c.Op)
+			assert.Equal(t, types.IntKey{K: i}, c.Key)
+			assert.Equal(t, int64(i), c.Value.V)
+		}
+	}
+	assert.Greater(t, changes[total-1].Position.Log.Segment, changes[0].Position.Log.Segment, "changes must span segments")
+
+	// resuming after a position, from a parsed token
+	mid, err := parrot.ParseChangePosition(changes[total/2].Position.String())
+	assert.NoError(t, err)
+	assert.Equal(t, changes[total/2].Position, mid)
+	rest := watch(t, db, "docs", mid, total/2-1)
+	assert.Equal(t, changes[total/2+1:], rest)
+
+	// only changes written after head are read from it
+	head, err := db.ChangeHead()
+	assert.NoError(t, err)
+	assert.NoError(t, docs.Put(types.IntKey{K: total}, &types.Int64Value{V: 1}).Err)
+	after := watch(t, db, "docs", head, 1)
+	assert.Equal(t, types.IntKey{K: total}, after[0].Key)
+
+	// flushed segments are kept for ChangeRetention & positions survive reopen
+	assert.NoError(t, db.Flush(true))
+	db.Close()
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	docs, err = db.Family("docs")
+	assert.NoError(t, err)
+	assert.NoError(t, docs.Put(types.IntKey{K: total + 1}, &types.Int64Value{V: 1}).Err)
+
+	all := watch(t, db, "docs", parrot.ChangePosition{}, total+2)
+	assert.Equal(t, changes, all[:total])
+	resumed := watch(t, db, "docs", after[0].Position, 1)
+	assert.Equal(t, types.IntKey{K: total + 1}, resumed[0].Key)
+
+	// a live subscriber reads changes as they're appended, poll by poll
+	head, err = db.ChangeHead()
+	assert.NoError(t, err)
+	live := make(chan []parrot.Change[types.IntKey, *types.Int64Value], 1)
+	go func() { live <- watch(t, db, "docs", head, 64) }()
+	for i := range 64 {
+		assert.NoError(t, docs.Put(types.IntKey{K: total + 2 + i}, &types.Int64Value{V: 1}).Err)
+		time.Sleep(time.Millisecond)
+	}
+	for i, c := range <-live {
+		assert.Equal(t, types.IntKey{K: total + 2 + i}, c.Key)
+	}
+
+	// positions of segments never logged fail right away
+	unknown := after[0].Position
+	unknown.Log.Segment = 1
+	err = db.Subscribe(ctx, "docs", unknown, func(parrot.Change[types.IntKey, *types.Int64Value]) error {
+		return nil
+	})
+	var changeErr errors.ChangeLogErr
+	assert.ErrorAs(t, err, &changeErr)
+	db.Close()
+
+	// without retention flushed segments are dropped & positions in them fail
+	opts.ChangeRetention = 0
+	db = parrot.NewStorage[types.IntKey, *types.Int64Value]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	assert.NoError(t, db.Flush(true))
+	assert.Eventually(t, func() bool {
+		err := db.Subscribe(ctx, "docs", changes[0].Position, func(parrot.Change[types.IntKey, *types.Int64Value]) error {
+			return fmt.Errorf("change of dropped segment")
+		})
+		var changeErr errors.ChangeLogErr
+		return stderrors.As(err, &changeErr)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	_, err = parrot.ParseChangePosition("12.x.3")
+	assert.Error(t, err)
+}
+
+// TestStorage_Encryption_PerStorage verifies that storages on same VFS keep
+// keys of their own, opening one doesn't change how another's files are
+// written or read.
+func TestStorage_Encryption_PerStorage(t *testing.T) {
+	log.Disable()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	rings := map[string]*io.Keyring{"k1": io.NewKeyring(), "k2": io.NewKeyring(), "": nil}
+	assert.NoError(t, rings["k1"].Add("k1", bytes.Repeat([]byte{1}, 32)))
+	assert.NoError(t, rings["k2"].Add("k2", bytes.Repeat([]byte{2}, 32)))
+
+	dirs := map[string]string{}
+	open := func(keyID string) *parrot.Storage[types.StringKey, *types.StringValue] {
+		opts := counterOpts(dirs[keyID])
+		opts.MergeOperator = nil
+		opts.MemtableThreshold = 1 << 20
+		opts.Level0MaxSizeInBytes = 1 << 30
+		opts.Keyring = rings[keyID]
+		return parrot.NewStorage[types.StringKey, *types.StringValue]("test", ctx, opts)
+	}
+
+	// all are open at once, each opened after the previous one wrote
+	dbs := map[string]*parrot.Storage[types.StringKey, *types.StringValue]{}
+	for _, keyID := range []string{"k1", "k2", ""} {
+		dirs[keyID] = t.TempDir()
+		dbs[keyID] = open(keyID)
+		v := types.StringValue{V: "secret-" + keyID}
+		assert.NoError(t, dbs[keyID].Put(types.StringKey{K: "key"}, &v).Err)
+	}
+	for _, db := range dbs {
+		assert.NoError(t, db.Flush(true))
+		db.Close()
+	}
+
+	for keyID, dir := range dirs {
+		tables, err := filepath.Glob(filepath.Join(dir, "test", "level-*", "*.db"))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tables)
+		for _, path := range tables {
+			got, err := io.GetFileManager().KeyID(path)
+			assert.NoError(t, err)
+			assert.Equal(t, keyID, got, path)
+		}
+
+		db := open(keyID)
+		readRes := db.Get(types.StringKey{K: "key"})
+		if assert.NoError(t, readRes.Err, "key=%s", keyID) {
+			assert.Equal(t, "secret-"+keyID, readRes.Value.V)
+		}
+		db.Close()
+	}
+}
+
+// TestStorage_Encryption_ValueLog verifies that values kept in value log are
+// encrypted at rest like tables & read back once storage is reopened.
+func TestStorage_Encryption_ValueLog(t *testing.T) {
+	log.Disable()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ring := io.NewKeyring()
+	assert.NoError(t, ring.Add("k1", bytes.Repeat([]byte{1}, 32)))
+	opts := valueLogOpts(dir)
+	opts.Keyring = ring
+	db := parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+
+	value := func(i int) string {
+		return fmt.Sprintf("%04d-secret-%s", i, strings.Repeat("x", 512))
+	}
+	n := 64
+	for i := range n {
+		assert.NoError(t, db.Put(types.IntKey{K: i}, &types.StringValue{V: value(i)}).Err)
+	}
+	assert.NoError(t, db.Flush(true))
+	for i := range n {
+		readRes := db.Get(types.IntKey{K: i})
+		if assert.NoError(t, readRes.Err, "key=%d", i) {
+			assert.Equal(t, value(i), readRes.Value.V)
+		}
+	}
+	db.Close()
+
+	segments, err := filepath.Glob(filepath.Join(dir, "vlog", "vlog-*.log"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, segments)
+	for _, path := range segments {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.False(t, bytes.Contains(data, []byte("secret")), "plaintext found in %s", path)
+		keyID, err := io.GetFileManager().KeyID(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", keyID, path)
+	}
+
+	db = parrot.NewStorage[types.IntKey, *types.StringValue]("test", ctx, opts)
+	t.Cleanup(db.Close)
+	for i := range n {
+		readRes := db.Get(types.IntKey{K: i})
+		if assert.NoError(t, readRes.Err, "key=%d", i) {
+			assert.Equal(t, value(i), readRes.Value.V)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package torture_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math/rand"
+	"testing"
+	"time"
+
+	parrot "github.com/nagarajRPoojari/orange/parrot"
+	"github.com/nagarajRPoojari/orange/parrot/conf"
+	perrors "github.com/nagarajRPoojari/orange/parrot/errors"
+	"github.com/nagarajRPoojari/orange/parrot/io"
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/nagarajRPoojari/orange/parrot/utils/log"
+)
+
+var (
+	seed = flag.Int64("torture.seed", 1, "seed of torture run")
+	soak = flag.Duration("torture.soak", 0, "keep running torture with new seeds for given duration")
+)
+
+const (
+	dir = "/db"
+
+	keySpace = 512
+	// crashes per run
+	rounds = 10
+	// ops run against storage between crashes, unless crash comes first
+	opsPerRound = 3000
+)
+
+func tortureOpts(fs io.VFS) parrot.StorageOpts {
+	return parrot.StorageOpts{
+		Directory:                     dir,
+		VFS:                           fs,
+		MemtableThreshold:             1024 * 2,
+		TurnOnMemtableWal:             true,
+		FlushTimeInterval:             10 * time.Millisecond,
+		MemtableWALTimeInterval:       conf.DefaultWALTimeInterval,
+		MemtableWALEventChSize:        conf.DefaultWALEventBufferSize,
+		MemtableWALWriterBufferSize:   conf.DefaultWALEventBufferSize,
+		TurnOnCompaction:              true,
+		CompactionTimeInterval:        10 * time.Millisecond,
+		CompactionWALTimeInterval:     conf.DefaultWALTimeInterval,
+		CompactionWALEventChSize:      conf.DefaultWALEventBufferSize,
+		CompactionWALWriterBufferSize: conf.DefaultWriterBufferSize,
+		Level0MaxSizeInBytes:          1024 * 2,
+		MaxSizeInBytesGrowthFactor:    2,
+	}
+}
+
+// absent stands for a deleted or never written key, written values are
+// never negative
+const absent int32 = -1
+
+// model tracks states a key may be found in after crash: its last
+// acknowledged state, i.e synced before crash, or any state written since
+type model struct {
+	acked   map[int]int32
+	pending map[int][]int32
+}
+
+func newModel() *model {
+	return &model{acked: map[int]int32{}, pending: map[int][]int32{}}
+}
+
+func (t *model) write(key int, value int32) {
+	t.pending[key] = append(t.pending[key], value)
+}
+
+// ack marks writes so far as acknowledged
+func (t *model) ack() {
+	for key, values := range t.pending {
+		t.acked[key] = values[len(values)-1]
+	}
+	clear(t.pending)
+}
+
+func (t *model) get(key int) int32 {
+	if value, ok := t.acked[key]; ok {
+		return value
+	}
+	return absent
+}
+
+func (t *model) allowed(key int, value int32) bool {
+	if value == t.get(key) {
+		return true
+	}
+	for _, v := range t.pending[key] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks every key of reopened storage against model & takes found
+// state as acknowledged, it's durable already
+func verify(t *testing.T, db *parrot.Storage[types.IntKey, *types.IntValue], m *model, seed int64, round int) {
+	t.Helper()
+	for key := range keySpace {
+		got := absent
+		res := db.Get(types.IntKey{K: key})
+		var notFound perrors.KeyNotFoundErr
+		switch {
+		case errors.As(res.Err, &notFound):
+		case res.Err != nil:
+			t.Fatalf("seed=%d round=%d: failed to read key=%d, err=%v", seed, round, key, res.Err)
+		default:
+			got = res.Value.V
+		}
+
+		if !m.allowed(key, got) {
+			t.Errorf("seed=%d round=%d: key=%d is %d, acknowledged %d, written since %v", seed, round, key, got, m.get(key), m.pending[key])
+		}
+		if got == absent {
+			delete(m.acked, key)
+		} else {
+			m.acked[key] = got
+		}
+	}
+	clear(m.pending)
+}
+
+// torture runs rounds of random puts & deletes, each ended by a crash at a
+// random filesystem op, e.g mid-flush, mid-compaction or mid-manifest sync.
+// Storage is reopened on files as crash left them & checked to hold every
+// acknowledged write.
+func torture(t *testing.T, seed int64, rounds int) {
+	rng := rand.New(rand.NewSource(seed))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var image io.VFS = io.NewMemFS()
+	m := newModel()
+	next := int32(0)
+	// crash points are picked among ops a round takes
+	roundOps := int64(4000)
+
+	for round := range rounds {
+		fs := io.NewFaultFS(image)
+		db := parrot.NewStorage[types.IntKey, *types.IntValue]("test", ctx, tortureOpts(fs))
+		verify(t, db, m, seed, round)
+		if t.Failed() {
+			db.Close()
+			return
+		}
+
+		start := fs.Ops()
+		fs.CrashAfter(1+rng.Int63n(roundOps+roundOps/4), dir)
+		untilSync := 1 + rng.Intn(64)
+		for range opsPerRound {
+			if crashed, _ := fs.Crashed(); crashed != nil {
+				break
+			}
+
+			key := types.IntKey{K: rng.Intn(keySpace)}
+			switch p := rng.Intn(100); {
+			case p < 70:
+				next++
+				if res := db.Put(key, &types.IntValue{V: next}); res.Err != nil {
+					t.Fatalf("seed=%d round=%d: failed to put, err=%v", seed, round, res.Err)
+				}
+				m.write(key.K, next)
+			case p < 95:
+				if res := db.Delete(key, &types.IntValue{}); res.Err != nil {
+					t.Fatalf("seed=%d round=%d: failed to delete, err=%v", seed, round, res.Err)
+				}
+				m.write(key.K, absent)
+			case p < 98:
+				db.Flush(rng.Intn(2) == 0)
+			default:
+				db.CompactRange(types.IntKey{K: 0}, types.IntKey{K: keySpace})
+			}
+
+			if untilSync--; untilSync == 0 {
+				untilSync = 1 + rng.Intn(64)
+				err := db.Sync()
+				// writes synced before crash point are acknowledged
+				if crashed, _ := fs.Crashed(); err == nil && crashed == nil {
+					m.ack()
+				}
+			}
+		}
+
+		crashed, err := fs.Crashed()
+		if crashed == nil && err == nil {
+			crashed, err = fs.Durable(dir)
+			roundOps = fs.Ops() - start
+		}
+		if err != nil {
+			t.Fatalf("seed=%d round=%d: failed to capture crash, err=%v", seed, round, err)
+		}
+		db.Close()
+		image = crashed
+	}
+
+	fs := io.NewFaultFS(image)
+	db := parrot.NewStorage[types.IntKey, *types.IntValue]("test", ctx, tortureOpts(fs))
+	defer db.Close()
+	verify(t, db, m, seed, rounds)
+}
+
+func TestTorture(t *testing.T) {
+	log.Disable()
+	torture(t, *seed, rounds)
+}
+
+// TestTorture_Soak runs torture with a new seed after another until
+// -torture.soak elapses, e.g go test ./test/torture -run Soak -torture.soak=10m
+func TestTorture_Soak(t *testing.T) {
+	if *soak == 0 {
+		t.Skip("soak is off, see -torture.soak")
+	}
+	log.Disable()
+
+	deadline := time.Now().Add(*soak)
+	for s := *seed; time.Now().Before(deadline) && !t.Failed(); s++ {
+		t.Logf("seed=%d", s)
+		torture(t, s, rounds)
+	}
+}
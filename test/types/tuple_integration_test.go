@@ -0,0 +1,94 @@
+// Copyright (c) 2025 Nagaraj Poojari
+// SPDX-License-Identifier: MIT
+//
+// This file is part of: github.com/nagarajRPoojari/parrot
+// Licensed under the MIT License.
+
+package types_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nagarajRPoojari/orange/parrot/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuple_RoundTrip(t *testing.T) {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	key, err := types.EncodeTuple("tenant\x00a", int64(-42), ts, []byte{0x00, 0xff}, 7)
+	assert.NoError(t, err)
+
+	elems, err := types.DecodeTuple(key)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"tenant\x00a", int64(-42), ts, []byte{0x00, 0xff}, int64(7)}, elems)
+
+	_, err = types.EncodeTuple(3.14)
+	assert.Error(t, err)
+
+	_, err = types.DecodeTuple(types.NewBytesKey([]byte{0x02, 'a'}))
+	assert.Error(t, err)
+}
+
+func TestTuple_Order(t *testing.T) {
+	// expected order
+	tuples := [][]any{
+		{"a"},
+		{"a", int64(-5)},
+		{"a", int64(0)},
+		{"a", int64(3), "x"},
+		{"a", int64(3), "y"},
+		{"a\x00"},
+		{"a\x00b"},
+		{"ab"},
+		{"b", time.Unix(0, -1)},
+		{"b", time.Unix(10, 0)},
+	}
+
+	keys := make([]types.BytesKey, len(tuples))
+	for i, tuple := range tuples {
+		keys[len(tuples)-1-i] = types.MustEncodeTuple(tuple...)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+
+	for i, tuple := range tuples {
+		assert.Equal(t, types.MustEncodeTuple(tuple...), keys[i], "tuple=%v", tuple)
+	}
+}
+
+func TestTuple_Prefix(t *testing.T) {
+	bytewise := types.OrderOf[types.BytesKey](nil)
+	r := types.Prefix(types.MustEncodeTuple("a", int64(3)))
+
+	assert.True(t, r.Contains(bytewise, types.MustEncodeTuple("a", int64(3))))
+	assert.True(t, r.Contains(bytewise, types.MustEncodeTuple("a", int64(3), "z")))
+	assert.False(t, r.Contains(bytewise, types.MustEncodeTuple("a", int64(4))))
+	assert.False(t, r.Contains(bytewise, types.MustEncodeTuple("a")))
+
+	// no upper bound exists for prefix of 0xff bytes
+	r = types.Prefix(types.NewBytesKey([]byte{0xff, 0xff}))
+	assert.False(t, r.HasEnd)
+	assert.True(t, r.Contains(bytewise, types.NewBytesKey([]byte{0xff, 0xff, 0xff})))
+}
+
+func TestComparator_Registry(t *testing.T) {
+	a, b := types.NewBytesKey([]byte("a")), types.NewBytesKey([]byte("b"))
+	assert.True(t, a.Less(b))
+	assert.True(t, types.OrderOf[types.BytesKey](nil)(a, b))
+
+	reverse, err := types.LookupComparator(types.ReverseBytewiseComparator.Name())
+	assert.NoError(t, err)
+	assert.True(t, types.OrderOf[types.BytesKey](reverse)(b, a))
+	// order of other keys isn't affected
+	assert.True(t, types.OrderOf[types.IntKey](reverse)(types.IntKey{K: 1}, types.IntKey{K: 2}))
+
+	// keys are compared in place
+	x, y := types.MustEncodeTuple("acme", int64(1)), types.MustEncodeTuple("acme", int64(2))
+	less := types.OrderOf[types.BytesKey](reverse)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { less(x, y) }))
+
+	_, err = types.LookupComparator("unknown")
+	assert.Error(t, err)
+	assert.Error(t, types.RegisterComparator(types.BytewiseComparator))
+}
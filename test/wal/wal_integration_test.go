@@ -12,9 +12,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/nagarajRPoojari/orange/parrot/codec"
 	"github.com/nagarajRPoojari/orange/parrot/conf"
 	"github.com/nagarajRPoojari/orange/parrot/wal"
 	"github.com/stretchr/testify/assert"
@@ -44,12 +46,87 @@ func TestWAL_Write(t *testing.T) {
 	data, err := os.ReadFile(logFile)
 	assert.NoError(t, err)
 
+	// header names codec, events follow as size prefixed records
+	id, data, ok := codec.ParseHeader(data)
+	assert.True(t, ok)
+	assert.Equal(t, codec.GobID, id)
+
+	record, _, ok := codec.ReadFrame(data)
+	assert.True(t, ok)
+
 	var decodedEvent event
-	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&decodedEvent)
+	err = gob.NewDecoder(bytes.NewReader(record)).Decode(&decodedEvent)
 	assert.NoError(t, err)
 	assert.Equal(t, decodedEvent, testEvent)
 }
 
+// TestWAL_Replay_Legacy verifies that logs written as a plain gob stream,
+// before logs had headers, are still replayed
+func TestWAL_Replay_Legacy(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	testEvents := []event{{Data: "a"}, {Data: "b"}}
+	for _, ev := range testEvents {
+		assert.NoError(t, encoder.Encode(ev))
+	}
+	assert.NoError(t, os.WriteFile(logFile, buf.Bytes(), 0644))
+
+	events, err := wal.Replay[event](nil, logFile)
+	assert.NoError(t, err)
+	assert.Equal(t, testEvents, events)
+}
+
+type upperCodec struct{}
+
+func (upperCodec) ID() byte { return 200 }
+
+func (upperCodec) Encode(buf []byte, ev event) ([]byte, error) {
+	return append(buf, strings.ToUpper(ev.Data)...), nil
+}
+
+func (upperCodec) Decode(data []byte) (event, error) {
+	return event{Data: string(data)}, nil
+}
+
+// TestWAL_Codec verifies that a log is replayed with codec named by its
+// header & reopening it with another codec fails
+func TestWAL_Codec(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	opts := wal.WALOpts{
+		Path:             logFile,
+		TimeInterval:     conf.DefaultWALTimeInterval,
+		EventChSize:      conf.DefaultWALEventBufferSize,
+		WriterBufferSize: conf.DefaultWriterBufferSize,
+	}
+
+	wl, err := wal.NewWALWithCodec[event](opts, upperCodec{})
+	assert.NoError(t, err)
+	wl.Append(event{Data: "a"})
+	wl.Append(event{Data: "b"})
+	wl.Close()
+
+	// a torn record at tail is dropped
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	f.Write([]byte{5, 'x'})
+	f.Close()
+
+	events, err := wal.ReplayWithCodec(nil, logFile, func(id byte) (wal.Codec[event], error) {
+		assert.Equal(t, byte(200), id)
+		return upperCodec{}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []event{{Data: "A"}, {Data: "B"}}, events)
+
+	_, err = wal.Replay[event](nil, logFile)
+	assert.Error(t, err)
+
+	_, err = wal.NewWAL[event](opts)
+	assert.Error(t, err)
+}
+
 func TestWAL_Replay(t *testing.T) {
 	logFile := filepath.Join(t.TempDir(), "test.log")
 	wl, err := wal.NewWAL[event](
@@ -71,7 +148,56 @@ func TestWAL_Replay(t *testing.T) {
 
 	time.Sleep(10 * time.Millisecond)
 
-	events, err := wal.Replay[event](logFile)
+	events, err := wal.Replay[event](nil, logFile)
 	assert.NoError(t, err)
 	assert.Equal(t, testEvents, events)
 }
+
+type pickyCodec struct{ upperCodec }
+
+func (pickyCodec) Decode(data []byte) (event, error) {
+	if string(data) == "BAD" {
+		return event{}, fmt.Errorf("bad event")
+	}
+	return event{Data: string(data)}, nil
+}
+
+// TestWAL_Inspect verifies that inspecting a log reports records failing to
+// decode & goes on past them, while replay stops at them
+func TestWAL_Inspect(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	wl, err := wal.NewWALWithCodec[event](wal.WALOpts{
+		Path:             logFile,
+		TimeInterval:     conf.DefaultWALTimeInterval,
+		EventChSize:      conf.DefaultWALEventBufferSize,
+		WriterBufferSize: conf.DefaultWriterBufferSize,
+	}, pickyCodec{})
+	assert.NoError(t, err)
+	for _, data := range []string{"a", "bad", "c"} {
+		wl.Append(event{Data: data})
+	}
+	wl.Close()
+
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	f.Write([]byte{5, 'x'})
+	f.Close()
+
+	resolve := func(id byte) (wal.Codec[event], error) {
+		return pickyCodec{}, nil
+	}
+	records, err := wal.Inspect(nil, logFile, resolve)
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+
+	assert.NoError(t, records[0].Err)
+	assert.Equal(t, int64(codec.HeaderSize), records[0].Offset)
+	assert.Error(t, records[1].Err)
+	assert.NoError(t, records[2].Err)
+	assert.Equal(t, event{Data: "C"}, records[2].Event)
+	assert.Error(t, records[3].Err)
+
+	events, err := wal.ReplayWithCodec(nil, logFile, resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, []event{{Data: "A"}}, events)
+}